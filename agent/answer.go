@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"encoding/json"
+	"strings"
+
+	"go-manus/schema"
+)
+
+// AnswerMode 决定 Run 返回的最终结果形式
+type AnswerMode string
+
+const (
+	// AnswerModeAuto 存在 create_chat_completion 输出时返回该输出，否则返回步骤日志
+	AnswerModeAuto AnswerMode = ""
+	// AnswerModeSteps 返回 "Step N: ..." 形式的步骤日志
+	AnswerModeSteps AnswerMode = "steps"
+	// AnswerModeFinal 依次尝试 create_chat_completion 的输出、调用 terminate 时的助手消息和最后一条助手消息
+	AnswerModeFinal AnswerMode = "final"
+	// AnswerModeLastMessage 返回最后一条有内容的助手消息
+	AnswerModeLastMessage AnswerMode = "last_message"
+)
+
+// extractAnswer 按 AnswerMode 从本次运行新增的消息中提取最终回答，找不到时返回步骤日志；
+// added 为运行开始时的 Memory.Added()，之前运行留下的消息不参与提取
+func (a *BaseAgent) extractAnswer(stepLog string, added int) string {
+	a.mu.RLock()
+	messages := a.Memory.MessagesSince(added)
+	a.mu.RUnlock()
+
+	switch a.AnswerMode {
+	case AnswerModeSteps:
+		return stepLog
+	case AnswerModeFinal:
+		if answer, ok := finalToolAnswer(messages, true); ok {
+			return answer
+		}
+		if answer, ok := lastAssistantContent(messages); ok {
+			return answer
+		}
+	case AnswerModeLastMessage:
+		if answer, ok := lastAssistantContent(messages); ok {
+			return answer
+		}
+	default:
+		if answer, ok := finalToolAnswer(messages, false); ok {
+			return answer
+		}
+	}
+	return stepLog
+}
+
+// finalToolAnswer 查找最近一次 create_chat_completion 调用的 response 参数，
+// 没有时若 includeTerminate 为 true 则返回调用 terminate 的助手消息内容
+func finalToolAnswer(messages []schema.Message, includeTerminate bool) (string, bool) {
+	for i := len(messages) - 1; i >= 0; i-- {
+		for _, call := range assistantToolCalls(messages[i], "create_chat_completion") {
+			var args struct {
+				Response string `json:"response"`
+			}
+			if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err == nil && strings.TrimSpace(args.Response) != "" {
+				return args.Response, true
+			}
+		}
+	}
+
+	if !includeTerminate {
+		return "", false
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if len(assistantToolCalls(msg, "terminate")) > 0 && msg.Content != nil && strings.TrimSpace(*msg.Content) != "" {
+			return *msg.Content, true
+		}
+	}
+	return "", false
+}
+
+// assistantToolCalls 返回助手消息中调用指定工具的调用，按从后到前的顺序
+func assistantToolCalls(msg schema.Message, name string) []schema.ToolCall {
+	if msg.Role != schema.RoleAssistant {
+		return nil
+	}
+	calls := make([]schema.ToolCall, 0)
+	for i := len(msg.ToolCalls) - 1; i >= 0; i-- {
+		if msg.ToolCalls[i].Function.Name == name {
+			calls = append(calls, msg.ToolCalls[i])
+		}
+	}
+	return calls
+}
+
+// lastAssistantContent 返回最后一条有内容的助手消息
+func lastAssistantContent(messages []schema.Message) (string, bool) {
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if msg.Role == schema.RoleAssistant && msg.Content != nil && strings.TrimSpace(*msg.Content) != "" {
+			return *msg.Content, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,39 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go-manus/tool"
+)
+
+func TestRunReturnsCreateChatCompletionAnswer(t *testing.T) {
+	fake := newFakeLLM(
+		toolCallsReply("create_chat_completion", `{"response": "The answer is 42."}`, "terminate", `{"status": "success"}`),
+		toolCallReply("call_2", "terminate", `{"status": "success"}`),
+	)
+	a := NewToolCallAgent("answer")
+	a.AvailableTools.AddTool(tool.NewCreateChatCompletion())
+	a.LLM.SetProvider(fake)
+
+	answer, err := a.Run(context.Background(), "what is the answer?")
+	if err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	if answer != "The answer is 42." {
+		t.Errorf("first answer = %q, want the create_chat_completion response", answer)
+	}
+
+	// 第二次运行没有调用 create_chat_completion，不能返回上一次的回答
+	answer, err = a.Run(context.Background(), "now just finish")
+	if err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if strings.Contains(answer, "The answer is 42.") {
+		t.Errorf("second answer reuses the previous run's answer: %q", answer)
+	}
+	if !strings.HasPrefix(answer, "Step 1:") {
+		t.Errorf("second answer = %q, want the step log", answer)
+	}
+}
@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
+	"go-manus/config"
 	"go-manus/llm"
 	"go-manus/logger"
 	"go-manus/schema"
+	"go-manus/telemetry"
 )
 
 // BaseAgent Agent 基础结构
@@ -18,6 +21,10 @@ type BaseAgent struct {
 
 	SystemPrompt    string
 	NextStepPrompt string
+	// AssistantName 助手名称，来自 [agent] name，Manus 等 Agent 构造时写入 SystemPrompt
+	AssistantName string
+	// Persona 人设说明，来自 [agent] persona，非空时附加在系统提示词之后
+	Persona string
 
 	LLM    *llm.Client
 	Memory *schema.Memory
@@ -26,22 +33,62 @@ type BaseAgent struct {
 	MaxSteps     int
 	CurrentStep  int
 	DuplicateThreshold int
+	// MaxDuration 单次 Run 的最长运行时间，超时后停止并返回已有结果，0 表示不限制
+	MaxDuration time.Duration
+	// AnswerMode 最终结果的提取方式，默认优先返回 create_chat_completion 的输出
+	AnswerMode AnswerMode
+	// MaxInputLength 用户请求的最大字符数，0 表示不限制；超出时按 InputOverflow 处理
+	MaxInputLength int
+	// InputOverflow 请求超长时拒绝执行还是先压缩，默认拒绝
+	InputOverflow InputOverflow
+
+	// InputGuard 在执行前检查用户请求，返回错误时拒绝执行，nil 时不做处理
+	InputGuard func(ctx context.Context, request string) error
+	// OutputGuard 在 Run 返回前检查最终回答，可以脱敏、改写或通过返回错误拒绝，nil 时不做处理
+	OutputGuard func(ctx context.Context, answer string) (string, error)
+
+	// pinnedTask 当前固定的任务消息的内容指针，下一次运行时据此取消固定
+	pinnedTask *string
+
+	// stepper 实现单步执行的子类，由 ReActAgent 等在构造时设置
+	stepper Stepper
 
 	mu sync.RWMutex
 }
 
+// Stepper 实现单步执行的 Agent，BaseAgent 的运行循环通过它调用子类的 Step
+type Stepper interface {
+	Step(ctx context.Context) (string, error)
+}
+
 // NewBaseAgent 创建基础 Agent
 func NewBaseAgent(name string) *BaseAgent {
+	settings := config.GetInstance().GetAgent()
 	return &BaseAgent{
-		Name:        name,
-		LLM:         llm.NewClient("default"),
-		Memory:      schema.NewMemory(),
-		State:       schema.AgentStateIDLE,
-		MaxSteps:    10,
+		Name:               name,
+		AssistantName:      settings.Name,
+		Persona:            settings.Persona,
+		LLM:                llm.NewClient("default"),
+		Memory:             schema.NewMemory(),
+		State:              schema.AgentStateIDLE,
+		MaxSteps:           10,
 		DuplicateThreshold: 2,
 	}
 }
 
+// ComposedSystemPrompt 返回发送给 LLM 的系统提示词：SystemPrompt 后附加 Persona
+func (a *BaseAgent) ComposedSystemPrompt() string {
+	persona := strings.TrimSpace(a.Persona)
+	switch {
+	case persona == "":
+		return a.SystemPrompt
+	case a.SystemPrompt == "":
+		return persona
+	default:
+		return a.SystemPrompt + "\n\n" + persona
+	}
+}
+
 // UpdateMemory 更新记忆
 func (a *BaseAgent) UpdateMemory(role schema.MessageRole, content string, toolCallID ...string) {
 	a.mu.Lock()
@@ -67,29 +114,125 @@ func (a *BaseAgent) UpdateMemory(role schema.MessageRole, content string, toolCa
 	a.Memory.AddMessage(msg)
 }
 
-// Run 执行 Agent 主循环
+// Run 执行 Agent 主循环，返回最终回答
 func (a *BaseAgent) Run(ctx context.Context, request string) (string, error) {
+	result, err := a.RunDetailed(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	return result.Answer, nil
+}
+
+// RunDetailed 执行 Agent 主循环，返回最终回答和每一步的执行记录；
+// 步骤出错时同时返回已完成的记录和错误
+func (a *BaseAgent) RunDetailed(ctx context.Context, request string) (*RunResult, error) {
 	if a.State != schema.AgentStateIDLE {
-		return "", fmt.Errorf("cannot run agent from state: %s", a.State)
+		return nil, fmt.Errorf("cannot run agent from state: %s", a.State)
+	}
+	// 结束后回到 IDLE 并清零步数，同一个 Agent 可以再次运行（交互模式、计划的多个步骤），
+	// 本次运行的最终状态记录在 RunResult.State 中
+	defer func() {
+		a.CurrentStep = 0
+		a.State = schema.AgentStateIDLE
+	}()
+	runStart := time.Now()
+	runAdded := a.Memory.Added()
+
+	// 调用方没有指定运行 ID 时生成一个，本次运行的日志都带有该 ID
+	if logger.RunID(ctx) == "" {
+		ctx = logger.WithRunID(ctx, logger.NewRunID())
+	}
+	run := &RunResult{RunID: logger.RunID(ctx)}
+
+	ctx, span := telemetry.StartSpan(ctx, "agent.run")
+	span.SetAttribute("agent.name", a.Name)
+	span.SetAttribute("agent.run_id", run.RunID)
+	defer span.End()
+
+	// 运行结束时记录本次消耗的 token 数
+	usageStart := a.LLM.TokenUsage()
+	defer func() {
+		run.Usage = a.LLM.TokenUsage().Sub(usageStart)
+		span.SetAttribute("llm.total_tokens", run.Usage.TotalTokens)
+		logger.FromContext(ctx).Infof("📊 Token usage: %d prompt + %d completion = %d total",
+			run.Usage.PromptTokens, run.Usage.CompletionTokens, run.Usage.TotalTokens)
+	}()
+
+	if request != "" {
+		limited, err := a.limitInput(ctx, request)
+		if err != nil {
+			logger.FromContext(ctx).Warnf("Request refused: %v", err)
+			span.RecordError(err)
+			return nil, err
+		}
+		request = limited
+	}
+
+	if a.InputGuard != nil && request != "" {
+		if err := a.InputGuard(ctx, request); err != nil {
+			logger.FromContext(ctx).Warnf("Request refused by input guard: %v", err)
+			span.RecordError(err)
+			return nil, fmt.Errorf("request refused by input guard: %w", err)
+		}
 	}
 
 	if request != "" {
 		a.UpdateMemory(schema.RoleUser, request)
+		a.pinTask(len(a.Memory.Messages) - 1)
+	}
+
+	// 整个运行循环共享一个截止时间，区分超时与调用方取消
+	parentCtx := ctx
+	if a.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.MaxDuration)
+		defer cancel()
+	}
+	timedOut := func() bool {
+		return a.MaxDuration > 0 && ctx.Err() != nil && parentCtx.Err() == nil
 	}
 
 	results := make([]string, 0)
 	a.State = schema.AgentStateRUNNING
 
 	for a.CurrentStep < a.MaxSteps && a.State != schema.AgentStateFINISHED {
+		if timedOut() {
+			break
+		}
 		a.CurrentStep++
-		logger.Infof("Executing step %d/%d", a.CurrentStep, a.MaxSteps)
+		stepCtx := logger.WithStep(ctx, a.CurrentStep)
+		logger.FromContext(stepCtx).Infof("Executing step %d/%d", a.CurrentStep, a.MaxSteps)
 
-		stepResult, err := a.Step(ctx)
+		stepCtx, stepSpan := telemetry.StartSpan(stepCtx, "agent.step")
+		stepSpan.SetAttribute("agent.name", a.Name)
+		stepSpan.SetAttribute("agent.step", a.CurrentStep)
+		stepStart := time.Now()
+		added := a.Memory.Added()
+		stepResult, err := a.Step(stepCtx)
+		record := newStepRecord(a.CurrentStep, stepStart, a.Memory.MessagesSince(added))
+		record.Result = stepResult
 		if err != nil {
-			logger.Errorf("Step %d failed: %v", a.CurrentStep, err)
+			record.Error = err.Error()
+		}
+		run.Steps = append(run.Steps, record)
+		if err != nil && timedOut() {
+			// 步骤因超时被中断，保留之前的结果
+			logger.FromContext(stepCtx).Warnf("Step %d interrupted by time limit: %v", a.CurrentStep, err)
+			stepSpan.RecordError(err)
+			stepSpan.End()
+			break
+		}
+		if err != nil {
+			logger.FromContext(stepCtx).Errorf("Step %d failed: %v", a.CurrentStep, err)
 			a.State = schema.AgentStateERROR
-			return "", err
+			stepSpan.RecordError(err)
+			stepSpan.End()
+			span.RecordError(err)
+			run.State = a.State
+			run.Duration = time.Since(runStart)
+			return run, err
 		}
+		stepSpan.End()
 
 		// 检查是否卡住
 		if a.IsStuck() {
@@ -99,20 +242,58 @@ func (a *BaseAgent) Run(ctx context.Context, request string) (string, error) {
 		results = append(results, fmt.Sprintf("Step %d: %s", a.CurrentStep, stepResult))
 	}
 
-	if a.CurrentStep >= a.MaxSteps {
+	span.SetAttribute("agent.steps", a.CurrentStep)
+
+	if a.State != schema.AgentStateFINISHED && timedOut() {
+		span.SetAttribute("agent.timed_out", true)
+		results = append(results, fmt.Sprintf("Terminated: Reached time limit (%s)", a.MaxDuration))
+	} else if a.CurrentStep >= a.MaxSteps {
 		results = append(results, fmt.Sprintf("Terminated: Reached max steps (%d)", a.MaxSteps))
 	}
 
-	if len(results) == 0 {
-		return "No steps executed", nil
+	answer := "No steps executed"
+	if len(results) > 0 {
+		answer = strings.Join(results, "\n")
 	}
+	answer = a.extractAnswer(answer, runAdded)
 
-	return strings.Join(results, "\n"), nil
+	if a.OutputGuard != nil {
+		guarded, err := a.OutputGuard(parentCtx, answer)
+		if err != nil {
+			span.RecordError(err)
+			return nil, fmt.Errorf("answer rejected by output guard: %w", err)
+		}
+		answer = guarded
+	}
+
+	run.Answer = answer
+	run.State = a.State
+	run.Duration = time.Since(runStart)
+	return run, nil
+}
+
+// pinTask 固定本次运行的任务，避免长时间运行时被裁剪掉；之前运行固定的任务取消固定，
+// 交互模式下过期的任务不会一直留在上下文最前面
+func (a *BaseAgent) pinTask(index int) {
+	if a.pinnedTask != nil {
+		for i, msg := range a.Memory.Messages {
+			if msg.Content == a.pinnedTask {
+				a.Memory.Unpin(i)
+				break
+			}
+		}
+	}
+	if a.Memory.Pin(index) {
+		a.pinnedTask = a.Memory.Messages[index].Content
+	}
 }
 
-// Step 执行单步（子类实现）
+// Step 执行单步，转发给构造时设置的子类实现
 func (a *BaseAgent) Step(ctx context.Context) (string, error) {
-	return "", fmt.Errorf("Step method must be implemented by subclass")
+	if a.stepper == nil {
+		return "", fmt.Errorf("Step method must be implemented by subclass")
+	}
+	return a.stepper.Step(ctx)
 }
 
 // IsStuck 检查是否卡住
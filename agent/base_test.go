@@ -0,0 +1,266 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"go-manus/llm"
+	"go-manus/logger"
+	"go-manus/schema"
+	"go-manus/tool"
+)
+
+func TestManusRunEndToEnd(t *testing.T) {
+	fake := newFakeLLM(
+		toolCallReply("call_1", "file_saver", `{"file_path": "e2e/notes.txt", "content": "hello from the agent"}`),
+		toolCallReply("call_2", "terminate", `{"status": "success"}`),
+	)
+	manus := NewManus()
+	manus.LLM.SetProvider(fake)
+
+	run, err := manus.RunDetailed(context.Background(), "write a note")
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	answer := run.Answer
+
+	if fake.calls() != 2 {
+		t.Errorf("LLM calls = %d, want 2", fake.calls())
+	}
+	if run.State != schema.AgentStateFINISHED {
+		t.Errorf("state = %s, want %s", run.State, schema.AgentStateFINISHED)
+	}
+	if len(run.Steps) != 2 {
+		t.Errorf("steps = %d, want 2", len(run.Steps))
+	}
+
+	data, err := os.ReadFile(filepath.Join("workspace", "e2e", "notes.txt"))
+	if err != nil {
+		t.Fatalf("file_saver did not write the file: %v", err)
+	}
+	if string(data) != "hello from the agent" {
+		t.Errorf("file content = %q", data)
+	}
+	if !strings.Contains(answer, "The interaction has been completed with status: success") {
+		t.Errorf("answer does not include the terminate result: %q", answer)
+	}
+
+	// 第二次请求应带上第一次工具调用的结果
+	second := fake.requests[1].Messages
+	last := second[len(second)-2]
+	if last.Role != "tool" || last.ToolCallID != "call_1" {
+		t.Errorf("second request does not carry the file_saver result, got role %q id %q", last.Role, last.ToolCallID)
+	}
+}
+
+func TestBaseAgentStepWithoutSubclass(t *testing.T) {
+	a := NewBaseAgent("bare")
+	if _, err := a.Run(context.Background(), "hi"); err == nil {
+		t.Fatal("Run on a BaseAgent without a Step implementation should fail")
+	}
+}
+
+// thinkOnly 只重写 Think 的 Agent，用于确认 Step 调用的是子类方法
+type thinkOnly struct {
+	*ReActAgent
+	thought int
+}
+
+func (a *thinkOnly) Think(ctx context.Context) (bool, error) {
+	a.thought++
+	a.State = schema.AgentStateFINISHED
+	return false, nil
+}
+
+func TestReActAgentStepDispatchesToSubclass(t *testing.T) {
+	a := &thinkOnly{ReActAgent: NewReActAgent("think_only")}
+	a.bind(a)
+
+	result, err := a.Run(context.Background(), "go")
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if a.thought != 1 {
+		t.Errorf("subclass Think called %d times, want 1", a.thought)
+	}
+	if !strings.Contains(result, "Thinking complete") {
+		t.Errorf("result = %q", result)
+	}
+}
+
+func TestRunReturnsAgentToIdle(t *testing.T) {
+	fake := newFakeLLM(
+		toolCallReply("call_1", "terminate", `{"status": "success"}`),
+		toolCallReply("call_2", "terminate", `{"status": "success"}`),
+	)
+	a := NewToolCallAgent("twice")
+	a.LLM.SetProvider(fake)
+
+	for i := 1; i <= 2; i++ {
+		if _, err := a.Run(context.Background(), "task"); err != nil {
+			t.Fatalf("run %d: %v", i, err)
+		}
+		if a.State != schema.AgentStateIDLE || a.CurrentStep != 0 {
+			t.Errorf("after run %d: state %s, step %d; want IDLE, 0", i, a.State, a.CurrentStep)
+		}
+	}
+	if fake.calls() != 2 {
+		t.Errorf("LLM calls = %d, want 2", fake.calls())
+	}
+}
+
+func TestRunPinsCurrentTaskWhenMemoryOverflows(t *testing.T) {
+	fake := newFakeLLM(
+		toolCallReply("call_1", "create_chat_completion", `{"response": "one"}`),
+		toolCallReply("call_2", "create_chat_completion", `{"response": "two"}`),
+		toolCallReply("call_3", "create_chat_completion", `{"response": "three"}`),
+		toolCallReply("call_4", "terminate", `{"status": "success"}`),
+		toolCallReply("call_5", "create_chat_completion", `{"response": "four"}`),
+		toolCallReply("call_6", "create_chat_completion", `{"response": "five"}`),
+		toolCallReply("call_7", "terminate", `{"status": "success"}`),
+	)
+	a := NewToolCallAgent("pinned")
+	a.AvailableTools.AddTool(tool.NewCreateChatCompletion())
+	a.LLM.SetProvider(fake)
+	a.Memory.MaxMessages = 4
+
+	firstContent := func() string {
+		messages := a.GetMessages()
+		if len(messages) == 0 || messages[0].Content == nil {
+			return ""
+		}
+		return *messages[0].Content
+	}
+
+	if _, err := a.Run(context.Background(), "first task"); err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	if len(a.GetMessages()) != 4 {
+		t.Errorf("memory holds %d messages, want 4", len(a.GetMessages()))
+	}
+	if firstContent() != "first task" {
+		t.Errorf("first message = %q, want the pinned task", firstContent())
+	}
+
+	// 第二次运行固定新的任务，之前的任务不再固定并随裁剪移除
+	if _, err := a.Run(context.Background(), "second task"); err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if firstContent() != "second task" {
+		t.Errorf("first message = %q, want the current task", firstContent())
+	}
+	for _, msg := range a.GetMessages() {
+		if msg.Content != nil && *msg.Content == "first task" {
+			t.Error("the previous task is still in memory")
+		}
+	}
+}
+
+func TestRunLogsCarryRunID(t *testing.T) {
+	log := logger.GetLogger()
+	defer log.ReplaceHooks(log.ReplaceHooks(make(logrus.LevelHooks)))
+	hook := logtest.NewLocal(log)
+
+	a := NewToolCallAgent("traced")
+	a.LLM.SetProvider(newFakeLLM(toolCallReply("call_1", "terminate", `{"status": "success"}`)))
+	run, err := a.RunDetailed(context.Background(), "finish")
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if run.RunID == "" {
+		t.Fatal("run has no RunID")
+	}
+
+	entries := hook.AllEntries()
+	if len(entries) == 0 {
+		t.Fatal("the run logged nothing")
+	}
+	stepped := false
+	for _, entry := range entries {
+		if id := entry.Data["run_id"]; id != run.RunID {
+			t.Errorf("entry %q has run_id %v, want %s", entry.Message, id, run.RunID)
+		}
+		if _, ok := entry.Data["step"]; ok {
+			stepped = true
+		}
+	}
+	if !stepped {
+		t.Error("no entry carries the step field")
+	}
+}
+
+// tickStepper 每步等待 delay 后返回，用于测试运行循环本身
+type tickStepper struct{ delay time.Duration }
+
+func (s tickStepper) Step(ctx context.Context) (string, error) {
+	select {
+	case <-time.After(s.delay):
+		return "tick", nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func TestRunStopsAtMaxDuration(t *testing.T) {
+	a := NewBaseAgent("looping")
+	a.stepper = tickStepper{delay: 5 * time.Millisecond}
+	a.MaxSteps = 10000
+	a.MaxDuration = 100 * time.Millisecond
+
+	start := time.Now()
+	run, err := a.RunDetailed(context.Background(), "loop forever")
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Run took %v with a 100ms limit", elapsed)
+	}
+	if !strings.HasSuffix(run.Answer, "Terminated: Reached time limit (100ms)") {
+		t.Errorf("answer does not report the time limit: %q", run.Answer)
+	}
+	if !strings.HasPrefix(run.Answer, "Step 1: tick") {
+		t.Errorf("answer lost the partial output: %q", run.Answer)
+	}
+	if n := len(run.Steps); n == 0 || n >= a.MaxSteps {
+		t.Errorf("ran %d steps, want the time limit to stop the loop early", n)
+	}
+	if run.State == schema.AgentStateERROR {
+		t.Errorf("state = %s after hitting the time limit", run.State)
+	}
+}
+
+func TestRunReportsTokenUsagePerRun(t *testing.T) {
+	a := NewToolCallAgent("metered")
+	a.AvailableTools.AddTool(&countingTool{})
+	a.LLM.SetProvider(newFakeLLM(
+		toolCallReply("call_1", "lookup", `{}`),
+		toolCallReply("call_2", "terminate", `{"status": "success"}`),
+		toolCallReply("call_3", "terminate", `{"status": "success"}`),
+	))
+
+	// 每个回复消耗 10 + 5 个 token
+	first, err := a.RunDetailed(context.Background(), "look it up")
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if want := (llm.TokenUsage{PromptTokens: 20, CompletionTokens: 10, TotalTokens: 30}); first.Usage != want {
+		t.Errorf("first run usage = %+v, want %+v", first.Usage, want)
+	}
+
+	second, err := a.RunDetailed(context.Background(), "finish")
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if second.Usage.TotalTokens != 15 {
+		t.Errorf("second run usage = %+v, want only its own 15 tokens", second.Usage)
+	}
+	if a.LLM.TokenUsage().TotalTokens != 45 {
+		t.Errorf("client usage = %+v, want 45 tokens in total", a.LLM.TokenUsage())
+	}
+}
@@ -2,10 +2,11 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 
 	"go-manus/logger"
-	"go-manus/schema"
 	"go-manus/tool"
 )
 
@@ -33,13 +34,34 @@ func (b *BrowserContextHelper) GetBrowserState(ctx context.Context) (map[string]
 		return nil, fmt.Errorf("BrowserUseTool not found")
 	}
 
-	// 尝试获取浏览器状态
-	// 注意：这需要 BrowserUse 工具支持 get_current_state 方法
-	// 目前简化实现，返回空状态
+	result, err := browserTool.Execute(ctx, map[string]interface{}{"action": "get_current_state"})
+	if err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("%s", result.Error)
+	}
+
+	var state tool.BrowserState
+	if err := json.Unmarshal([]byte(result.Output), &state); err != nil {
+		return nil, fmt.Errorf("invalid browser state: %w", err)
+	}
+
+	tabs := make([]string, 0, len(state.Tabs))
+	for _, tab := range state.Tabs {
+		entry := fmt.Sprintf("%d: %s (%s)", tab.ID, tab.Title, tab.URL)
+		if tab.ID == state.ActiveTab {
+			entry += " [current]"
+		}
+		tabs = append(tabs, entry)
+	}
 	return map[string]interface{}{
-		"url":   "N/A",
-		"title": "N/A",
-		"tabs":  []string{},
+		"url":                  state.URL,
+		"title":                state.Title,
+		"tabs":                 tabs,
+		"interactive_elements": state.InteractiveElements,
+		"pixels_above":         state.PixelsAbove,
+		"pixels_below":         state.PixelsBelow,
 	}, nil
 }
 
@@ -47,28 +69,40 @@ func (b *BrowserContextHelper) GetBrowserState(ctx context.Context) (map[string]
 func (b *BrowserContextHelper) FormatNextStepPrompt(ctx context.Context) (string, error) {
 	state, err := b.GetBrowserState(ctx)
 	if err != nil {
-		logger.Warningf("Failed to get browser state: %v", err)
+		logger.FromContext(ctx).Warnf("Failed to get browser state: %v", err)
 		state = map[string]interface{}{}
 	}
 
 	urlInfo := ""
 	titleInfo := ""
 	tabsInfo := ""
+	contentAbove := ""
+	contentBelow := ""
+	currentState := ""
 
-	if url, ok := state["url"].(string); ok && url != "N/A" {
+	if url, ok := state["url"].(string); ok && url != "" {
 		urlInfo = fmt.Sprintf("\n   URL: %s", url)
 	}
-	if title, ok := state["title"].(string); ok && title != "N/A" {
+	if title, ok := state["title"].(string); ok && title != "" {
 		titleInfo = fmt.Sprintf("\n   Title: %s", title)
 	}
 	if tabs, ok := state["tabs"].([]string); ok && len(tabs) > 0 {
-		tabsInfo = fmt.Sprintf("\n   %d tab(s) available", len(tabs))
+		tabsInfo = fmt.Sprintf("\n   %d tab(s) available: %s", len(tabs), strings.Join(tabs, "; "))
+	}
+	if pixels, ok := state["pixels_above"].(int); ok && pixels > 0 {
+		contentAbove = fmt.Sprintf(" (%d pixels)", pixels)
+	}
+	if pixels, ok := state["pixels_below"].(int); ok && pixels > 0 {
+		contentBelow = fmt.Sprintf(" (%d pixels)", pixels)
+	}
+	if elements, ok := state["interactive_elements"].(string); ok && elements != "" {
+		currentState = "\n\n[Current state starts here]\n" + elements
 	}
 
 	prompt := fmt.Sprintf(`What should I do next to achieve my goal?
 
 When you see [Current state starts here], focus on the following:
-- Current URL and page title%s
+- Current URL and page title%s%s
 - Available tabs%s
 - Interactive elements and their indices
 - Content above%s or below%s the viewport (if indicated)
@@ -84,8 +118,8 @@ For browser interactions:
 Consider both what's visible and what might be beyond the current viewport.
 Be methodical - remember your progress and what you've learned so far.
 
-If you want to stop the interaction at any point, use the terminate tool/function call.`,
-		urlInfo, tabsInfo, "", "")
+If you want to stop the interaction at any point, use the terminate tool/function call.%s`,
+		urlInfo, titleInfo, tabsInfo, contentAbove, contentBelow, currentState)
 
 	return prompt, nil
 }
@@ -116,6 +150,8 @@ func (b *BrowserContextHelper) CleanupBrowser(ctx context.Context) error {
 type BrowserAgent struct {
 	*ToolCallAgent
 	browserContextHelper *BrowserContextHelper
+	// customNextStep 提示词目录中提供了 next_step_prompt.md，此时不再用浏览器状态改写 NextStepPrompt
+	customNextStep bool
 }
 
 // NewBrowserAgent 创建浏览器 Agent
@@ -226,6 +262,8 @@ If you want to stop the interaction at any point, use the terminate tool/functio
 
 	// 初始化浏览器上下文助手
 	agent.browserContextHelper = NewBrowserContextHelper(agent.ToolCallAgent)
+	agent.customNextStep = agent.preparePrompts()
+	agent.bind(agent)
 
 	return agent
 }
@@ -233,9 +271,11 @@ If you want to stop the interaction at any point, use the terminate tool/functio
 // Think 思考下一步行动，包含浏览器状态
 func (b *BrowserAgent) Think(ctx context.Context) (bool, error) {
 	// 更新提示词以包含浏览器状态
-	prompt, err := b.browserContextHelper.FormatNextStepPrompt(ctx)
-	if err == nil {
-		b.NextStepPrompt = prompt
+	if !b.customNextStep {
+		prompt, err := b.browserContextHelper.FormatNextStepPrompt(ctx)
+		if err == nil {
+			b.NextStepPrompt = prompt
+		}
 	}
 
 	return b.ToolCallAgent.Think(ctx)
@@ -1,7 +1,6 @@
 package agent
 
 import (
-	"fmt"
 	"go-manus/tool"
 )
 
@@ -17,13 +16,12 @@ func NewDataAnalysis() *DataAnalysis {
 	}
 
 	// 设置提示词（来自 Python 版本的 app/prompt/visualization.py）
-	// 注意：需要从 config 获取 workspace_root，这里先使用默认值
-	workspaceRoot := "workspace" // TODO: 从 config 获取
-	agent.SystemPrompt = fmt.Sprintf(`You are an AI agent designed to data analysis / visualization task. You have various tools at your disposal that you can call upon to efficiently complete complex requests.
+	// 初始目录为配置的工作目录（绝对路径），文件工具只能访问其中的文件；变量在 preparePrompts 中替换
+	agent.SystemPrompt = `You are {assistant_name}, an AI agent designed to data analysis / visualization task. You have various tools at your disposal that you can call upon to efficiently complete complex requests.
 # Note:
-1. The workspace directory is: %s; Read / write file in workspace
+1. The workspace directory is: {workspace_root}; Read / write file in workspace
 2. Generate analysis conclusion report in the end
-3. Use FileSaver to save analysis results, StrReplaceEditor to view/edit data files, VisualizationPrepare and DataVisualization for creating charts`, workspaceRoot)
+3. Use FileSaver to save analysis results, StrReplaceEditor to view/edit data files, VisualizationPrepare and DataVisualization for creating charts`
 
 	agent.NextStepPrompt = `Based on user needs, break down the problem and use different tools step by step to solve it.
 # Note
@@ -34,15 +32,21 @@ func NewDataAnalysis() *DataAnalysis {
 Available tools:
 - FileSaver: Save analysis results, reports, and processed data
 - StrReplaceEditor: View and edit data files
+- Convert: Convert data files between CSV, JSON and YAML
 - VisualizationPrepare: Prepare data for visualization
-- DataVisualization: Generate charts and visualizations`
+- DataVisualization: Generate charts and visualizations
+- get_more: Read the rest of a truncated tool output by its handle`
 
 	// 配置工具（数据分析 Agent 使用 FileSaver, StrReplaceEditor, VisualizationPrepare, DataVisualization）
+	editor := tool.NewStrReplaceEditor()
+	editor.SetOutputStore(agent.Outputs)
 	agent.AvailableTools = tool.NewToolCollection(
 		tool.NewFileSaver(),
-		tool.NewStrReplaceEditor(),
+		editor,
+		tool.NewConvert(),
 		tool.NewVisualizationPrepare(),
 		tool.NewDataVisualization(),
+		tool.NewGetMore(agent.Outputs),
 		tool.NewTerminate(),
 	)
 
@@ -50,6 +54,7 @@ Available tools:
 	agent.Description = "An analytical agent that utilizes data visualization tools to solve diverse data analysis tasks"
 	agent.MaxSteps = 20
 	agent.MaxObserve = 15000
+	agent.preparePrompts()
 
 	return agent
 }
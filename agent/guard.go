@@ -0,0 +1,34 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go-manus/llm"
+)
+
+// Moderator 内容分类器，返回是否应拒绝以及原因
+type Moderator interface {
+	Moderate(ctx context.Context, input string) (bool, []string, error)
+}
+
+// ModerationGuard 使用分类器（如 llm.Client 的 moderation 接口）构造 InputGuard，分类器调用失败时同样拒绝请求
+func ModerationGuard(moderator Moderator) func(ctx context.Context, request string) error {
+	return func(ctx context.Context, request string) error {
+		flagged, categories, err := moderator.Moderate(ctx, request)
+		if err != nil {
+			return fmt.Errorf("moderation failed: %w", err)
+		}
+		if flagged {
+			if len(categories) == 0 {
+				return fmt.Errorf("request flagged by moderation")
+			}
+			return fmt.Errorf("request flagged by moderation: %s", strings.Join(categories, ", "))
+		}
+		return nil
+	}
+}
+
+// 确保 llm.Client 可以作为 Moderator 使用
+var _ Moderator = (*llm.Client)(nil)
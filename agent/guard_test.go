@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestOutputGuardMasksBannedWord(t *testing.T) {
+	a := NewToolCallAgent("guarded")
+	a.LLM.SetProvider(newFakeLLM(
+		textReply("The password is swordfish."),
+		toolCallReply("call_1", "terminate", `{"status": "success"}`),
+	))
+	a.OutputGuard = func(ctx context.Context, answer string) (string, error) {
+		return strings.ReplaceAll(answer, "swordfish", "*****"), nil
+	}
+
+	answer, err := a.Run(context.Background(), "what is the password?")
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if strings.Contains(answer, "swordfish") {
+		t.Errorf("answer still contains the banned word: %q", answer)
+	}
+	if !strings.Contains(answer, "The password is *****.") {
+		t.Errorf("answer = %q, want the masked reply", answer)
+	}
+}
+
+// stubModerator 标记包含 banned 的输入
+type stubModerator struct {
+	banned string
+	calls  int
+}
+
+func (m *stubModerator) Moderate(ctx context.Context, input string) (bool, []string, error) {
+	m.calls++
+	if strings.Contains(input, m.banned) {
+		return true, []string{"violence"}, nil
+	}
+	return false, nil, nil
+}
+
+func TestInputGuardRefusesFlaggedRequest(t *testing.T) {
+	fake := newFakeLLM(toolCallReply("call_1", "terminate", `{"status": "success"}`))
+	moderator := &stubModerator{banned: "build a weapon"}
+	a := NewToolCallAgent("moderated")
+	a.LLM.SetProvider(fake)
+	a.InputGuard = ModerationGuard(moderator)
+
+	_, err := a.Run(context.Background(), "explain how to build a weapon")
+	if err == nil || !strings.Contains(err.Error(), "flagged by moderation: violence") {
+		t.Fatalf("Run error = %v, want a moderation refusal", err)
+	}
+	if fake.calls() != 0 || len(a.GetMessages()) != 0 {
+		t.Errorf("refused request reached the agent: %d LLM calls, %d messages", fake.calls(), len(a.GetMessages()))
+	}
+
+	// 未被标记的请求正常执行
+	if _, err := a.Run(context.Background(), "summarize the news"); err != nil {
+		t.Fatalf("Run returned error for an allowed request: %v", err)
+	}
+	if moderator.calls != 2 || fake.calls() != 1 {
+		t.Errorf("moderator called %d times and LLM %d times, want 2 and 1", moderator.calls, fake.calls())
+	}
+}
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go-manus/logger"
+	"go-manus/schema"
+)
+
+// InputOverflow 决定请求超过 MaxInputLength 时的处理方式
+type InputOverflow string
+
+const (
+	// InputOverflowReject 拒绝执行并返回 ErrInputTooLong
+	InputOverflowReject InputOverflow = ""
+	// InputOverflowSummarize 先用 LLM 压缩请求，再按压缩后的请求执行
+	InputOverflowSummarize InputOverflow = "summarize"
+)
+
+// ErrInputTooLong 请求超过 MaxInputLength 且 InputOverflow 为 InputOverflowReject
+var ErrInputTooLong = errors.New("request exceeds the maximum input length")
+
+const summarizeInputPrompt = `You condense long user requests for an AI agent. Rewrite the text you are given so it is much shorter while keeping everything the agent needs to do the task: the goal, every explicit instruction and constraint, and the names, numbers, paths, URLs and code identifiers involved. Drop repetition and boilerplate. Reply with the condensed text only.`
+
+// limitInput 检查请求长度，超过 MaxInputLength 时按 InputOverflow 拒绝或压缩
+func (a *BaseAgent) limitInput(ctx context.Context, request string) (string, error) {
+	length := len([]rune(request))
+	if a.MaxInputLength <= 0 || length <= a.MaxInputLength {
+		return request, nil
+	}
+
+	switch a.InputOverflow {
+	case InputOverflowReject:
+		return "", fmt.Errorf("%w: %d characters, limit is %d", ErrInputTooLong, length, a.MaxInputLength)
+	case InputOverflowSummarize:
+		logger.FromContext(ctx).Infof("Request has %d characters, over the limit of %d, summarizing it", length, a.MaxInputLength)
+		return a.summarizeInput(ctx, request)
+	default:
+		return "", fmt.Errorf("unknown input overflow mode %q", a.InputOverflow)
+	}
+}
+
+// summarizeInput 将请求按 MaxInputLength 分段压缩后拼接，结果仍超长时截断
+func (a *BaseAgent) summarizeInput(ctx context.Context, request string) (string, error) {
+	runes := []rune(request)
+	system := []schema.Message{schema.NewSystemMessage(summarizeInputPrompt)}
+
+	parts := make([]string, 0)
+	for start := 0; start < len(runes); start += a.MaxInputLength {
+		end := start + a.MaxInputLength
+		if end > len(runes) {
+			end = len(runes)
+		}
+		summary, err := a.LLM.Ask(ctx, []schema.Message{schema.NewUserMessage(string(runes[start:end]))}, system)
+		if err != nil {
+			return "", fmt.Errorf("failed to summarize the request: %w", err)
+		}
+		parts = append(parts, strings.TrimSpace(summary))
+	}
+
+	condensed := []rune(strings.Join(parts, "\n\n"))
+	if len(condensed) > a.MaxInputLength {
+		condensed = condensed[:a.MaxInputLength]
+	}
+	return string(condensed), nil
+}
@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRunRejectsOverlongRequest(t *testing.T) {
+	fake := newFakeLLM()
+	a := NewToolCallAgent("limited")
+	a.LLM.SetProvider(fake)
+	a.MaxInputLength = 10
+
+	_, err := a.Run(context.Background(), strings.Repeat("x", 11))
+	if !errors.Is(err, ErrInputTooLong) {
+		t.Fatalf("Run error = %v, want ErrInputTooLong", err)
+	}
+	if fake.calls() != 0 {
+		t.Errorf("LLM called %d times for a rejected request", fake.calls())
+	}
+}
+
+func TestRunSummarizesOverlongRequest(t *testing.T) {
+	fake := newFakeLLM(
+		textReply("fix the bug"),
+		textReply("and test it"),
+		toolCallReply("call_1", "terminate", `{"status": "success"}`),
+	)
+	a := NewToolCallAgent("condensed")
+	a.LLM.SetProvider(fake)
+	a.MaxInputLength = 30
+	a.InputOverflow = InputOverflowSummarize
+
+	// 34 个字符按 30 分为两段，每段压缩一次
+	if _, err := a.Run(context.Background(), strings.Repeat("please ", 4)+"fix it"); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if fake.calls() != 3 {
+		t.Fatalf("LLM called %d times, want 2 summaries and 1 step", fake.calls())
+	}
+	task := fake.requests[2].Messages
+	var found bool
+	for _, msg := range task {
+		if msg.Role == "user" && msg.Content == "fix the bug\n\nand test it" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("agent did not run the condensed request; messages = %+v", task)
+	}
+}
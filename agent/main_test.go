@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"go-manus/config"
+)
+
+// testConfig 测试使用的最小配置，LLM 请求都由 fakeLLM 处理；[agent] 用于检查名称和人设写入提示词
+const testConfig = `[llm]
+model = "test-model"
+base_url = "http://127.0.0.1:1/v1"
+api_key = "test"
+
+[agent]
+name = "Acme Assistant"
+persona = "Answer in the style of a ship's log."
+`
+
+// TestMain 在临时目录中运行测试，配置和工作目录都不会落在仓库里
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "go-manus-agent-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "config"), 0755); err == nil {
+		err = os.WriteFile(filepath.Join(dir, "config", "config.toml"), []byte(testConfig), 0644)
+	}
+	if err == nil {
+		err = os.Chdir(dir)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	config.SetInteractive(false)
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// fakeLLM 按顺序返回预设的回复，并记录收到的请求
+type fakeLLM struct {
+	mu        sync.Mutex
+	responses []openai.ChatCompletionResponse
+	requests  []openai.ChatCompletionRequest
+}
+
+func newFakeLLM(responses ...openai.ChatCompletionResponse) *fakeLLM {
+	return &fakeLLM{responses: responses}
+}
+
+func (f *fakeLLM) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, req)
+	if len(f.responses) == 0 {
+		return openai.ChatCompletionResponse{}, fmt.Errorf("fakeLLM: unexpected request %d", len(f.requests))
+	}
+	resp := f.responses[0]
+	f.responses = f.responses[1:]
+	return resp, nil
+}
+
+// calls 返回收到的请求数
+func (f *fakeLLM) calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.requests)
+}
+
+// textReply 只有文本内容的回复
+func textReply(content string) openai.ChatCompletionResponse {
+	return reply(openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content}, openai.FinishReasonStop)
+}
+
+// toolCallReply 调用一个工具的回复，args 为 JSON 参数
+func toolCallReply(id, name, args string) openai.ChatCompletionResponse {
+	return reply(openai.ChatCompletionMessage{
+		Role: openai.ChatMessageRoleAssistant,
+		ToolCalls: []openai.ToolCall{{
+			ID:       id,
+			Type:     openai.ToolTypeFunction,
+			Function: openai.FunctionCall{Name: name, Arguments: args},
+		}},
+	}, openai.FinishReasonToolCalls)
+}
+
+func reply(msg openai.ChatCompletionMessage, finish openai.FinishReason) openai.ChatCompletionResponse {
+	return openai.ChatCompletionResponse{
+		Model:   "test-model",
+		Choices: []openai.ChatCompletionChoice{{Message: msg, FinishReason: finish}},
+		Usage:   openai.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	}
+}
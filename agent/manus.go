@@ -1,7 +1,6 @@
 package agent
 
 import (
-	"fmt"
 	"go-manus/tool"
 )
 
@@ -17,18 +16,27 @@ func NewManus() *Manus {
 	}
 
 	// 设置提示词（来自 Python 版本的 app/prompt/manus.py）
-	// 注意：需要从 config 获取 workspace_root，这里先使用默认值
-	workspaceRoot := "workspace" // TODO: 从 config 获取
-	manus.SystemPrompt = fmt.Sprintf("You are OpenManus, an all-capable AI assistant, aimed at solving any task presented by the user. You have various tools at your disposal that you can call upon to efficiently complete complex requests. Whether it's programming, information retrieval, file processing, web browsing, or human interaction (only for extreme cases), you can handle it all.\nThe initial directory is: %s", workspaceRoot)
+	// 初始目录为配置的工作目录（绝对路径），文件工具只能访问其中的文件；变量在 preparePrompts 中替换
+	manus.SystemPrompt = "You are {assistant_name}, an all-capable AI assistant, aimed at solving any task presented by the user. You have various tools at your disposal that you can call upon to efficiently complete complex requests. Whether it's programming, information retrieval, file processing, web browsing, or human interaction (only for extreme cases), you can handle it all.\nThe initial directory is: {workspace_root}"
 
 	manus.NextStepPrompt = `You can interact with the computer using various tools:
 
 FileSaver: Save files locally, such as txt, html, json, etc.
 
+Template: Render Go text/template content with variables to scaffold configs or code files.
+
 StrReplaceEditor: View, create, and edit files. Supports commands: view, create, str_replace, insert, undo_edit.
 
 Bash: Execute bash commands in the terminal. Supports interactive sessions, background tasks, and process management.
 
+Sleep: Wait for a number of seconds or until a given time, e.g. between checks on a background job, instead of running sleep in bash.
+
+Git: Run git operations (status, diff, add, commit, log, branch) on repositories inside the workspace.
+
+GoTest: Run go test in a Go module and get a structured pass/fail summary with failing test excerpts.
+
+Format: Check formatting of code files (gofmt/goimports or configured formatters), show the diff and optionally apply it.
+
 BrowserUseTool: Open, browse, and use web browsers. If you open a local HTML file, you must provide the absolute path to the file.
 
 WebSearch: Unified web search supporting multiple engines (google, baidu, bing, duckduckgo). Automatically falls back to other engines if one fails.
@@ -45,12 +53,21 @@ VisualizationPrepare: Prepare data for visualization. Generates CSV and JSON met
 
 DataVisualization: Visualize statistical charts with JSON info. Generate charts in PNG or HTML format.
 
+Recall: Look back at your own recent messages and tool results, filtered by role or tool name.
+
+GetMore: Read the rest of a truncated tool output by its handle instead of running the tool again.
+
 AskHuman: Ask the user for clarification, additional information, or confirmation when needed.
 
 Based on user needs, proactively select the most appropriate tool or combination of tools. For complex tasks, you can break down the problem and use different tools step by step to solve it. After using each tool, clearly explain the execution results and suggest the next steps.
 
 If you want to stop the interaction at any point, use the terminate tool/function call.`
 
+	// 超长的工具输出被截断，完整内容可通过 get_more 读取
+	manus.MaxObserve = 10000
+	editor := tool.NewStrReplaceEditor()
+	editor.SetOutputStore(manus.Outputs)
+
 	// 添加工具集合
 	manus.AvailableTools = tool.NewToolCollection(
 		tool.NewGoogleSearch(),
@@ -60,8 +77,13 @@ If you want to stop the interaction at any point, use the terminate tool/functio
 		tool.NewWebSearch(),
 		tool.NewBrowserUse(),
 		tool.NewFileSaver(),
-		tool.NewStrReplaceEditor(),
+		tool.NewTemplate(),
+		editor,
 		tool.NewBash(),
+		tool.NewSleep(),
+		tool.NewGit(),
+		tool.NewGoTest(),
+		tool.NewFormat(),
 		tool.NewAskHuman(),
 		tool.NewWebCrawler(),
 		tool.NewPlanningTool(),
@@ -69,10 +91,13 @@ If you want to stop the interaction at any point, use the terminate tool/functio
 		tool.NewComputerUseTool(),
 		tool.NewVisualizationPrepare(),
 		tool.NewDataVisualization(),
+		tool.NewRecall(manus.Memory),
+		tool.NewGetMore(manus.Outputs),
 		tool.NewTerminate(),
 	)
 
 	manus.Description = "A versatile agent that can solve various tasks using multiple tools"
+	manus.preparePrompts()
 
 	return manus
 }
@@ -56,6 +56,8 @@ If you've already made progress, consider what additional information you need o
 	agent.Description = "An agent that connects to an MCP server and uses its tools"
 	agent.MaxSteps = 20
 	agent.SpecialToolNames = []string{"terminate"}
+	agent.preparePrompts()
+	agent.bind(agent)
 
 	return agent
 }
@@ -125,7 +127,7 @@ func (m *MCPAgent) Initialize(ctx context.Context, connectionType string, server
 func (m *MCPAgent) refreshTools(ctx context.Context) {
 	tools, err := m.mcpClients.ListTools(ctx)
 	if err != nil {
-		logger.Warningf("Failed to refresh MCP tools: %v", err)
+		logger.FromContext(ctx).Warnf("Failed to refresh MCP tools: %v", err)
 		return
 	}
 
@@ -139,7 +141,7 @@ func (m *MCPAgent) refreshTools(ctx context.Context) {
 func (m *MCPAgent) Think(ctx context.Context) (bool, error) {
 	// 检查 MCP 会话和工具可用性
 	if len(m.mcpClients.Sessions()) == 0 || len(m.mcpClients.Tools()) == 0 {
-		logger.Info("MCP service is no longer available, ending interaction")
+		logger.FromContext(ctx).Info("MCP service is no longer available, ending interaction")
 		m.State = schema.AgentStateFINISHED
 		return false, nil
 	}
@@ -149,7 +151,7 @@ func (m *MCPAgent) Think(ctx context.Context) (bool, error) {
 		m.refreshTools(ctx)
 		// 如果所有工具都被移除，表示服务器关闭
 		if len(m.mcpClients.Tools()) == 0 {
-			logger.Info("MCP service has shut down, ending interaction")
+			logger.FromContext(ctx).Info("MCP service has shut down, ending interaction")
 			m.State = schema.AgentStateFINISHED
 			return false, nil
 		}
@@ -163,9 +165,9 @@ func (m *MCPAgent) Think(ctx context.Context) (bool, error) {
 func (m *MCPAgent) Cleanup(ctx context.Context) error {
 	for serverID := range m.connectedServers {
 		if err := m.mcpClients.Disconnect(serverID); err != nil {
-			logger.Warningf("Error disconnecting from MCP server %s: %v", serverID, err)
+			logger.FromContext(ctx).Warnf("Error disconnecting from MCP server %s: %v", serverID, err)
 		}
 	}
-	logger.Info("MCP connection closed")
+	logger.FromContext(ctx).Info("MCP connection closed")
 	return nil
 }
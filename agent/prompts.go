@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-manus/config"
+	"go-manus/logger"
+	"go-manus/tool"
+)
+
+const (
+	systemPromptFile   = "system_prompt.md"
+	nextStepPromptFile = "next_step_prompt.md"
+)
+
+// promptDir 返回 Agent 的提示词目录：<[prompts] dir>/<小写的 Agent 名称>，
+// 如 prompts/manus、prompts/data_analysis、prompts/browser、prompts/mcp_agent、prompts/swe
+func promptDir(name string) string {
+	return filepath.Join(config.GetInstance().GetPrompts().Dir, strings.ToLower(name))
+}
+
+// loadPromptOverrides 用提示词目录中的文件替换内置的 SystemPrompt/NextStepPrompt，
+// 文件不存在时保留内置提示词。返回 NextStepPrompt 是否被替换
+func (a *BaseAgent) loadPromptOverrides() bool {
+	dir := promptDir(a.Name)
+	if prompt, ok := readPromptFile(filepath.Join(dir, systemPromptFile)); ok {
+		a.SystemPrompt = prompt
+	}
+	prompt, ok := readPromptFile(filepath.Join(dir, nextStepPromptFile))
+	if ok {
+		a.NextStepPrompt = prompt
+	}
+	return ok
+}
+
+// readPromptFile 读取提示词文件并去掉首尾空白，文件不存在或读取失败时返回 false
+func readPromptFile(path string) (string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Warningf("Failed to read prompt file %s, using the built-in prompt: %v", path, err)
+		}
+		return "", false
+	}
+	logger.Infof("Using prompt from %s", path)
+	return strings.TrimSpace(string(data)), true
+}
+
+// preparePrompts 载入提示词目录中的覆盖文件并替换提示词变量，在各 Agent 构造函数的最后调用。
+// 返回 NextStepPrompt 是否被提示词文件替换
+func (a *ToolCallAgent) preparePrompts() bool {
+	customNextStep := a.loadPromptOverrides()
+	vars := a.promptVariables()
+	a.SystemPrompt = renderPrompt(a.SystemPrompt, vars)
+	a.NextStepPrompt = renderPrompt(a.NextStepPrompt, vars)
+	a.Persona = renderPrompt(a.Persona, vars)
+	return customNextStep
+}
+
+// promptVariables 返回提示词中可以使用的变量：
+//
+//	{assistant_name} 助手名称，来自 [agent] name
+//	{workspace_root} 工作目录的绝对路径
+//	{tool_list}      可用工具，每行一个，格式为 "- 名称: 描述的第一行"
+//	{current_date}   当前日期，格式为 2006-01-02
+//	{max_steps}      最大步骤数
+func (a *ToolCallAgent) promptVariables() map[string]string {
+	workspaceRoot, _ := tool.ResolveWorkspacePath(".")
+	return map[string]string{
+		"assistant_name": a.AssistantName,
+		"workspace_root": workspaceRoot,
+		"tool_list":      toolList(a.AvailableTools),
+		"current_date":   time.Now().Format("2006-01-02"),
+		"max_steps":      strconv.Itoa(a.MaxSteps),
+	}
+}
+
+// renderPrompt 将 prompt 中的 {name} 替换为 vars 中的值，未知的占位符保持不变
+func renderPrompt(prompt string, vars map[string]string) string {
+	if !strings.Contains(prompt, "{") {
+		return prompt
+	}
+	pairs := make([]string, 0, len(vars)*2)
+	for name, value := range vars {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(prompt)
+}
+
+// toolList 列出工具名称和描述的第一行
+func toolList(tools *tool.ToolCollection) string {
+	if tools == nil {
+		return ""
+	}
+	lines := make([]string, 0)
+	for _, t := range tools.Tools() {
+		description, _, _ := strings.Cut(strings.TrimSpace(t.Description()), "\n")
+		lines = append(lines, "- "+t.Name()+": "+description)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestManusSystemPromptUsesConfiguredNameAndPersona(t *testing.T) {
+	manus := NewManus()
+
+	prompt := manus.ComposedSystemPrompt()
+	if !strings.HasPrefix(prompt, "You are Acme Assistant, an all-capable AI assistant") {
+		t.Errorf("system prompt does not use the configured name: %q", prompt)
+	}
+	if strings.Contains(prompt, "OpenManus") {
+		t.Errorf("system prompt still mentions OpenManus: %q", prompt)
+	}
+	if !strings.HasSuffix(prompt, "\n\nAnswer in the style of a ship's log.") {
+		t.Errorf("system prompt does not end with the persona: %q", prompt)
+	}
+
+	// 发送给模型的系统消息就是组合后的提示词
+	fake := newFakeLLM(toolCallReply("call_1", "terminate", `{"status": "success"}`))
+	manus.LLM.SetProvider(fake)
+	if _, err := manus.Run(context.Background(), "hello"); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if got := fake.requests[0].Messages[0]; got.Role != "system" || got.Content != prompt {
+		t.Errorf("first message sent to the model = %s %q, want the composed system prompt", got.Role, got.Content)
+	}
+}
+
+// writePromptFile 在提示词目录中写入 Agent 的提示词文件，测试结束后删除该 Agent 的目录
+func writePromptFile(t *testing.T, agent, name, content string) {
+	t.Helper()
+	dir := promptDir(agent)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPromptFilesOverrideBuiltInPrompts(t *testing.T) {
+	writePromptFile(t, "swe", systemPromptFile, "\nYou fix bugs in small, reviewed steps.\n")
+	writePromptFile(t, "swe", nextStepPromptFile, "Run the tests before the next edit.")
+
+	swe := NewSWEAgent()
+	if swe.SystemPrompt != "You fix bugs in small, reviewed steps." {
+		t.Errorf("SystemPrompt = %q, want the prompt file", swe.SystemPrompt)
+	}
+	if swe.NextStepPrompt != "Run the tests before the next edit." {
+		t.Errorf("NextStepPrompt = %q, want the prompt file", swe.NextStepPrompt)
+	}
+
+	// 其他 Agent 的目录里没有文件，继续使用内置提示词
+	manus := NewManus()
+	if !strings.Contains(manus.SystemPrompt, "all-capable AI assistant") {
+		t.Errorf("Manus SystemPrompt = %q, want the built-in prompt", manus.SystemPrompt)
+	}
+	if !strings.Contains(manus.NextStepPrompt, "You can interact with the computer") {
+		t.Errorf("Manus NextStepPrompt = %q, want the built-in prompt", manus.NextStepPrompt)
+	}
+}
+
+func TestPromptVariablesAreSubstituted(t *testing.T) {
+	writePromptFile(t, "swe", systemPromptFile,
+		"You are {assistant_name}. Work in {workspace_root} on {current_date}, within {max_steps} steps.\nTools:\n{tool_list}\nKeep {unknown} as is.")
+
+	swe := NewSWEAgent()
+	root, err := filepath.Abs("workspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "You are Acme Assistant. Work in " + root + " on " + time.Now().Format("2006-01-02") +
+		", within " + strconv.Itoa(swe.MaxSteps) + " steps.\nTools:\n" + toolList(swe.AvailableTools) + "\nKeep {unknown} as is."
+	if swe.SystemPrompt != want {
+		t.Errorf("SystemPrompt = %q, want %q", swe.SystemPrompt, want)
+	}
+	if !strings.Contains(swe.SystemPrompt, "- bash: ") {
+		t.Errorf("tool list does not include bash: %q", swe.SystemPrompt)
+	}
+}
@@ -4,16 +4,34 @@ import (
 	"context"
 )
 
+// ThinkActor 实现思考和行动的 Agent，ReActAgent.Step 通过它调用子类的 Think 和 Act
+type ThinkActor interface {
+	Think(ctx context.Context) (bool, error)
+	Act(ctx context.Context) (string, error)
+}
+
 // ReActAgent ReAct 模式的 Agent
 type ReActAgent struct {
 	*BaseAgent
+
+	// self 实际的 Agent（如 ToolCallAgent），由 bind 设置
+	self ThinkActor
 }
 
 // NewReActAgent 创建 ReAct Agent
 func NewReActAgent(name string) *ReActAgent {
-	return &ReActAgent{
+	a := &ReActAgent{
 		BaseAgent: NewBaseAgent(name),
 	}
+	a.bind(a)
+	return a
+}
+
+// bind 设置实际的 Agent，嵌入 ReActAgent 并重写 Think 或 Act 的类型需要在构造时调用，
+// 否则运行循环只会调用到被嵌入类型的方法
+func (a *ReActAgent) bind(self ThinkActor) {
+	a.self = self
+	a.BaseAgent.stepper = a
 }
 
 // Think 思考下一步行动（子类实现）
@@ -28,7 +46,7 @@ func (a *ReActAgent) Act(ctx context.Context) (string, error) {
 
 // Step 执行单步：思考 + 行动
 func (a *ReActAgent) Step(ctx context.Context) (string, error) {
-	shouldAct, err := a.Think(ctx)
+	shouldAct, err := a.self.Think(ctx)
 	if err != nil {
 		return "", err
 	}
@@ -37,6 +55,5 @@ func (a *ReActAgent) Step(ctx context.Context) (string, error) {
 		return "Thinking complete - no action needed", nil
 	}
 
-	return a.Act(ctx)
+	return a.self.Act(ctx)
 }
-
@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"go-manus/llm"
+)
+
+// toolCallNames 按顺序返回运行中每次工具调用的名称和参数
+func toolCallNames(run *RunResult) []string {
+	var names []string
+	for _, step := range run.Steps {
+		for _, call := range step.ToolCalls {
+			names = append(names, call.Name+" "+call.Arguments)
+		}
+	}
+	return names
+}
+
+func TestReplayRepeatsRecordedRun(t *testing.T) {
+	var trace bytes.Buffer
+	recorded := &countingTool{}
+	a := NewToolCallAgent("record")
+	a.AvailableTools.AddTool(recorded)
+	a.LLM.SetProvider(newFakeLLM(
+		toolCallReply("call_1", "lookup", `{"q": "go"}`),
+		toolCallReply("call_2", "terminate", `{"status": "success"}`),
+	))
+	a.LLM.RecordTrace(&trace)
+
+	want, err := a.RunDetailed(context.Background(), "look it up")
+	if err != nil {
+		t.Fatalf("recorded run returned error: %v", err)
+	}
+
+	replay, err := llm.LoadReplay(&trace)
+	if err != nil {
+		t.Fatalf("LoadReplay: %v", err)
+	}
+	if replay.Remaining() != 2 {
+		t.Fatalf("trace holds %d responses, want 2", replay.Remaining())
+	}
+
+	replayed := &countingTool{}
+	b := NewToolCallAgent("replay")
+	b.AvailableTools.AddTool(replayed)
+	b.LLM.SetProvider(replay)
+
+	got, err := b.RunDetailed(context.Background(), "look it up")
+	if err != nil {
+		t.Fatalf("replayed run returned error: %v", err)
+	}
+	if replayed.calls != recorded.calls || replayed.calls != 1 {
+		t.Errorf("lookup ran %d times on replay and %d times when recorded, want 1", replayed.calls, recorded.calls)
+	}
+	wantCalls, gotCalls := toolCallNames(want), toolCallNames(got)
+	if len(gotCalls) != len(wantCalls) {
+		t.Fatalf("replayed tool calls = %v, want %v", gotCalls, wantCalls)
+	}
+	for i := range wantCalls {
+		if gotCalls[i] != wantCalls[i] {
+			t.Fatalf("replayed tool calls = %v, want %v", gotCalls, wantCalls)
+		}
+	}
+
+	// 轨迹回放完后不再返回响应
+	if _, err := replay.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{Model: "test-model"}); !errors.Is(err, llm.ErrReplayExhausted) {
+		t.Errorf("request after the trace = %v, want ErrReplayExhausted", err)
+	}
+}
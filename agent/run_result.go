@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"strings"
+	"time"
+
+	"go-manus/llm"
+	"go-manus/schema"
+)
+
+// RunResult RunDetailed 的返回值，包含最终回答和每一步的执行记录
+type RunResult struct {
+	// RunID 本次运行的关联 ID，与日志中的 run_id 字段一致
+	RunID    string
+	Answer   string
+	State    schema.AgentState
+	Steps    []StepRecord
+	Duration time.Duration
+	// Usage 本次运行消耗的 token 数；共享同一客户端的其他 Agent 同时运行时，其消耗也会计入
+	Usage llm.TokenUsage
+}
+
+// StepRecord 单步执行记录，内容取自该步骤新增的记忆
+type StepRecord struct {
+	Step      int
+	Thought   string
+	ToolCalls []ToolCallRecord
+	Result    string
+	Error     string
+	StartedAt time.Time
+	Duration  time.Duration
+}
+
+// ToolCallRecord 一次工具调用及其观察结果
+type ToolCallRecord struct {
+	ID          string
+	Name        string
+	Arguments   string
+	Observation string
+}
+
+// newStepRecord 根据步骤期间新增的消息生成执行记录
+func newStepRecord(step int, startedAt time.Time, messages []schema.Message) StepRecord {
+	record := StepRecord{
+		Step:      step,
+		StartedAt: startedAt,
+		Duration:  time.Since(startedAt),
+	}
+
+	thoughts := make([]string, 0)
+	index := make(map[string]int)
+	for _, msg := range messages {
+		switch msg.Role {
+		case schema.RoleAssistant:
+			if msg.Content != nil && strings.TrimSpace(*msg.Content) != "" {
+				thoughts = append(thoughts, *msg.Content)
+			}
+			for _, call := range msg.ToolCalls {
+				index[call.ID] = len(record.ToolCalls)
+				record.ToolCalls = append(record.ToolCalls, ToolCallRecord{
+					ID:        call.ID,
+					Name:      call.Function.Name,
+					Arguments: call.Function.Arguments,
+				})
+			}
+		case schema.RoleTool:
+			if msg.ToolCallID == nil || msg.Content == nil {
+				continue
+			}
+			if i, ok := index[*msg.ToolCallID]; ok {
+				record.ToolCalls[i].Observation = *msg.Content
+			}
+		}
+	}
+	record.Thought = strings.Join(thoughts, "\n")
+	return record
+}
@@ -40,16 +40,23 @@ Note that the environment does NOT support interactive session commands (e.g. py
 
 	agent.NextStepPrompt = ""
 
-	// 配置工具（SWE Agent 使用 Bash, StrReplaceEditor, Terminate）
+	// 配置工具（SWE Agent 使用 Bash, StrReplaceEditor, Git, GoTest, Format, Terminate）
+	editor := tool.NewStrReplaceEditor()
+	editor.SetOutputStore(agent.Outputs)
 	agent.AvailableTools = tool.NewToolCollection(
 		tool.NewBash(),
-		tool.NewStrReplaceEditor(),
+		editor,
+		tool.NewGit(),
+		tool.NewGoTest(),
+		tool.NewFormat(),
+		tool.NewGetMore(agent.Outputs),
 		tool.NewTerminate(),
 	)
 
 	agent.SpecialToolNames = []string{"terminate"}
 	agent.Description = "an autonomous AI programmer that interacts directly with the computer to solve tasks"
 	agent.MaxSteps = 20
+	agent.preparePrompts()
 
 	return agent
 }
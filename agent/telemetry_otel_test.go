@@ -0,0 +1,68 @@
+//go:build otel
+
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"go-manus/telemetry"
+	"go-manus/tool"
+)
+
+func TestRunEmitsOTelSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	telemetry.SetTracer(telemetry.NewOTelTracer("go-manus-test", exporter))
+	defer func() {
+		telemetry.SetTracer(nil)
+		telemetry.Shutdown(context.Background())
+	}()
+
+	fake := newFakeLLM(
+		toolCallReply("call_1", "create_chat_completion", `{"response": "done"}`),
+		toolCallReply("call_2", "terminate", `{"status": "success"}`),
+	)
+	a := NewToolCallAgent("traced")
+	a.AvailableTools.AddTool(tool.NewCreateChatCompletion())
+	a.LLM.SetProvider(fake)
+
+	if _, err := a.Run(context.Background(), "answer"); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	// 区间批量导出，检查前先导出所有已结束的区间
+	if err := otel.GetTracerProvider().(*sdktrace.TracerProvider).ForceFlush(context.Background()); err != nil {
+		t.Fatalf("ForceFlush: %v", err)
+	}
+
+	counts := make(map[string]int)
+	attrs := make(map[string]map[string]string)
+	for _, span := range exporter.GetSpans() {
+		counts[span.Name]++
+		if attrs[span.Name] == nil {
+			attrs[span.Name] = make(map[string]string)
+		}
+		for _, kv := range span.Attributes {
+			attrs[span.Name][string(kv.Key)] = kv.Value.Emit()
+		}
+	}
+
+	want := map[string]int{"agent.run": 1, "agent.step": 2, "llm.ask_tool": 2, "tool.execute": 2}
+	for name, n := range want {
+		if counts[name] != n {
+			t.Errorf("%s spans = %d, want %d (all: %v)", name, counts[name], n, counts)
+		}
+	}
+	if attrs["agent.run"]["agent.name"] != "traced" {
+		t.Errorf("agent.run attributes = %v", attrs["agent.run"])
+	}
+	if attrs["llm.ask_tool"]["llm.total_tokens"] != "15" {
+		t.Errorf("llm.ask_tool attributes = %v", attrs["llm.ask_tool"])
+	}
+	if _, ok := attrs["tool.execute"]["tool.name"]; !ok {
+		t.Errorf("tool.execute attributes = %v", attrs["tool.execute"])
+	}
+}
@@ -2,10 +2,13 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/sashabaranov/go-openai"
+	"go-manus/config"
 	"go-manus/logger"
 	"go-manus/schema"
 	"go-manus/tool"
@@ -19,8 +22,40 @@ type ToolCallAgent struct {
 	ToolChoices    string // "none", "auto", "required"
 	SpecialToolNames []string
 	ToolCalls      []schema.ToolCall
+
+	// AllowedTools 非空时只允许调用其中的工具和特殊工具，其余工具调用会被拒绝（工具仍保留在 schema 中）
+	AllowedTools map[string]bool
+
+	// ReflectionInterval 每隔多少步进行一次自我反思，0 表示关闭
+	ReflectionInterval int
+	// ReflectionPrompt 反思时注入的提示词
+	ReflectionPrompt string
+
+	// TerminateConfirmer 非 nil 时，特殊工具（如 terminate）结束执行前需要确认，
+	// 返回 false 时继续执行，feedback 作为用户消息告知模型未结束的原因
+	TerminateConfirmer func(ctx context.Context, name string, result string) (approved bool, feedback string)
+
+	// MaxObserve 工具输出的最大字符数，0 表示不限制。超出部分保存在 Outputs 中，可通过 get_more 工具读取
+	MaxObserve int
+	// Outputs 保存被截断的完整工具输出
+	Outputs *tool.OutputStore
+
+	// RetryEmptyResponse 为 true 时，模型既没有回复内容也没有调用工具，会提醒模型后再思考一次
+	RetryEmptyResponse bool
+
+	// lastFinishReason 最近一次 LLM 回复的结束原因，用于诊断空回复
+	lastFinishReason string
+	// retryingEmpty 正在因空回复重新思考，避免重复重试
+	retryingEmpty bool
 }
 
+// defaultReflectionPrompt 默认的反思提示词
+const defaultReflectionPrompt = `Pause and reflect on your progress so far before taking the next action.
+Evaluate whether the steps taken are moving you toward completing the original task, identify any mistakes, dead ends or missing information, and state how you will adjust your approach. Do not call any tools in this reply.`
+
+// emptyResponseRetryPrompt 模型回复为空时重新思考前注入的提示词
+const emptyResponseRetryPrompt = `Your previous reply was empty: it had no content and no tool calls. Continue with the task: call a tool, or reply with your answer.`
+
 // NewToolCallAgent 创建工具调用 Agent
 func NewToolCallAgent(name string) *ToolCallAgent {
 	tc := &ToolCallAgent{
@@ -28,13 +63,23 @@ func NewToolCallAgent(name string) *ToolCallAgent {
 		ToolChoices:     "auto",
 		SpecialToolNames: []string{"terminate"},
 		AvailableTools:  tool.NewToolCollection(tool.NewTerminate()),
+		ReflectionPrompt: defaultReflectionPrompt,
+		Outputs:          tool.NewOutputStore(),
+		RetryEmptyResponse: true,
 	}
 	tc.BaseAgent.MaxSteps = 30
+	tc.bind(tc)
 	return tc
 }
 
 // Think 思考下一步行动
 func (a *ToolCallAgent) Think(ctx context.Context) (bool, error) {
+	if a.shouldReflect() {
+		if err := a.reflect(ctx); err != nil {
+			logger.FromContext(ctx).Warnf("Reflection failed: %v", err)
+		}
+	}
+
 	if a.NextStepPrompt != "" {
 		userMsg := schema.NewUserMessage(a.NextStepPrompt)
 		a.Memory.AddMessage(userMsg)
@@ -42,8 +87,8 @@ func (a *ToolCallAgent) Think(ctx context.Context) (bool, error) {
 
 	// 准备系统消息
 	systemMsgs := make([]schema.Message, 0)
-	if a.SystemPrompt != "" {
-		systemMsgs = append(systemMsgs, schema.NewSystemMessage(a.SystemPrompt))
+	if prompt := a.ComposedSystemPrompt(); prompt != "" {
+		systemMsgs = append(systemMsgs, schema.NewSystemMessage(prompt))
 	}
 
 	// 转换工具为 OpenAI 格式
@@ -63,27 +108,28 @@ func (a *ToolCallAgent) Think(ctx context.Context) (bool, error) {
 		}
 	}
 
-	// 调用 LLM
-	response, err := a.LLM.AskTool(ctx, a.Memory.Messages, systemMsgs, openAITools, a.ToolChoices)
+	// 调用 LLM（失败时重试，重试耗尽后切换到备用模型）
+	response, err := a.LLM.AskToolWithRetry(ctx, a.Memory.Messages, systemMsgs, openAITools, a.ToolChoices, 3)
 	if err != nil {
-		logger.Errorf("LLM request failed: %v", err)
+		logger.FromContext(ctx).Errorf("LLM request failed: %v", err)
 		a.Memory.AddMessage(schema.NewAssistantMessage("Error encountered while processing: " + err.Error()))
 		return false, err
 	}
 
-	logger.Infof("✨ %s's thoughts: %s", a.Name, response.Content)
-	logger.Infof("🛠️ %s selected %d tools to use", a.Name, len(response.ToolCalls))
+	logger.FromContext(ctx).Infof("✨ %s's thoughts: %s", a.Name, response.Content)
+	logger.FromContext(ctx).Infof("🛠️ %s selected %d tools to use", a.Name, len(response.ToolCalls))
 
 	if len(response.ToolCalls) > 0 {
 		toolNames := make([]string, 0, len(response.ToolCalls))
 		for _, tc := range response.ToolCalls {
 			toolNames = append(toolNames, tc.Function.Name)
 		}
-		logger.Infof("🧰 Tools being prepared: %v", toolNames)
+		logger.FromContext(ctx).Infof("🧰 Tools being prepared: %v", toolNames)
 	}
 
 	// 保存工具调用
 	a.ToolCalls = response.ToolCalls
+	a.lastFinishReason = response.FinishReason
 
 	// 创建助手消息
 	var assistantMsg schema.Message
@@ -97,7 +143,7 @@ func (a *ToolCallAgent) Think(ctx context.Context) (bool, error) {
 	// 处理不同的工具选择模式
 	if a.ToolChoices == "none" {
 		if len(response.ToolCalls) > 0 {
-			logger.Warningf("🤔 Hmm, %s tried to use tools when they weren't available!", a.Name)
+			logger.FromContext(ctx).Warnf("🤔 Hmm, %s tried to use tools when they weren't available!", a.Name)
 		}
 		return response.Content != "", nil
 	}
@@ -107,12 +153,41 @@ func (a *ToolCallAgent) Think(ctx context.Context) (bool, error) {
 	}
 
 	if a.ToolChoices == "auto" && len(response.ToolCalls) == 0 {
-		return response.Content != "", nil
+		return true, nil // 有内容时由 act() 返回内容，空回复时由 act() 说明原因并重试
 	}
 
 	return len(response.ToolCalls) > 0, nil
 }
 
+// shouldReflect 判断当前步骤是否需要反思
+func (a *ToolCallAgent) shouldReflect() bool {
+	return a.ReflectionInterval > 0 && a.CurrentStep > 1 && (a.CurrentStep-1)%a.ReflectionInterval == 0
+}
+
+// reflect 注入反思提示词并额外思考一次，只记录反思内容而不执行任何工具
+func (a *ToolCallAgent) reflect(ctx context.Context) error {
+	prompt := a.ReflectionPrompt
+	if prompt == "" {
+		prompt = defaultReflectionPrompt
+	}
+	logger.FromContext(ctx).Infof("🪞 %s is reflecting on its progress (step %d)", a.Name, a.CurrentStep)
+	a.Memory.AddMessage(schema.NewUserMessage(prompt))
+
+	systemMsgs := make([]schema.Message, 0)
+	if prompt := a.ComposedSystemPrompt(); prompt != "" {
+		systemMsgs = append(systemMsgs, schema.NewSystemMessage(prompt))
+	}
+
+	reflection, err := a.LLM.AskWithRetry(ctx, a.Memory.Messages, systemMsgs, 3)
+	if err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx).Infof("🪞 %s's reflection: %s", a.Name, reflection)
+	a.Memory.AddMessage(schema.NewAssistantMessage(reflection))
+	return nil
+}
+
 // Act 执行工具调用
 func (a *ToolCallAgent) Act(ctx context.Context) (string, error) {
 	if len(a.ToolCalls) == 0 {
@@ -123,65 +198,177 @@ func (a *ToolCallAgent) Act(ctx context.Context) (string, error) {
 		// 返回最后一条消息内容
 		if len(a.Memory.Messages) > 0 {
 			lastMsg := a.Memory.Messages[len(a.Memory.Messages)-1]
-			if lastMsg.Content != nil {
+			if lastMsg.Content != nil && *lastMsg.Content != "" {
 				return *lastMsg.Content, nil
 			}
 		}
-		return "No content or commands to execute", nil
+
+		// 既没有内容也没有工具调用：说明原因，并可提醒模型后重新思考一次
+		reason := a.emptyResponseReason()
+		if a.RetryEmptyResponse && !a.retryingEmpty {
+			logger.FromContext(ctx).Warnf("⚠️ %s: %s, asking the model again", a.Name, reason)
+			a.retryingEmpty = true
+			defer func() { a.retryingEmpty = false }()
+			a.Memory.AddMessage(schema.NewUserMessage(emptyResponseRetryPrompt))
+			if _, err := a.self.Think(ctx); err != nil {
+				return "", fmt.Errorf("%s, and the retry failed: %w", reason, err)
+			}
+			return a.Act(ctx)
+		}
+		if a.retryingEmpty {
+			reason += " again after being reminded"
+		}
+		logger.FromContext(ctx).Warnf("⚠️ %s: %s", a.Name, reason)
+		return fmt.Sprintf("No content or commands to execute: %s", reason), nil
 	}
 
 	results := make([]string, 0)
+	// 工具返回的图片在所有工具消息之后统一追加，工具消息必须紧跟在带 tool_calls 的助手消息之后
+	images := make([]schema.Message, 0)
+	// declined 结束请求被拒绝时告知模型的用户消息，同样在工具消息之后追加
+	declined := ""
+	// 同一次回复中名称和参数都相同的调用只执行一次，重复的调用复用结果，但仍按调用 ID 各回复一条工具消息
+	executed := make(map[string]string)
 	for _, toolCall := range a.ToolCalls {
-		result, err := a.ExecuteTool(ctx, toolCall)
+		key := toolCallKey(toolCall)
+		if result, ok := executed[key]; ok {
+			logger.FromContext(ctx).Infof("♻️ Skipping duplicate call to '%s', reusing its result", toolCall.Function.Name)
+			a.Memory.AddMessage(schema.NewToolMessage(result, toolCall.Function.Name, toolCall.ID))
+			continue
+		}
+
+		result, image, err := a.executeTool(ctx, toolCall)
 		if err != nil {
-			logger.Errorf("Tool execution failed: %v", err)
+			logger.FromContext(ctx).Errorf("Tool execution failed: %v", err)
 			result = fmt.Sprintf("Error: %v", err)
 		} else {
-			logger.Infof("🎯 Tool '%s' completed its mission! Result: %s", toolCall.Function.Name, result)
+			logger.FromContext(ctx).Infof("🎯 Tool '%s' completed its mission! Result: %s", toolCall.Function.Name, result)
 		}
 
+		executed[key] = result
+
 		// 添加工具响应到记忆
 		toolMsg := schema.NewToolMessage(result, toolCall.Function.Name, toolCall.ID)
 		a.Memory.AddMessage(toolMsg)
 		results = append(results, result)
 
+		// 工具消息不支持图片，模型接受图片输入时通过用户图片消息让它看到截图等结果
+		if image != "" && a.LLM.SupportsImages() {
+			images = append(images, schema.NewUserImageMessage(
+				fmt.Sprintf("Image returned by tool `%s`:", toolCall.Function.Name), image))
+		}
+
 		// 处理特殊工具（如 terminate）
 		if a.isSpecialTool(toolCall.Function.Name) {
-			if a.shouldFinishExecution(toolCall.Function.Name, result) {
-				logger.Infof("🏁 Special tool '%s' has completed the task!", toolCall.Function.Name)
+			finish, feedback := a.shouldFinishExecution(ctx, toolCall.Function.Name, result)
+			if finish {
+				logger.FromContext(ctx).Infof("🏁 Special tool '%s' has completed the task!", toolCall.Function.Name)
 				a.State = schema.AgentStateFINISHED
+			} else {
+				declined = feedback
 			}
 		}
 	}
+	a.Memory.AddMessages(images)
+	if declined != "" {
+		a.Memory.AddMessage(schema.NewUserMessage(declined))
+	}
 
 	return strings.Join(results, "\n\n"), nil
 }
 
+// toolCallKey 返回用于识别重复调用的键：工具名加规范化后的参数（忽略空白和键的顺序），
+// 参数不是合法 JSON 时使用原始字符串
+func toolCallKey(toolCall schema.ToolCall) string {
+	args := toolCall.Function.Arguments
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(args), &parsed); err == nil {
+		if normalized, err := json.Marshal(parsed); err == nil {
+			args = string(normalized)
+		}
+	}
+	return toolCall.Function.Name + "\x00" + args
+}
+
+// emptyResponseReason 根据最近一次回复的结束原因说明为什么没有内容也没有工具调用
+func (a *ToolCallAgent) emptyResponseReason() string {
+	switch a.lastFinishReason {
+	case "content_filter":
+		return "the model's reply was blocked by the provider's content filter"
+	case "length":
+		return "the model hit the max_tokens limit before producing any content or tool calls"
+	case "", "stop":
+		return "the model returned an empty reply"
+	default:
+		return fmt.Sprintf("the model returned an empty reply (finish reason: %s)", a.lastFinishReason)
+	}
+}
+
+// GetTool 按名称获取可用工具，不存在时返回 nil
+func (a *ToolCallAgent) GetTool(name string) tool.Tool {
+	if a.AvailableTools == nil {
+		return nil
+	}
+	t, ok := a.AvailableTools.GetTool(name)
+	if !ok {
+		return nil
+	}
+	return t
+}
+
 // ExecuteTool 执行单个工具调用
 func (a *ToolCallAgent) ExecuteTool(ctx context.Context, toolCall schema.ToolCall) (string, error) {
+	observation, _, err := a.executeTool(ctx, toolCall)
+	return observation, err
+}
+
+// executeTool 执行单个工具调用，同时返回工具结果中的图片
+func (a *ToolCallAgent) executeTool(ctx context.Context, toolCall schema.ToolCall) (string, string, error) {
 	if toolCall.Function.Name == "" {
-		return "Error: Invalid command format", nil
+		return "Error: Invalid command format", "", nil
+	}
+
+	if len(a.AllowedTools) > 0 && !a.AllowedTools[toolCall.Function.Name] && !a.isSpecialTool(toolCall.Function.Name) {
+		logger.FromContext(ctx).Warnf("🚫 Tool '%s' is not allowed for %s", toolCall.Function.Name, a.Name)
+		return fmt.Sprintf("Error: Tool '%s' is not allowed for this task. Allowed tools: %s",
+			toolCall.Function.Name, strings.Join(a.allowedToolNames(), ", ")), "", nil
 	}
 
 	// 解析参数
 	args, err := tool.ParseToolArgs(toolCall.Function.Arguments)
 	if err != nil {
-		return fmt.Sprintf("Error parsing arguments for %s: Invalid JSON format", toolCall.Function.Name), nil
+		return fmt.Sprintf("Error parsing arguments for %s: Invalid JSON format", toolCall.Function.Name), "", nil
 	}
 
 	// 执行工具
-	logger.Infof("🔧 Activating tool: '%s'...", toolCall.Function.Name)
+	logger.FromContext(ctx).Infof("🔧 Activating tool: '%s'...", toolCall.Function.Name)
 	result, err := a.AvailableTools.Execute(ctx, toolCall.Function.Name, args)
 	if err != nil {
-		return fmt.Sprintf("⚠️ Tool '%s' encountered a problem: %v", toolCall.Function.Name, err), nil
+		return fmt.Sprintf("⚠️ Tool '%s' encountered a problem: %v", toolCall.Function.Name, err), "", nil
 	}
 
 	if result.Error != "" {
-		return fmt.Sprintf("Error: %s", result.Error), nil
+		return fmt.Sprintf("Error: %s", result.Error), "", nil
 	}
 
-	observation := fmt.Sprintf("Observed output of cmd `%s` executed:\n%s", toolCall.Function.Name, result.Output)
-	return observation, nil
+	output := result.Output
+	if a.MaxObserve > 0 && a.Outputs != nil {
+		output = a.Outputs.Truncate(output, a.MaxObserve)
+	}
+	observation := fmt.Sprintf("Observed output of cmd `%s` executed:\n%s", toolCall.Function.Name, output)
+	return observation, result.Base64Image, nil
+}
+
+// allowedToolNames 返回排序后的允许工具列表
+func (a *ToolCallAgent) allowedToolNames() []string {
+	names := make([]string, 0, len(a.AllowedTools))
+	for name, allowed := range a.AllowedTools {
+		if allowed {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
 }
 
 // isSpecialTool 检查是否是特殊工具
@@ -194,8 +381,43 @@ func (a *ToolCallAgent) isSpecialTool(name string) bool {
 	return false
 }
 
-// shouldFinishExecution 判断是否应该结束执行
-func (a *ToolCallAgent) shouldFinishExecution(name string, result string) bool {
-	return true // 默认 terminate 工具会结束执行
+// shouldFinishExecution 判断是否应该结束执行，不结束时返回告知模型原因的消息，
+// 由调用方在记录完所有工具消息后添加
+func (a *ToolCallAgent) shouldFinishExecution(ctx context.Context, name string, result string) (bool, string) {
+	if a.TerminateConfirmer == nil {
+		return true, "" // 默认 terminate 工具会结束执行
+	}
+
+	approved, feedback := a.TerminateConfirmer(ctx, name, result)
+	if approved {
+		return true, ""
+	}
+
+	logger.FromContext(ctx).Warnf("✋ %s's request to finish via '%s' was declined", a.Name, name)
+	if feedback == "" {
+		return false, "Finishing the task was not approved. Continue working on the task."
+	}
+	return false, fmt.Sprintf("Finishing the task was not approved: %s\nContinue working on the task.", feedback)
+}
+
+// AskHumanConfirmer 通过 AskHuman 在终端询问用户是否允许结束，用作 TerminateConfirmer；
+// 回答 yes/y/ok 时结束，其他回答作为反馈让 Agent 继续，无法读取输入或非交互模式时直接结束
+func AskHumanConfirmer(ask *tool.AskHuman) func(ctx context.Context, name string, result string) (bool, string) {
+	return func(ctx context.Context, name string, result string) (bool, string) {
+		if !config.Interactive() {
+			return true, ""
+		}
+		inquire := fmt.Sprintf("The agent wants to finish the task (%s):\n%s\n\nApprove? Answer yes, or explain what is still missing.", name, result)
+		answer, err := ask.Execute(ctx, map[string]interface{}{"inquire": inquire})
+		if err != nil || answer.Error != "" {
+			logger.FromContext(ctx).Warnf("Could not ask for termination approval, finishing anyway")
+			return true, ""
+		}
+		switch strings.ToLower(strings.TrimSpace(answer.Output)) {
+		case "yes", "y", "ok":
+			return true, ""
+		}
+		return false, strings.TrimSpace(answer.Output)
+	}
 }
 
@@ -0,0 +1,328 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"go-manus/llm"
+	"go-manus/schema"
+	"go-manus/tool"
+)
+
+// screenshotTool 返回固定截图的工具
+type screenshotTool struct{}
+
+func (screenshotTool) Name() string        { return "screenshot" }
+func (screenshotTool) Description() string { return "Take a screenshot" }
+func (screenshotTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object"}
+}
+func (screenshotTool) Execute(ctx context.Context, args map[string]interface{}) (*tool.ToolResult, error) {
+	return &tool.ToolResult{Output: "captured", Base64Image: "iVBORw0KGgo="}, nil
+}
+
+// toolCallsReply 一次调用多个工具的回复，calls 依次为工具名和 JSON 参数
+func toolCallsReply(calls ...string) openai.ChatCompletionResponse {
+	msg := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}
+	for i := 0; i+1 < len(calls); i += 2 {
+		msg.ToolCalls = append(msg.ToolCalls, openai.ToolCall{
+			ID:       "call_" + calls[i],
+			Type:     openai.ToolTypeFunction,
+			Function: openai.FunctionCall{Name: calls[i], Arguments: calls[i+1]},
+		})
+	}
+	return reply(msg, openai.FinishReasonToolCalls)
+}
+
+func newScreenshotAgent(supportsImages bool) *ToolCallAgent {
+	a := NewToolCallAgent("vision")
+	a.AvailableTools.AddTool(screenshotTool{})
+	a.LLM.SetProvider(newFakeLLM(toolCallsReply("screenshot", `{}`, "terminate", `{"status": "success"}`)))
+	a.LLM.SetSupportsImages(supportsImages)
+	return a
+}
+
+func TestActAddsScreenshotAfterToolMessages(t *testing.T) {
+	a := newScreenshotAgent(true)
+	if _, err := a.Run(context.Background(), "look at the screen"); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	messages := a.GetMessages()
+	roles := make([]schema.MessageRole, 0, len(messages))
+	for _, msg := range messages {
+		roles = append(roles, msg.Role)
+	}
+	want := []schema.MessageRole{schema.RoleUser, schema.RoleAssistant, schema.RoleTool, schema.RoleTool, schema.RoleUser}
+	if len(roles) != len(want) {
+		t.Fatalf("roles = %v, want %v", roles, want)
+	}
+	for i := range want {
+		if roles[i] != want[i] {
+			t.Fatalf("roles = %v, want %v", roles, want)
+		}
+	}
+
+	image := messages[len(messages)-1]
+	if image.Base64Image == nil || *image.Base64Image != "iVBORw0KGgo=" {
+		t.Fatalf("last message does not carry the screenshot: %+v", image)
+	}
+	formatted := llm.FormatMessages([]schema.Message{image})[0].MultiContent
+	if len(formatted) != 2 || formatted[1].Type != openai.ChatMessagePartTypeImageURL {
+		t.Errorf("image message is not sent as multimodal content: %+v", formatted)
+	}
+}
+
+func TestActDropsScreenshotForTextOnlyModel(t *testing.T) {
+	a := newScreenshotAgent(false)
+	if _, err := a.Run(context.Background(), "look at the screen"); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	for _, msg := range a.GetMessages() {
+		if msg.Base64Image != nil {
+			t.Fatalf("text-only model received an image message: %+v", msg)
+		}
+	}
+}
+
+func TestDeclinedTerminateContinuesAfterToolResults(t *testing.T) {
+	fake := newFakeLLM(
+		toolCallsReply("terminate", `{"status": "success"}`, "screenshot", `{}`),
+		toolCallReply("call_2", "terminate", `{"status": "success"}`),
+	)
+	a := NewToolCallAgent("supervised")
+	a.AvailableTools.AddTool(screenshotTool{})
+	a.LLM.SetProvider(fake)
+	asked := 0
+	a.TerminateConfirmer = func(ctx context.Context, name, result string) (bool, string) {
+		asked++
+		return asked > 1, "the report is missing"
+	}
+
+	run, err := a.RunDetailed(context.Background(), "write the report")
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if asked != 2 || fake.calls() != 2 {
+		t.Fatalf("confirmer asked %d times, LLM called %d times; want 2 and 2", asked, fake.calls())
+	}
+	if run.State != schema.AgentStateFINISHED {
+		t.Errorf("state = %s, want %s", run.State, schema.AgentStateFINISHED)
+	}
+
+	// 第二次请求中，拒绝结束的说明排在两条工具结果之后
+	second := fake.requests[1].Messages
+	roles := make([]string, 0, len(second))
+	for _, msg := range second {
+		roles = append(roles, msg.Role)
+	}
+	tail := roles[len(roles)-4:]
+	want := []string{"assistant", "tool", "tool", "user"}
+	for i := range want {
+		if tail[i] != want[i] {
+			t.Fatalf("second request roles = %v, want them to end with %v", roles, want)
+		}
+	}
+	if last := second[len(second)-1].Content; !strings.Contains(last, "the report is missing") {
+		t.Errorf("decline message = %q", last)
+	}
+}
+
+func TestActExplainsEmptyReplyAndRetriesOnce(t *testing.T) {
+	empty := reply(openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}, openai.FinishReasonContentFilter)
+	fake := newFakeLLM(empty, empty)
+	a := NewToolCallAgent("empty")
+	a.MaxSteps = 1
+	a.LLM.SetProvider(fake)
+
+	run, err := a.RunDetailed(context.Background(), "do something")
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if fake.calls() != 2 {
+		t.Errorf("LLM calls = %d, want 2 (one retry)", fake.calls())
+	}
+	want := "No content or commands to execute: the model's reply was blocked by the provider's content filter again after being reminded"
+	if len(run.Steps) != 1 || run.Steps[0].Result != want {
+		t.Errorf("step result = %+v, want %q", run.Steps, want)
+	}
+
+	retry := fake.requests[1].Messages
+	if last := retry[len(retry)-1]; last.Role != openai.ChatMessageRoleUser || last.Content != emptyResponseRetryPrompt {
+		t.Errorf("retry request does not end with the reminder: %+v", last)
+	}
+}
+
+func TestActUsesRetriedReply(t *testing.T) {
+	empty := reply(openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}, openai.FinishReasonStop)
+	fake := newFakeLLM(empty, textReply("Here is the answer."))
+	a := NewToolCallAgent("empty")
+	a.MaxSteps = 1
+	a.LLM.SetProvider(fake)
+
+	run, err := a.RunDetailed(context.Background(), "do something")
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if len(run.Steps) != 1 || run.Steps[0].Result != "Here is the answer." {
+		t.Errorf("step result = %+v, want the retried reply", run.Steps)
+	}
+}
+
+// countingTool 记录执行次数的工具
+type countingTool struct{ calls int }
+
+func (c *countingTool) Name() string        { return "lookup" }
+func (c *countingTool) Description() string { return "Look something up" }
+func (c *countingTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object"}
+}
+func (c *countingTool) Execute(ctx context.Context, args map[string]interface{}) (*tool.ToolResult, error) {
+	c.calls++
+	return &tool.ToolResult{Output: "found it"}, nil
+}
+
+func TestActRunsIdenticalToolCallsOnce(t *testing.T) {
+	call := func(id, args string) openai.ToolCall {
+		return openai.ToolCall{ID: id, Type: openai.ToolTypeFunction, Function: openai.FunctionCall{Name: "lookup", Arguments: args}}
+	}
+	fake := newFakeLLM(
+		reply(openai.ChatCompletionMessage{
+			Role: openai.ChatMessageRoleAssistant,
+			ToolCalls: []openai.ToolCall{
+				call("call_a", `{"q": "go", "limit": 1}`),
+				call("call_b", `{"limit":1,"q":"go"}`),
+			},
+		}, openai.FinishReasonToolCalls),
+		toolCallReply("call_c", "terminate", `{"status": "success"}`),
+	)
+	lookup := &countingTool{}
+	a := NewToolCallAgent("dedup")
+	a.AvailableTools.AddTool(lookup)
+	a.LLM.SetProvider(fake)
+
+	run, err := a.RunDetailed(context.Background(), "look it up twice")
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if lookup.calls != 1 {
+		t.Errorf("tool ran %d times, want 1", lookup.calls)
+	}
+
+	// 每个调用 ID 仍有各自的工具消息和相同的结果
+	calls := run.Steps[0].ToolCalls
+	if len(calls) != 2 {
+		t.Fatalf("step 1 recorded %d tool calls, want 2", len(calls))
+	}
+	for _, c := range calls {
+		if !strings.Contains(c.Observation, "found it") {
+			t.Errorf("call %s observation = %q", c.ID, c.Observation)
+		}
+	}
+}
+
+func TestAskHumanConfirmerApprovesWhenNonInteractive(t *testing.T) {
+	a := NewToolCallAgent("unattended")
+	a.LLM.SetProvider(newFakeLLM(toolCallReply("call_1", "terminate", `{"status": "success"}`)))
+	a.TerminateConfirmer = AskHumanConfirmer(tool.NewAskHuman())
+
+	run, err := a.RunDetailed(context.Background(), "finish without asking")
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if run.State != schema.AgentStateFINISHED {
+		t.Errorf("state = %s, want %s", run.State, schema.AgentStateFINISHED)
+	}
+}
+
+func TestReflectionPromptInjectedAtInterval(t *testing.T) {
+	lookup := func(n string) openai.ChatCompletionResponse {
+		return toolCallReply("call_"+n, "lookup", `{"q": "`+n+`"}`)
+	}
+	fake := newFakeLLM(
+		lookup("1"),
+		lookup("2"),
+		textReply("On track, keep looking."),
+		lookup("3"),
+		lookup("4"),
+		textReply("Nearly done."),
+		toolCallReply("call_5", "terminate", `{"status": "success"}`),
+	)
+	a := NewToolCallAgent("reflective")
+	a.AvailableTools.AddTool(&countingTool{})
+	a.LLM.SetProvider(fake)
+	a.ReflectionInterval = 2
+	a.ReflectionPrompt = "Are you on track?"
+
+	if _, err := a.Run(context.Background(), "find it"); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if fake.calls() != 7 {
+		t.Fatalf("LLM called %d times, want 5 steps and 2 reflections", fake.calls())
+	}
+
+	// 第 3 步和第 5 步开始时各反思一次，反思请求以反思提示词结尾且不带工具
+	var reflections []int
+	for i, req := range fake.requests {
+		last := req.Messages[len(req.Messages)-1]
+		if last.Content != "Are you on track?" {
+			continue
+		}
+		reflections = append(reflections, i)
+		if len(req.Tools) != 0 {
+			t.Errorf("reflection request %d offers %d tools, want none", i, len(req.Tools))
+		}
+	}
+	if len(reflections) != 2 || reflections[0] != 2 || reflections[1] != 5 {
+		t.Errorf("reflection prompt sent in requests %v, want [2 5]", reflections)
+	}
+}
+
+func TestAllowedToolsRefusesOtherTools(t *testing.T) {
+	fake := newFakeLLM(
+		toolCallsReply("lookup", `{}`, "screenshot", `{}`),
+		toolCallReply("call_t", "terminate", `{"status": "success"}`),
+	)
+	lookup := &countingTool{}
+	a := NewToolCallAgent("restricted")
+	a.AvailableTools.AddTool(lookup)
+	a.AvailableTools.AddTool(screenshotTool{})
+	a.LLM.SetProvider(fake)
+	a.AllowedTools = map[string]bool{"screenshot": true}
+
+	run, err := a.RunDetailed(context.Background(), "look it up")
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if lookup.calls != 0 {
+		t.Errorf("disallowed tool ran %d times", lookup.calls)
+	}
+	if run.State != schema.AgentStateFINISHED {
+		t.Errorf("state = %s, want terminate to stay allowed", run.State)
+	}
+
+	calls := run.Steps[0].ToolCalls
+	if len(calls) != 2 {
+		t.Fatalf("step 1 recorded %d tool calls, want 2", len(calls))
+	}
+	if !strings.Contains(calls[0].Observation, "Tool 'lookup' is not allowed for this task. Allowed tools: screenshot") {
+		t.Errorf("lookup observation = %q", calls[0].Observation)
+	}
+	if !strings.Contains(calls[1].Observation, "captured") {
+		t.Errorf("screenshot observation = %q", calls[1].Observation)
+	}
+
+	// 被拒绝的工具仍在发送给模型的 schema 中
+	var offered bool
+	for _, tool := range fake.requests[0].Tools {
+		if tool.Function.Name == "lookup" {
+			offered = true
+		}
+	}
+	if !offered {
+		t.Error("lookup was removed from the tool schema")
+	}
+}
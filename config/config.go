@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sync"
 
 	"github.com/pelletier/go-toml/v2"
@@ -15,10 +16,127 @@ type LLMSettings struct {
 	APIKey      string  `toml:"api_key"`
 	MaxTokens   int     `toml:"max_tokens"`
 	Temperature float64 `toml:"temperature"`
+	// SupportsImages 模型是否接受图片输入，为 true 时截图等工具结果会以图片消息发送给模型
+	SupportsImages bool `toml:"supports_images"`
+}
+
+// TelemetrySettings 追踪配置
+type TelemetrySettings struct {
+	Enabled     bool   `toml:"enabled"`
+	Exporter    string `toml:"exporter"`
+	ServiceName string `toml:"service_name"`
+	// OTLPEndpoint exporter 为 otel 时 OTLP/HTTP 收集器的地址（host:port），为空时将区间以 JSON 写到标准输出
+	OTLPEndpoint string `toml:"otlp_endpoint"`
+	// OTLPInsecure 为 true 时使用 HTTP 而不是 HTTPS 连接收集器
+	OTLPInsecure bool `toml:"otlp_insecure"`
+}
+
+// CircuitBreakerSettings 工具熔断配置
+type CircuitBreakerSettings struct {
+	// FailureThreshold 触发熔断的连续失败次数，默认为 0 即关闭熔断
+	FailureThreshold int `toml:"failure_threshold"`
+	WindowSeconds    int `toml:"window_seconds"`
+	CooldownSeconds  int `toml:"cooldown_seconds"`
+}
+
+// VisualizationSettings 数据可视化配置
+type VisualizationSettings struct {
+	Offline bool `toml:"offline"`
+	// ChartJSPath 离线模式内联的本地 Chart.js，相对路径相对于工作目录
+	ChartJSPath string `toml:"chartjs_path"`
+	// OutputDir 图表及其 CSV/JSON 的输出目录，相对于工作目录
+	OutputDir string `toml:"output_dir"`
+}
+
+// SearchSettings 搜索引擎配置
+type SearchSettings struct {
+	BingAPIKey   string `toml:"bing_api_key"`
+	BingEndpoint string `toml:"bing_endpoint"`
+	SearxngURL   string `toml:"searxng_url"`
+	// DefaultEngine 未指定 engine 时使用的搜索引擎
+	DefaultEngine string `toml:"default_engine"`
+	// FallbackOrder 默认引擎失败时依次尝试的引擎，为空时不回退
+	FallbackOrder []string `toml:"fallback_order"`
+}
+
+// ComputerUseSettings 桌面自动化配置
+type ComputerUseSettings struct {
+	// Bounds 允许鼠标操作的区域 [x, y, width, height]，为空时不限制
+	Bounds []int `toml:"bounds"`
+	// ScreenshotDir 截图保存目录，相对于工作目录
+	ScreenshotDir string `toml:"screenshot_dir"`
+}
+
+// BashSettings bash 工具的命令限制，列表均为空时不做限制
+type BashSettings struct {
+	// Allow/Deny 按命令名（每段命令的第一个词）匹配
+	Allow []string `toml:"allow"`
+	Deny  []string `toml:"deny"`
+	// AllowPatterns/DenyPatterns 按正则匹配完整命令
+	AllowPatterns []string `toml:"allow_patterns"`
+	DenyPatterns  []string `toml:"deny_patterns"`
+}
+
+// PlanningSettings 计划管理配置
+type PlanningSettings struct {
+	// StorageDir 计划文件保存目录，相对于工作目录
+	StorageDir string `toml:"storage_dir"`
+}
+
+// AgentSettings 助手身份配置
+type AgentSettings struct {
+	// Name 助手名称，写入 Agent 的系统提示词
+	Name string `toml:"name"`
+	// Persona 可选的人设说明，附加在所有 Agent 的系统提示词之后
+	Persona string `toml:"persona"`
+}
+
+// PromptSettings 提示词配置
+type PromptSettings struct {
+	// Dir 提示词目录，相对于启动目录。<Dir>/<agent>/system_prompt.md 和
+	// next_step_prompt.md 存在时替换对应 Agent 的内置提示词
+	Dir string `toml:"dir"`
+}
+
+// RuntimeSettings 运行模式配置
+type RuntimeSettings struct {
+	// Interactive 是否以交互模式运行，nil 表示根据标准输入是否为终端自动判断
+	Interactive *bool `toml:"interactive"`
+}
+
+// WorkspaceSettings 工作目录配置
+type WorkspaceSettings struct {
+	// Root 工具读写文件的根目录，相对路径相对于启动目录
+	Root string `toml:"workspace_root"`
+	// Unrestricted 为 true 时不限制文件工具和 bash 只能访问 Root 以内的路径
+	Unrestricted bool `toml:"unrestricted"`
+}
+
+// BrowserSettings 浏览器配置
+type BrowserSettings struct {
+	// ChromePath Chrome/Chromium 可执行文件路径，为空时自动查找
+	ChromePath string `toml:"chrome_path"`
+	// Flags 额外的启动参数，如 "--proxy-server=http://127.0.0.1:8080"、"--no-sandbox"
+	Flags []string `toml:"flags"`
+	// ScreenshotDir 截图保存目录，相对于工作目录
+	ScreenshotDir string `toml:"screenshot_dir"`
 }
 
 type AppConfig struct {
-	LLM map[string]LLMSettings `toml:"llm"`
+	LLM            map[string]LLMSettings `toml:"llm"`
+	Formatters     map[string]string      `toml:"format"`
+	Telemetry      TelemetrySettings      `toml:"telemetry"`
+	CircuitBreaker CircuitBreakerSettings `toml:"circuit_breaker"`
+	Visualization  VisualizationSettings  `toml:"visualization"`
+	Search         SearchSettings         `toml:"search"`
+	ComputerUse    ComputerUseSettings    `toml:"computer_use"`
+	Planning       PlanningSettings       `toml:"planning"`
+	Bash           BashSettings           `toml:"bash"`
+	Browser        BrowserSettings        `toml:"browser"`
+	Workspace      WorkspaceSettings      `toml:"workspace"`
+	Runtime        RuntimeSettings        `toml:"runtime"`
+	Agent          AgentSettings          `toml:"agent"`
+	Prompts        PromptSettings         `toml:"prompts"`
 }
 
 type Config struct {
@@ -92,18 +210,19 @@ func (c *Config) loadConfig() {
 
 	// 获取基础配置
 	baseLLM := LLMSettings{
-		Model:       getString(llmRaw, "model", ""),
-		BaseURL:     getString(llmRaw, "base_url", ""),
-		APIKey:      getString(llmRaw, "api_key", ""),
-		MaxTokens:   getInt(llmRaw, "max_tokens", 4096),
-		Temperature: getFloat(llmRaw, "temperature", 0.0),
+		Model:          getString(llmRaw, "model", ""),
+		BaseURL:        getString(llmRaw, "base_url", ""),
+		APIKey:         getString(llmRaw, "api_key", ""),
+		MaxTokens:      getInt(llmRaw, "max_tokens", 4096),
+		Temperature:    getFloat(llmRaw, "temperature", 0.0),
+		SupportsImages: getBool(llmRaw, "supports_images", false),
 	}
 
 	llmConfig["default"] = baseLLM
 
 	// 处理覆盖配置（如 llm.vision）
 	for k, v := range llmRaw {
-		if k == "model" || k == "base_url" || k == "api_key" || k == "max_tokens" || k == "temperature" {
+		if k == "model" || k == "base_url" || k == "api_key" || k == "max_tokens" || k == "temperature" || k == "supports_images" {
 			continue
 		}
 		if override, ok := v.(map[string]interface{}); ok {
@@ -123,11 +242,145 @@ func (c *Config) loadConfig() {
 			if temp := getFloat(override, "temperature", -1); temp >= 0 {
 				overrideSettings.Temperature = temp
 			}
+			if supportsImages, ok := override["supports_images"].(bool); ok {
+				overrideSettings.SupportsImages = supportsImages
+			}
 			llmConfig[k] = overrideSettings
 		}
 	}
 
-	c.config = &AppConfig{LLM: llmConfig}
+	// 解析格式化工具配置（文件扩展名 -> 格式化命令）
+	formatters := make(map[string]string)
+	if formatRaw, ok := rawConfig["format"].(map[string]interface{}); ok {
+		for ext := range formatRaw {
+			if command := getString(formatRaw, ext, ""); command != "" {
+				formatters[ext] = command
+			}
+		}
+	}
+
+	// 解析追踪配置
+	telemetryRaw, _ := rawConfig["telemetry"].(map[string]interface{})
+	telemetry := TelemetrySettings{
+		Enabled:     getBool(telemetryRaw, "enabled", false),
+		Exporter:    getString(telemetryRaw, "exporter", "log"),
+		ServiceName: getString(telemetryRaw, "service_name", "go-manus"),
+		OTLPEndpoint: getString(telemetryRaw, "otlp_endpoint", ""),
+		OTLPInsecure: getBool(telemetryRaw, "otlp_insecure", false),
+	}
+
+	// 解析工具熔断配置
+	breakerRaw, _ := rawConfig["circuit_breaker"].(map[string]interface{})
+	circuitBreaker := CircuitBreakerSettings{
+		FailureThreshold: getInt(breakerRaw, "failure_threshold", 0),
+		WindowSeconds:    getInt(breakerRaw, "window_seconds", 60),
+		CooldownSeconds:  getInt(breakerRaw, "cooldown_seconds", 120),
+	}
+
+	// 解析数据可视化配置
+	visualizationRaw, _ := rawConfig["visualization"].(map[string]interface{})
+	visualization := VisualizationSettings{
+		Offline:     getBool(visualizationRaw, "offline", false),
+		ChartJSPath: getString(visualizationRaw, "chartjs_path", "assets/chart.umd.min.js"),
+		OutputDir:   getString(visualizationRaw, "output_dir", "charts"),
+	}
+
+	// 解析搜索引擎配置
+	searchRaw, _ := rawConfig["search"].(map[string]interface{})
+	search := SearchSettings{
+		BingAPIKey:    getString(searchRaw, "bing_api_key", ""),
+		BingEndpoint:  getString(searchRaw, "bing_endpoint", "https://api.bing.microsoft.com/v7.0/search"),
+		SearxngURL:    getString(searchRaw, "searxng_url", ""),
+		DefaultEngine: getString(searchRaw, "default_engine", "google"),
+		FallbackOrder: []string{"bing", "duckduckgo", "baidu"},
+	}
+	// 显式配置的空列表表示不回退
+	if _, ok := searchRaw["fallback_order"]; ok {
+		search.FallbackOrder = getStringSlice(searchRaw, "fallback_order")
+	}
+
+	// 解析桌面自动化配置
+	computerUseRaw, _ := rawConfig["computer_use"].(map[string]interface{})
+	computerUse := ComputerUseSettings{
+		ScreenshotDir: getString(computerUseRaw, "screenshot_dir", "screenshots"),
+	}
+	if bounds, ok := computerUseRaw["bounds"].([]interface{}); ok {
+		for _, v := range bounds {
+			switch n := v.(type) {
+			case int64:
+				computerUse.Bounds = append(computerUse.Bounds, int(n))
+			case float64:
+				computerUse.Bounds = append(computerUse.Bounds, int(n))
+			}
+		}
+	}
+
+	// 解析计划管理配置
+	planningRaw, _ := rawConfig["planning"].(map[string]interface{})
+	planning := PlanningSettings{
+		StorageDir: getString(planningRaw, "storage_dir", "plans"),
+	}
+
+	// 解析 bash 命令限制
+	bashRaw, _ := rawConfig["bash"].(map[string]interface{})
+	bash := BashSettings{
+		Allow:         getStringSlice(bashRaw, "allow"),
+		Deny:          getStringSlice(bashRaw, "deny"),
+		AllowPatterns: getStringSlice(bashRaw, "allow_patterns"),
+		DenyPatterns:  getStringSlice(bashRaw, "deny_patterns"),
+	}
+
+	// 解析浏览器配置
+	browserRaw, _ := rawConfig["browser"].(map[string]interface{})
+	browser := BrowserSettings{
+		ChromePath:    getString(browserRaw, "chrome_path", ""),
+		Flags:         getStringSlice(browserRaw, "flags"),
+		ScreenshotDir: getString(browserRaw, "screenshot_dir", "screenshots"),
+	}
+
+	// 解析工作目录配置
+	workspaceRaw, _ := rawConfig["workspace"].(map[string]interface{})
+	workspace := WorkspaceSettings{
+		Root:         getString(workspaceRaw, "workspace_root", "workspace"),
+		Unrestricted: getBool(workspaceRaw, "unrestricted", false),
+	}
+
+	// 解析运行模式配置，未设置 interactive 时自动判断
+	runtimeRaw, _ := rawConfig["runtime"].(map[string]interface{})
+	runtime := RuntimeSettings{}
+	if interactive, ok := runtimeRaw["interactive"].(bool); ok {
+		runtime.Interactive = &interactive
+	}
+
+	// 解析助手身份配置
+	agentRaw, _ := rawConfig["agent"].(map[string]interface{})
+	agent := AgentSettings{
+		Name:    getString(agentRaw, "name", "OpenManus"),
+		Persona: getString(agentRaw, "persona", ""),
+	}
+
+	// 解析提示词配置
+	promptsRaw, _ := rawConfig["prompts"].(map[string]interface{})
+	prompts := PromptSettings{
+		Dir: getString(promptsRaw, "dir", "prompts"),
+	}
+
+	c.config = &AppConfig{
+		LLM:            llmConfig,
+		Formatters:     formatters,
+		Telemetry:      telemetry,
+		CircuitBreaker: circuitBreaker,
+		Visualization:  visualization,
+		Search:         search,
+		ComputerUse:    computerUse,
+		Planning:       planning,
+		Bash:           bash,
+		Browser:        browser,
+		Workspace:      workspace,
+		Runtime:        runtime,
+		Agent:          agent,
+		Prompts:        prompts,
+	}
 }
 
 // GetLLM 获取 LLM 配置
@@ -141,6 +394,62 @@ func (c *Config) GetLLM(name string) LLMSettings {
 	return c.config.LLM["default"]
 }
 
+// HasLLM 检查是否显式配置了指定的 LLM 配置
+func (c *Config) HasLLM(name string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	_, ok := c.config.LLM[name]
+	return ok
+}
+
+// GetFormatters 获取按文件扩展名配置的格式化命令
+func (c *Config) GetFormatters() map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	formatters := make(map[string]string, len(c.config.Formatters))
+	for ext, command := range c.config.Formatters {
+		formatters[ext] = command
+	}
+	return formatters
+}
+
+// GetTelemetry 获取追踪配置
+func (c *Config) GetTelemetry() TelemetrySettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config.Telemetry
+}
+
+// GetCircuitBreaker 获取工具熔断配置
+func (c *Config) GetCircuitBreaker() CircuitBreakerSettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config.CircuitBreaker
+}
+
+// GetVisualization 获取数据可视化配置
+func (c *Config) GetVisualization() VisualizationSettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config.Visualization
+}
+
+// GetSearch 获取搜索引擎配置
+func (c *Config) GetSearch() SearchSettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config.Search
+}
+
+// GetComputerUse 获取桌面自动化配置
+func (c *Config) GetComputerUse() ComputerUseSettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config.ComputerUse
+}
+
 // 辅助函数
 func getString(m map[string]interface{}, key string, defaultValue string) string {
 	if v, ok := m[key].(string); ok {
@@ -159,6 +468,13 @@ func getInt(m map[string]interface{}, key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getBool(m map[string]interface{}, key string, defaultValue bool) bool {
+	if v, ok := m[key].(bool); ok {
+		return v
+	}
+	return defaultValue
+}
+
 func getFloat(m map[string]interface{}, key string, defaultValue float64) float64 {
 	if v, ok := m[key].(float64); ok {
 		return v
@@ -166,3 +482,94 @@ func getFloat(m map[string]interface{}, key string, defaultValue float64) float6
 	return defaultValue
 }
 
+func getStringSlice(m map[string]interface{}, key string) []string {
+	items, _ := m[key].([]interface{})
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok && s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+
+// GetBash 获取 bash 命令限制配置
+func (c *Config) GetBash() BashSettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config.Bash
+}
+
+// GetPlanning 获取计划管理配置
+func (c *Config) GetPlanning() PlanningSettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config.Planning
+}
+
+// GetBrowser 获取浏览器配置
+func (c *Config) GetBrowser() BrowserSettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config.Browser
+}
+
+// GetWorkspace 获取工作目录配置
+func (c *Config) GetWorkspace() WorkspaceSettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config.Workspace
+}
+
+// GetRuntime 获取运行模式配置
+func (c *Config) GetRuntime() RuntimeSettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config.Runtime
+}
+
+// GetAgent 获取助手身份配置
+func (c *Config) GetAgent() AgentSettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config.Agent
+}
+
+// GetPrompts 获取提示词配置
+func (c *Config) GetPrompts() PromptSettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config.Prompts
+}
+
+// Validate 检查配置是否完整，返回发现的第一个问题
+func (c *Config) Validate() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	settings, ok := c.config.LLM["default"]
+	if !ok {
+		return fmt.Errorf("[llm] section is missing")
+	}
+	if settings.Model == "" {
+		return fmt.Errorf("llm.model is empty")
+	}
+	if settings.BaseURL == "" {
+		return fmt.Errorf("llm.base_url is empty")
+	}
+	if settings.APIKey == "" {
+		return fmt.Errorf("llm.api_key is empty")
+	}
+	for _, patterns := range [][]string{c.config.Bash.AllowPatterns, c.config.Bash.DenyPatterns} {
+		for _, pattern := range patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("invalid bash pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	if bounds := c.config.ComputerUse.Bounds; len(bounds) != 0 && len(bounds) != 4 {
+		return fmt.Errorf("computer_use.bounds must be [x, y, width, height]")
+	}
+	return nil
+}
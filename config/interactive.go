@@ -0,0 +1,39 @@
+package config
+
+import (
+	"os"
+	"sync"
+)
+
+var (
+	interactiveMu sync.RWMutex
+	// interactiveOverride 通过 SetInteractive 设置的交互模式，nil 表示未设置
+	interactiveOverride *bool
+)
+
+// SetInteractive 设置全局交互模式（如来自命令行参数），优先于配置文件和终端检测
+func SetInteractive(interactive bool) {
+	interactiveMu.Lock()
+	defer interactiveMu.Unlock()
+	interactiveOverride = &interactive
+}
+
+// Interactive 返回是否以交互模式运行，AskHuman、结束确认和命令行都据此决定是否等待用户输入。
+// 依次取 SetInteractive 的设置、配置文件中的 [runtime] interactive，都没有时检测标准输入是否为终端
+func Interactive() bool {
+	interactiveMu.RLock()
+	override := interactiveOverride
+	interactiveMu.RUnlock()
+	if override != nil {
+		return *override
+	}
+	if v := GetInstance().GetRuntime().Interactive; v != nil {
+		return *v
+	}
+	return stdinIsTerminal()
+}
+
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"go-manus/agent"
+	"go-manus/config"
+	"go-manus/tool"
+)
+
+// runDoctor 检查运行环境与各工具的依赖，打印 PASS/FAIL 报告，全部通过时返回 0
+func runDoctor() int {
+	ctx := context.Background()
+
+	// 配置文件缺失时加载会 panic，RunChecks 将其记为失败；配置无效时跳过其余检查
+	configCheck := tool.Check{Name: "config", Run: func(ctx context.Context) error {
+		return config.GetInstance().Validate()
+	}}
+	results := tool.RunChecks(ctx, []tool.Check{configCheck})
+	if results[0].Err == nil {
+		manus := agent.NewManus()
+		checks := []tool.Check{
+			tool.CommandCheck("python3", "python3", "python"),
+			{Name: "llm", Run: manus.LLM.Ping},
+		}
+		checks = append(checks, manus.AvailableTools.ToolChecks()...)
+		results = append(results, tool.RunChecks(ctx, checks)...)
+	}
+
+	report, ok := tool.FormatReport(results)
+	fmt.Println(report)
+	if !ok {
+		return 1
+	}
+	return 0
+}
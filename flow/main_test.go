@@ -0,0 +1,153 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"go-manus/agent"
+	"go-manus/config"
+)
+
+// testConfig 测试使用的最小配置，LLM 请求都由 stepLLM 处理
+const testConfig = `[llm]
+model = "test-model"
+base_url = "http://127.0.0.1:1/v1"
+api_key = "test"
+`
+
+// TestMain 在临时目录中运行测试，计划文件都写在临时目录下
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "go-manus-flow-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "config"), 0755); err == nil {
+		err = os.WriteFile(filepath.Join(dir, "config", "config.toml"), []byte(testConfig), 0644)
+	}
+	if err == nil {
+		err = os.Chdir(dir)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	config.SetInteractive(false)
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// stepLLM 模拟执行计划步骤的模型：每次请求记录最后一条用户消息（即步骤请求），
+// 然后调用 terminate 结束该步骤。planReply 非空时，规划请求返回该内容
+type stepLLM struct {
+	mu        sync.Mutex
+	planReply string
+	planErr   error
+	prompts   []string
+	// reply 非空时，执行步骤的请求只回复该文本而不调用 terminate，步骤一直运行到步数上限
+	reply string
+	// block 非 nil 时，执行步骤的请求会等待 block 关闭或 ctx 取消
+	block chan struct{}
+}
+
+func (s *stepLLM) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	if len(req.Messages) > 0 && req.Messages[0].Content == planningPrompt {
+		if s.planErr != nil {
+			return openai.ChatCompletionResponse{}, s.planErr
+		}
+		return chatReply(openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: s.planReply}), nil
+	}
+
+	last := ""
+	for _, msg := range req.Messages {
+		if msg.Role == openai.ChatMessageRoleUser {
+			last = msg.Content
+		}
+	}
+	s.mu.Lock()
+	s.prompts = append(s.prompts, last)
+	n := len(s.prompts)
+	s.mu.Unlock()
+
+	if s.block != nil {
+		select {
+		case <-s.block:
+		case <-ctx.Done():
+			return openai.ChatCompletionResponse{}, ctx.Err()
+		}
+	}
+
+	if s.reply != "" {
+		return chatReply(openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: fmt.Sprintf("%s %d", s.reply, n)}), nil
+	}
+	return chatReply(openai.ChatCompletionMessage{
+		Role: openai.ChatMessageRoleAssistant,
+		ToolCalls: []openai.ToolCall{{
+			ID:       fmt.Sprintf("call_%d", n),
+			Type:     openai.ToolTypeFunction,
+			Function: openai.FunctionCall{Name: "terminate", Arguments: `{"status": "success"}`},
+		}},
+	}), nil
+}
+
+// calls 返回执行步骤的请求数
+func (s *stepLLM) calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.prompts)
+}
+
+// stepPrompts 返回执行过的步骤请求，只保留第一行（去掉共享记录）
+func (s *stepLLM) stepPrompts() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prompts := make([]string, 0, len(s.prompts))
+	for _, p := range s.prompts {
+		first, _, _ := strings.Cut(p, "\n")
+		prompts = append(prompts, first)
+	}
+	return prompts
+}
+
+func chatReply(msg openai.ChatCompletionMessage) openai.ChatCompletionResponse {
+	return openai.ChatCompletionResponse{
+		Model:   "test-model",
+		Choices: []openai.ChatCompletionChoice{{Message: msg, FinishReason: openai.FinishReasonStop}},
+	}
+}
+
+// newTestFlow 创建只有一个执行 Agent 的 PlanningFlow，计划保存在以测试名命名的目录中
+func newTestFlow(t *testing.T, llm *stepLLM) (*PlanningFlow, *agent.ToolCallAgent) {
+	t.Helper()
+	executor := agent.NewToolCallAgent("executor")
+	executor.LLM.SetProvider(llm)
+	f := NewPlanningFlow(map[string]*agent.BaseAgent{"executor": executor.BaseAgent}, "executor")
+	f.planningTool.SetStorageDir(filepath.Join("plans", t.Name()))
+	return f, executor
+}
+
+// createTestPlan 直接通过 planning 工具创建计划
+func createTestPlan(t *testing.T, f *PlanningFlow, planID string, steps ...string) {
+	t.Helper()
+	items := make([]interface{}, 0, len(steps))
+	for _, s := range steps {
+		items = append(items, s)
+	}
+	result, err := f.planningTool.Execute(context.Background(), map[string]interface{}{
+		"command": "create",
+		"plan_id": planID,
+		"title":   "Test plan",
+		"steps":   items,
+	})
+	if err != nil || !result.IsSuccess() {
+		t.Fatalf("create plan: %v %s", err, result.Error)
+	}
+}
@@ -2,8 +2,12 @@ package flow
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"regexp"
 	"strings"
+	"time"
 
 	"go-manus/agent"
 	"go-manus/logger"
@@ -18,6 +22,16 @@ type PlanningFlow struct {
 	activePlanID string
 	currentStepIndex int
 	executorKeys []string
+
+	// StepTimeout 单个步骤的最长执行时间，超时后步骤被标记为 blocked，0 表示不限制
+	StepTimeout time.Duration
+	// StepMaxSteps 执行单个步骤时覆盖 Agent 的 MaxSteps，0 表示使用 Agent 自身配置
+	StepMaxSteps int
+	// PreviewOnly 为 true 时 Execute 只创建计划并返回计划内容，不执行任何步骤
+	PreviewOnly bool
+	// Scratchpad 非 nil 时在步骤之间共享：每个步骤完成后记录其结果，执行步骤时注入提示词，
+	// 调用方也可以预先写入需要所有步骤知道的信息
+	Scratchpad *Scratchpad
 }
 
 // NewPlanningFlow 创建 Planning Flow
@@ -35,18 +49,97 @@ func NewPlanningFlow(agents map[string]*agent.BaseAgent, primaryKey string) *Pla
 	}
 }
 
+// newPlanID 生成计划 ID：时间戳加随机后缀，同一秒内创建的计划也不会重名
+func newPlanID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("plan_%d", time.Now().UnixNano())
+	}
+	return fmt.Sprintf("plan_%d_%s", time.Now().Unix(), hex.EncodeToString(b))
+}
+
 // Execute 执行规划流程
 func (p *PlanningFlow) Execute(ctx context.Context, inputText string) (string, error) {
-	logger.Infof("Starting PlanningFlow execution for: %s", inputText)
+	if p.PreviewOnly {
+		planID, preview, err := p.Preview(ctx, inputText)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Plan %s was created for review and has not been executed. Call ExecuteApproved with plan ID %s to run it.\n\n%s", planID, planID, preview), nil
+	}
+
+	logger.FromContext(ctx).Infof("Starting PlanningFlow execution for: %s", inputText)
 
 	// 创建初始计划
-	planID := fmt.Sprintf("plan_%d", p.planningTool.GetActivePlan() != nil)
+	planID := newPlanID()
 	if err := p.createInitialPlan(ctx, inputText, planID); err != nil {
 		return "", fmt.Errorf("failed to create plan: %w", err)
 	}
 
 	p.activePlanID = planID
 
+	return p.executePlan(ctx)
+}
+
+// Preview 创建计划并返回格式化的计划内容供审阅，不执行任何步骤
+func (p *PlanningFlow) Preview(ctx context.Context, inputText string) (string, string, error) {
+	logger.FromContext(ctx).Infof("Creating plan preview for: %s", inputText)
+
+	planID := newPlanID()
+	if err := p.createInitialPlan(ctx, inputText, planID); err != nil {
+		return "", "", fmt.Errorf("failed to create plan: %w", err)
+	}
+
+	p.activePlanID = planID
+
+	result, err := p.planningTool.Execute(ctx, map[string]interface{}{
+		"command": "get",
+		"plan_id": planID,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	if !result.IsSuccess() {
+		return "", "", fmt.Errorf("failed to get plan: %s", result.Error)
+	}
+
+	return planID, result.Output, nil
+}
+
+// ExecuteApproved 执行经过审阅的计划
+func (p *PlanningFlow) ExecuteApproved(ctx context.Context, planID string) (string, error) {
+	logger.FromContext(ctx).Infof("Executing approved plan %s", planID)
+	return p.ResumePlan(ctx, planID)
+}
+
+// ResumePlan 从磁盘加载的计划中恢复执行，从第一个未开始或进行中的步骤继续
+func (p *PlanningFlow) ResumePlan(ctx context.Context, planID string) (string, error) {
+	plan := p.planningTool.GetPlan(planID)
+	if plan == nil {
+		return "", fmt.Errorf("plan %s not found", planID)
+	}
+
+	args := map[string]interface{}{
+		"command": "set_active",
+		"plan_id": planID,
+	}
+	if result, err := p.planningTool.Execute(ctx, args); err != nil {
+		return "", err
+	} else if !result.IsSuccess() {
+		return "", fmt.Errorf("failed to activate plan: %s", result.Error)
+	}
+
+	p.activePlanID = planID
+
+	if stepIndex, _ := p.getCurrentStepInfo(); stepIndex != nil {
+		logger.FromContext(ctx).Infof("Resuming plan %s from step %d", planID, *stepIndex)
+	}
+
+	return p.executePlan(ctx)
+}
+
+// executePlan 依次执行活动计划中尚未完成的步骤
+func (p *PlanningFlow) executePlan(ctx context.Context) (string, error) {
 	var result strings.Builder
 	for {
 		// 获取当前步骤
@@ -72,27 +165,39 @@ func (p *PlanningFlow) Execute(ctx context.Context, inputText string) (string, e
 		}
 
 		result.WriteString(fmt.Sprintf("Step %d: %s\n", *stepIndex, stepResult))
-
-		// 检查 Agent 是否完成
-		if toolCallAgent, ok := executor.(*agent.ToolCallAgent); ok {
-			if toolCallAgent.State == schema.AgentStateFINISHED {
-				break
-			}
-		}
 	}
 
 	return result.String(), nil
 }
 
-// createInitialPlan 创建初始计划
+// planningPrompt 让主 Agent 的 LLM 为请求生成编号步骤列表的系统提示词
+const planningPrompt = `You are a planning assistant. Break the user's request into a short sequence of concrete, actionable steps that an AI agent with tools (shell, file editing, web browsing and search, data analysis) can carry out one at a time.
+Reply with a numbered list only, one step per line, e.g.
+1. Search the web for ...
+2. Save the findings to ...
+Use between 2 and 8 steps. Each step must be specific to this request; do not add steps like "Analyze the request" or "Verify the results" unless they involve concrete work.`
+
+// defaultPlanSteps LLM 不可用时使用的通用步骤模板
+var defaultPlanSteps = []string{
+	"Analyze the request",
+	"Plan the solution",
+	"Execute the plan",
+	"Verify the results",
+}
+
+// planStepLine 匹配编号步骤行，如 "1. xxx"、"2) xxx"、"Step 3: xxx"、"**4.** xxx"
+var planStepLine = regexp.MustCompile(`^(?:[-*]\s*)?(?:\*\*)?(?:(?i:step)\s*)?\d+\s*[.):](?:\*\*)?\s+(.+)$`)
+
+// createInitialPlan 创建初始计划，步骤由主 Agent 的 LLM 根据请求生成，调用失败时使用通用模板
 func (p *PlanningFlow) createInitialPlan(ctx context.Context, request string, planID string) error {
-	// 生成计划步骤（简化实现，实际应该调用 LLM）
-	// 这里使用固定的步骤模板
-	steps := []interface{}{
-		"Analyze the request",
-		"Plan the solution",
-		"Execute the plan",
-		"Verify the results",
+	planSteps, err := p.generatePlanSteps(ctx, request)
+	if err != nil {
+		logger.FromContext(ctx).Warnf("Failed to generate plan steps, using the default template: %v", err)
+		planSteps = defaultPlanSteps
+	}
+	steps := make([]interface{}, 0, len(planSteps))
+	for _, step := range planSteps {
+		steps = append(steps, step)
 	}
 
 	// 创建计划
@@ -103,18 +208,64 @@ func (p *PlanningFlow) createInitialPlan(ctx context.Context, request string, pl
 		"steps":   steps,
 	}
 
-	_, err := p.planningTool.Execute(ctx, args)
+	result, err := p.planningTool.Execute(ctx, args)
 	if err != nil {
 		return err
 	}
+	if !result.IsSuccess() {
+		return fmt.Errorf("%s", result.Error)
+	}
 
 	// 设置活动计划
 	args = map[string]interface{}{
 		"command": "set_active",
 		"plan_id": planID,
 	}
-	_, err = p.planningTool.Execute(ctx, args)
-	return err
+	result, err = p.planningTool.Execute(ctx, args)
+	if err != nil {
+		return err
+	}
+	if !result.IsSuccess() {
+		return fmt.Errorf("%s", result.Error)
+	}
+	return nil
+}
+
+// generatePlanSteps 用主 Agent 的 LLM 为请求生成步骤列表
+func (p *PlanningFlow) generatePlanSteps(ctx context.Context, request string) ([]string, error) {
+	primary := p.GetPrimaryAgent()
+	if primary == nil || primary.LLM == nil {
+		return nil, fmt.Errorf("no primary agent LLM available")
+	}
+
+	reply, err := primary.LLM.Ask(ctx,
+		[]schema.Message{schema.NewUserMessage(request)},
+		[]schema.Message{schema.NewSystemMessage(planningPrompt)},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := parsePlanSteps(reply)
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("no numbered steps in the LLM reply: %q", reply)
+	}
+	return steps, nil
+}
+
+// parsePlanSteps 从 LLM 回复中提取编号步骤，忽略其他行
+func parsePlanSteps(text string) []string {
+	steps := make([]string, 0)
+	for _, line := range strings.Split(text, "\n") {
+		match := planStepLine.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		if step := strings.TrimSpace(match[1]); step != "" {
+			steps = append(steps, step)
+		}
+	}
+	return steps
 }
 
 // getCurrentStepInfo 获取当前步骤信息
@@ -124,9 +275,9 @@ func (p *PlanningFlow) getCurrentStepInfo() (*int, map[string]interface{}) {
 		return nil, nil
 	}
 
-	// 查找下一个未完成的步骤
+	// 查找下一个依赖都已完成的未完成步骤，依赖未完成的步骤留到之后执行
 	for i, step := range plan.Steps {
-		if step.Status == tool.PlanStepNotStarted || step.Status == tool.PlanStepInProgress {
+		if (step.Status == tool.PlanStepNotStarted || step.Status == tool.PlanStepInProgress) && plan.DependenciesMet(i) {
 			idx := i
 			return &idx, map[string]interface{}{
 				"index":       i,
@@ -168,18 +319,20 @@ func (p *PlanningFlow) executeStep(ctx context.Context, executor *agent.BaseAgen
 	// 标记步骤为进行中
 	args := map[string]interface{}{
 		"command":    "mark_step",
-		"step_index": stepIndex,
+		"plan_id":    p.activePlanID,
+		"step_index": float64(stepIndex),
 		"status":      "in_progress",
 	}
 	p.planningTool.Execute(ctx, args)
 
 	// 执行步骤
-	result, err := executor.Run(ctx, description)
+	result, err := p.runExecutor(ctx, executor, p.stepPrompt(description))
 	if err != nil {
 		// 标记为失败
 		args = map[string]interface{}{
 			"command":    "mark_step",
-			"step_index": stepIndex,
+			"plan_id":    p.activePlanID,
+			"step_index": float64(stepIndex),
 			"status":      "blocked",
 			"result":      fmt.Sprintf("Error: %v", err),
 		}
@@ -187,10 +340,15 @@ func (p *PlanningFlow) executeStep(ctx context.Context, executor *agent.BaseAgen
 		return "", err
 	}
 
+	if p.Scratchpad != nil {
+		p.Scratchpad.Set(fmt.Sprintf("Step %d (%s)", stepIndex+1, description), truncateValue(result, scratchpadValueLimit))
+	}
+
 	// 标记为完成
 	args = map[string]interface{}{
 		"command":    "mark_step",
-		"step_index": stepIndex,
+		"plan_id":    p.activePlanID,
+		"step_index": float64(stepIndex),
 		"status":      "completed",
 		"result":      result,
 	}
@@ -199,19 +357,70 @@ func (p *PlanningFlow) executeStep(ctx context.Context, executor *agent.BaseAgen
 	return result, nil
 }
 
-// finalizePlan 完成计划
+// stepPrompt 生成步骤的执行请求，启用共享记录时附加之前步骤留下的信息
+func (p *PlanningFlow) stepPrompt(description string) string {
+	if p.Scratchpad == nil || p.Scratchpad.Len() == 0 {
+		return description
+	}
+	return fmt.Sprintf("%s\n\nShared notes from previous steps:\n%s", description, p.Scratchpad.String())
+}
+
+// runExecutor 在步骤超时和步数限制下运行 Agent。超时时取消 Agent 的 context，
+// 并等待 Agent 返回后才恢复 MaxSteps 并返回，避免步骤被标记为 blocked 后 Agent 仍在调用工具
+func (p *PlanningFlow) runExecutor(ctx context.Context, executor *agent.BaseAgent, description string) (string, error) {
+	if p.StepMaxSteps > 0 {
+		maxSteps := executor.MaxSteps
+		executor.MaxSteps = p.StepMaxSteps
+		defer func() { executor.MaxSteps = maxSteps }()
+	}
+
+	if p.StepTimeout <= 0 {
+		return executor.Run(ctx, description)
+	}
+
+	stepCtx, cancel := context.WithTimeout(ctx, p.StepTimeout)
+	defer cancel()
+
+	output, err := executor.Run(stepCtx, description)
+	if err != nil && stepCtx.Err() != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("step timed out after %s", p.StepTimeout)
+	}
+	return output, err
+}
+
+// finalizePlan 完成计划，生成执行结果摘要
 func (p *PlanningFlow) finalizePlan() string {
 	plan := p.planningTool.GetActivePlan()
 	if plan == nil {
 		return "Plan execution completed."
 	}
 
+	// 受阻的步骤以及依赖未完成（包括依赖受阻步骤）而无法开始的步骤单独列出
 	completed := 0
-	for _, step := range plan.Steps {
-		if step.Status == tool.PlanStepCompleted {
+	var blocked, waiting []string
+	for i, step := range plan.Steps {
+		switch {
+		case step.Status == tool.PlanStepCompleted:
 			completed++
+		case step.Status == tool.PlanStepBlocked:
+			blocked = append(blocked, fmt.Sprintf("step %d", i+1))
+		case !plan.DependenciesMet(i):
+			waiting = append(waiting, fmt.Sprintf("step %d", i+1))
 		}
 	}
 
-	return fmt.Sprintf("Plan execution completed. %d/%d steps completed.", completed, len(plan.Steps))
+	if len(blocked) == 0 && len(waiting) == 0 {
+		return fmt.Sprintf("Plan execution completed. %d/%d steps completed.", completed, len(plan.Steps))
+	}
+	summary := fmt.Sprintf("Plan execution stopped. %d/%d steps completed.", completed, len(plan.Steps))
+	if len(blocked) > 0 {
+		summary += fmt.Sprintf(" Blocked: %s.", strings.Join(blocked, ", "))
+	}
+	if len(waiting) > 0 {
+		summary += fmt.Sprintf(" Not started, waiting for blocked or unfinished steps: %s.", strings.Join(waiting, ", "))
+	}
+	return summary
 }
@@ -0,0 +1,281 @@
+package flow
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go-manus/schema"
+	"go-manus/tool"
+)
+
+func TestResumePlanRunsOnlyRemainingSteps(t *testing.T) {
+	llm := &stepLLM{}
+	interrupted, _ := newTestFlow(t, llm)
+	createTestPlan(t, interrupted, "plan_resume", "Fetch the data", "Clean the data", "Build the chart", "Write the report")
+	for _, i := range []float64{0, 1} {
+		result, err := interrupted.planningTool.Execute(context.Background(), map[string]interface{}{
+			"command":    "mark_step",
+			"plan_id":    "plan_resume",
+			"step_index": i,
+			"status":     "completed",
+		})
+		if err != nil || !result.IsSuccess() {
+			t.Fatalf("mark_step: %v %s", err, result.Error)
+		}
+	}
+
+	// 新的 PlanningFlow 从磁盘加载计划后继续执行
+	f, _ := newTestFlow(t, llm)
+	output, err := f.ResumePlan(context.Background(), "plan_resume")
+	if err != nil {
+		t.Fatalf("ResumePlan: %v", err)
+	}
+
+	if got, want := llm.stepPrompts(), []string{"Build the chart", "Write the report"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("executed steps = %q, want %q", got, want)
+	}
+	plan := f.planningTool.GetPlan("plan_resume")
+	for i, step := range plan.Steps {
+		if step.Status != tool.PlanStepCompleted {
+			t.Errorf("step %d status = %s, want completed", i, step.Status)
+		}
+	}
+	if !strings.Contains(output, "4/4 steps completed") {
+		t.Errorf("output = %q", output)
+	}
+}
+
+func TestStepsWaitForDependencies(t *testing.T) {
+	llm := &stepLLM{}
+	f, _ := newTestFlow(t, llm)
+	createTestPlan(t, f, "plan_deps", "Write the report", "Fetch the data", "Build the chart")
+	// 报告依赖图表，图表依赖数据，列表顺序与执行顺序相反
+	for step, dep := range map[float64]float64{0: 2, 2: 1} {
+		result, err := f.planningTool.Execute(context.Background(), map[string]interface{}{
+			"command":    "set_dependencies",
+			"plan_id":    "plan_deps",
+			"step_index": step,
+			"depends_on": []interface{}{dep},
+		})
+		if err != nil || !result.IsSuccess() {
+			t.Fatalf("set_dependencies: %v %s", err, result.Error)
+		}
+	}
+
+	output, err := f.ResumePlan(context.Background(), "plan_deps")
+	if err != nil {
+		t.Fatalf("ResumePlan: %v", err)
+	}
+
+	if got, want := llm.stepPrompts(), []string{"Fetch the data", "Build the chart", "Write the report"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("executed steps = %q, want %q", got, want)
+	}
+	if !strings.Contains(output, "Plan execution completed. 3/3 steps completed.") {
+		t.Errorf("output = %q", output)
+	}
+
+	// 依赖受阻步骤的步骤不执行，也不算作完成
+	llm = &stepLLM{}
+	f, _ = newTestFlow(t, llm)
+	createTestPlan(t, f, "plan_deps_blocked", "Fetch the data", "Build the chart", "Write the notes")
+	for _, args := range []map[string]interface{}{
+		{"command": "set_dependencies", "plan_id": "plan_deps_blocked", "step_index": float64(1), "depends_on": []interface{}{float64(0)}},
+		{"command": "mark_step", "plan_id": "plan_deps_blocked", "step_index": float64(0), "status": "blocked", "result": "Error: source is down"},
+	} {
+		result, err := f.planningTool.Execute(context.Background(), args)
+		if err != nil || !result.IsSuccess() {
+			t.Fatalf("%s: %v %s", args["command"], err, result.Error)
+		}
+	}
+
+	output, err = f.ResumePlan(context.Background(), "plan_deps_blocked")
+	if err != nil {
+		t.Fatalf("ResumePlan: %v", err)
+	}
+	if got, want := llm.stepPrompts(), []string{"Write the notes"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("executed steps = %q, want %q", got, want)
+	}
+	for _, want := range []string{
+		"Plan execution stopped. 1/3 steps completed.",
+		"Blocked: step 1.",
+		"Not started, waiting for blocked or unfinished steps: step 2.",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q: %q", want, output)
+		}
+	}
+	if status := f.planningTool.GetPlan("plan_deps_blocked").Steps[1].Status; status != tool.PlanStepNotStarted {
+		t.Errorf("dependent step status = %s, want not_started", status)
+	}
+}
+
+func TestResumePlanUnknownPlan(t *testing.T) {
+	f, _ := newTestFlow(t, &stepLLM{})
+	if _, err := f.ResumePlan(context.Background(), "plan_missing"); err == nil {
+		t.Error("ResumePlan should fail for an unknown plan")
+	}
+}
+
+func TestStepTimeoutMarksStepBlocked(t *testing.T) {
+	llm := &stepLLM{block: make(chan struct{})}
+	defer close(llm.block)
+	f, executor := newTestFlow(t, llm)
+	f.StepTimeout = 50 * time.Millisecond
+	createTestPlan(t, f, "plan_timeout", "Wait forever", "Never reached")
+
+	start := time.Now()
+	output, err := f.ResumePlan(context.Background(), "plan_timeout")
+	if err != nil {
+		t.Fatalf("ResumePlan: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("timed out step took %s", elapsed)
+	}
+	if !strings.Contains(output, "step timed out after 50ms") {
+		t.Errorf("output = %q", output)
+	}
+
+	plan := f.planningTool.GetPlan("plan_timeout")
+	if plan.Steps[0].Status != tool.PlanStepBlocked {
+		t.Errorf("step 0 status = %s, want blocked", plan.Steps[0].Status)
+	}
+	if !strings.Contains(plan.Steps[0].Result, "timed out") {
+		t.Errorf("step 0 result = %q", plan.Steps[0].Result)
+	}
+	if plan.Steps[1].Status != tool.PlanStepNotStarted {
+		t.Errorf("step 1 status = %s, want not_started", plan.Steps[1].Status)
+	}
+
+	// 返回时 Agent 已经停止，不会在步骤被标记为 blocked 之后继续请求模型
+	if executor.State != schema.AgentStateIDLE {
+		t.Errorf("executor state = %s, want IDLE", executor.State)
+	}
+	calls := llm.calls()
+	time.Sleep(100 * time.Millisecond)
+	if llm.calls() != calls {
+		t.Errorf("executor kept running after the step timed out")
+	}
+}
+
+func TestStepMaxStepsOverride(t *testing.T) {
+	llm := &stepLLM{reply: "still working"}
+	f, executor := newTestFlow(t, llm)
+	executor.MaxSteps = 10
+	f.StepMaxSteps = 2
+	createTestPlan(t, f, "plan_max_steps", "Keep going")
+
+	if _, err := f.ResumePlan(context.Background(), "plan_max_steps"); err != nil {
+		t.Fatalf("ResumePlan: %v", err)
+	}
+	if llm.calls() != 2 {
+		t.Errorf("executor made %d model calls, want 2 (StepMaxSteps)", llm.calls())
+	}
+	if executor.MaxSteps != 10 {
+		t.Errorf("executor MaxSteps = %d after the step, want 10 restored", executor.MaxSteps)
+	}
+}
+
+func TestPreviewCreatesPlanWithoutExecuting(t *testing.T) {
+	llm := &stepLLM{planReply: "1. Download the sales CSV\n2. Sum revenue per region\n3. Write summary.md"}
+	f, _ := newTestFlow(t, llm)
+
+	planID, preview, err := f.Preview(context.Background(), "summarize sales")
+	if err != nil {
+		t.Fatalf("Preview: %v", err)
+	}
+	if llm.calls() != 0 {
+		t.Errorf("preview ran %d step requests, want 0", llm.calls())
+	}
+	plan := f.planningTool.GetPlan(planID)
+	if plan == nil {
+		t.Fatalf("plan %s was not created", planID)
+	}
+	if len(plan.Steps) != 3 {
+		t.Fatalf("plan has %d steps, want 3", len(plan.Steps))
+	}
+	for i, step := range plan.Steps {
+		if step.Status != tool.PlanStepNotStarted {
+			t.Errorf("step %d status = %s, want not_started", i, step.Status)
+		}
+	}
+	if !strings.Contains(preview, "Sum revenue per region") {
+		t.Errorf("preview does not show the steps:\n%s", preview)
+	}
+
+	if _, err := f.ExecuteApproved(context.Background(), planID); err != nil {
+		t.Fatalf("ExecuteApproved: %v", err)
+	}
+	if llm.calls() != 3 {
+		t.Errorf("ExecuteApproved ran %d steps, want 3", llm.calls())
+	}
+}
+
+func TestPreviewOnlyExecuteReturnsPlanID(t *testing.T) {
+	llm := &stepLLM{planReply: "1. First\n2. Second"}
+	f, _ := newTestFlow(t, llm)
+	f.PreviewOnly = true
+
+	first, err := f.Execute(context.Background(), "task one")
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	second, err := f.Execute(context.Background(), "task two")
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if llm.calls() != 0 {
+		t.Errorf("PreviewOnly executed %d steps", llm.calls())
+	}
+
+	ids := make([]string, 0, 2)
+	for _, output := range []string{first, second} {
+		fields := strings.Fields(output)
+		if len(fields) < 2 || fields[0] != "Plan" {
+			t.Fatalf("output does not start with the plan ID: %q", output)
+		}
+		id := fields[1]
+		if f.planningTool.GetPlan(id) == nil {
+			t.Errorf("plan ID %q in the output does not exist", id)
+		}
+		ids = append(ids, id)
+	}
+	// 同一秒内的两次预览不会重名
+	if ids[0] == ids[1] {
+		t.Errorf("two previews got the same plan ID %s", ids[0])
+	}
+}
+
+func TestScratchpadCarriesResultsToLaterSteps(t *testing.T) {
+	llm := &stepLLM{reply: "the export is at data/export.csv, request"}
+	f, _ := newTestFlow(t, llm)
+	f.StepMaxSteps = 1
+	f.Scratchpad = NewScratchpad()
+	f.Scratchpad.Set("Deadline", "Friday")
+	createTestPlan(t, f, "plan_scratchpad", "Fetch the data", "Build the chart")
+
+	if _, err := f.ResumePlan(context.Background(), "plan_scratchpad"); err != nil {
+		t.Fatalf("ResumePlan: %v", err)
+	}
+
+	llm.mu.Lock()
+	prompts := append([]string(nil), llm.prompts...)
+	llm.mu.Unlock()
+	if len(prompts) != 2 {
+		t.Fatalf("step requests = %q, want one per step", prompts)
+	}
+	// 调用方预先写入的记录注入每个步骤，步骤 1 的结果注入步骤 2
+	if !strings.Contains(prompts[0], "- Deadline: Friday") || strings.Contains(prompts[0], "Step 1") {
+		t.Errorf("step 1 prompt = %q, want only the caller's note", prompts[0])
+	}
+	for _, want := range []string{"Build the chart\n\nShared notes from previous steps:\n", "- Deadline: Friday", "- Step 1 (Fetch the data): ", "the export is at data/export.csv, request 1"} {
+		if !strings.Contains(prompts[1], want) {
+			t.Errorf("step 2 prompt does not contain %q:\n%s", want, prompts[1])
+		}
+	}
+	if value, ok := f.Scratchpad.Get("Step 2 (Build the chart)"); !ok || !strings.Contains(value, "request 2") {
+		t.Errorf("scratchpad step 2 = %q, %v, want its result recorded", value, ok)
+	}
+}
@@ -0,0 +1,70 @@
+package flow
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// scratchpadValueLimit 自动记录步骤结果时保留的最大字符数
+const scratchpadValueLimit = 500
+
+// Scratchpad 在 Flow 的各个步骤之间共享的键值记录，按写入顺序输出
+type Scratchpad struct {
+	mu     sync.RWMutex
+	keys   []string
+	values map[string]string
+}
+
+// NewScratchpad 创建空的共享记录
+func NewScratchpad() *Scratchpad {
+	return &Scratchpad{values: make(map[string]string)}
+}
+
+// Set 写入或覆盖一条记录，覆盖时保持原有顺序
+func (s *Scratchpad) Set(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.values[key]; !exists {
+		s.keys = append(s.keys, key)
+	}
+	s.values[key] = value
+}
+
+// Get 读取一条记录
+func (s *Scratchpad) Get(key string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	value, ok := s.values[key]
+	return value, ok
+}
+
+// Len 返回记录数
+func (s *Scratchpad) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.keys)
+}
+
+// String 格式化为注入提示词的文本
+func (s *Scratchpad) String() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var sb strings.Builder
+	for _, key := range s.keys {
+		sb.WriteString(fmt.Sprintf("- %s: %s\n", key, s.values[key]))
+	}
+	return sb.String()
+}
+
+// truncateValue 截断过长的记录，避免提示词随步骤数无限增长
+func truncateValue(value string, limit int) string {
+	runes := []rune(strings.TrimSpace(value))
+	if len(runes) <= limit {
+		return string(runes)
+	}
+	return string(runes[:limit]) + "..."
+}
@@ -3,19 +3,35 @@ package llm
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/sashabaranov/go-openai"
 	"github.com/sirupsen/logrus"
 	"go-manus/config"
 	"go-manus/schema"
+	"go-manus/telemetry"
 )
 
+// fallbackConfigName 主模型重试失败后切换使用的 LLM 配置名
+const fallbackConfigName = "fallback"
+
+// Provider 生成对话补全的后端，默认为 OpenAI 兼容接口，*openai.Client 即满足该接口
+type Provider interface {
+	CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error)
+}
+
 type Client struct {
 	client      *openai.Client
+	provider    Provider
 	model       string
 	maxTokens   int
 	temperature float64
+	// supportsImages 模型是否接受图片输入
+	supportsImages bool
+	fallback    *Client
+	retryAfter  *retryAfterTracker
+	usage       *usageTracker
 }
 
 // NewClient 创建新的 LLM 客户端
@@ -23,15 +39,52 @@ func NewClient(configName string) *Client {
 	cfg := config.GetInstance()
 	settings := cfg.GetLLM(configName)
 
+	// 通过自定义 Transport 记录 429 响应中的 Retry-After
+	retryAfter := &retryAfterTracker{}
 	clientConfig := openai.DefaultConfig(settings.APIKey)
 	clientConfig.BaseURL = settings.BaseURL
+	clientConfig.HTTPClient = &http.Client{
+		Transport: &retryAfterTransport{base: http.DefaultTransport, tracker: retryAfter},
+	}
 
-	return &Client{
-		client:      openai.NewClientWithConfig(clientConfig),
+	openaiClient := openai.NewClientWithConfig(clientConfig)
+	client := &Client{
+		client:      openaiClient,
+		provider:    openaiClient,
 		model:       settings.Model,
 		maxTokens:   settings.MaxTokens,
 		temperature: settings.Temperature,
+		supportsImages: settings.SupportsImages,
+		retryAfter:  retryAfter,
+		usage:       &usageTracker{},
+	}
+
+	// 配置了 [llm.fallback] 时，主模型重试耗尽后切换到备用模型
+	if configName != fallbackConfigName && cfg.HasLLM(fallbackConfigName) {
+		client.fallback = NewClient(fallbackConfigName)
+		client.fallback.usage = client.usage
 	}
+
+	return client
+}
+
+// SetProvider 替换 Ask、AskStream、AskTool 和 Ping 使用的后端，备用模型也改用同一个后端；
+// 内容审核仍直接调用 OpenAI 接口
+func (c *Client) SetProvider(p Provider) {
+	c.provider = p
+	if c.fallback != nil {
+		c.fallback.SetProvider(p)
+	}
+}
+
+// SupportsImages 返回模型是否接受图片输入，不接受时调用方不应发送图片消息
+func (c *Client) SupportsImages() bool {
+	return c.supportsImages
+}
+
+// SetSupportsImages 覆盖配置中的 supports_images
+func (c *Client) SetSupportsImages(supported bool) {
+	c.supportsImages = supported
 }
 
 // FormatMessages 格式化消息为 OpenAI 格式
@@ -41,7 +94,22 @@ func FormatMessages(messages []schema.Message) []openai.ChatCompletionMessage {
 		formattedMsg := openai.ChatCompletionMessage{
 			Role: string(msg.Role),
 		}
-		if msg.Content != nil {
+		if msg.Base64Image != nil && *msg.Base64Image != "" {
+			// 带图片的消息使用多模态内容，Content 与 MultiContent 不能同时设置
+			if msg.Content != nil && *msg.Content != "" {
+				formattedMsg.MultiContent = append(formattedMsg.MultiContent, openai.ChatMessagePart{
+					Type: openai.ChatMessagePartTypeText,
+					Text: *msg.Content,
+				})
+			}
+			formattedMsg.MultiContent = append(formattedMsg.MultiContent, openai.ChatMessagePart{
+				Type: openai.ChatMessagePartTypeImageURL,
+				ImageURL: &openai.ChatMessageImageURL{
+					URL:    "data:image/png;base64," + *msg.Base64Image,
+					Detail: openai.ImageURLDetailAuto,
+				},
+			})
+		} else if msg.Content != nil {
 			formattedMsg.Content = *msg.Content
 		}
 		if len(msg.ToolCalls) > 0 {
@@ -83,13 +151,13 @@ func (c *Client) Ask(ctx context.Context, messages []schema.Message, systemMsgs
 		Messages:    FormatMessages(allMessages),
 		MaxTokens:   c.maxTokens,
 		Temperature: float32(c.temperature),
-		Stream:      false,
 	}
 
-	resp, err := c.client.CreateChatCompletion(ctx, req)
+	resp, err := c.provider.CreateChatCompletion(ctx, req)
 	if err != nil {
 		return "", fmt.Errorf("failed to create chat completion: %w", err)
 	}
+	c.usage.add(resp.Usage)
 
 	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == "" {
 		return "", fmt.Errorf("empty response from LLM")
@@ -126,18 +194,32 @@ func (c *Client) AskTool(ctx context.Context, messages []schema.Message, systemM
 		req.ToolChoice = "auto"
 	}
 
-	resp, err := c.client.CreateChatCompletion(ctx, req)
+	ctx, span := telemetry.StartSpan(ctx, "llm.ask_tool")
+	span.SetAttribute("llm.model", c.model)
+	span.SetAttribute("llm.tools", len(tools))
+	defer span.End()
+
+	start := time.Now()
+	resp, err := c.provider.CreateChatCompletion(ctx, req)
+	span.SetAttribute("llm.duration_ms", time.Since(start).Milliseconds())
 	if err != nil {
+		span.RecordError(err)
 		return nil, fmt.Errorf("failed to create chat completion: %w", err)
 	}
 
+	c.usage.add(resp.Usage)
+	span.SetAttribute("llm.prompt_tokens", resp.Usage.PromptTokens)
+	span.SetAttribute("llm.completion_tokens", resp.Usage.CompletionTokens)
+	span.SetAttribute("llm.total_tokens", resp.Usage.TotalTokens)
+
 	if len(resp.Choices) == 0 {
 		return nil, fmt.Errorf("empty response from LLM")
 	}
 
 	msg := resp.Choices[0].Message
 	result := &ChatCompletionMessage{
-		Content: msg.Content,
+		Content:      msg.Content,
+		FinishReason: string(resp.Choices[0].FinishReason),
 	}
 
 	// 转换工具调用
@@ -165,10 +247,72 @@ func (c *Client) AskTool(ctx context.Context, messages []schema.Message, systemM
 	return result, nil
 }
 
+// Ping 发送一个极小的请求检查模型服务是否可达以及凭据是否有效
+func (c *Client) Ping(ctx context.Context) error {
+	req := openai.ChatCompletionRequest{
+		Model:     c.model,
+		Messages:  []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "ping"}},
+		MaxTokens: 1,
+	}
+	if _, err := c.provider.CreateChatCompletion(ctx, req); err != nil {
+		return fmt.Errorf("failed to reach model %s: %w", c.model, err)
+	}
+	return nil
+}
+
+// Moderate 调用 moderation 接口检查输入，返回是否被标记以及命中的类别
+func (c *Client) Moderate(ctx context.Context, input string) (bool, []string, error) {
+	resp, err := c.client.Moderations(ctx, openai.ModerationRequest{Input: input})
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to create moderation: %w", err)
+	}
+
+	flagged := false
+	categories := make([]string, 0)
+	for _, result := range resp.Results {
+		if !result.Flagged {
+			continue
+		}
+		flagged = true
+		categories = append(categories, flaggedCategories(result.Categories)...)
+	}
+	return flagged, categories, nil
+}
+
+// flaggedCategories 返回被标记的类别名称
+func flaggedCategories(c openai.ResultCategories) []string {
+	all := []struct {
+		name    string
+		flagged bool
+	}{
+		{"hate", c.Hate},
+		{"hate/threatening", c.HateThreatening},
+		{"harassment", c.Harassment},
+		{"harassment/threatening", c.HarassmentThreatening},
+		{"self-harm", c.SelfHarm},
+		{"self-harm/intent", c.SelfHarmIntent},
+		{"self-harm/instructions", c.SelfHarmInstructions},
+		{"sexual", c.Sexual},
+		{"sexual/minors", c.SexualMinors},
+		{"violence", c.Violence},
+		{"violence/graphic", c.ViolenceGraphic},
+	}
+
+	categories := make([]string, 0)
+	for _, item := range all {
+		if item.flagged {
+			categories = append(categories, item.name)
+		}
+	}
+	return categories
+}
+
 // ChatCompletionMessage LLM 响应消息
 type ChatCompletionMessage struct {
 	Content   string
 	ToolCalls []schema.ToolCall
+	// FinishReason 模型停止生成的原因，如 stop、length、tool_calls、content_filter
+	FinishReason string
 }
 
 // AskWithRetry 带重试的请求
@@ -176,9 +320,11 @@ func (c *Client) AskWithRetry(ctx context.Context, messages []schema.Message, sy
 	var lastErr error
 	for i := 0; i < maxRetries; i++ {
 		if i > 0 {
-			waitTime := time.Duration(i) * time.Second
+			waitTime := c.retryDelay(i, lastErr)
 			logrus.Warnf("Retrying after %v...", waitTime)
-			time.Sleep(waitTime)
+			if err := sleepContext(ctx, waitTime); err != nil {
+				return "", err
+			}
 		}
 		result, err := c.Ask(ctx, messages, systemMsgs)
 		if err == nil {
@@ -187,6 +333,11 @@ func (c *Client) AskWithRetry(ctx context.Context, messages []schema.Message, sy
 		lastErr = err
 		logrus.Errorf("Attempt %d failed: %v", i+1, err)
 	}
+
+	if c.fallback != nil && ctx.Err() == nil {
+		logrus.Warnf("Model %s failed after %d retries, falling back to %s", c.model, maxRetries, c.fallback.model)
+		return c.fallback.AskWithRetry(ctx, messages, systemMsgs, maxRetries)
+	}
 	return "", fmt.Errorf("failed after %d retries: %w", maxRetries, lastErr)
 }
 
@@ -195,9 +346,11 @@ func (c *Client) AskToolWithRetry(ctx context.Context, messages []schema.Message
 	var lastErr error
 	for i := 0; i < maxRetries; i++ {
 		if i > 0 {
-			waitTime := time.Duration(i) * time.Second
+			waitTime := c.retryDelay(i, lastErr)
 			logrus.Warnf("Retrying after %v...", waitTime)
-			time.Sleep(waitTime)
+			if err := sleepContext(ctx, waitTime); err != nil {
+				return nil, err
+			}
 		}
 		result, err := c.AskTool(ctx, messages, systemMsgs, tools, toolChoice)
 		if err == nil {
@@ -206,6 +359,11 @@ func (c *Client) AskToolWithRetry(ctx context.Context, messages []schema.Message
 		lastErr = err
 		logrus.Errorf("Attempt %d failed: %v", i+1, err)
 	}
+
+	if c.fallback != nil && ctx.Err() == nil {
+		logrus.Warnf("Model %s failed after %d retries, falling back to %s", c.model, maxRetries, c.fallback.model)
+		return c.fallback.AskToolWithRetry(ctx, messages, systemMsgs, tools, toolChoice, maxRetries)
+	}
 	return nil, fmt.Errorf("failed after %d retries: %w", maxRetries, lastErr)
 }
 
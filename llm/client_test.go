@@ -0,0 +1,83 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"go-manus/schema"
+)
+
+func TestFallbackAfterPrimaryFails(t *testing.T) {
+	mockServer.handle(t, func(w http.ResponseWriter, model string, attempt int) {
+		if model == "primary" {
+			writeError(w, http.StatusServiceUnavailable, "primary is down")
+			return
+		}
+		writeCompletion(w, "answer from the fallback")
+	})
+
+	client := NewClient("default")
+	answer, err := client.AskWithRetry(context.Background(), []schema.Message{schema.NewUserMessage("hello")}, nil, 2)
+	if err != nil {
+		t.Fatalf("AskWithRetry returned error: %v", err)
+	}
+	if answer != "answer from the fallback" {
+		t.Errorf("answer = %q, want the fallback's answer", answer)
+	}
+	if got := mockServer.count("primary"); got != 2 {
+		t.Errorf("primary received %d requests, want 2 (all retries)", got)
+	}
+	if got := mockServer.count("fallback"); got != 1 {
+		t.Errorf("fallback received %d requests, want 1", got)
+	}
+	if usage := client.TokenUsage(); usage.TotalTokens != 15 {
+		t.Errorf("token usage = %+v, want the fallback's usage counted", usage)
+	}
+}
+
+func TestRateLimitWaitsForRetryAfter(t *testing.T) {
+	mockServer.handle(t, func(w http.ResponseWriter, model string, attempt int) {
+		if attempt == 1 {
+			w.Header().Set("Retry-After", "2")
+			writeError(w, http.StatusTooManyRequests, "rate limit reached")
+			return
+		}
+		writeCompletion(w, "hello")
+	})
+
+	client := NewClient("default")
+	start := time.Now()
+	answer, err := client.AskWithRetry(context.Background(), []schema.Message{schema.NewUserMessage("hello")}, nil, 3)
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("AskWithRetry returned error: %v", err)
+	}
+	if answer != "hello" {
+		t.Errorf("answer = %q, want hello", answer)
+	}
+	if mockServer.count("primary") != 2 || mockServer.count("fallback") != 0 {
+		t.Errorf("requests: primary %d, fallback %d; want 2 and 0", mockServer.count("primary"), mockServer.count("fallback"))
+	}
+	// 通用退避是 1 秒，等待时间应接近 Retry-After 的 2 秒
+	if elapsed < 2*time.Second || elapsed > 3*time.Second {
+		t.Errorf("retried after %v, want about 2s", elapsed)
+	}
+}
+
+func TestRetryDelayCapsRetryAfter(t *testing.T) {
+	client := NewClient("default")
+	client.retryAfter.set(10 * time.Minute)
+	err := &openai.APIError{HTTPStatusCode: http.StatusTooManyRequests, Message: "rate limit reached"}
+	if d := client.retryDelay(1, err); d != maxRetryAfter {
+		t.Errorf("retryDelay = %v, want the %v cap", d, maxRetryAfter)
+	}
+
+	// 没有 Retry-After 头时从错误消息中读取建议等待时间
+	err.Message = "Rate limit reached. Please try again in 6.5s."
+	if d := client.retryDelay(1, err); d != 6500*time.Millisecond {
+		t.Errorf("retryDelay = %v, want 6.5s from the error message", d)
+	}
+}
@@ -0,0 +1,112 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-manus/config"
+)
+
+// testConfig 测试使用的配置，主模型和备用模型都指向本地的 mock 服务，按路径区分
+const testConfig = `[llm]
+model = "primary-model"
+base_url = "%[1]s/primary/v1"
+api_key = "test"
+
+[llm.fallback]
+model = "fallback-model"
+base_url = "%[1]s/fallback/v1"
+api_key = "test"
+`
+
+// mockServer 模拟 OpenAI 接口，各测试通过 handle 设置处理函数，并可查看每个模型收到的请求数
+var mockServer = &mockOpenAI{}
+
+type mockOpenAI struct {
+	mu       sync.Mutex
+	handler  func(w http.ResponseWriter, model string, attempt int)
+	requests map[string]int
+}
+
+// handle 设置处理函数，参数为请求的模型配置（primary/fallback）和该模型的第几次请求（从 1 开始）
+func (m *mockOpenAI) handle(t *testing.T, handler func(w http.ResponseWriter, model string, attempt int)) {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handler = handler
+	m.requests = make(map[string]int)
+}
+
+// count 返回模型收到的请求数
+func (m *mockOpenAI) count(model string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.requests[model]
+}
+
+func (m *mockOpenAI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	model, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	m.mu.Lock()
+	m.requests[model]++
+	attempt := m.requests[model]
+	handler := m.handler
+	m.mu.Unlock()
+	handler(w, model, attempt)
+}
+
+// writeCompletion 返回一条文本回复
+func writeCompletion(w http.ResponseWriter, content string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":     "chatcmpl-test",
+		"object": "chat.completion",
+		"choices": []map[string]interface{}{{
+			"index":         0,
+			"message":       map[string]string{"role": "assistant", "content": content},
+			"finish_reason": "stop",
+		}},
+		"usage": map[string]int{"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15},
+	})
+}
+
+// writeError 返回 OpenAI 格式的错误响应
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{"message": message, "type": "server_error"},
+	})
+}
+
+// TestMain 启动 mock 服务并在临时目录中写入指向它的配置
+func TestMain(m *testing.M) {
+	srv := httptest.NewServer(mockServer)
+	dir, err := os.MkdirTemp("", "go-manus-llm-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "config"), 0755); err == nil {
+		err = os.WriteFile(filepath.Join(dir, "config", "config.toml"), []byte(fmt.Sprintf(testConfig, srv.URL)), 0644)
+	}
+	if err == nil {
+		err = os.Chdir(dir)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	config.SetInteractive(false)
+
+	code := m.Run()
+	srv.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -0,0 +1,137 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// traceEntry 轨迹文件中的一行：一次对话补全的响应，请求失败时为错误信息
+type traceEntry struct {
+	Model    string                         `json:"model"`
+	Response *openai.ChatCompletionResponse `json:"response,omitempty"`
+	Error    string                         `json:"error,omitempty"`
+}
+
+// traceWriter 将轨迹逐行写为 JSON，主模型和备用模型共用，保证顺序与请求一致
+type traceWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (t *traceWriter) write(entry traceEntry) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.enc.Encode(entry)
+}
+
+// recordingProvider 转发请求到 base，并把每次的响应写入轨迹
+type recordingProvider struct {
+	base  Provider
+	trace *traceWriter
+}
+
+func (r *recordingProvider) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	resp, err := r.base.CreateChatCompletion(ctx, req)
+	entry := traceEntry{Model: req.Model}
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		entry.Response = &resp
+	}
+	if werr := r.trace.write(entry); werr != nil {
+		return resp, fmt.Errorf("failed to record trace: %w", werr)
+	}
+	return resp, err
+}
+
+// RecordTrace 将之后每次对话补全的响应（包括备用模型的）以 JSON Lines 写入 w，
+// 写出的轨迹可以用 LoadReplay 读取后回放
+func (c *Client) RecordTrace(w io.Writer) {
+	c.recordTo(&traceWriter{enc: json.NewEncoder(w)})
+}
+
+func (c *Client) recordTo(trace *traceWriter) {
+	c.provider = &recordingProvider{base: c.provider, trace: trace}
+	if c.fallback != nil {
+		c.fallback.recordTo(trace)
+	}
+}
+
+// ErrReplayExhausted 回放的请求数超过了轨迹中记录的响应数
+var ErrReplayExhausted = errors.New("replay trace exhausted")
+
+// ReplayProvider 按顺序返回轨迹中记录的响应，不发送任何网络请求，用于离线复现问题。
+// 记录时失败的请求在回放时返回同样内容的错误
+type ReplayProvider struct {
+	mu      sync.Mutex
+	entries []traceEntry
+	next    int
+}
+
+// LoadReplay 从 RecordTrace 写出的 JSON Lines 轨迹创建 ReplayProvider
+func LoadReplay(r io.Reader) (*ReplayProvider, error) {
+	p := &ReplayProvider{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry traceEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid trace line %d: %w", line, err)
+		}
+		if entry.Response == nil && entry.Error == "" {
+			return nil, fmt.Errorf("invalid trace line %d: no response or error", line)
+		}
+		p.entries = append(p.entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read trace: %w", err)
+	}
+	return p, nil
+}
+
+// LoadReplayFile 从文件读取轨迹，见 LoadReplay
+func LoadReplayFile(path string) (*ReplayProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadReplay(f)
+}
+
+// Remaining 返回尚未回放的响应数
+func (p *ReplayProvider) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.entries) - p.next
+}
+
+func (p *ReplayProvider) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return openai.ChatCompletionResponse{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.next >= len(p.entries) {
+		return openai.ChatCompletionResponse{}, fmt.Errorf("%w after %d responses", ErrReplayExhausted, len(p.entries))
+	}
+	entry := p.entries[p.next]
+	p.next++
+
+	if entry.Error != "" {
+		return openai.ChatCompletionResponse{}, errors.New(entry.Error)
+	}
+	return *entry.Response, nil
+}
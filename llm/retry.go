@@ -0,0 +1,130 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// maxRetryAfter 服务端建议等待时间的上限
+const maxRetryAfter = 60 * time.Second
+
+// retryAfterMessageRe 从限流错误消息中提取建议等待时间，如 "Please try again in 6.5s"
+var retryAfterMessageRe = regexp.MustCompile(`try again in ((?:\d+h)?(?:\d+m)?(?:[\d.]+s)?(?:[\d.]+ms)?)`)
+
+// retryAfterTracker 记录最近一次 429 响应中的 Retry-After
+type retryAfterTracker struct {
+	mu    sync.Mutex
+	delay time.Duration
+}
+
+func (t *retryAfterTracker) set(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.delay = d
+}
+
+// take 取出记录的等待时间并清空
+func (t *retryAfterTracker) take() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	d := t.delay
+	t.delay = 0
+	return d
+}
+
+// retryAfterTransport 在 429 响应时记录 Retry-After 头，go-openai 的错误中不包含响应头
+type retryAfterTransport struct {
+	base    http.RoundTripper
+	tracker *retryAfterTracker
+}
+
+func (t *retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+		if d, ok := parseRetryAfter(resp.Header, time.Now()); ok {
+			t.tracker.set(d)
+		}
+	}
+	return resp, err
+}
+
+// parseRetryAfter 解析 retry-after-ms 或 Retry-After 头（秒数或 HTTP 日期）
+func parseRetryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	if ms := header.Get("Retry-After-Ms"); ms != "" {
+		if v, err := strconv.ParseFloat(ms, 64); err == nil && v >= 0 {
+			return time.Duration(v * float64(time.Millisecond)), true
+		}
+	}
+
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := date.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// isRateLimitError 判断是否为 429 限流错误
+func isRateLimitError(err error) bool {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatusCode == http.StatusTooManyRequests
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.HTTPStatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// retryDelay 计算第 attempt 次重试前的等待时间，限流时优先使用服务端建议的时间
+func (c *Client) retryDelay(attempt int, err error) time.Duration {
+	backoff := time.Duration(attempt) * time.Second
+	if !isRateLimitError(err) {
+		return backoff
+	}
+
+	delay := c.retryAfter.take()
+	if delay == 0 {
+		if m := retryAfterMessageRe.FindStringSubmatch(err.Error()); m != nil && m[1] != "" {
+			if d, parseErr := time.ParseDuration(m[1]); parseErr == nil {
+				delay = d
+			}
+		}
+	}
+	if delay == 0 {
+		return backoff
+	}
+	if delay > maxRetryAfter {
+		delay = maxRetryAfter
+	}
+	return delay
+}
+
+// sleepContext 等待指定时间，context 取消时提前返回
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,158 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sirupsen/logrus"
+	"go-manus/schema"
+)
+
+// StreamChunk 流式响应中的一段内容
+type StreamChunk struct {
+	Content string
+	// Usage 本次请求的 token 消耗，后端在某一段中返回时非 nil
+	Usage *openai.Usage
+}
+
+// ChatStream 流式对话补全的响应，Recv 在流结束时返回 io.EOF
+type ChatStream interface {
+	Recv() (StreamChunk, error)
+	Close() error
+}
+
+// StreamProvider 支持流式输出的 Provider。不支持流式的 Provider（如回放、轨迹录制）
+// 由 AskStream 改用普通请求，整段回复作为一段推送
+type StreamProvider interface {
+	CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (ChatStream, error)
+}
+
+// openAIStream 把 go-openai 的流式响应适配为 ChatStream；OpenAI 的流式响应不含 usage
+type openAIStream struct {
+	stream *openai.ChatCompletionStream
+}
+
+func (s *openAIStream) Recv() (StreamChunk, error) {
+	resp, err := s.stream.Recv()
+	if err != nil {
+		return StreamChunk{}, err
+	}
+	if len(resp.Choices) == 0 {
+		return StreamChunk{}, nil
+	}
+	return StreamChunk{Content: resp.Choices[0].Delta.Content}, nil
+}
+
+func (s *openAIStream) Close() error {
+	return s.stream.Close()
+}
+
+// completedStream 把一次非流式响应作为只有一段的流返回
+type completedStream struct {
+	resp openai.ChatCompletionResponse
+	done bool
+}
+
+func (s *completedStream) Recv() (StreamChunk, error) {
+	if s.done {
+		return StreamChunk{}, io.EOF
+	}
+	s.done = true
+	chunk := StreamChunk{Usage: &s.resp.Usage}
+	if len(s.resp.Choices) > 0 {
+		chunk.Content = s.resp.Choices[0].Message.Content
+	}
+	return chunk, nil
+}
+
+func (s *completedStream) Close() error {
+	return nil
+}
+
+// openStream 通过当前的 Provider 发起流式请求
+func (c *Client) openStream(ctx context.Context, req openai.ChatCompletionRequest) (ChatStream, error) {
+	switch p := c.provider.(type) {
+	case StreamProvider:
+		return p.CreateChatCompletionStream(ctx, req)
+	case *openai.Client:
+		req.Stream = true
+		stream, err := p.CreateChatCompletionStream(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return &openAIStream{stream: stream}, nil
+	default:
+		resp, err := p.CreateChatCompletion(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return &completedStream{resp: resp}, nil
+	}
+}
+
+// AskStream 以流式方式发送消息（无工具调用），逐段推送回复内容。
+// 内容通道在流结束或出错时关闭，之后错误通道会收到一个值：正常结束为 nil，否则为中途出现的错误。
+// 主模型无法建立流时切换到备用模型；后端返回的 usage 计入 TokenUsage
+func (c *Client) AskStream(ctx context.Context, messages []schema.Message, systemMsgs []schema.Message) (<-chan string, <-chan error) {
+	allMessages := make([]schema.Message, 0)
+	if len(systemMsgs) > 0 {
+		allMessages = append(allMessages, systemMsgs...)
+	}
+	allMessages = append(allMessages, messages...)
+
+	req := openai.ChatCompletionRequest{
+		Model:       c.model,
+		Messages:    FormatMessages(allMessages),
+		MaxTokens:   c.maxTokens,
+		Temperature: float32(c.temperature),
+	}
+
+	stream, err := c.openStream(ctx, req)
+	if err != nil {
+		if c.fallback != nil && ctx.Err() == nil {
+			logrus.Warnf("Model %s failed to stream: %v, falling back to %s", c.model, err, c.fallback.model)
+			return c.fallback.AskStream(ctx, messages, systemMsgs)
+		}
+		deltas := make(chan string)
+		errs := make(chan error, 1)
+		close(deltas)
+		errs <- fmt.Errorf("failed to create chat completion: %w", err)
+		return deltas, errs
+	}
+
+	deltas := make(chan string)
+	errs := make(chan error, 1)
+	go func() {
+		defer stream.Close()
+		defer close(deltas)
+
+		for {
+			chunk, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				errs <- nil
+				return
+			}
+			if err != nil {
+				errs <- fmt.Errorf("chat completion stream failed: %w", err)
+				return
+			}
+			if chunk.Usage != nil {
+				c.usage.add(*chunk.Usage)
+			}
+			if chunk.Content == "" {
+				continue
+			}
+			select {
+			case deltas <- chunk.Content:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return deltas, errs
+}
@@ -0,0 +1,141 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"go-manus/schema"
+)
+
+// fakeStreamProvider 按模型返回预设的流，failModel 的流无法建立
+type fakeStreamProvider struct {
+	chunks    []StreamChunk
+	failModel string
+	models    []string
+}
+
+func (p *fakeStreamProvider) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	return openai.ChatCompletionResponse{}, errors.New("non-streaming request")
+}
+
+func (p *fakeStreamProvider) CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (ChatStream, error) {
+	p.models = append(p.models, req.Model)
+	if req.Model == p.failModel {
+		return nil, errors.New("stream unavailable")
+	}
+	return &fakeStream{chunks: p.chunks}, nil
+}
+
+type fakeStream struct {
+	chunks []StreamChunk
+}
+
+func (s *fakeStream) Recv() (StreamChunk, error) {
+	if len(s.chunks) == 0 {
+		return StreamChunk{}, io.EOF
+	}
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return chunk, nil
+}
+
+func (s *fakeStream) Close() error { return nil }
+
+// drainStream 读完内容通道，返回拼接的内容和错误通道的值
+func drainStream(deltas <-chan string, errs <-chan error) ([]string, error) {
+	var got []string
+	for delta := range deltas {
+		got = append(got, delta)
+	}
+	return got, <-errs
+}
+
+func TestAskStreamReadsChunks(t *testing.T) {
+	provider := &fakeStreamProvider{chunks: []StreamChunk{
+		{Content: "Hel"},
+		{Content: ""},
+		{Content: "lo"},
+		{Usage: &openai.Usage{PromptTokens: 4, CompletionTokens: 2, TotalTokens: 6}},
+	}}
+	client := NewClient("default")
+	client.SetProvider(provider)
+
+	got, err := drainStream(client.AskStream(context.Background(), []schema.Message{schema.NewUserMessage("hi")}, nil))
+	if err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	if strings.Join(got, "|") != "Hel|lo" {
+		t.Errorf("chunks = %q, want [Hel lo]", got)
+	}
+	if usage := client.TokenUsage(); usage.TotalTokens != 6 {
+		t.Errorf("token usage = %+v, want the stream's usage counted", usage)
+	}
+}
+
+func TestAskStreamFallsBackWhenStreamFails(t *testing.T) {
+	provider := &fakeStreamProvider{failModel: "primary-model", chunks: []StreamChunk{{Content: "from the fallback"}}}
+	client := NewClient("default")
+	client.SetProvider(provider)
+
+	got, err := drainStream(client.AskStream(context.Background(), []schema.Message{schema.NewUserMessage("hi")}, nil))
+	if err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	if strings.Join(got, "") != "from the fallback" {
+		t.Errorf("chunks = %q, want the fallback's reply", got)
+	}
+	if strings.Join(provider.models, ",") != "primary-model,fallback-model" {
+		t.Errorf("stream requests went to %v, want primary then fallback", provider.models)
+	}
+}
+
+func TestAskStreamWithoutStreamingProvider(t *testing.T) {
+	mockServer.handle(t, func(w http.ResponseWriter, model string, attempt int) {
+		writeCompletion(w, "whole reply")
+	})
+	var trace strings.Builder
+	client := NewClient("default")
+	client.RecordTrace(&trace)
+
+	got, err := drainStream(client.AskStream(context.Background(), []schema.Message{schema.NewUserMessage("hi")}, nil))
+	if err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	if strings.Join(got, "|") != "whole reply" {
+		t.Errorf("chunks = %q, want the whole reply as one chunk", got)
+	}
+	if usage := client.TokenUsage(); usage.TotalTokens != 15 {
+		t.Errorf("token usage = %+v, want the response's usage counted", usage)
+	}
+	if !strings.Contains(trace.String(), "whole reply") {
+		t.Errorf("trace = %q, want the streamed request recorded", trace.String())
+	}
+}
+
+func TestAskStreamFromOpenAIServer(t *testing.T) {
+	mockServer.handle(t, func(w http.ResponseWriter, model string, attempt int) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, delta := range []string{"stre", "amed"} {
+			fmt.Fprintf(w, "data: {\"id\":\"chatcmpl-test\",\"object\":\"chat.completion.chunk\",\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", delta)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	client := NewClient("default")
+	got, err := drainStream(client.AskStream(context.Background(), []schema.Message{schema.NewUserMessage("hi")}, nil))
+	if err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	if strings.Join(got, "|") != "stre|amed" {
+		t.Errorf("chunks = %q, want [stre amed]", got)
+	}
+	if got := mockServer.count("fallback"); got != 0 {
+		t.Errorf("fallback received %d requests, want 0", got)
+	}
+}
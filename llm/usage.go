@@ -0,0 +1,60 @@
+package llm
+
+import (
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// TokenUsage 累计消耗的 token 数
+type TokenUsage struct {
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+}
+
+// Sub 返回 u 相对于之前某次 TokenUsage() 结果的增量
+func (u TokenUsage) Sub(prev TokenUsage) TokenUsage {
+	return TokenUsage{
+		PromptTokens:     u.PromptTokens - prev.PromptTokens,
+		CompletionTokens: u.CompletionTokens - prev.CompletionTokens,
+		TotalTokens:      u.TotalTokens - prev.TotalTokens,
+	}
+}
+
+// usageTracker 累计每次请求返回的 usage，可被多个 Agent 共享的客户端并发更新
+type usageTracker struct {
+	mu    sync.Mutex
+	usage TokenUsage
+}
+
+func (t *usageTracker) add(u openai.Usage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.usage.PromptTokens += u.PromptTokens
+	t.usage.CompletionTokens += u.CompletionTokens
+	t.usage.TotalTokens += u.TotalTokens
+}
+
+func (t *usageTracker) get() TokenUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.usage
+}
+
+func (t *usageTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.usage = TokenUsage{}
+}
+
+// TokenUsage 返回客户端创建以来 Ask、AskStream 和 AskTool 累计消耗的 token 数，包括切换到备用模型后的消耗。
+// OpenAI 接口的流式响应不含 usage，不计入统计
+func (c *Client) TokenUsage() TokenUsage {
+	return c.usage.get()
+}
+
+// ResetTokenUsage 清零累计的 token 数，例如在每次运行开始时调用以统计单次运行的消耗
+func (c *Client) ResetTokenUsage() {
+	c.usage.reset()
+}
@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/sirupsen/logrus"
+)
+
+type contextKey int
+
+const (
+	runIDKey contextKey = iota
+	stepKey
+)
+
+// NewRunID 生成一次运行的关联 ID
+func NewRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// WithRunID 在 context 中记录运行 ID，之后通过 FromContext 输出的日志都带有 run_id 字段
+func WithRunID(ctx context.Context, runID string) context.Context {
+	return context.WithValue(ctx, runIDKey, runID)
+}
+
+// RunID 返回 context 中的运行 ID，没有时返回空字符串
+func RunID(ctx context.Context) string {
+	id, _ := ctx.Value(runIDKey).(string)
+	return id
+}
+
+// WithStep 在 context 中记录当前步骤号，日志带有 step 字段
+func WithStep(ctx context.Context, step int) context.Context {
+	return context.WithValue(ctx, stepKey, step)
+}
+
+// FromContext 返回带有 context 中 run_id 和 step 字段的日志条目
+func FromContext(ctx context.Context) *logrus.Entry {
+	fields := logrus.Fields{}
+	if ctx != nil {
+		if id := RunID(ctx); id != "" {
+			fields["run_id"] = id
+		}
+		if step, ok := ctx.Value(stepKey).(int); ok {
+			fields["step"] = step
+		}
+	}
+	return log.WithFields(fields)
+}
@@ -1,37 +1,110 @@
 package main
 
 import (
-	"bufio"
 	"context"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
 	"strings"
 
 	"go-manus/agent"
+	"go-manus/config"
+	"go-manus/llm"
 	"go-manus/logger"
+	"go-manus/telemetry"
+	"go-manus/tool"
 )
 
 func main() {
 	// 初始化日志
 	logger.Setup("INFO", "DEBUG", "go-manus")
 
+	dumpTools := flag.Bool("dump-tools", false, "print the JSON schema of all tools and exit")
+	recordTrace := flag.String("record-trace", "", "write every LLM response to this JSON Lines file")
+	replayTrace := flag.String("replay", "", "replay LLM responses from a file written by -record-trace instead of calling the API")
+	interactive := flag.String("interactive", "auto", "interactive mode: auto (detect from stdin), true or false")
+	flag.Parse()
+
+	if *interactive != "auto" {
+		v, err := strconv.ParseBool(*interactive)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -interactive value %q: use auto, true or false\n", *interactive)
+			os.Exit(2)
+		}
+		config.SetInteractive(v)
+	}
+
+	// go-manus doctor：检查运行环境后退出
+	if flag.Arg(0) == "doctor" {
+		os.Exit(runDoctor())
+	}
+
+	// 初始化追踪（未启用时为 no-op）
+	if err := telemetry.Setup(config.GetInstance().GetTelemetry()); err != nil {
+		logger.Errorf("Failed to setup telemetry: %v", err)
+	}
+	defer func() {
+		if err := telemetry.Shutdown(context.Background()); err != nil {
+			logger.Errorf("Failed to flush telemetry: %v", err)
+		}
+	}()
+
 	// 创建 Agent
 	manusAgent := agent.NewManus()
 
+	// --dump-tools：输出工具定义供外部编排系统使用
+	if *dumpTools {
+		if err := manusAgent.AvailableTools.DumpSchemas(os.Stdout); err != nil {
+			logger.Errorf("Failed to dump tool schemas: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// -replay：按顺序回放记录的 LLM 响应，用于离线复现问题；-record-trace：记录本次运行的响应
+	if *replayTrace != "" {
+		replay, err := llm.LoadReplayFile(*replayTrace)
+		if err != nil {
+			logger.Errorf("Failed to load trace: %v", err)
+			os.Exit(1)
+		}
+		manusAgent.LLM.SetProvider(replay)
+	}
+	if *recordTrace != "" {
+		f, err := os.Create(*recordTrace)
+		if err != nil {
+			logger.Errorf("Failed to create trace file: %v", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		manusAgent.LLM.RecordTrace(f)
+	}
+
 	// 创建上下文
 	ctx := context.Background()
 
-	// 交互式循环
-	scanner := bufio.NewScanner(os.Stdin)
+	// 非交互模式：执行一个任务后退出
+	if !config.Interactive() {
+		os.Exit(runOnce(ctx, manusAgent))
+	}
+
+	// 交互式循环，与 ask_human 共享标准输入的读取
+	input := tool.Stdin()
 	fmt.Println("Go-Manus - Enter your prompt (or 'exit' to quit):")
 
 	for {
 		fmt.Print("> ")
-		if !scanner.Scan() {
+		line, err := input.ReadLine(ctx)
+		if err != nil {
+			if err != io.EOF {
+				logger.Errorf("Error reading input: %v", err)
+			}
 			break
 		}
 
-		prompt := strings.TrimSpace(scanner.Text())
+		prompt := strings.TrimSpace(line)
 		if prompt == "" {
 			continue
 		}
@@ -54,9 +127,30 @@ func main() {
 		fmt.Println(result)
 		fmt.Println()
 	}
+}
 
-	if err := scanner.Err(); err != nil {
-		logger.Errorf("Error reading input: %v", err)
+// runOnce 从命令行参数读取任务，没有参数时读取全部标准输入，执行一次并返回退出码
+func runOnce(ctx context.Context, manusAgent *agent.Manus) int {
+	prompt := strings.TrimSpace(strings.Join(flag.Args(), " "))
+	if prompt == "" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			logger.Errorf("Error reading input: %v", err)
+			return 1
+		}
+		prompt = strings.TrimSpace(string(data))
+	}
+	if prompt == "" {
+		fmt.Fprintln(os.Stderr, "No prompt given: pass it as arguments or on stdin")
+		return 2
 	}
-}
 
+	result, err := manusAgent.Run(ctx, prompt)
+	if err != nil {
+		logger.Errorf("Error: %v", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Println(result)
+	return 0
+}
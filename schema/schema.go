@@ -40,6 +40,7 @@ type Message struct {
 	ToolCalls    []ToolCall  `json:"tool_calls,omitempty"`
 	Name         *string     `json:"name,omitempty"`
 	ToolCallID   *string     `json:"tool_call_id,omitempty"`
+	Base64Image  *string     `json:"base64_image,omitempty"`
 }
 
 // NewUserMessage 创建用户消息
@@ -50,6 +51,15 @@ func NewUserMessage(content string) Message {
 	}
 }
 
+// NewUserImageMessage 创建带图片的用户消息，图片为 base64 编码的 PNG
+func NewUserImageMessage(content string, base64Image string) Message {
+	return Message{
+		Role:        RoleUser,
+		Content:     &content,
+		Base64Image: &base64Image,
+	}
+}
+
 // NewSystemMessage 创建系统消息
 func NewSystemMessage(content string) Message {
 	return Message{
@@ -92,6 +102,11 @@ func NewMessageFromToolCalls(content string, toolCalls []ToolCall) Message {
 type Memory struct {
 	Messages   []Message `json:"messages"`
 	MaxMessages int      `json:"max_messages"`
+
+	// pinned 固定的消息下标（升序），裁剪时始终保留并移到最前面
+	pinned []int
+	// added 累计添加的消息数，不受裁剪影响
+	added int
 }
 
 // NewMemory 创建新的记忆
@@ -105,22 +120,116 @@ func NewMemory() *Memory {
 // AddMessage 添加消息
 func (m *Memory) AddMessage(msg Message) {
 	m.Messages = append(m.Messages, msg)
-	if len(m.Messages) > m.MaxMessages {
-		m.Messages = m.Messages[len(m.Messages)-m.MaxMessages:]
-	}
+	m.added++
+	m.trim()
 }
 
 // AddMessages 添加多条消息
 func (m *Memory) AddMessages(msgs []Message) {
 	m.Messages = append(m.Messages, msgs...)
-	if len(m.Messages) > m.MaxMessages {
+	m.added += len(msgs)
+	m.trim()
+}
+
+// Pin 固定指定下标的消息，使其在裁剪时不被移除
+func (m *Memory) Pin(index int) bool {
+	if index < 0 || index >= len(m.Messages) {
+		return false
+	}
+	for i, p := range m.pinned {
+		if p == index {
+			return true
+		}
+		if p > index {
+			m.pinned = append(m.pinned[:i], append([]int{index}, m.pinned[i:]...)...)
+			return true
+		}
+	}
+	m.pinned = append(m.pinned, index)
+	return true
+}
+
+// Unpin 取消固定指定下标的消息，之后它与其他消息一样按时间顺序被裁剪
+func (m *Memory) Unpin(index int) bool {
+	for i, p := range m.pinned {
+		if p == index {
+			m.pinned = append(m.pinned[:i], m.pinned[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// IsPinned 检查指定下标的消息是否被固定
+func (m *Memory) IsPinned(index int) bool {
+	for _, p := range m.pinned {
+		if p == index {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPinned 检查是否存在固定的消息
+func (m *Memory) HasPinned() bool {
+	return len(m.pinned) > 0
+}
+
+// trim 超出 MaxMessages 时从最早的未固定消息开始移除，固定消息保持在最前面
+func (m *Memory) trim() {
+	if m.MaxMessages <= 0 || len(m.Messages) <= m.MaxMessages {
+		return
+	}
+
+	if len(m.pinned) == 0 {
 		m.Messages = m.Messages[len(m.Messages)-m.MaxMessages:]
+		return
+	}
+
+	// 固定消息数量超过上限时只保留固定消息
+	drop := len(m.Messages) - m.MaxMessages
+	kept := make([]Message, 0, m.MaxMessages)
+	rest := make([]Message, 0, m.MaxMessages)
+	for i, msg := range m.Messages {
+		switch {
+		case m.IsPinned(i):
+			kept = append(kept, msg)
+		case drop > 0:
+			drop--
+		default:
+			rest = append(rest, msg)
+		}
+	}
+
+	pinned := make([]int, len(kept))
+	for i := range kept {
+		pinned[i] = i
+	}
+	m.pinned = pinned
+	m.Messages = append(kept, rest...)
+}
+
+// Added 返回累计添加的消息数，与之前的值相减即可得到期间新增的消息数
+func (m *Memory) Added() int {
+	return m.added
+}
+
+// MessagesSince 返回累计添加数为 added 之后新增且仍在记忆中的消息
+func (m *Memory) MessagesSince(added int) []Message {
+	n := m.added - added
+	if n > len(m.Messages) {
+		n = len(m.Messages)
+	}
+	if n <= 0 {
+		return nil
 	}
+	return m.Messages[len(m.Messages)-n:]
 }
 
 // Clear 清空消息
 func (m *Memory) Clear() {
 	m.Messages = make([]Message, 0)
+	m.pinned = nil
 }
 
 // GetRecentMessages 获取最近 N 条消息
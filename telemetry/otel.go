@@ -0,0 +1,106 @@
+//go:build otel
+
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"go-manus/config"
+)
+
+// 使用 -tags otel 构建时注册 otel 导出方式：配置了 otlp_endpoint 时通过 OTLP/HTTP 发送到收集器，
+// 否则以 JSON 写到标准输出
+func init() {
+	RegisterExporter("otel", func(settings config.TelemetrySettings) (Tracer, error) {
+		exporter, err := newSpanExporter(settings)
+		if err != nil {
+			return nil, err
+		}
+		return NewOTelTracer(settings.ServiceName, exporter), nil
+	})
+}
+
+// newSpanExporter 根据配置创建 OpenTelemetry 区间导出器
+func newSpanExporter(settings config.TelemetrySettings) (sdktrace.SpanExporter, error) {
+	if settings.OTLPEndpoint == "" {
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		return exporter, nil
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(settings.OTLPEndpoint)}
+	if settings.OTLPInsecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+	return exporter, nil
+}
+
+// NewOTelTracer 创建通过 OpenTelemetry SDK 导出区间的追踪器，并将其 TracerProvider 设为全局默认；
+// 区间批量发送给 exporter，Shutdown 时导出剩余区间
+func NewOTelTracer(serviceName string, exporter sdktrace.SpanExporter) Tracer {
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	RegisterShutdown(provider.Shutdown)
+	return &otelTracer{tracer: provider.Tracer(serviceName)}
+}
+
+type otelTracer struct {
+	tracer trace.Tracer
+}
+
+func (t *otelTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	ctx, s := t.tracer.Start(ctx, name)
+	return ctx, &otelSpan{span: s}
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s *otelSpan) SetAttribute(key string, value interface{}) {
+	var kv attribute.KeyValue
+	switch v := value.(type) {
+	case string:
+		kv = attribute.String(key, v)
+	case bool:
+		kv = attribute.Bool(key, v)
+	case int:
+		kv = attribute.Int(key, v)
+	case int64:
+		kv = attribute.Int64(key, v)
+	case float64:
+		kv = attribute.Float64(key, v)
+	default:
+		kv = attribute.String(key, fmt.Sprint(v))
+	}
+	s.span.SetAttributes(kv)
+}
+
+func (s *otelSpan) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s *otelSpan) End() {
+	s.span.End()
+}
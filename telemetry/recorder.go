@@ -0,0 +1,137 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-manus/logger"
+)
+
+// SpanData 已结束区间的数据
+type SpanData struct {
+	Name       string
+	Parent     string
+	Attributes map[string]interface{}
+	Err        error
+	StartTime  time.Time
+	EndTime    time.Time
+}
+
+// Duration 区间耗时
+func (s SpanData) Duration() time.Duration {
+	return s.EndTime.Sub(s.StartTime)
+}
+
+type spanKey struct{}
+
+// span 记录属性和耗时的区间实现，结束时回调 onEnd
+type span struct {
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+	onEnd func(SpanData)
+}
+
+func startSpan(ctx context.Context, name string, onEnd func(SpanData)) (context.Context, Span) {
+	s := &span{
+		data: SpanData{
+			Name:       name,
+			Attributes: make(map[string]interface{}),
+			StartTime:  time.Now(),
+		},
+		onEnd: onEnd,
+	}
+	if parent, ok := ctx.Value(spanKey{}).(*span); ok {
+		s.data.Parent = parent.data.Name
+	}
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+func (s *span) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes[key] = value
+}
+
+func (s *span) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Err = err
+}
+
+func (s *span) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.EndTime = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	s.onEnd(data)
+}
+
+// Recorder 内存追踪器，保存所有已结束的区间
+type Recorder struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// NewRecorder 创建内存追踪器
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) Start(ctx context.Context, name string) (context.Context, Span) {
+	return startSpan(ctx, name, func(data SpanData) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.spans = append(r.spans, data)
+	})
+}
+
+// Spans 返回已结束的区间
+func (r *Recorder) Spans() []SpanData {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	spans := make([]SpanData, len(r.spans))
+	copy(spans, r.spans)
+	return spans
+}
+
+// LogTracer 将结束的区间写入日志
+type LogTracer struct {
+	serviceName string
+}
+
+// NewLogTracer 创建日志追踪器
+func NewLogTracer(serviceName string) *LogTracer {
+	return &LogTracer{serviceName: serviceName}
+}
+
+func (l *LogTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return startSpan(ctx, name, func(data SpanData) {
+		keys := make([]string, 0, len(data.Attributes))
+		for k := range data.Attributes {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		attrs := make([]string, 0, len(keys))
+		for _, k := range keys {
+			attrs = append(attrs, fmt.Sprintf("%s=%v", k, data.Attributes[k]))
+		}
+
+		if data.Err != nil {
+			logger.Errorf("[%s] span %s failed after %s: %v {%s}", l.serviceName, data.Name, data.Duration(), data.Err, strings.Join(attrs, " "))
+			return
+		}
+		logger.Infof("[%s] span %s finished in %s {%s}", l.serviceName, data.Name, data.Duration(), strings.Join(attrs, " "))
+	})
+}
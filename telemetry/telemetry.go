@@ -0,0 +1,119 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go-manus/config"
+)
+
+// Span 一次操作的追踪区间
+type Span interface {
+	// SetAttribute 设置区间属性
+	SetAttribute(key string, value interface{})
+	// RecordError 记录错误并将区间标记为失败
+	RecordError(err error)
+	// End 结束区间
+	End()
+}
+
+// Tracer 追踪器，负责创建区间
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// TracerFactory 根据配置创建追踪器
+type TracerFactory func(settings config.TelemetrySettings) (Tracer, error)
+
+var (
+	mu     sync.RWMutex
+	tracer Tracer = noopTracer{}
+	// shutdown 关闭当前导出方式并导出剩余区间，导出方式不需要关闭时为 nil
+	shutdown func(ctx context.Context) error
+
+	// exporters 已注册的导出方式
+	exporters = map[string]TracerFactory{
+		"log": func(settings config.TelemetrySettings) (Tracer, error) {
+			return NewLogTracer(settings.ServiceName), nil
+		},
+	}
+)
+
+// Setup 根据配置初始化追踪，未启用时保持 no-op
+func Setup(settings config.TelemetrySettings) error {
+	if !settings.Enabled {
+		SetTracer(nil)
+		return nil
+	}
+
+	mu.RLock()
+	factory, ok := exporters[settings.Exporter]
+	mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown telemetry exporter: %s", settings.Exporter)
+	}
+
+	t, err := factory(settings)
+	if err != nil {
+		return err
+	}
+	SetTracer(t)
+	return nil
+}
+
+// RegisterExporter 注册导出方式
+func RegisterExporter(name string, factory TracerFactory) {
+	mu.Lock()
+	defer mu.Unlock()
+	exporters[name] = factory
+}
+
+// RegisterShutdown 设置 Shutdown 时调用的关闭函数，由需要导出剩余区间的导出方式在创建追踪器时调用
+func RegisterShutdown(fn func(ctx context.Context) error) {
+	mu.Lock()
+	defer mu.Unlock()
+	shutdown = fn
+}
+
+// Shutdown 导出尚未发送的区间并关闭导出方式，程序退出前调用
+func Shutdown(ctx context.Context) error {
+	mu.Lock()
+	fn := shutdown
+	shutdown = nil
+	mu.Unlock()
+	if fn == nil {
+		return nil
+	}
+	return fn(ctx)
+}
+
+// SetTracer 设置全局追踪器，传入 nil 时恢复为 no-op
+func SetTracer(t Tracer) {
+	mu.Lock()
+	defer mu.Unlock()
+	if t == nil {
+		t = noopTracer{}
+	}
+	tracer = t
+}
+
+// StartSpan 使用全局追踪器创建区间
+func StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	mu.RLock()
+	t := tracer
+	mu.RUnlock()
+	return t.Start(ctx, name)
+}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+func (noopSpan) RecordError(err error)                      {}
+func (noopSpan) End()                                       {}
@@ -4,10 +4,17 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"sync"
+
+	"go-manus/config"
 )
 
-type AskHuman struct{}
+type AskHuman struct {
+	// Input 读取回答的输入源，nil 时使用与命令行共享的 Stdin()
+	Input *LineReader
+}
 
 func NewAskHuman() *AskHuman {
 	return &AskHuman{}
@@ -40,14 +47,83 @@ func (a *AskHuman) Execute(ctx context.Context, args map[string]interface{}) (*T
 		return &ToolResult{Error: "inquire parameter is required"}, nil
 	}
 
+	// 非交互模式下没有人可以回答，不等待输入
+	if !config.Interactive() {
+		return &ToolResult{Error: "No human is available to answer: running in non-interactive mode. Continue with your best judgement."}, nil
+	}
+
 	// Print question and wait for user input
 	fmt.Printf("Bot: %s\n\nYou: ", inquire)
 
-	scanner := bufio.NewScanner(os.Stdin)
-	if !scanner.Scan() {
+	input := a.Input
+	if input == nil {
+		input = Stdin()
+	}
+	answer, err := input.ReadLine(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return &ToolResult{Error: fmt.Sprintf("Waiting for user input cancelled: %v", ctx.Err())}, nil
+		}
 		return &ToolResult{Error: "Failed to read user input"}, nil
 	}
+	return &ToolResult{Output: answer}, nil
+}
+
+// LineReader 按行读取输入。只有一个后台 goroutine 读取底层输入，读到的每一行交给下一个
+// ReadLine 调用方；ReadLine 因 ctx 取消返回后，用户之后输入的行由下一次 ReadLine 取得，不会丢失
+type LineReader struct {
+	start sync.Once
+	r     io.Reader
+	lines chan string
+	done  chan struct{}
+	err   error
+}
+
+// NewLineReader 创建从 r 按行读取的 LineReader，首次 ReadLine 时开始读取
+func NewLineReader(r io.Reader) *LineReader {
+	return &LineReader{
+		r:     r,
+		lines: make(chan string),
+		done:  make(chan struct{}),
+	}
+}
 
-	response := scanner.Text()
-	return &ToolResult{Output: response}, nil
+var (
+	stdinOnce   sync.Once
+	stdinReader *LineReader
+)
+
+// Stdin 返回标准输入的共享 LineReader，命令行和 ask_human 都通过它读取，避免多个读取方争抢输入
+func Stdin() *LineReader {
+	stdinOnce.Do(func() {
+		stdinReader = NewLineReader(os.Stdin)
+	})
+	return stdinReader
+}
+
+// ReadLine 等待下一行输入，ctx 取消时立即返回 ctx 的错误；输入结束时返回 io.EOF 或读取错误
+func (l *LineReader) ReadLine(ctx context.Context) (string, error) {
+	l.start.Do(func() { go l.read() })
+
+	select {
+	case line := <-l.lines:
+		return line, nil
+	case <-l.done:
+		return "", l.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// read 在后台逐行读取，每行等到有 ReadLine 调用方接收后才读取下一行
+func (l *LineReader) read() {
+	scanner := bufio.NewScanner(l.r)
+	for scanner.Scan() {
+		l.lines <- scanner.Text()
+	}
+	l.err = scanner.Err()
+	if l.err == nil {
+		l.err = io.EOF
+	}
+	close(l.done)
 }
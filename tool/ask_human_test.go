@@ -0,0 +1,73 @@
+package tool
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"go-manus/config"
+)
+
+func TestAskHumanCancelDoesNotSwallowNextLine(t *testing.T) {
+	config.SetInteractive(true)
+	defer config.SetInteractive(false)
+
+	r, w := io.Pipe()
+	input := NewLineReader(r)
+	ask := &AskHuman{Input: input}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	result, err := ask.Execute(ctx, map[string]interface{}{"inquire": "Which file?"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error == "" {
+		t.Fatalf("cancelled ask_human returned output %q", result.Output)
+	}
+
+	// 取消后用户输入的下一行应交给下一个读取方（如命令行提示符），而不是被 ask_human 吞掉
+	go w.Write([]byte("next prompt\n"))
+	line, err := input.ReadLine(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "next prompt" {
+		t.Errorf("next line = %q, want %q", line, "next prompt")
+	}
+
+	go w.Write([]byte("main.go\n"))
+	result, err = ask.Execute(context.Background(), map[string]interface{}{"inquire": "Which file?"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Output != "main.go" {
+		t.Errorf("answer = %+v, want main.go", result)
+	}
+
+	w.Close()
+	if _, err := input.ReadLine(context.Background()); err != io.EOF {
+		t.Errorf("ReadLine after close = %v, want io.EOF", err)
+	}
+}
+
+func TestAskHumanNonInteractiveDoesNotWait(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	ask := &AskHuman{Input: NewLineReader(r)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	result, err := ask.Execute(ctx, map[string]interface{}{"inquire": "Which file?"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("ask_human waited for input in non-interactive mode")
+	}
+	if !strings.Contains(result.Error, "non-interactive") {
+		t.Errorf("result = %+v, want a non-interactive error", result)
+	}
+}
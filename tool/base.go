@@ -3,13 +3,31 @@ package tool
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"go-manus/config"
+	"go-manus/logger"
+	"go-manus/telemetry"
 )
 
+// defaultWorkspaceRoot 工具默认使用的工作目录
+const defaultWorkspaceRoot = "workspace"
+
 // ToolResult 工具执行结果
 type ToolResult struct {
 	Output string
 	Error  string
 	System string
+	// Base64Image base64 编码的 PNG 图片（如截图），会以图片消息的形式提供给模型
+	Base64Image string
 }
 
 // IsSuccess 检查是否成功
@@ -35,13 +53,20 @@ type Tool interface {
 
 // ToolCollection 工具集合
 type ToolCollection struct {
-	tools map[string]Tool
+	tools   map[string]Tool
+	breaker *circuitBreaker
 }
 
 // NewToolCollection 创建工具集合
 func NewToolCollection(tools ...Tool) *ToolCollection {
+	settings := config.GetInstance().GetCircuitBreaker()
 	tc := &ToolCollection{
 		tools: make(map[string]Tool),
+		breaker: newCircuitBreaker(
+			settings.FailureThreshold,
+			time.Duration(settings.WindowSeconds)*time.Second,
+			time.Duration(settings.CooldownSeconds)*time.Second,
+		),
 	}
 	for _, t := range tools {
 		tc.AddTool(t)
@@ -54,12 +79,31 @@ func (tc *ToolCollection) AddTool(t Tool) {
 	tc.tools[t.Name()] = t
 }
 
+// SetCircuitBreaker 设置工具熔断参数，threshold 小于等于 0 时关闭熔断
+func (tc *ToolCollection) SetCircuitBreaker(threshold int, window, cooldown time.Duration) {
+	tc.breaker = newCircuitBreaker(threshold, window, cooldown)
+}
+
 // GetTool 获取工具
 func (tc *ToolCollection) GetTool(name string) (Tool, bool) {
 	t, ok := tc.tools[name]
 	return t, ok
 }
 
+// Tools 返回按名称排序的全部工具
+func (tc *ToolCollection) Tools() []Tool {
+	names := make([]string, 0, len(tc.tools))
+	for name := range tc.tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tools := make([]Tool, 0, len(names))
+	for _, name := range names {
+		tools = append(tools, tc.tools[name])
+	}
+	return tools
+}
+
 // Execute 执行工具
 func (tc *ToolCollection) Execute(ctx context.Context, name string, args map[string]interface{}) (*ToolResult, error) {
 	t, ok := tc.GetTool(name)
@@ -69,7 +113,29 @@ func (tc *ToolCollection) Execute(ctx context.Context, name string, args map[str
 		}, nil
 	}
 
-	return t.Execute(ctx, args)
+	// 连续失败的工具在冷却期内直接短路
+	if ok, remaining := tc.breaker.allow(name); !ok {
+		return &ToolResult{
+			Error: fmt.Sprintf("Tool %s is temporarily disabled after repeated failures, try again in %s or use another tool", name, remaining.Round(time.Second)),
+		}, nil
+	}
+
+	ctx, span := telemetry.StartSpan(ctx, "tool.execute")
+	span.SetAttribute("tool.name", name)
+	defer span.End()
+
+	start := time.Now()
+	result, err := safeExecute(ctx, t, args)
+	span.SetAttribute("tool.duration_ms", time.Since(start).Milliseconds())
+	if err != nil {
+		span.RecordError(err)
+	} else if result != nil && result.Error != "" {
+		span.SetAttribute("tool.error", result.Error)
+	}
+
+	tc.breaker.record(name, err == nil && (result == nil || result.Error == ""))
+
+	return result, err
 }
 
 // ToOpenAITools 转换为 OpenAI 工具格式
@@ -89,6 +155,38 @@ func (tc *ToolCollection) ToOpenAITools() []interface{} {
 	return tools
 }
 
+// safeExecute 执行工具，工具 panic 时记录堆栈并转换为错误结果，避免整个进程崩溃
+func safeExecute(ctx context.Context, t Tool, args map[string]interface{}) (result *ToolResult, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.FromContext(ctx).Errorf("Tool %s panicked: %v\n%s", t.Name(), r, debug.Stack())
+			result = &ToolResult{Error: fmt.Sprintf("Tool %s crashed: %v", t.Name(), r)}
+			err = nil
+		}
+	}()
+	return t.Execute(ctx, args)
+}
+
+// DumpSchemas 以缩进 JSON 输出全部工具的 OpenAI function 定义，按工具名排序便于比对
+func (tc *ToolCollection) DumpSchemas(w io.Writer) error {
+	tools := tc.ToOpenAITools()
+	sort.Slice(tools, func(i, j int) bool {
+		return toolSchemaName(tools[i]) < toolSchemaName(tools[j])
+	})
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(tools)
+}
+
+func toolSchemaName(schema interface{}) string {
+	m, _ := schema.(map[string]interface{})
+	fn, _ := m["function"].(map[string]interface{})
+	name, _ := fn["name"].(string)
+	return name
+}
+
 // ParseToolArgs 解析工具参数
 func ParseToolArgs(argsJSON string) (map[string]interface{}, error) {
 	var args map[string]interface{}
@@ -99,3 +197,81 @@ func ParseToolArgs(argsJSON string) (map[string]interface{}, error) {
 	return args, err
 }
 
+// workspaceRoot 返回配置的工作目录，未配置时为 defaultWorkspaceRoot
+func workspaceRoot() string {
+	if root := config.GetInstance().GetWorkspace().Root; root != "" {
+		return root
+	}
+	return defaultWorkspaceRoot
+}
+
+// workspaceSubdir 将输出目录限定在工作目录下，绝对路径和 ".." 都不会越出工作目录
+func workspaceSubdir(dir string) string {
+	return filepath.Join(workspaceRoot(), filepath.Clean(string(filepath.Separator)+dir))
+}
+
+// ResolveWorkspacePath 将路径解析为工作目录下的绝对路径，相对路径相对于工作目录。
+// 解析后（包括跟随已存在部分的符号链接）位于工作目录之外的路径会被拒绝；
+// 配置 workspace.unrestricted = true 时不做限制，只将相对路径解析到工作目录下
+func ResolveWorkspacePath(p string) (string, error) {
+	root, err := filepath.Abs(workspaceRoot())
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve workspace: %v", err)
+	}
+	path := filepath.Clean(p)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	if config.GetInstance().GetWorkspace().Unrestricted {
+		return path, nil
+	}
+
+	realRoot, err := evalExistingSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve workspace: %v", err)
+	}
+	realPath, err := evalExistingSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %v", p, err)
+	}
+	rel, err := filepath.Rel(realRoot, realPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %s is outside the workspace %s", p, root)
+	}
+	return path, nil
+}
+
+// evalExistingSymlinks 解析路径中已存在部分的符号链接，不存在的部分原样拼接在后面
+func evalExistingSymlinks(path string) (string, error) {
+	suffix := ""
+	for {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(resolved, suffix), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return filepath.Join(path, suffix), nil
+		}
+		suffix = filepath.Join(filepath.Base(path), suffix)
+		path = parent
+	}
+}
+
+// stringSliceArg 将 JSON 数组参数转换为字符串切片
+func stringSliceArg(raw interface{}) []string {
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil
+	}
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok && s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
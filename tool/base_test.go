@@ -0,0 +1,181 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileToolsRejectPathsOutsideWorkspace(t *testing.T) {
+	outside, err := filepath.Abs(filepath.Join(workspaceRoot(), "..", "outside.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeWorkspaceFile(t, "inside.csv", "a,b\n1,2\n")
+
+	tests := []struct {
+		name string
+		tool Tool
+		args map[string]interface{}
+	}{
+		{"template output", NewTemplate(), map[string]interface{}{"template": "x", "output_path": "../outside.txt"}},
+		{"template file", NewTemplate(), map[string]interface{}{"template_path": "/etc/hostname"}},
+		{"convert input", NewConvert(), map[string]interface{}{"input_path": "../outside.csv", "output_format": "json"}},
+		{"convert output", NewConvert(), map[string]interface{}{"input_path": "inside.csv", "output_format": "json", "output_path": outside}},
+		{"format", NewFormat(), map[string]interface{}{"path": "../../main.go"}},
+		{"go_test dir", NewGoTest(), map[string]interface{}{"dir": "/"}},
+		{"visualization_prepare file", NewVisualizationPrepare(), map[string]interface{}{"data": "../outside.csv"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.tool.Execute(context.Background(), tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(result.Error, "outside the workspace") {
+				t.Errorf("result = %+v, want an outside the workspace error", result)
+			}
+		})
+	}
+
+	if _, err := os.Stat(outside); !os.IsNotExist(err) {
+		t.Errorf("a tool wrote %s outside the workspace", outside)
+	}
+}
+
+// panickyTool 执行时 panic 的工具
+type panickyTool struct{}
+
+func (panickyTool) Name() string        { return "panicky" }
+func (panickyTool) Description() string { return "A tool that panics" }
+func (panickyTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object"}
+}
+func (panickyTool) Execute(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	var m map[string]int
+	m["boom"] = 1
+	return nil, nil
+}
+
+func TestToolPanicBecomesErrorResult(t *testing.T) {
+	tc := NewToolCollection(panickyTool{})
+	result, err := tc.Execute(context.Background(), "panicky", nil)
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if result == nil || !strings.Contains(result.Error, "Tool panicky crashed") {
+		t.Errorf("result = %+v, want a crashed error result", result)
+	}
+}
+
+func TestToolsWriteToConfiguredOutputDirs(t *testing.T) {
+	// inDir 检查 path 位于工作目录下的 dir 中
+	inDir := func(t *testing.T, path, dir string) {
+		t.Helper()
+		want, err := filepath.Abs(filepath.Join(workspaceRoot(), dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := filepath.Abs(filepath.Dir(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s was written to %s, want %s", filepath.Base(path), got, want)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Error(err)
+		}
+	}
+
+	t.Run("charts", func(t *testing.T) {
+		prepare := NewVisualizationPrepare()
+		prepare.SetOutputDir("custom/prepared")
+		result, err := prepare.Execute(context.Background(), map[string]interface{}{"data": "fruit,sold\napple,3\npear,5\n", "title": "Custom Dir"})
+		if err != nil || result.Error != "" {
+			t.Fatalf("visualization_prepare: %+v, %v", result, err)
+		}
+		_, rest, _ := strings.Cut(result.Output, "CSV: ")
+		csvPath, rest, _ := strings.Cut(rest, "\nJSON: ")
+		jsonPath, _, _ := strings.Cut(rest, "\n")
+		inDir(t, csvPath, "custom/prepared")
+		inDir(t, jsonPath, "custom/prepared")
+
+		viz := NewDataVisualization()
+		viz.SetOutputDir("custom/charts")
+		result, err = viz.Execute(context.Background(), map[string]interface{}{"json_path": jsonPath})
+		if err != nil || result.Error != "" {
+			t.Fatalf("data_visualization: %+v, %v", result, err)
+		}
+		_, chartPath, _ := strings.Cut(result.Output, "Chart saved in: ")
+		inDir(t, chartPath, "custom/charts")
+	})
+
+	t.Run("plans", func(t *testing.T) {
+		p := NewPlanningTool()
+		p.SetStorageDir("custom/plans")
+		runPlanning(t, p, map[string]interface{}{"command": "create", "plan_id": "custom-dir", "title": "Custom dir", "steps": []interface{}{"one"}})
+		inDir(t, filepath.Join(workspaceRoot(), "custom/plans", "custom-dir.json"), "custom/plans")
+	})
+
+	t.Run("screenshots", func(t *testing.T) {
+		if _, err := desktopCapture(); err != nil {
+			t.Skipf("no desktop to capture: %v", err)
+		}
+		c := NewComputerUseTool()
+		c.SetOutputDir("custom/screenshots")
+		result, err := c.Execute(context.Background(), map[string]interface{}{"action": "screenshot"})
+		if err != nil || result.Error != "" {
+			t.Fatalf("screenshot: %+v, %v", result, err)
+		}
+		_, rest, _ := strings.Cut(result.Output, "Screenshot saved to: ")
+		screenshotPath, _, _ := strings.Cut(rest, "\n")
+		inDir(t, screenshotPath, "custom/screenshots")
+	})
+
+	// 绝对路径和 ".." 也落在工作目录下
+	if got, want := workspaceSubdir("/etc/../../charts"), filepath.Join(workspaceRoot(), "charts"); got != want {
+		t.Errorf("workspaceSubdir = %q, want %q", got, want)
+	}
+}
+
+func TestDumpSchemasIncludesToolParameters(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewToolCollection(NewBash(), NewSleep()).DumpSchemas(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var schemas []struct {
+		Type     string `json:"type"`
+		Function struct {
+			Name        string                 `json:"name"`
+			Description string                 `json:"description"`
+			Parameters  map[string]interface{} `json:"parameters"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &schemas); err != nil {
+		t.Fatalf("dumped schemas are not valid JSON: %v\n%s", err, buf.String())
+	}
+	if len(schemas) != 2 || schemas[0].Function.Name != "bash" || schemas[1].Function.Name != "sleep" {
+		t.Fatalf("dumped schemas = %+v, want bash and sleep sorted by name", schemas)
+	}
+
+	bash := schemas[0]
+	if bash.Type != "function" || bash.Function.Description != NewBash().Description() {
+		t.Errorf("bash schema = %+v, want its type and description", bash)
+	}
+	properties, _ := bash.Function.Parameters["properties"].(map[string]interface{})
+	for _, name := range []string{"command", "commands", "stdin", "session_id"} {
+		if _, ok := properties[name]; !ok {
+			t.Errorf("bash schema has no %q parameter: %v", name, properties)
+		}
+	}
+	// 描述中的 && 原样输出，不转义为 \u0026
+	if !strings.Contains(buf.String(), "chaining with && to run them") {
+		t.Error("dumped schemas escape HTML characters in descriptions")
+	}
+}
@@ -6,13 +6,17 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"go-manus/config"
 )
 
 type Bash struct {
 	sessions map[string]*BashSession
+	policy   *commandPolicy
 	mu       sync.RWMutex
 }
 
@@ -32,6 +36,7 @@ type BashSession struct {
 func NewBash() *Bash {
 	return &Bash{
 		sessions: make(map[string]*BashSession),
+		policy:   newCommandPolicy(config.GetInstance().GetBash()),
 	}
 }
 
@@ -43,7 +48,16 @@ func (b *Bash) Description() string {
 	return `Execute a bash command in the terminal.
 * Long running commands: For commands that may run indefinitely, it should be run in the background and the output should be redirected to a file, e.g. command = "python3 app.py > server.log 2>&1 &".
 * Interactive: If a bash command returns exit code -1, this means the process is not yet finished. The assistant must then send a second call to terminal with an empty "command" (which will retrieve any additional logs), or it can send additional text (set "command" to the text) to STDIN of the running process, or it can send command="ctrl+c" to interrupt the process.
-* Timeout: If a command execution result says "Command timed out. Sending SIGINT to the process", the assistant should retry running the command in the background.`
+* Timeout: If a command execution result says "Command timed out. Sending SIGINT to the process", the assistant should retry running the command in the background.
+* Multiple commands: Pass "commands" instead of chaining with && to run them one by one and get each command's exit code and output. Execution stops at the first failure unless "continue_on_error" is true.`
+}
+
+// SelfTest checks that the shell used for sessions is available.
+func (b *Bash) SelfTest(ctx context.Context) error {
+	if _, err := os.Stat("/bin/bash"); err != nil {
+		return fmt.Errorf("/bin/bash not available: %w", err)
+	}
+	return nil
 }
 
 func (b *Bash) Parameters() map[string]interface{} {
@@ -54,26 +68,52 @@ func (b *Bash) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "The bash command to execute. Use empty string to retrieve additional logs from a running process, or 'ctrl+c' to interrupt.",
 			},
+			"stdin": map[string]interface{}{
+				"type":        "string",
+				"description": "(optional) Text passed to the command's standard input, for programs that read input interactively.",
+			},
+			"commands": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "(optional) Commands to run sequentially in the same session, reporting exit code and output per command. Used instead of \"command\".",
+			},
+			"continue_on_error": map[string]interface{}{
+				"type":        "boolean",
+				"description": "(optional) With \"commands\", keep running the remaining commands after one fails. Default: false.",
+			},
 			"session_id": map[string]interface{}{
 				"type":        "string",
 				"description": "(optional) Session ID for maintaining state across multiple commands. If not provided, a new session will be created.",
 			},
 		},
-		"required": []string{"command"},
 	}
 }
 
 func (b *Bash) Execute(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
-	command, ok := args["command"].(string)
-	if !ok {
-		return &ToolResult{Error: "command parameter is required"}, nil
-	}
-
 	sessionID := "default"
 	if sid, ok := args["session_id"].(string); ok && sid != "" {
 		sessionID = sid
 	}
 
+	if commands := stringSliceArg(args["commands"]); len(commands) > 0 {
+		for _, command := range commands {
+			if err := b.policy.check(command); err != nil {
+				return &ToolResult{Error: fmt.Sprintf("Command rejected by policy: %v: %s", err, command)}, nil
+			}
+		}
+		session := b.getOrCreateSession(sessionID)
+		if session == nil {
+			return &ToolResult{Error: "Failed to create bash session"}, nil
+		}
+		continueOnError, _ := args["continue_on_error"].(bool)
+		return b.runCommands(ctx, session, commands, continueOnError)
+	}
+
+	command, ok := args["command"].(string)
+	if !ok {
+		return &ToolResult{Error: "command or commands parameter is required"}, nil
+	}
+
 	// Handle special commands
 	if command == "ctrl+c" {
 		return b.interruptSession(ctx, sessionID)
@@ -90,6 +130,15 @@ func (b *Bash) Execute(ctx context.Context, args map[string]interface{}) (*ToolR
 		return b.retrieveOutput(ctx, session)
 	}
 
+	if err := b.policy.check(command); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Command rejected by policy: %v", err)}, nil
+	}
+
+	// Feed stdin through a heredoc so interactive programs run non-interactively
+	if stdin, ok := args["stdin"].(string); ok && stdin != "" {
+		command = withStdin(command, stdin)
+	}
+
 	// Execute command
 	return b.runCommand(ctx, session, command)
 }
@@ -103,9 +152,18 @@ func (b *Bash) getOrCreateSession(sessionID string) *BashSession {
 		return session
 	}
 
-	// Create new session
+	// Create new session, starting in the workspace directory
+	dir, err := ResolveWorkspacePath(".")
+	if err != nil {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil
+	}
 	cmd := exec.Command("/bin/bash")
+	cmd.Dir = dir
 	cmd.Env = os.Environ()
+	setProcessGroup(cmd)
 
 	stdinPipe, err := cmd.StdinPipe()
 	if err != nil {
@@ -216,6 +274,14 @@ func (b *Bash) runCommand(ctx context.Context, session *BashSession, command str
 	case err := <-errChan:
 		return &ToolResult{Error: fmt.Sprintf("Read error: %v", err)}, nil
 	case <-outputCtx.Done():
+		if ctx.Err() != nil {
+			// The caller gave up: kill the shell and its children so the reader
+			// goroutine unblocks, and start a fresh session on the next call.
+			killProcessGroup(session.process)
+			go session.process.Wait()
+			session.started = false
+			return &ToolResult{Error: fmt.Sprintf("Command cancelled: %v", ctx.Err())}, nil
+		}
 		session.timedOut = true
 		return &ToolResult{
 			Error: fmt.Sprintf("Command timed out. Sending SIGINT to the process"),
@@ -223,6 +289,100 @@ func (b *Bash) runCommand(ctx context.Context, session *BashSession, command str
 	}
 }
 
+// exitCodeMarker prefixes the line carrying a command's exit status in runCommands.
+const exitCodeMarker = "__GOMANUS_EXIT_CODE="
+
+// commandResult is the outcome of one command run by runCommands.
+type commandResult struct {
+	command  string
+	exitCode int
+	output   string
+	err      string
+}
+
+// runCommands runs each command on its own in the session and reports the
+// exit code and combined output of every command, stopping at the first
+// failure unless continueOnError is set.
+func (b *Bash) runCommands(ctx context.Context, session *BashSession, commands []string, continueOnError bool) (*ToolResult, error) {
+	results := make([]commandResult, 0, len(commands))
+	stoppedAt := -1
+	for i, command := range commands {
+		res := commandResult{command: command, exitCode: -1}
+		// Group the command so stderr is captured with stdout and $? is the command's own status
+		wrapped := "{ " + command + "\n} 2>&1; echo \"" + exitCodeMarker + "$?\""
+		out, err := b.runCommand(ctx, session, wrapped)
+		if err != nil {
+			return nil, err
+		}
+		if out.Error != "" {
+			res.err = out.Error
+		} else {
+			res.output, res.exitCode = splitExitCode(out.Output)
+		}
+		results = append(results, res)
+
+		// A timed out or cancelled session cannot run later commands either
+		if res.err != "" || (res.exitCode != 0 && !continueOnError) {
+			stoppedAt = i
+			break
+		}
+	}
+
+	return &ToolResult{Output: formatCommandResults(results, len(commands), stoppedAt)}, nil
+}
+
+// splitExitCode separates the output of a wrapped command from its exit status line.
+func splitExitCode(output string) (string, int) {
+	idx := strings.LastIndex(output, exitCodeMarker)
+	if idx < 0 {
+		return output, -1
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(output[idx+len(exitCodeMarker):]))
+	if err != nil {
+		code = -1
+	}
+	return strings.TrimRight(output[:idx], "\n"), code
+}
+
+func formatCommandResults(results []commandResult, total, stoppedAt int) string {
+	var sb strings.Builder
+	failed := 0
+	for i, res := range results {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString(fmt.Sprintf("[%d/%d] $ %s\n", i+1, total, res.command))
+		if res.err != "" {
+			failed++
+			sb.WriteString("error: " + res.err)
+			continue
+		}
+		if res.exitCode != 0 {
+			failed++
+		}
+		sb.WriteString(fmt.Sprintf("exit code: %d", res.exitCode))
+		if res.output != "" {
+			sb.WriteString("\n" + res.output)
+		}
+	}
+
+	sb.WriteString(fmt.Sprintf("\n\nRan %d of %d commands, %d failed", len(results), total, failed))
+	if stoppedAt >= 0 && len(results) < total {
+		sb.WriteString(fmt.Sprintf("; stopped after command %d failed", stoppedAt+1))
+	}
+	return sb.String()
+}
+
+// withStdin wraps the command in a group whose standard input is the given text.
+// The heredoc terminator must be alone on its line, so a no-op ":" follows it for the sentinel echo to attach to.
+func withStdin(command, stdin string) string {
+	marker := fmt.Sprintf("GOMANUS_STDIN_%d", time.Now().UnixNano())
+	if !strings.HasSuffix(stdin, "\n") {
+		stdin += "\n"
+	}
+	return "{ " + command + "\n} <<'" + marker + "'\n" + stdin + marker + "\n:"
+}
+
 func (b *Bash) retrieveOutput(ctx context.Context, session *BashSession) (*ToolResult, error) {
 	if !session.started {
 		return &ToolResult{Error: "Session has not started"}, nil
@@ -0,0 +1,114 @@
+package tool
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go-manus/config"
+)
+
+// commandPolicy restricts which commands bash may run. Names match the first
+// word of every segment of a command line, patterns match the whole line.
+// With empty lists every command is allowed.
+type commandPolicy struct {
+	allow         map[string]bool
+	deny          map[string]bool
+	allowPatterns []*regexp.Regexp
+	denyPatterns  []*regexp.Regexp
+}
+
+// commandSeparators splits a command line into the commands it runs.
+var commandSeparators = regexp.MustCompile(`&&|\|\||[;|&\n]|\$\(|` + "`")
+
+// redirections keeps fd redirections such as 2>&1 from being split as background "&".
+var redirections = strings.NewReplacer(">&", ">", "&>", ">", "<&", "<")
+
+// envAssignment matches a leading VAR=value prefix of a command.
+var envAssignment = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+func newCommandPolicy(settings config.BashSettings) *commandPolicy {
+	p := &commandPolicy{
+		allow: make(map[string]bool),
+		deny:  make(map[string]bool),
+	}
+	for _, name := range settings.Allow {
+		p.allow[name] = true
+	}
+	for _, name := range settings.Deny {
+		p.deny[name] = true
+	}
+	p.allowPatterns = compilePatterns(settings.AllowPatterns)
+	p.denyPatterns = compilePatterns(settings.DenyPatterns)
+	return p
+}
+
+// compilePatterns compiles the configured regexes. An invalid pattern is
+// matched literally instead of being dropped, so a typo in a deny rule does
+// not silently allow the command.
+func compilePatterns(patterns []string) []*regexp.Regexp {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			logrus.Warnf("Invalid bash pattern %q, matching it literally: %v", pattern, err)
+			re = regexp.MustCompile(regexp.QuoteMeta(pattern))
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled
+}
+
+// check returns an error describing why the command is not allowed.
+func (p *commandPolicy) check(command string) error {
+	names := commandNames(command)
+
+	for _, re := range p.denyPatterns {
+		if re.MatchString(command) {
+			return fmt.Errorf("command matches denied pattern %q", re.String())
+		}
+	}
+	for _, name := range names {
+		if p.deny[name] {
+			return fmt.Errorf("command %q is denied", name)
+		}
+	}
+
+	if len(p.allow) == 0 && len(p.allowPatterns) == 0 {
+		return nil
+	}
+	for _, re := range p.allowPatterns {
+		if re.MatchString(command) {
+			return nil
+		}
+	}
+	for _, name := range names {
+		if !p.allow[name] {
+			return fmt.Errorf("command %q is not in the allowlist", name)
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("command is not in the allowlist")
+	}
+	return nil
+}
+
+// commandNames returns the program name of each command in a command line,
+// skipping environment assignments and directory prefixes (/bin/rm -> rm).
+func commandNames(command string) []string {
+	names := make([]string, 0)
+	for _, segment := range commandSeparators.Split(redirections.Replace(command), -1) {
+		for _, field := range strings.Fields(segment) {
+			field = strings.TrimLeft(field, "({!")
+			if field == "" || envAssignment.MatchString(field) {
+				continue
+			}
+			names = append(names, filepath.Base(field))
+			break
+		}
+	}
+	return names
+}
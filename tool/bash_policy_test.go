@@ -0,0 +1,55 @@
+package tool
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"go-manus/config"
+)
+
+func TestBashPolicyDeniesRm(t *testing.T) {
+	victim := writeWorkspaceFile(t, "policy/victim.txt", "keep me")
+	bash := NewBash()
+	bash.policy = newCommandPolicy(config.BashSettings{Deny: []string{"rm"}})
+	defer bash.stopSession("default")
+
+	for _, command := range []string{"rm policy/victim.txt", "ls && /bin/rm -f policy/victim.txt", "FORCE=1 rm policy/victim.txt"} {
+		result, err := bash.Execute(context.Background(), map[string]interface{}{"command": command})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(result.Error, `Command rejected by policy: command "rm" is denied`) {
+			t.Errorf("%q: result = %+v, want it rejected", command, result)
+		}
+	}
+	if _, err := os.Stat(victim); err != nil {
+		t.Fatalf("denied rm removed the file: %v", err)
+	}
+
+	result, err := bash.Execute(context.Background(), map[string]interface{}{"command": "ls policy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != "" || !strings.Contains(result.Output, "victim.txt") {
+		t.Errorf("ls result = %+v, want the directory listing", result)
+	}
+}
+
+func TestBashPolicyAllowlist(t *testing.T) {
+	policy := newCommandPolicy(config.BashSettings{Allow: []string{"ls", "grep"}, DenyPatterns: []string{`--force`}})
+
+	for command, allowed := range map[string]bool{
+		"ls -la | grep go":     true,
+		"ls 2>&1":              true,
+		"ls; rm -rf /":         false,
+		"echo $(whoami)":       false,
+		"grep --force pattern": false,
+		"PATH=/tmp ls":         true,
+	} {
+		if err := policy.check(command); (err == nil) != allowed {
+			t.Errorf("check(%q) = %v, want allowed %v", command, err, allowed)
+		}
+	}
+}
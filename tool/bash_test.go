@@ -0,0 +1,140 @@
+package tool
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestBash 创建 Bash 工具，测试结束时关闭 session
+func newTestBash(t *testing.T, sessionID string) *Bash {
+	t.Helper()
+	b := NewBash()
+	t.Cleanup(func() { b.stopSession(sessionID) })
+	return b
+}
+
+func TestBashReturnsOnCancel(t *testing.T) {
+	b := newTestBash(t, t.Name())
+	ticks := filepath.Join(workspaceRoot(), "bash-cancel-ticks")
+	os.Remove(ticks)
+
+	// 后台子进程持续写文件，取消后整个进程组都应被结束
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	result, err := b.Execute(ctx, map[string]interface{}{
+		"session_id": t.Name(),
+		"command":    "(while true; do echo tick >> bash-cancel-ticks; sleep 0.05; done) & sleep 30",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("Execute returned %s after the context was cancelled", elapsed-500*time.Millisecond)
+	}
+	if !strings.Contains(result.Error, "Command cancelled") {
+		t.Fatalf("result = %+v, want a cancellation error", result)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	before, err := os.ReadFile(ticks)
+	if err != nil {
+		t.Fatalf("background command never ran: %v", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if after, _ := os.ReadFile(ticks); len(after) != len(before) {
+		t.Error("background command kept running after cancellation")
+	}
+
+	// 被取消的 session 之后重新创建
+	result, err = b.Execute(context.Background(), map[string]interface{}{"session_id": t.Name(), "command": "echo alive"})
+	if err != nil || result.Output != "alive" {
+		t.Errorf("command after cancellation = %+v, %v, want alive", result, err)
+	}
+}
+
+func TestBashCommandsStopAtFirstFailure(t *testing.T) {
+	b := newTestBash(t, t.Name())
+	marker := filepath.Join(workspaceRoot(), "bash-third-ran")
+	os.Remove(marker)
+	commands := []interface{}{"echo first", "sh -c 'echo broken >&2; exit 3'", "touch bash-third-ran && echo third"}
+
+	result, err := b.Execute(context.Background(), map[string]interface{}{"session_id": t.Name(), "commands": commands})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"[1/3] $ echo first\nexit code: 0\nfirst",
+		"[2/3] $ sh -c 'echo broken >&2; exit 3'\nexit code: 3\nbroken",
+		"Ran 2 of 3 commands, 1 failed; stopped after command 2 failed",
+	} {
+		if !strings.Contains(result.Output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, result.Output)
+		}
+	}
+	if _, err := os.Stat(marker); err == nil || strings.Contains(result.Output, "[3/3]") {
+		t.Errorf("the third command ran after the second failed:\n%s", result.Output)
+	}
+
+	result, err = b.Execute(context.Background(), map[string]interface{}{
+		"session_id": t.Name(), "commands": commands, "continue_on_error": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"[2/3] $ sh -c 'echo broken >&2; exit 3'\nexit code: 3\nbroken",
+		"[3/3] $ touch bash-third-ran && echo third\nexit code: 0\nthird",
+		"Ran 3 of 3 commands, 1 failed",
+	} {
+		if !strings.Contains(result.Output, want) {
+			t.Errorf("with continue_on_error, output does not contain %q:\n%s", want, result.Output)
+		}
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("the third command did not run with continue_on_error: %v", err)
+	}
+}
+
+func TestBashFeedsStdinToPythonScript(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 is not installed")
+	}
+	b := newTestBash(t, t.Name())
+	writeWorkspaceFile(t, "echo_line.py", "line = input()\nprint('echo: ' + line)\n")
+
+	result, err := b.Execute(context.Background(), map[string]interface{}{
+		"session_id": t.Name(), "command": "python3 echo_line.py", "stdin": "hello from stdin",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Output != "echo: hello from stdin" {
+		t.Errorf("result = %+v, want the line echoed back", result)
+	}
+}
+
+func TestBashFeedsStdinToCommand(t *testing.T) {
+	b := newTestBash(t, t.Name())
+
+	result, err := b.Execute(context.Background(), map[string]interface{}{
+		"session_id": t.Name(), "command": `read first; read second; echo "$second $first"`, "stdin": "world\nhello\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Output != "hello world" {
+		t.Errorf("result = %+v, want both lines read from stdin", result)
+	}
+
+	// stdin 只交给这一条命令，之后的命令照常执行
+	result, err = b.Execute(context.Background(), map[string]interface{}{"session_id": t.Name(), "command": "echo next"})
+	if err != nil || result.Output != "next" {
+		t.Errorf("command after stdin = %+v, %v, want next", result, err)
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+
+package tool
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup runs the shell in its own process group so that
+// killProcessGroup also reaches the commands it started.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup kills the shell and every process in its group.
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd.Process == nil {
+		return
+	}
+	if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil {
+		cmd.Process.Kill()
+	}
+}
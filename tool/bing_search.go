@@ -2,18 +2,27 @@ package tool
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
 	"strings"
+
+	"go-manus/config"
 )
 
 type BingSearch struct {
 	*BaseSearch
+	apiKey   string
+	endpoint string
 }
 
 func NewBingSearch() *BingSearch {
+	settings := config.GetInstance().GetSearch()
 	return &BingSearch{
 		BaseSearch: NewBaseSearch(),
+		apiKey:     settings.BingAPIKey,
+		endpoint:   settings.BingEndpoint,
 	}
 }
 
@@ -38,6 +47,11 @@ func (b *BingSearch) Parameters() map[string]interface{} {
 				"description": "(optional) The number of search results to return. Default is 10.",
 				"default":     10,
 			},
+			"snippet_length": map[string]interface{}{
+				"type":        "integer",
+				"description": "(optional) Maximum number of characters kept from each result snippet. Default is 200.",
+				"default":     200,
+			},
 		},
 		"required": []string{"query"},
 	}
@@ -53,6 +67,7 @@ func (b *BingSearch) Execute(ctx context.Context, args map[string]interface{}) (
 	if n, ok := args["num_results"].(float64); ok {
 		numResults = int(n)
 	}
+	snippetLength := snippetLengthArg(args)
 
 	results, err := b.Search(ctx, query, numResults)
 	if err != nil {
@@ -69,7 +84,7 @@ func (b *BingSearch) Execute(ctx context.Context, args map[string]interface{}) (
 		output.WriteString(fmt.Sprintf("%d. %s\n", i+1, result.Title))
 		output.WriteString(fmt.Sprintf("   URL: %s\n", result.URL))
 		if result.Snippet != "" {
-			output.WriteString(fmt.Sprintf("   %s\n", result.Snippet))
+			output.WriteString(fmt.Sprintf("   %s\n", truncateSnippet(result.Snippet, snippetLength)))
 		}
 		output.WriteString("\n")
 	}
@@ -78,6 +93,11 @@ func (b *BingSearch) Execute(ctx context.Context, args map[string]interface{}) (
 }
 
 func (b *BingSearch) Search(ctx context.Context, query string, numResults int) ([]SearchResult, error) {
+	// 配置了订阅密钥时使用 Bing Web Search API，否则抓取搜索结果页
+	if b.apiKey != "" {
+		return b.searchAPI(ctx, query, numResults)
+	}
+
 	searchURL := fmt.Sprintf("https://www.bing.com/search?q=%s&count=%d",
 		url.QueryEscape(query), numResults)
 
@@ -86,10 +106,61 @@ func (b *BingSearch) Search(ctx context.Context, query string, numResults int) (
 		return nil, err
 	}
 
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	if err := b.checkStatus(resp); err != nil {
+		return nil, err
 	}
 
 	// Parse Bing results
 	return b.parseHTMLResults(resp, "h2 a", numResults)
 }
+
+// bingAPIResponse Bing Web Search API 响应中用到的字段
+type bingAPIResponse struct {
+	WebPages struct {
+		Value []struct {
+			Name    string `json:"name"`
+			URL     string `json:"url"`
+			Snippet string `json:"snippet"`
+		} `json:"value"`
+	} `json:"webPages"`
+}
+
+// searchAPI 调用 Bing Web Search API 获取结构化结果
+func (b *BingSearch) searchAPI(ctx context.Context, query string, numResults int) ([]SearchResult, error) {
+	searchURL := fmt.Sprintf("%s?q=%s&count=%d", b.endpoint, url.QueryEscape(query), numResults)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Ocp-Apim-Subscription-Key", b.apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("Bing API HTTP %d", resp.StatusCode)
+	}
+
+	var data bingAPIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode Bing API response: %w", err)
+	}
+
+	results := make([]SearchResult, 0, len(data.WebPages.Value))
+	for _, page := range data.WebPages.Value {
+		if len(results) >= numResults {
+			break
+		}
+		results = append(results, SearchResult{
+			Title:   page.Name,
+			URL:     page.URL,
+			Snippet: page.Snippet,
+		})
+	}
+	return results, nil
+}
@@ -0,0 +1,72 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBingSearchUsesAPIWithKey(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Ocp-Apim-Subscription-Key"); got != "test-key" {
+			t.Errorf("Ocp-Apim-Subscription-Key = %q, want test-key", got)
+		}
+		if q, count := r.URL.Query().Get("q"), r.URL.Query().Get("count"); q != "golang" || count != "2" {
+			t.Errorf("query = %q, count = %q", q, count)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"webPages": {"value": [
+			{"name": "The Go Programming Language", "url": "https://go.dev/", "snippet": "Go is an open source language."},
+			{"name": "Go on Wikipedia", "url": "https://en.wikipedia.org/wiki/Go_(programming_language)", "snippet": "Go is a statically typed language."},
+			{"name": "Extra", "url": "https://example.com/extra", "snippet": "Beyond num_results."}
+		]}}`)
+	}))
+	defer srv.Close()
+
+	bing := NewBingSearch()
+	bing.apiKey = "test-key"
+	bing.endpoint = srv.URL + "/v7.0/search"
+
+	results, err := bing.Search(context.Background(), "golang", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SearchResult{
+		{Title: "The Go Programming Language", URL: "https://go.dev/", Snippet: "Go is an open source language."},
+		{Title: "Go on Wikipedia", URL: "https://en.wikipedia.org/wiki/Go_(programming_language)", Snippet: "Go is a statically typed language."},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("results = %+v, want %+v", results, want)
+	}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, results[i], want[i])
+		}
+	}
+}
+
+func TestBingSearchScrapesWithoutKey(t *testing.T) {
+	// 测试配置中没有 bing_api_key
+	bing := NewBingSearch()
+	var requested string
+	stubSearchClient(bing.BaseSearch, func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Host + r.URL.Path
+		if r.Header.Get("Ocp-Apim-Subscription-Key") != "" {
+			t.Error("scraping request carries a subscription key")
+		}
+		fmt.Fprint(w, `<html><body><li><h2><a href="https://go.dev/">The Go Programming Language</a></h2></li></body></html>`)
+	})
+
+	results, err := bing.Search(context.Background(), "golang", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requested != "www.bing.com/search" {
+		t.Errorf("requested %q, want the Bing results page", requested)
+	}
+	if len(results) != 1 || results[0].URL != "https://go.dev/" || results[0].Title != "The Go Programming Language" {
+		t.Errorf("results = %+v, want the scraped link", results)
+	}
+}
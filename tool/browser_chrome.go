@@ -0,0 +1,96 @@
+package tool
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// ErrChromeNotFound 找不到 Chrome/Chromium 可执行文件
+var ErrChromeNotFound = errors.New("Chrome not found; install Google Chrome or Chromium, or set chrome_path in the [browser] config section. " +
+	"Meanwhile use web_crawler or web_search to read web pages without a browser")
+
+// chromeCandidates 各平台上常见的 Chrome 可执行文件名和安装路径，与 chromedp 的查找顺序一致
+func chromeCandidates() []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{
+			"/Applications/Chromium.app/Contents/MacOS/Chromium",
+			"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+			"chromium",
+			"google-chrome",
+		}
+	case "windows":
+		return []string{
+			"chrome",
+			"chrome.exe",
+			`C:\Program Files (x86)\Google\Chrome\Application\chrome.exe`,
+			`C:\Program Files\Google\Chrome\Application\chrome.exe`,
+		}
+	default:
+		return []string{
+			"headless_shell",
+			"headless-shell",
+			"chromium",
+			"chromium-browser",
+			"google-chrome",
+			"google-chrome-stable",
+			"google-chrome-beta",
+			"google-chrome-unstable",
+			"/usr/bin/google-chrome",
+			"/usr/local/bin/chrome",
+			"/snap/bin/chromium",
+			"chrome",
+		}
+	}
+}
+
+// findChrome 返回要启动的 Chrome 路径。指定了 execPath 时只检查该路径，否则依次查找常见位置
+func findChrome(execPath string) (string, error) {
+	if execPath != "" {
+		path, err := exec.LookPath(execPath)
+		if err != nil {
+			return "", fmt.Errorf("%w (configured path %q: %v)", ErrChromeNotFound, execPath, err)
+		}
+		return path, nil
+	}
+
+	for _, candidate := range chromeCandidates() {
+		if path, err := exec.LookPath(candidate); err == nil {
+			return path, nil
+		}
+	}
+	return "", ErrChromeNotFound
+}
+
+// allocatorOptions 在 chromedp 默认参数的基础上加入可执行文件路径和配置的额外启动参数
+func allocatorOptions(execPath string, flags []string) []chromedp.ExecAllocatorOption {
+	opts := append([]chromedp.ExecAllocatorOption{}, chromedp.DefaultExecAllocatorOptions[:]...)
+	if execPath != "" {
+		opts = append(opts, chromedp.ExecPath(execPath))
+	}
+	for _, flag := range flags {
+		name, value, ok := parseChromeFlag(flag)
+		if !ok {
+			continue
+		}
+		opts = append(opts, chromedp.Flag(name, value))
+	}
+	return opts
+}
+
+// parseChromeFlag 解析 "--name=value" 或 "--name" 形式的启动参数
+func parseChromeFlag(flag string) (string, interface{}, bool) {
+	flag = strings.TrimLeft(strings.TrimSpace(flag), "-")
+	if flag == "" {
+		return "", nil, false
+	}
+	if name, value, found := strings.Cut(flag, "="); found {
+		return name, value, true
+	}
+	return flag, true, true
+}
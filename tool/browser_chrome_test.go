@@ -0,0 +1,161 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+func TestAllocatorOptionsUseConfiguredExecPathAndFlags(t *testing.T) {
+	execPath := "/opt/custom/chrome"
+	opts := allocatorOptions(execPath, []string{"--proxy-server=http://proxy:3128", "lang=de", "--", "--no-first-run"})
+
+	// 在启动前截获 chromedp 生成的命令；路径不存在，启动必然失败，不会真的运行浏览器
+	var cmd *exec.Cmd
+	opts = append(opts, chromedp.ModifyCmdFunc(func(c *exec.Cmd) { cmd = c }))
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), opts...)
+	defer cancelAlloc()
+	browserCtx, cancelBrowser := chromedp.NewContext(allocCtx)
+	defer cancelBrowser()
+	if err := chromedp.Run(browserCtx); err == nil {
+		t.Fatal("starting a nonexistent browser succeeded")
+	}
+
+	if cmd == nil {
+		t.Fatal("chromedp did not build a command")
+	}
+	if cmd.Path != execPath {
+		t.Errorf("command path = %q, want %q", cmd.Path, execPath)
+	}
+	args := make(map[string]bool)
+	for _, arg := range cmd.Args[1:] {
+		args[arg] = true
+	}
+	for _, want := range []string{"--proxy-server=http://proxy:3128", "--lang=de", "--no-first-run", "--headless"} {
+		if !args[want] {
+			t.Errorf("command args %v do not contain %q", cmd.Args, want)
+		}
+	}
+}
+
+func TestBrowserUseReportsMissingChrome(t *testing.T) {
+	if _, err := findChrome("/nonexistent/chrome"); !errors.Is(err, ErrChromeNotFound) {
+		t.Fatalf("findChrome = %v, want ErrChromeNotFound", err)
+	}
+
+	browser := NewBrowserUse()
+	browser.SetExecPath("/nonexistent/chrome")
+	result, err := browser.Execute(context.Background(), map[string]interface{}{"action": "navigate", "url": "https://example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Chrome not found", "chrome_path", "web_crawler"} {
+		if !strings.Contains(result.Error, want) {
+			t.Errorf("error %q does not mention %q", result.Error, want)
+		}
+	}
+	if browser.started() {
+		t.Error("browser is marked as started after failing to find Chrome")
+	}
+}
+
+// requireChrome 未安装 Chrome 时跳过需要真实浏览器的测试
+func requireChrome(t *testing.T) {
+	t.Helper()
+	if _, err := findChrome(""); err != nil {
+		t.Skip("Chrome is not installed")
+	}
+}
+
+func TestBrowserReturnsOnCancel(t *testing.T) {
+	requireChrome(t)
+
+	// 页面一直不返回，navigate 只能因取消而结束
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	browser := NewBrowserUse()
+	defer browser.Cleanup()
+	if result, err := browser.Execute(context.Background(), map[string]interface{}{"action": "get_html"}); err != nil || result.Error != "" {
+		t.Fatalf("starting the browser: %+v, %v", result, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	result, err := browser.Execute(ctx, map[string]interface{}{"action": "navigate", "url": srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("navigate returned %s after the context was cancelled", elapsed-500*time.Millisecond)
+	}
+	if result.Error == "" {
+		t.Fatalf("result = %+v, want the cancelled navigation to fail", result)
+	}
+
+	// 取消只中止当前操作，浏览器仍可继续使用
+	result, err = browser.Execute(context.Background(), map[string]interface{}{"action": "execute_js", "script": "'still ' + 'usable'"})
+	if err != nil || result.Output != "still usable" {
+		t.Errorf("execute_js after cancellation = %+v, %v, want %q", result, err, "still usable")
+	}
+}
+
+func TestVerifyTypedText(t *testing.T) {
+	for _, tc := range []struct {
+		intended, actual, want string
+	}{
+		{"hello", "hello", "Verified: field value matches the intended text"},
+		{"hello", "old texthello", "Warning: field contains extra text before the input"},
+		{"hello", "hel", `Warning: field value "hel" does not match the intended text "hello"`},
+	} {
+		if got := verifyTypedText(tc.intended, tc.actual); !strings.HasPrefix(got, tc.want) {
+			t.Errorf("verifyTypedText(%q, %q) = %q, want %q", tc.intended, tc.actual, got, tc.want)
+		}
+	}
+}
+
+func TestBrowserInputTextVerifyReadsBack(t *testing.T) {
+	requireChrome(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><input id="name" type="text"><input id="code" type="text" maxlength="3"></body></html>`)
+	}))
+	defer srv.Close()
+
+	browser := NewBrowserUse()
+	defer browser.Cleanup()
+	run := func(args map[string]interface{}) *ToolResult {
+		t.Helper()
+		result, err := browser.Execute(context.Background(), args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	if result := run(map[string]interface{}{"action": "navigate", "url": srv.URL}); result.Error != "" {
+		t.Fatalf("navigate: %s", result.Error)
+	}
+
+	result := run(map[string]interface{}{"action": "input_text", "index": float64(0), "text": "Ada Lovelace", "verify": true})
+	if result.Error != "" || !strings.Contains(result.Output, "Verified: field value matches the intended text") {
+		t.Errorf("input_text with verify = %+v, want the read-back to match", result)
+	}
+
+	// maxlength 截断了输入，回读发现不一致
+	result = run(map[string]interface{}{"action": "input_text", "index": float64(1), "text": "12345", "verify": true})
+	if !strings.Contains(result.Output, `Warning: field value "123" does not match the intended text "12345"`) {
+		t.Errorf("input_text into a maxlength field = %+v, want a mismatch warning", result)
+	}
+}
@@ -0,0 +1,189 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// maxListedElements 页面元素列表中最多输出的元素数
+const maxListedElements = 150
+
+// interactiveElement 页面上可交互的元素，Index 即 click/input_text 使用的编号
+type interactiveElement struct {
+	Index    int    `json:"index"`
+	Tag      string `json:"tag"`
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	Selector string `json:"selector"`
+	XPath    string `json:"xpath"`
+}
+
+// collectElementsScript 收集可见的可交互元素，按文档顺序编号，并在元素上记录编号以便之后定位
+const collectElementsScript = `(() => {
+  const query = 'a[href], button, input:not([type="hidden"]), select, textarea, summary, [role="button"], [role="link"], [role="checkbox"], [role="tab"], [role="menuitem"], [onclick], [contenteditable="true"]';
+  const xpathOf = (el) => {
+    const parts = [];
+    for (; el && el.nodeType === 1; el = el.parentNode) {
+      let i = 1;
+      for (let s = el.previousElementSibling; s; s = s.previousElementSibling) {
+        if (s.tagName === el.tagName) i++;
+      }
+      parts.unshift(el.tagName.toLowerCase() + '[' + i + ']');
+    }
+    return '/' + parts.join('/');
+  };
+  const visible = (el) => {
+    const rect = el.getBoundingClientRect();
+    if (rect.width === 0 || rect.height === 0) return false;
+    const style = window.getComputedStyle(el);
+    return style.visibility !== 'hidden' && style.display !== 'none';
+  };
+  document.querySelectorAll('[data-gomanus-index]').forEach((el) => el.removeAttribute('data-gomanus-index'));
+  const result = [];
+  document.querySelectorAll(query).forEach((el) => {
+    if (!visible(el)) return;
+    const index = result.length;
+    el.setAttribute('data-gomanus-index', String(index));
+    const text = (el.innerText || el.value || el.getAttribute('placeholder') || el.getAttribute('aria-label') || el.getAttribute('title') || el.getAttribute('alt') || '').replace(/\s+/g, ' ').trim();
+    result.push({
+      index: index,
+      tag: el.tagName.toLowerCase(),
+      type: el.getAttribute('type') || el.getAttribute('role') || '',
+      text: text.slice(0, 100),
+      selector: '[data-gomanus-index="' + index + '"]',
+      xpath: xpathOf(el),
+    });
+  });
+  return result;
+})()`
+
+// refreshElements 重新收集当前页面的可交互元素
+func (b *BrowserUse) refreshElements(ctx context.Context) ([]interactiveElement, error) {
+	var elements []interactiveElement
+	if err := chromedp.Run(ctx, chromedp.Evaluate(collectElementsScript, &elements)); err != nil {
+		b.mu.Lock()
+		b.elements = nil
+		b.mu.Unlock()
+		return nil, err
+	}
+
+	b.mu.Lock()
+	b.elements = elements
+	b.mu.Unlock()
+	return elements, nil
+}
+
+// lookupElement 按编号查找最近一次收集的元素
+func (b *BrowserUse) lookupElement(index int) (interactiveElement, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if index < 0 || index >= len(b.elements) {
+		return interactiveElement{}, fmt.Errorf("element index %d not found (%d elements known); call get_interactive_elements to refresh the list", index, len(b.elements))
+	}
+	return b.elements[index], nil
+}
+
+// locateElement 返回定位元素的查询条件：优先使用编号属性，页面重新渲染后属性丢失时退回 XPath
+func locateElement(ctx context.Context, el interactiveElement) (string, chromedp.QueryOption, error) {
+	var found bool
+	check := fmt.Sprintf("document.querySelector(%q) !== null", el.Selector)
+	if err := chromedp.Run(ctx, chromedp.Evaluate(check, &found)); err != nil {
+		return "", nil, err
+	}
+	if found {
+		return el.Selector, chromedp.ByQuery, nil
+	}
+
+	check = fmt.Sprintf("document.evaluate(%q, document, null, XPathResult.FIRST_ORDERED_NODE_TYPE, null).singleNodeValue !== null", el.XPath)
+	if err := chromedp.Run(ctx, chromedp.Evaluate(check, &found)); err != nil {
+		return "", nil, err
+	}
+	if !found {
+		return "", nil, fmt.Errorf("element %d is no longer on the page; call get_interactive_elements to refresh the list", el.Index)
+	}
+	return el.XPath, chromedp.BySearch, nil
+}
+
+// elementJS 返回在页面中取得该元素的 JS 表达式
+func elementJS(el interactiveElement) string {
+	return fmt.Sprintf("(document.querySelector(%q) || document.evaluate(%q, document, null, XPathResult.FIRST_ORDERED_NODE_TYPE, null).singleNodeValue)", el.Selector, el.XPath)
+}
+
+// formatElements 按 "[index]<type>text</type>" 格式列出元素
+func formatElements(elements []interactiveElement) string {
+	if len(elements) == 0 {
+		return "No interactive elements found on the page"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Interactive elements (%d):\n", len(elements)))
+	for i, el := range elements {
+		if i == maxListedElements {
+			sb.WriteString(fmt.Sprintf("... %d more elements, scroll or use get_html to see the rest\n", len(elements)-i))
+			break
+		}
+		kind := el.Tag
+		if el.Type != "" {
+			kind += " type=" + el.Type
+		}
+		sb.WriteString(fmt.Sprintf("[%d]<%s>%s</%s>\n", el.Index, kind, el.Text, el.Tag))
+	}
+	return sb.String()
+}
+
+// BrowserTab 浏览器标签页
+type BrowserTab struct {
+	ID    int    `json:"id"`
+	URL   string `json:"url"`
+	Title string `json:"title"`
+}
+
+// BrowserState get_current_state 返回的浏览器状态
+type BrowserState struct {
+	URL   string       `json:"url"`
+	Title string       `json:"title"`
+	Tabs  []BrowserTab `json:"tabs"`
+	// ActiveTab 当前标签页的 ID
+	ActiveTab int `json:"active_tab"`
+	// InteractiveElements 当前页面的可交互元素列表，格式同 get_interactive_elements
+	InteractiveElements string `json:"interactive_elements"`
+	// PixelsAbove/PixelsBelow 视口上方和下方尚未显示的内容高度
+	PixelsAbove int `json:"pixels_above"`
+	PixelsBelow int `json:"pixels_below"`
+}
+
+// scrollInfoScript 读取滚动位置、视口高度和页面总高度
+const scrollInfoScript = `({y: Math.round(window.scrollY), viewport: window.innerHeight, height: document.documentElement.scrollHeight})`
+
+// currentState 读取当前页面的地址、标题、滚动位置和可交互元素
+func (b *BrowserUse) currentState(ctx context.Context) (*BrowserState, error) {
+	state := &BrowserState{}
+	var scroll struct {
+		Y        int `json:"y"`
+		Viewport int `json:"viewport"`
+		Height   int `json:"height"`
+	}
+	err := chromedp.Run(ctx,
+		chromedp.Location(&state.URL),
+		chromedp.Title(&state.Title),
+		chromedp.Evaluate(scrollInfoScript, &scroll),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	state.PixelsAbove = scroll.Y
+	if below := scroll.Height - scroll.Y - scroll.Viewport; below > 0 {
+		state.PixelsBelow = below
+	}
+	state.Tabs = b.listTabs()
+	b.mu.Lock()
+	state.ActiveTab = b.activeTab
+	b.mu.Unlock()
+	state.InteractiveElements = b.describeElements(ctx)
+	return state, nil
+}
@@ -0,0 +1,151 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// tabStateTimeout 读取单个标签页地址和标题的超时时间
+const tabStateTimeout = 5 * time.Second
+
+// browserTab 一个标签页对应一个 chromedp target 上下文。
+// 第一个标签页的上下文持有浏览器本身，关闭它会关闭整个浏览器
+type browserTab struct {
+	id     int
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// activeTabContext 返回当前标签页的上下文
+func (b *BrowserUse) activeTabContext() context.Context {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, tab := range b.tabs {
+		if tab.id == b.activeTab {
+			return tab.ctx
+		}
+	}
+	return b.ctx
+}
+
+// findTabLocked 按 ID 查找标签页，调用方需持有锁
+func (b *BrowserUse) findTabLocked(id int) (int, *browserTab) {
+	for i, tab := range b.tabs {
+		if tab.id == id {
+			return i, tab
+		}
+	}
+	return -1, nil
+}
+
+// newTab 在新的标签页中打开 URL 并切换到该标签页
+func (b *BrowserUse) newTab(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	url, ok := args["url"].(string)
+	if !ok || url == "" {
+		return &ToolResult{Error: "URL is required for 'new_tab' action"}, nil
+	}
+
+	b.mu.Lock()
+	parent := b.ctx
+	b.mu.Unlock()
+
+	// 以浏览器上下文为父上下文创建新的 target，即同一浏览器中的新标签页
+	tabCtx, cancel := chromedp.NewContext(parent)
+	runCtx, cancelRun := context.WithTimeout(tabCtx, 30*time.Second)
+	defer cancelRun()
+	stop := context.AfterFunc(ctx, cancelRun)
+	defer stop()
+
+	err := chromedp.Run(runCtx,
+		chromedp.Navigate(url),
+		chromedp.WaitVisible("body", chromedp.ByQuery),
+	)
+	if err != nil {
+		cancel()
+		return &ToolResult{Error: "Failed to open new tab: " + err.Error()}, nil
+	}
+
+	b.mu.Lock()
+	b.nextTabID++
+	id := b.nextTabID
+	b.tabs = append(b.tabs, &browserTab{id: id, ctx: tabCtx, cancel: cancel})
+	b.activeTab = id
+	b.elements = nil
+	b.mu.Unlock()
+
+	return &ToolResult{Output: fmt.Sprintf("Opened %s in new tab %d\n\n%s", url, id, b.describeElements(runCtx))}, nil
+}
+
+// switchTab 切换当前标签页
+func (b *BrowserUse) switchTab(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	id, ok := args["tab_id"].(float64)
+	if !ok {
+		return &ToolResult{Error: "Tab ID is required for 'switch_tab' action"}, nil
+	}
+
+	b.mu.Lock()
+	_, tab := b.findTabLocked(int(id))
+	if tab == nil {
+		b.mu.Unlock()
+		return &ToolResult{Error: fmt.Sprintf("Tab %d not found", int(id))}, nil
+	}
+	b.activeTab = tab.id
+	b.elements = nil
+	tabCtx := tab.ctx
+	b.mu.Unlock()
+
+	runCtx, cancel := context.WithTimeout(tabCtx, 30*time.Second)
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+
+	return &ToolResult{Output: fmt.Sprintf("Switched to tab %d\n\n%s", int(id), b.describeElements(runCtx))}, nil
+}
+
+// closeTab 关闭标签页，关闭当前标签页时切换到第一个标签页
+func (b *BrowserUse) closeTab(args map[string]interface{}) (*ToolResult, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.activeTab
+	if v, ok := args["tab_id"].(float64); ok {
+		id = int(v)
+	}
+
+	i, tab := b.findTabLocked(id)
+	if tab == nil {
+		return &ToolResult{Error: fmt.Sprintf("Tab %d not found", id)}, nil
+	}
+	if i == 0 {
+		return &ToolResult{Error: "Cannot close the first tab, it owns the browser session; navigate it elsewhere instead"}, nil
+	}
+
+	tab.cancel()
+	b.tabs = append(b.tabs[:i], b.tabs[i+1:]...)
+	if b.activeTab == id {
+		b.activeTab = b.tabs[0].id
+		b.elements = nil
+	}
+	return &ToolResult{Output: fmt.Sprintf("Closed tab %d, current tab is %d", id, b.activeTab)}, nil
+}
+
+// listTabs 读取所有标签页的地址和标题，读取失败的标签页只保留 ID
+func (b *BrowserUse) listTabs() []BrowserTab {
+	b.mu.Lock()
+	tabs := append([]*browserTab(nil), b.tabs...)
+	b.mu.Unlock()
+
+	result := make([]BrowserTab, 0, len(tabs))
+	for _, tab := range tabs {
+		info := BrowserTab{ID: tab.id}
+		ctx, cancel := context.WithTimeout(tab.ctx, tabStateTimeout)
+		_ = chromedp.Run(ctx, chromedp.Location(&info.URL), chromedp.Title(&info.Title))
+		cancel()
+		result = append(result, info)
+	}
+	return result
+}
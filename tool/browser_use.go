@@ -2,12 +2,19 @@ package tool
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/chromedp/chromedp"
 	"github.com/sirupsen/logrus"
+
+	"go-manus/config"
 )
 
 type BrowserUse struct {
@@ -15,10 +22,48 @@ type BrowserUse struct {
 	ctx     context.Context
 	cancel  context.CancelFunc
 	allocCtx context.Context
+	execPath string
+	flags    []string
+	// outputDir 截图保存目录
+	outputDir string
+	// elements 最近一次收集的可交互元素，click/input_text 的 index 按此解析
+	elements []interactiveElement
+	// tabs 打开的标签页，activeTab 为当前标签页的 ID
+	tabs      []*browserTab
+	activeTab int
+	nextTabID int
 }
 
 func NewBrowserUse() *BrowserUse {
-	return &BrowserUse{}
+	settings := config.GetInstance().GetBrowser()
+	return &BrowserUse{
+		execPath:  settings.ChromePath,
+		flags:     settings.Flags,
+		outputDir: workspaceSubdir(settings.ScreenshotDir),
+	}
+}
+
+// started 浏览器是否已经启动
+func (b *BrowserUse) started() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.ctx != nil
+}
+
+// browserStateResult 将浏览器状态以 JSON 形式放入 Output
+func browserStateResult(state *BrowserState) (*ToolResult, error) {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return &ToolResult{Error: "Failed to encode browser state: " + err.Error()}, nil
+	}
+	return &ToolResult{Output: string(data)}, nil
+}
+
+// SetExecPath 指定 Chrome 可执行文件路径，为空时自动查找
+func (b *BrowserUse) SetExecPath(path string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.execPath = path
 }
 
 func (b *BrowserUse) Name() string {
@@ -26,7 +71,7 @@ func (b *BrowserUse) Name() string {
 }
 
 func (b *BrowserUse) Description() string {
-	return "Interact with a web browser to perform various actions such as navigation, element interaction, content extraction, and tab management. Supported actions include: navigate, click, input_text, screenshot, get_html, execute_js, scroll, switch_tab, new_tab, close_tab, refresh."
+	return "Interact with a web browser to perform various actions such as navigation, element interaction, content extraction, and tab management. Supported actions include: navigate, click, input_text, get_interactive_elements, get_current_state, screenshot, get_html, execute_js, scroll, switch_tab, new_tab, close_tab, refresh. Elements are addressed by the index shown in the interactive element list, which is returned after navigate and by get_interactive_elements."
 }
 
 func (b *BrowserUse) Parameters() map[string]interface{} {
@@ -37,7 +82,7 @@ func (b *BrowserUse) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "The browser action to perform",
 				"enum": []string{
-					"navigate", "click", "input_text", "screenshot",
+					"navigate", "click", "input_text", "get_interactive_elements", "get_current_state", "screenshot",
 					"get_html", "execute_js", "scroll", "switch_tab",
 					"new_tab", "close_tab", "refresh",
 				},
@@ -48,12 +93,16 @@ func (b *BrowserUse) Parameters() map[string]interface{} {
 			},
 			"index": map[string]interface{}{
 				"type":        "integer",
-				"description": "Element index for 'click' or 'input_text' actions",
+				"description": "Element index from the interactive element list for 'click' or 'input_text' actions",
 			},
 			"text": map[string]interface{}{
 				"type":        "string",
 				"description": "Text for 'input_text' action",
 			},
+			"verify": map[string]interface{}{
+				"type":        "boolean",
+				"description": "For 'input_text': read the field value back after typing and warn if it differs from the intended text",
+			},
 			"script": map[string]interface{}{
 				"type":        "string",
 				"description": "JavaScript code for 'execute_js' action",
@@ -64,7 +113,7 @@ func (b *BrowserUse) Parameters() map[string]interface{} {
 			},
 			"tab_id": map[string]interface{}{
 				"type":        "integer",
-				"description": "Tab ID for 'switch_tab' action",
+				"description": "Tab ID for 'switch_tab' and 'close_tab' actions (close_tab defaults to the current tab)",
 			},
 		},
 		"required": []string{"action"},
@@ -79,25 +128,58 @@ func (b *BrowserUse) ensureBrowser(ctx context.Context) error {
 		return nil // 浏览器已初始化
 	}
 
-	// 创建浏览器上下文
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+	// 先确认 Chrome 存在，否则 chromedp 只会返回难以理解的启动错误
+	execPath, err := findChrome(b.execPath)
+	if err != nil {
+		return err
+	}
+
+	// 创建浏览器上下文，浏览器的生命周期不跟随单次调用的 ctx
+	opts := append(allocatorOptions(execPath, b.flags),
 		chromedp.Flag("headless", false),
 		chromedp.Flag("disable-gpu", false),
 	)
 
-	allocCtx, cancel := chromedp.NewExecAllocator(ctx, opts...)
+	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), opts...)
+	browserCtx, cancelBrowser := chromedp.NewContext(allocCtx)
+
+	// 启动浏览器，失败时不保留上下文，下次调用重新尝试
+	if err := chromedp.Run(browserCtx); err != nil {
+		cancelBrowser()
+		cancel()
+		return fmt.Errorf("failed to start browser %s: %w", execPath, err)
+	}
+
 	b.allocCtx = allocCtx
 	b.cancel = cancel
-
-	browserCtx, cancelBrowser := chromedp.NewContext(allocCtx)
 	b.ctx = browserCtx
-	_ = cancelBrowser // 保存 cancel 函数以便后续清理
+	b.tabs = []*browserTab{{id: 0, ctx: browserCtx, cancel: cancelBrowser}}
+	b.activeTab = 0
+	b.nextTabID = 0
 
-	// 启动浏览器
-	if err := chromedp.Run(browserCtx); err != nil {
-		return fmt.Errorf("failed to start browser: %w", err)
+	return nil
+}
+
+// SelfTest 以无头模式启动一次 Chrome 检查浏览器是否可用，不影响工具自身的浏览器实例
+func (b *BrowserUse) SelfTest(ctx context.Context) error {
+	b.mu.Lock()
+	execPath, err := findChrome(b.execPath)
+	b.mu.Unlock()
+	if err != nil {
+		return err
 	}
 
+	b.mu.Lock()
+	opts := allocatorOptions(execPath, b.flags)
+	b.mu.Unlock()
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(ctx, opts...)
+	defer cancelAlloc()
+	browserCtx, cancelBrowser := chromedp.NewContext(allocCtx)
+	defer cancelBrowser()
+
+	if err := chromedp.Run(browserCtx, chromedp.Navigate("about:blank")); err != nil {
+		return fmt.Errorf("failed to launch Chrome: %w", err)
+	}
 	return nil
 }
 
@@ -107,23 +189,28 @@ func (b *BrowserUse) Execute(ctx context.Context, args map[string]interface{}) (
 		return &ToolResult{Error: "action parameter is required"}, nil
 	}
 
+	// 浏览器尚未启动时没有状态可报告，不为此启动浏览器
+	if action == "get_current_state" && !b.started() {
+		return browserStateResult(&BrowserState{Tabs: []BrowserTab{}})
+	}
+
 	// 确保浏览器已初始化
 	if err := b.ensureBrowser(ctx); err != nil {
 		return &ToolResult{Error: err.Error()}, nil
 	}
 
-	b.mu.Lock()
-	browserCtx := b.ctx
-	b.mu.Unlock()
+	browserCtx := b.activeTabContext()
 
-	// 创建带超时的上下文
+	// 创建带超时的上下文，调用方取消时中止正在执行的操作
 	timeoutCtx, cancel := context.WithTimeout(browserCtx, 30*time.Second)
 	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
 
 	switch action {
 	case "navigate":
 		return b.navigate(timeoutCtx, args)
-	case "click":
+	case "click", "click_element":
 		return b.click(timeoutCtx, args)
 	case "input_text":
 		return b.inputText(timeoutCtx, args)
@@ -137,6 +224,20 @@ func (b *BrowserUse) Execute(ctx context.Context, args map[string]interface{}) (
 		return b.scroll(timeoutCtx, args)
 	case "refresh":
 		return b.refresh(timeoutCtx)
+	case "get_interactive_elements":
+		return b.getInteractiveElements(timeoutCtx)
+	case "new_tab":
+		return b.newTab(ctx, args)
+	case "switch_tab":
+		return b.switchTab(ctx, args)
+	case "close_tab":
+		return b.closeTab(args)
+	case "get_current_state":
+		state, err := b.currentState(timeoutCtx)
+		if err != nil {
+			return &ToolResult{Error: "Failed to get browser state: " + err.Error()}, nil
+		}
+		return browserStateResult(state)
 	default:
 		return &ToolResult{Error: "Unknown action: " + action}, nil
 	}
@@ -156,7 +257,24 @@ func (b *BrowserUse) navigate(ctx context.Context, args map[string]interface{})
 		return &ToolResult{Error: "Failed to navigate: " + err.Error()}, nil
 	}
 
-	return &ToolResult{Output: "Navigated to " + url}, nil
+	return &ToolResult{Output: "Navigated to " + url + "\n\n" + b.describeElements(ctx)}, nil
+}
+
+// describeElements 重新收集元素并格式化，失败时返回说明而不是报错
+func (b *BrowserUse) describeElements(ctx context.Context) string {
+	elements, err := b.refreshElements(ctx)
+	if err != nil {
+		return "Failed to list interactive elements: " + err.Error()
+	}
+	return formatElements(elements)
+}
+
+func (b *BrowserUse) getInteractiveElements(ctx context.Context) (*ToolResult, error) {
+	elements, err := b.refreshElements(ctx)
+	if err != nil {
+		return &ToolResult{Error: "Failed to list interactive elements: " + err.Error()}, nil
+	}
+	return &ToolResult{Output: formatElements(elements)}, nil
 }
 
 func (b *BrowserUse) click(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
@@ -165,16 +283,25 @@ func (b *BrowserUse) click(ctx context.Context, args map[string]interface{}) (*T
 		return &ToolResult{Error: "Index is required for 'click' action"}, nil
 	}
 
-	// 简化实现：通过 CSS 选择器点击（实际应通过 index 查找元素）
-	selector := fmt.Sprintf("body > *:nth-child(%d)", int(index))
-	err := chromedp.Run(ctx,
-		chromedp.Click(selector, chromedp.ByQuery),
+	el, err := b.lookupElement(int(index))
+	if err != nil {
+		return &ToolResult{Error: err.Error()}, nil
+	}
+	selector, by, err := locateElement(ctx, el)
+	if err != nil {
+		return &ToolResult{Error: "Failed to click: " + err.Error()}, nil
+	}
+
+	err = chromedp.Run(ctx,
+		chromedp.Click(selector, by),
 	)
 	if err != nil {
 		return &ToolResult{Error: "Failed to click: " + err.Error()}, nil
 	}
 
-	return &ToolResult{Output: fmt.Sprintf("Clicked element at index %d", int(index))}, nil
+	// 点击可能改变页面，编号随之更新
+	output := fmt.Sprintf("Clicked element at index %d: <%s>%s</%s>", int(index), el.Tag, el.Text, el.Tag)
+	return &ToolResult{Output: output + "\n\n" + b.describeElements(ctx)}, nil
 }
 
 func (b *BrowserUse) inputText(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
@@ -184,15 +311,46 @@ func (b *BrowserUse) inputText(ctx context.Context, args map[string]interface{})
 		return &ToolResult{Error: "Index and text are required for 'input_text' action"}, nil
 	}
 
-	selector := fmt.Sprintf("body > *:nth-child(%d)", int(index))
-	err := chromedp.Run(ctx,
-		chromedp.SendKeys(selector, text, chromedp.ByQuery),
+	el, err := b.lookupElement(int(index))
+	if err != nil {
+		return &ToolResult{Error: err.Error()}, nil
+	}
+	selector, by, err := locateElement(ctx, el)
+	if err != nil {
+		return &ToolResult{Error: "Failed to input text: " + err.Error()}, nil
+	}
+
+	err = chromedp.Run(ctx,
+		chromedp.SendKeys(selector, text, by),
 	)
 	if err != nil {
 		return &ToolResult{Error: "Failed to input text: " + err.Error()}, nil
 	}
 
-	return &ToolResult{Output: fmt.Sprintf("Input '%s' into element at index %d", text, int(index))}, nil
+	output := fmt.Sprintf("Input '%s' into element at index %d", text, int(index))
+	if verify, _ := args["verify"].(bool); verify {
+		// 读取元素的 value（非表单元素读取 textContent）确认文本已输入
+		var value string
+		readBack := fmt.Sprintf(`(() => { const el = %s; if (!el) return ""; return el.value !== undefined ? String(el.value) : (el.textContent || ""); })()`, elementJS(el))
+		if err := chromedp.Run(ctx, chromedp.Evaluate(readBack, &value)); err != nil {
+			return &ToolResult{Output: output, Error: "Failed to verify input: " + err.Error()}, nil
+		}
+		output += "\n" + verifyTypedText(text, value)
+	}
+
+	return &ToolResult{Output: output}, nil
+}
+
+// verifyTypedText 比较回读的字段内容与期望输入的文本
+func verifyTypedText(intended, actual string) string {
+	switch {
+	case actual == intended:
+		return "Verified: field value matches the intended text"
+	case strings.HasSuffix(actual, intended):
+		return fmt.Sprintf("Warning: field contains extra text before the input, value is %q", actual)
+	default:
+		return fmt.Sprintf("Warning: field value %q does not match the intended text %q", actual, intended)
+	}
 }
 
 func (b *BrowserUse) screenshot(ctx context.Context) (*ToolResult, error) {
@@ -204,9 +362,19 @@ func (b *BrowserUse) screenshot(ctx context.Context) (*ToolResult, error) {
 		return &ToolResult{Error: "Failed to capture screenshot: " + err.Error()}, nil
 	}
 
+	// 保存截图，文件名精确到毫秒，避免连续截图互相覆盖
+	if err := os.MkdirAll(b.outputDir, 0755); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to create screenshot directory: %v", err)}, nil
+	}
+	timestamp := time.Now().Format("20060102_150405.000")
+	screenshotPath := filepath.Join(b.outputDir, fmt.Sprintf("browser_%s.png", strings.Replace(timestamp, ".", "_", 1)))
+	if err := os.WriteFile(screenshotPath, buf, 0644); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to create screenshot file: %v", err)}, nil
+	}
+
 	return &ToolResult{
-		Output: fmt.Sprintf("Screenshot captured (length: %d bytes)", len(buf)),
-		System: string(buf), // Base64 编码的截图
+		Output:      fmt.Sprintf("Screenshot saved to: %s", screenshotPath),
+		Base64Image: base64.StdEncoding.EncodeToString(buf),
 	}, nil
 }
 
@@ -275,7 +443,7 @@ func (b *BrowserUse) refresh(ctx context.Context) (*ToolResult, error) {
 		return &ToolResult{Error: "Failed to refresh: " + err.Error()}, nil
 	}
 
-	return &ToolResult{Output: "Refreshed current page"}, nil
+	return &ToolResult{Output: "Refreshed current page\n\n" + b.describeElements(ctx)}, nil
 }
 
 // Cleanup 清理浏览器资源
@@ -283,12 +451,19 @@ func (b *BrowserUse) Cleanup() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if b.cancel != nil {
-		b.cancel()
+	for i := len(b.tabs) - 1; i > 0; i-- {
+		b.tabs[i].cancel()
 	}
 	if b.ctx != nil {
 		chromedp.Cancel(b.ctx)
 	}
+	if b.cancel != nil {
+		b.cancel()
+	}
+	b.ctx = nil
+	b.cancel = nil
+	b.tabs = nil
+	b.elements = nil
 	logrus.Info("Browser resources cleaned up")
 }
 
@@ -0,0 +1,107 @@
+package tool
+
+import (
+	"sync"
+	"time"
+)
+
+// circuitBreaker 按工具统计连续失败次数，超过阈值后在冷却期内拒绝调用
+type circuitBreaker struct {
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu     sync.Mutex
+	states map[string]*breakerState
+}
+
+// breakerState 单个工具的熔断状态
+type breakerState struct {
+	failures  []time.Time
+	openUntil time.Time
+	halfOpen  bool
+}
+
+func newCircuitBreaker(threshold int, window, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		window:    window,
+		cooldown:  cooldown,
+		now:       time.Now,
+		states:    make(map[string]*breakerState),
+	}
+}
+
+func (cb *circuitBreaker) state(name string) *breakerState {
+	s, ok := cb.states[name]
+	if !ok {
+		s = &breakerState{}
+		cb.states[name] = s
+	}
+	return s
+}
+
+// allow 检查工具当前是否允许调用，不允许时返回剩余冷却时间
+func (cb *circuitBreaker) allow(name string) (bool, time.Duration) {
+	if cb.threshold <= 0 {
+		return true, 0
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	s := cb.state(name)
+	if s.openUntil.IsZero() {
+		return true, 0
+	}
+
+	now := cb.now()
+	if now.Before(s.openUntil) {
+		return false, s.openUntil.Sub(now)
+	}
+
+	// 冷却期结束，允许试探调用，再次失败会立即熔断
+	s.openUntil = time.Time{}
+	s.halfOpen = true
+	return true, 0
+}
+
+// record 记录一次调用结果
+func (cb *circuitBreaker) record(name string, success bool) {
+	if cb.threshold <= 0 {
+		return
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	s := cb.state(name)
+	if success {
+		s.failures = nil
+		s.halfOpen = false
+		return
+	}
+
+	now := cb.now()
+	if s.halfOpen {
+		s.halfOpen = false
+		s.failures = nil
+		s.openUntil = now.Add(cb.cooldown)
+		return
+	}
+
+	// 只保留窗口期内的失败记录
+	recent := s.failures[:0]
+	for _, t := range s.failures {
+		if now.Sub(t) <= cb.window {
+			recent = append(recent, t)
+		}
+	}
+	s.failures = append(recent, now)
+
+	if len(s.failures) >= cb.threshold {
+		s.failures = nil
+		s.openUntil = now.Add(cb.cooldown)
+	}
+}
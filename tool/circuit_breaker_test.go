@@ -0,0 +1,99 @@
+package tool
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flakyTool 按 fail 决定返回错误结果还是成功，并记录执行次数
+type flakyTool struct {
+	fail  bool
+	calls int
+}
+
+func (f *flakyTool) Name() string        { return "flaky" }
+func (f *flakyTool) Description() string { return "A tool that fails on demand" }
+func (f *flakyTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object"}
+}
+func (f *flakyTool) Execute(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	f.calls++
+	if f.fail {
+		return &ToolResult{Error: "service unavailable"}, nil
+	}
+	return &ToolResult{Output: "ok"}, nil
+}
+
+func TestCircuitBreakerTripsAndCoolsDown(t *testing.T) {
+	flaky := &flakyTool{fail: true}
+	tc := NewToolCollection(flaky)
+	tc.SetCircuitBreaker(3, time.Minute, 2*time.Minute)
+	now := time.Now()
+	tc.breaker.now = func() time.Time { return now }
+
+	execute := func() *ToolResult {
+		t.Helper()
+		result, err := tc.Execute(context.Background(), "flaky", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	disabled := func(r *ToolResult) bool { return strings.Contains(r.Error, "temporarily disabled") }
+
+	for i := 0; i < 3; i++ {
+		if r := execute(); disabled(r) {
+			t.Fatalf("call %d was short-circuited before the threshold", i+1)
+		}
+	}
+	if r := execute(); !disabled(r) {
+		t.Fatalf("call after the threshold = %+v, want the tool disabled", r)
+	}
+	if flaky.calls != 3 {
+		t.Errorf("tool ran %d times, want 3", flaky.calls)
+	}
+
+	// 冷却期内仍然短路
+	now = now.Add(time.Minute)
+	if r := execute(); !disabled(r) {
+		t.Fatalf("call during cooldown = %+v, want the tool disabled", r)
+	}
+
+	// 冷却期结束后允许一次试探，失败后立即再次熔断
+	now = now.Add(90 * time.Second)
+	if r := execute(); disabled(r) {
+		t.Fatal("probe call after cooldown was short-circuited")
+	}
+	if r := execute(); !disabled(r) {
+		t.Fatalf("call after a failed probe = %+v, want the tool disabled", r)
+	}
+
+	// 试探成功后恢复正常
+	now = now.Add(3 * time.Minute)
+	flaky.fail = false
+	for i := 0; i < 2; i++ {
+		if r := execute(); r.Output != "ok" {
+			t.Fatalf("call after recovery = %+v, want ok", r)
+		}
+	}
+}
+
+func TestCircuitBreakerDisabledByDefault(t *testing.T) {
+	flaky := &flakyTool{fail: true}
+	tc := NewToolCollection(flaky)
+
+	for i := 0; i < 10; i++ {
+		result, err := tc.Execute(context.Background(), "flaky", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(result.Error, "temporarily disabled") {
+			t.Fatalf("call %d was short-circuited with the default configuration", i+1)
+		}
+	}
+	if flaky.calls != 10 {
+		t.Errorf("tool ran %d times, want 10", flaky.calls)
+	}
+}
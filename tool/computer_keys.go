@@ -0,0 +1,171 @@
+package tool
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// keyAliases 常见按键别名到 robotgo 按键名的映射
+var keyAliases = map[string]string{
+	"control":    "ctrl",
+	"ctl":        "ctrl",
+	"return":     "enter",
+	"escape":     "esc",
+	"del":        "delete",
+	"ins":        "insert",
+	"bksp":       "backspace",
+	"spacebar":   "space",
+	"pgup":       "pageup",
+	"page_up":    "pageup",
+	"pgdn":       "pagedown",
+	"page_down":  "pagedown",
+	"arrowup":    "up",
+	"arrowdown":  "down",
+	"arrowleft":  "left",
+	"arrowright": "right",
+	"caps":       "capslock",
+	"caps_lock":  "capslock",
+	"prtsc":      "printscreen",
+	"print":      "printscreen",
+}
+
+// platformKeyAliases 各平台特有的别名：macOS 的 command/option，
+// Windows/Linux 的 win/super 键在 robotgo 中都叫 cmd
+var platformKeyAliases = map[string]map[string]string{
+	"darwin": {
+		"command": "cmd",
+		"super":   "cmd",
+		"meta":    "cmd",
+		"win":     "cmd",
+		"option":  "alt",
+		"opt":     "alt",
+	},
+	"windows": {
+		"win":     "cmd",
+		"windows": "cmd",
+		"super":   "cmd",
+		"meta":    "cmd",
+		"command": "cmd",
+	},
+	"linux": {
+		"super":   "cmd",
+		"meta":    "cmd",
+		"win":     "cmd",
+		"command": "cmd",
+	},
+}
+
+// modifierKeys robotgo 支持的修饰键
+var modifierKeys = map[string]bool{
+	"ctrl": true, "alt": true, "shift": true, "cmd": true,
+	"lctrl": true, "rctrl": true, "lalt": true, "ralt": true,
+	"lshift": true, "rshift": true, "lcmd": true, "rcmd": true,
+}
+
+// namedKeys robotgo 支持的非字符按键
+var namedKeys = map[string]bool{
+	"enter": true, "esc": true, "tab": true, "space": true, "backspace": true,
+	"delete": true, "insert": true, "home": true, "end": true, "pageup": true,
+	"pagedown": true, "up": true, "down": true, "left": true, "right": true,
+	"capslock": true, "printscreen": true, "menu": true,
+	"audio_mute": true, "audio_vol_up": true, "audio_vol_down": true,
+	"audio_play": true, "audio_stop": true, "audio_pause": true,
+}
+
+// normalizeKey 将按键名转换为当前平台 robotgo 使用的名称，未知按键返回错误
+func normalizeKey(name, goos string) (string, error) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if key == "" {
+		return "", fmt.Errorf("empty key name")
+	}
+
+	if alias, ok := platformKeyAliases[goos][key]; ok {
+		key = alias
+	} else if alias, ok := keyAliases[key]; ok {
+		key = alias
+	}
+
+	if modifierKeys[key] || namedKeys[key] || isFunctionKey(key) || len([]rune(key)) == 1 {
+		return key, nil
+	}
+	return "", fmt.Errorf("unknown key %q (supported: letters, digits, punctuation, f1-f24, %s)", name, strings.Join(supportedKeyNames(), ", "))
+}
+
+// isFunctionKey 判断是否为 f1-f24
+func isFunctionKey(key string) bool {
+	var n int
+	if _, err := fmt.Sscanf(key, "f%d", &n); err != nil {
+		return false
+	}
+	return n >= 1 && n <= 24 && key == fmt.Sprintf("f%d", n)
+}
+
+func supportedKeyNames() []string {
+	names := make([]string, 0, len(modifierKeys)+len(namedKeys))
+	for name := range modifierKeys {
+		names = append(names, name)
+	}
+	for name := range namedKeys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseKeyCombo 解析按键组合（如 ["ctrl", "c"] 或 "cmd+shift+t"），
+// 返回主键和修饰键，修饰键放在前面，最后一个非修饰键作为主键
+func parseKeyCombo(specs []string, goos string) (string, []string, error) {
+	var parts []string
+	for _, spec := range specs {
+		// 末尾的 "+" 表示加号键本身，如 "ctrl++"
+		plusKey := spec == "+" || strings.HasSuffix(spec, "++")
+		if plusKey {
+			spec = strings.TrimSuffix(spec, "+")
+		}
+		for _, part := range strings.Split(spec, "+") {
+			if strings.TrimSpace(part) != "" {
+				parts = append(parts, part)
+			}
+		}
+		if plusKey {
+			parts = append(parts, "+")
+		}
+	}
+	if len(parts) == 0 {
+		return "", nil, fmt.Errorf("no keys given")
+	}
+
+	key := ""
+	modifiers := make([]string, 0, len(parts))
+	for _, part := range parts {
+		normalized, err := normalizeKey(part, goos)
+		if err != nil {
+			return "", nil, err
+		}
+		if modifierKeys[normalized] {
+			modifiers = append(modifiers, normalized)
+			continue
+		}
+		if key != "" {
+			return "", nil, fmt.Errorf("key combination %q has more than one non-modifier key", strings.Join(specs, "+"))
+		}
+		key = normalized
+	}
+
+	// 只有修饰键时（如单独按 shift），最后一个修饰键作为主键
+	if key == "" {
+		key = modifiers[len(modifiers)-1]
+		modifiers = modifiers[:len(modifiers)-1]
+	}
+	return key, modifiers, nil
+}
+
+// formatKeyCombo 格式化按键组合用于输出
+func formatKeyCombo(key string, modifiers []string) string {
+	return strings.Join(append(append([]string{}, modifiers...), key), "+")
+}
+
+// currentGOOS 当前平台，用于按键名映射
+var currentGOOS = runtime.GOOS
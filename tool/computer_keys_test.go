@@ -0,0 +1,56 @@
+package tool
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseKeyComboPerPlatform(t *testing.T) {
+	for _, tc := range []struct {
+		goos      string
+		specs     []string
+		key       string
+		modifiers []string
+	}{
+		{"darwin", []string{"ctrl+c"}, "c", []string{"ctrl"}},
+		{"linux", []string{"ctrl+c"}, "c", []string{"ctrl"}},
+		{"windows", []string{"Control", "C"}, "c", []string{"ctrl"}},
+		{"darwin", []string{"cmd+c"}, "c", []string{"cmd"}},
+		{"darwin", []string{"command+shift+t"}, "t", []string{"cmd", "shift"}},
+		{"darwin", []string{"option+left"}, "left", []string{"alt"}},
+		{"windows", []string{"win+r"}, "r", []string{"cmd"}},
+		{"linux", []string{"super+l"}, "l", []string{"cmd"}},
+		{"linux", []string{"ctrl++"}, "+", []string{"ctrl"}},
+		{"linux", []string{"return"}, "enter", []string{}},
+		{"linux", []string{"shift"}, "shift", []string{}},
+		{"windows", []string{"alt+f4"}, "f4", []string{"alt"}},
+	} {
+		key, modifiers, err := parseKeyCombo(tc.specs, tc.goos)
+		if err != nil {
+			t.Errorf("%s %q: %v", tc.goos, tc.specs, err)
+			continue
+		}
+		if key != tc.key || !reflect.DeepEqual(modifiers, tc.modifiers) {
+			t.Errorf("%s %q = %q %q, want %q %q", tc.goos, tc.specs, key, modifiers, tc.key, tc.modifiers)
+		}
+	}
+}
+
+func TestParseKeyComboRejectsUnknownKeys(t *testing.T) {
+	for _, tc := range []struct {
+		goos  string
+		specs []string
+		want  string
+	}{
+		{"linux", []string{"ctrl+hyper"}, `unknown key "hyper"`},
+		{"linux", []string{"option+c"}, `unknown key "option"`}, // option 只在 macOS 上是 alt
+		{"darwin", []string{"f25"}, `unknown key "f25"`},
+		{"linux", []string{"ctrl+a+b"}, "more than one non-modifier key"},
+		{"linux", []string{""}, "no keys given"},
+	} {
+		if _, _, err := parseKeyCombo(tc.specs, tc.goos); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s %q: error = %v, want %q", tc.goos, tc.specs, err, tc.want)
+		}
+	}
+}
@@ -1,24 +1,69 @@
 package tool
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"image"
 	"image/png"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
+
+	"go-manus/config"
 )
 
+// errDesktopUnsupported 未以 robotgo 构建时桌面操作不可用
+var errDesktopUnsupported = errors.New("desktop automation is not supported on this platform: go-manus was built without robotgo (rebuild with CGO_ENABLED=1 go build -tags robotgo)")
+
+// mouseButtons 支持的鼠标按键
+var mouseButtons = map[string]bool{"left": true, "right": true, "middle": true}
+
 // ComputerUseTool 计算机使用工具（屏幕控制）
 type ComputerUseTool struct {
 	outputDir string
+	macroDir  string
+	bounds    *image.Rectangle
+
+	// 正在录制的宏名称和已录制的动作
+	recording string
+	recorded  []map[string]interface{}
 }
 
 func NewComputerUseTool() *ComputerUseTool {
-	return &ComputerUseTool{
-		outputDir: "workspace/screenshots",
+	settings := config.GetInstance().GetComputerUse()
+	c := &ComputerUseTool{
+		outputDir: workspaceSubdir(settings.ScreenshotDir),
+		macroDir:  workspaceSubdir("macros"),
 	}
+	if b := settings.Bounds; len(b) == 4 {
+		c.SetBounds(b[0], b[1], b[2], b[3])
+	}
+	return c
+}
+
+// SetBounds 限制鼠标操作只能在指定矩形内进行，超出范围的坐标会被拒绝
+func (c *ComputerUseTool) SetBounds(x, y, width, height int) {
+	rect := image.Rect(x, y, x+width, y+height)
+	c.bounds = &rect
+}
+
+// ClearBounds 取消鼠标操作的区域限制
+func (c *ComputerUseTool) ClearBounds() {
+	c.bounds = nil
+}
+
+// checkBounds 检查坐标是否在允许的区域内
+func (c *ComputerUseTool) checkBounds(x, y int) *ToolResult {
+	if c.bounds == nil || image.Pt(x, y).In(*c.bounds) {
+		return nil
+	}
+	return &ToolResult{Error: fmt.Sprintf("Coordinates (%d, %d) are outside the allowed region (%d, %d)-(%d, %d)",
+		x, y, c.bounds.Min.X, c.bounds.Min.Y, c.bounds.Max.X-1, c.bounds.Max.Y-1)}
 }
 
 func (c *ComputerUseTool) Name() string {
@@ -34,7 +79,8 @@ Key capabilities include:
 * Mouse Control: Move, click, drag, scroll
 * Keyboard Input: Type text, press keys or key combinations
 * Screenshots: Capture and save screen images
-* Waiting: Pause execution for specified duration`
+* Waiting: Pause execution for specified duration
+* Macros: start_record/stop_record save the actions in between as a named macro, replay runs it again`
 }
 
 func (c *ComputerUseTool) Parameters() map[string]interface{} {
@@ -56,8 +102,15 @@ func (c *ComputerUseTool) Parameters() map[string]interface{} {
 					"drag_to",
 					"hotkey",
 					"screenshot",
+					"start_record",
+					"stop_record",
+					"replay",
 				},
 			},
+			"macro": map[string]interface{}{
+				"type":        "string",
+				"description": "Macro name for start_record and replay",
+			},
 			"x": map[string]interface{}{
 				"type":        "number",
 				"description": "X coordinate for mouse actions",
@@ -86,14 +139,18 @@ func (c *ComputerUseTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "Text to type",
 			},
+			"verify": map[string]interface{}{
+				"type":        "boolean",
+				"description": "For typing: select and copy the focused field afterwards and warn if its content differs from the typed text",
+			},
 			"key": map[string]interface{}{
 				"type":        "string",
-				"description": "Key to press",
+				"description": "Key to press, optionally with modifiers (e.g. enter, ctrl+c, cmd+shift+t)",
 			},
 			"keys": map[string]interface{}{
 				"type":        "array",
 				"items":       map[string]interface{}{"type": "string"},
-				"description": "Keys for hotkey combination",
+				"description": "Keys for hotkey combination (e.g. [\"ctrl\", \"c\"]); aliases like control, command, super, option are mapped to the platform key names",
 			},
 			"duration": map[string]interface{}{
 				"type":        "number",
@@ -104,12 +161,48 @@ func (c *ComputerUseTool) Parameters() map[string]interface{} {
 	}
 }
 
+// SetOutputDir 设置截图保存目录，相对于工作目录
+func (c *ComputerUseTool) SetOutputDir(dir string) {
+	c.outputDir = workspaceSubdir(dir)
+}
+
+// SelfTest 检查是否能获取到屏幕，无图形界面时鼠标键盘操作不可用
+func (c *ComputerUseTool) SelfTest(ctx context.Context) error {
+	width, height, err := desktopScreenSize()
+	if err != nil {
+		return err
+	}
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("no display available")
+	}
+	return nil
+}
+
 func (c *ComputerUseTool) Execute(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
 	action, ok := args["action"].(string)
 	if !ok {
 		return &ToolResult{Error: "action parameter is required"}, nil
 	}
 
+	switch action {
+	case "start_record":
+		return c.startRecord(args)
+	case "stop_record":
+		return c.stopRecord()
+	case "replay":
+		return c.replay(ctx, args)
+	}
+
+	result, err := c.runAction(ctx, action, args)
+	// 录制执行成功的动作
+	if c.recording != "" && err == nil && result != nil && result.Error == "" {
+		c.recorded = append(c.recorded, copyArgs(args))
+	}
+	return result, err
+}
+
+// runAction 执行单个桌面动作
+func (c *ComputerUseTool) runAction(ctx context.Context, action string, args map[string]interface{}) (*ToolResult, error) {
 	switch action {
 	case "move_to":
 		return c.moveTo(ctx, args)
@@ -147,46 +240,46 @@ func (c *ComputerUseTool) moveTo(ctx context.Context, args map[string]interface{
 	if !ok {
 		return &ToolResult{Error: "y coordinate is required for move_to"}, nil
 	}
+	if result := c.checkBounds(int(x), int(y)); result != nil {
+		return result, nil
+	}
 
-	robotgo.Move(int(x), int(y))
+	if err := desktopMove(int(x), int(y)); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to move mouse: %v", err)}, nil
+	}
 	return &ToolResult{Output: fmt.Sprintf("Mouse moved to (%d, %d)", int(x), int(y))}, nil
 }
 
 func (c *ComputerUseTool) click(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
-	button := "left"
-	if b, ok := args["button"].(string); ok && b != "" {
-		button = b
+	button, result := mouseButtonArg(args)
+	if result != nil {
+		return result, nil
 	}
 
 	numClicks := 1
 	if nc, ok := args["num_clicks"].(float64); ok {
 		numClicks = int(nc)
 	}
+	if numClicks < 1 || numClicks > 3 {
+		return &ToolResult{Error: "num_clicks must be 1, 2 or 3"}, nil
+	}
 
 	x, hasX := args["x"].(float64)
 	y, hasY := args["y"].(float64)
 
 	if hasX && hasY {
+		if result := c.checkBounds(int(x), int(y)); result != nil {
+			return result, nil
+		}
 		// 点击指定坐标
-		robotgo.Move(int(x), int(y))
-	}
-
-	// TODO: Implement mouse clicks using platform-specific libraries
-	// switch button {
-	// case "left":
-	// 	for i := 0; i < numClicks; i++ {
-	// 		robotgo.Click("left")
-	// 	}
-	// case "right":
-	// 	for i := 0; i < numClicks; i++ {
-	// 		robotgo.Click("right")
-	// 	}
-	// case "middle":
-	// 	for i := 0; i < numClicks; i++ {
-	// 		robotgo.Click("center")
-	// 	}
-	// }
+		if err := desktopMove(int(x), int(y)); err != nil {
+			return &ToolResult{Error: fmt.Sprintf("Failed to move mouse: %v", err)}, nil
+		}
+	}
 
+	if err := desktopClick(button, numClicks); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to click: %v", err)}, nil
+	}
 	return &ToolResult{Output: fmt.Sprintf("Clicked %s button %d times", button, numClicks)}, nil
 }
 
@@ -196,8 +289,9 @@ func (c *ComputerUseTool) scroll(ctx context.Context, args map[string]interface{
 		return &ToolResult{Error: "amount is required for scroll"}, nil
 	}
 
-	// robotgo.Scroll(int(amount), 0)
-	// TODO: Implement scroll using platform-specific libraries
+	if err := desktopScroll(int(amount)); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to scroll: %v", err)}, nil
+	}
 	return &ToolResult{Output: fmt.Sprintf("Scrolled %d units", int(amount))}, nil
 }
 
@@ -207,9 +301,46 @@ func (c *ComputerUseTool) typing(ctx context.Context, args map[string]interface{
 		return &ToolResult{Error: "text is required for typing"}, nil
 	}
 
-	// robotgo.TypeStr(text)
-	// TODO: Implement typing using platform-specific libraries
-	return &ToolResult{Output: fmt.Sprintf("Typed: %s", text)}, nil
+	if err := desktopType(text); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to type: %v", err)}, nil
+	}
+	output := fmt.Sprintf("Typed: %s", text)
+
+	if verify, _ := args["verify"].(bool); verify {
+		value, err := readFocusedField()
+		if err != nil {
+			return &ToolResult{Output: output, Error: fmt.Sprintf("Failed to verify typing: %v", err)}, nil
+		}
+		output += "\n" + verifyTypedText(text, value)
+	}
+	return &ToolResult{Output: output}, nil
+}
+
+// readFocusedField 通过全选、复制读取当前焦点输入框的内容，读取后恢复剪贴板并把光标移到末尾
+func readFocusedField() (string, error) {
+	modifier := "ctrl"
+	if currentGOOS == "darwin" {
+		modifier = "cmd"
+	}
+
+	previous, err := desktopReadClipboard()
+	if err != nil {
+		return "", err
+	}
+	defer desktopWriteClipboard(previous)
+
+	if err := desktopKeyTap("a", []string{modifier}); err != nil {
+		return "", err
+	}
+	if err := desktopKeyTap("c", []string{modifier}); err != nil {
+		return "", err
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := desktopKeyTap("end", nil); err != nil {
+		return "", err
+	}
+
+	return desktopReadClipboard()
 }
 
 func (c *ComputerUseTool) press(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
@@ -218,9 +349,16 @@ func (c *ComputerUseTool) press(ctx context.Context, args map[string]interface{}
 		return &ToolResult{Error: "key is required for press"}, nil
 	}
 
-	// robotgo.KeyTap(key)
-	// TODO: Implement key press using platform-specific libraries
-	return &ToolResult{Output: fmt.Sprintf("Pressed key: %s", key)}, nil
+	// 支持 "ctrl+c" 形式的组合键，并统一各平台的按键名
+	name, modifiers, err := parseKeyCombo([]string{key}, currentGOOS)
+	if err != nil {
+		return &ToolResult{Error: err.Error()}, nil
+	}
+
+	if err := desktopKeyTap(name, modifiers); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to press key: %v", err)}, nil
+	}
+	return &ToolResult{Output: fmt.Sprintf("Pressed key: %s", formatKeyCombo(name, modifiers))}, nil
 }
 
 func (c *ComputerUseTool) wait(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
@@ -229,29 +367,37 @@ func (c *ComputerUseTool) wait(ctx context.Context, args map[string]interface{})
 		duration = d
 	}
 
-	time.Sleep(time.Duration(duration * float64(time.Second)))
+	timer := time.NewTimer(time.Duration(duration * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return &ToolResult{Error: fmt.Sprintf("Wait cancelled: %v", ctx.Err())}, nil
+	case <-timer.C:
+	}
 	return &ToolResult{Output: fmt.Sprintf("Waited for %.2f seconds", duration)}, nil
 }
 
 func (c *ComputerUseTool) mouseDown(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
-	button := "left"
-	if b, ok := args["button"].(string); ok && b != "" {
-		button = b
+	button, result := mouseButtonArg(args)
+	if result != nil {
+		return result, nil
 	}
 
-	// robotgo.Toggle(button, "down")
-	// TODO: Implement mouse down using platform-specific libraries
+	if err := desktopToggle(button, "down"); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to press mouse button: %v", err)}, nil
+	}
 	return &ToolResult{Output: fmt.Sprintf("Mouse button %s pressed down", button)}, nil
 }
 
 func (c *ComputerUseTool) mouseUp(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
-	button := "left"
-	if b, ok := args["button"].(string); ok && b != "" {
-		button = b
+	button, result := mouseButtonArg(args)
+	if result != nil {
+		return result, nil
 	}
 
-	// robotgo.Toggle(button, "up")
-	// TODO: Implement mouse up using platform-specific libraries
+	if err := desktopToggle(button, "up"); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to release mouse button: %v", err)}, nil
+	}
 	return &ToolResult{Output: fmt.Sprintf("Mouse button %s released", button)}, nil
 }
 
@@ -264,9 +410,13 @@ func (c *ComputerUseTool) dragTo(ctx context.Context, args map[string]interface{
 	if !ok {
 		return &ToolResult{Error: "y coordinate is required for drag_to"}, nil
 	}
+	if result := c.checkBounds(int(x), int(y)); result != nil {
+		return result, nil
+	}
 
-	// robotgo.Drag(int(x), int(y))
-	// TODO: Implement drag using platform-specific libraries
+	if err := desktopDrag(int(x), int(y)); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to drag: %v", err)}, nil
+	}
 	return &ToolResult{Output: fmt.Sprintf("Dragged to (%d, %d)", int(x), int(y))}, nil
 }
 
@@ -276,41 +426,170 @@ func (c *ComputerUseTool) hotkey(ctx context.Context, args map[string]interface{
 		return &ToolResult{Error: "keys array is required for hotkey"}, nil
 	}
 
-	keyStrs := make([]string, len(keys))
-	for i, k := range keys {
-		keyStrs[i] = k.(string)
+	keyStrs := stringSliceArg(keys)
+	if len(keyStrs) != len(keys) {
+		return &ToolResult{Error: "keys must be non-empty strings"}, nil
+	}
+
+	name, modifiers, err := parseKeyCombo(keyStrs, currentGOOS)
+	if err != nil {
+		return &ToolResult{Error: err.Error()}, nil
 	}
 
-	// robotgo.KeyTap(keyStrs...)
-	// TODO: Implement hotkey using platform-specific libraries
-	return &ToolResult{Output: fmt.Sprintf("Pressed hotkey: %v", keyStrs)}, nil
+	if err := desktopKeyTap(name, modifiers); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to press hotkey: %v", err)}, nil
+	}
+	return &ToolResult{Output: fmt.Sprintf("Pressed hotkey: %s", formatKeyCombo(name, modifiers))}, nil
 }
 
-func (c *ComputerUseTool) screenshot(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
-	os.MkdirAll(c.outputDir, 0755)
+// mouseButtonArg 读取 button 参数，默认为左键
+func mouseButtonArg(args map[string]interface{}) (string, *ToolResult) {
+	button := "left"
+	if b, ok := args["button"].(string); ok && b != "" {
+		button = b
+	}
+	if !mouseButtons[button] {
+		return "", &ToolResult{Error: fmt.Sprintf("Unknown mouse button %q, expected left, right or middle", button)}
+	}
+	return button, nil
+}
 
-	// TODO: Implement screenshot using platform-specific libraries
-	// For now, return a placeholder
-	// bitmap := robotgo.CaptureScreen()
-	// defer robotgo.FreeBitmap(bitmap)
-	// img := robotgo.ToImage(bitmap)
-	
-	// Create a placeholder image (1x1 pixel)
-	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+func (c *ComputerUseTool) screenshot(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	img, err := desktopCapture()
+	if err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to capture screen: %v", err)}, nil
+	}
+	if err := os.MkdirAll(c.outputDir, 0755); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to create screenshot directory: %v", err)}, nil
+	}
 
 	// 保存截图
 	timestamp := time.Now().Format("20060102_150405")
 	screenshotPath := filepath.Join(c.outputDir, fmt.Sprintf("screenshot_%s.png", timestamp))
 
-	file, err := os.Create(screenshotPath)
-	if err != nil {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to encode screenshot: %v", err)}, nil
+	}
+
+	if err := os.WriteFile(screenshotPath, buf.Bytes(), 0644); err != nil {
 		return &ToolResult{Error: fmt.Sprintf("Failed to create screenshot file: %v", err)}, nil
 	}
-	defer file.Close()
 
-	if err := png.Encode(file, img); err != nil {
-		return &ToolResult{Error: fmt.Sprintf("Failed to encode screenshot: %v", err)}, nil
+	return &ToolResult{
+		Output:      fmt.Sprintf("Screenshot saved to: %s\n%s", screenshotPath, describeResolution(img.Bounds())),
+		Base64Image: base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// describeResolution 说明截图分辨率；在高分屏上截图像素可能多于鼠标坐标，此时同时给出屏幕坐标范围
+func describeResolution(bounds image.Rectangle) string {
+	desc := fmt.Sprintf("Resolution: %dx%d", bounds.Dx(), bounds.Dy())
+	width, height, err := desktopScreenSize()
+	if err == nil && width > 0 && height > 0 && (width != bounds.Dx() || height != bounds.Dy()) {
+		desc += fmt.Sprintf(" (screen coordinates for mouse actions: %dx%d)", width, height)
+	}
+	return desc
+}
+
+// startRecord 开始录制宏，之后成功执行的动作都会被记录
+func (c *ComputerUseTool) startRecord(args map[string]interface{}) (*ToolResult, error) {
+	name, err := macroName(args)
+	if err != nil {
+		return &ToolResult{Error: err.Error()}, nil
+	}
+	if c.recording != "" {
+		return &ToolResult{Error: fmt.Sprintf("Already recording macro %q, stop it first", c.recording)}, nil
+	}
+
+	c.recording = name
+	c.recorded = nil
+	return &ToolResult{Output: fmt.Sprintf("Started recording macro %q", name)}, nil
+}
+
+// stopRecord 停止录制并把宏保存为 JSON
+func (c *ComputerUseTool) stopRecord() (*ToolResult, error) {
+	if c.recording == "" {
+		return &ToolResult{Error: "No macro is being recorded"}, nil
+	}
+	name, actions := c.recording, c.recorded
+	c.recording, c.recorded = "", nil
+
+	if actions == nil {
+		actions = []map[string]interface{}{}
+	}
+	data, err := json.MarshalIndent(actions, "", "  ")
+	if err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to encode macro: %v", err)}, nil
+	}
+	if err := os.MkdirAll(c.macroDir, 0755); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to create macro directory: %v", err)}, nil
+	}
+	path := filepath.Join(c.macroDir, name+".json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to save macro: %v", err)}, nil
+	}
+
+	return &ToolResult{Output: fmt.Sprintf("Saved macro %q with %d actions to %s", name, len(actions), path)}, nil
+}
+
+// replay 按顺序重新执行已保存的宏，遇到失败的动作时停止
+func (c *ComputerUseTool) replay(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	name, err := macroName(args)
+	if err != nil {
+		return &ToolResult{Error: err.Error()}, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(c.macroDir, name+".json"))
+	if err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to load macro %q: %v", name, err)}, nil
+	}
+	var actions []map[string]interface{}
+	if err := json.Unmarshal(data, &actions); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to parse macro %q: %v", name, err)}, nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Replaying macro %q (%d actions)\n", name, len(actions)))
+	for i, actionArgs := range actions {
+		if err := ctx.Err(); err != nil {
+			return &ToolResult{Output: output.String(), Error: fmt.Sprintf("Replay cancelled: %v", err)}, nil
+		}
+		action, _ := actionArgs["action"].(string)
+		result, err := c.runAction(ctx, action, actionArgs)
+		if err != nil {
+			return nil, err
+		}
+		if result.Error != "" {
+			return &ToolResult{
+				Output: output.String(),
+				Error:  fmt.Sprintf("Action %d (%s) failed: %s", i+1, action, result.Error),
+			}, nil
+		}
+		output.WriteString(fmt.Sprintf("%d. %s: %s\n", i+1, action, result.Output))
 	}
 
-	return &ToolResult{Output: fmt.Sprintf("Screenshot saved to: %s", screenshotPath)}, nil
+	return &ToolResult{Output: output.String()}, nil
+}
+
+// macroName 读取并校验宏名称，名称会作为文件名使用
+func macroName(args map[string]interface{}) (string, error) {
+	name, _ := args["macro"].(string)
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("macro name is required")
+	}
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid macro name: %s", name)
+	}
+	return name, nil
+}
+
+// copyArgs 复制动作参数，避免录制内容被调用方修改
+func copyArgs(args map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		copied[k] = v
+	}
+	return copied
 }
@@ -1,14 +0,0 @@
-package tool
-
-// ComputerUseTool 的简化实现说明
-// 
-// 注意：完整的计算机自动化功能需要平台特定的库：
-// 
-// Windows: 可以使用 github.com/go-vgo/robotgo 或 github.com/lxn/walk
-// Linux: 可以使用 github.com/go-vgo/robotgo 或 xdotool (通过 exec)
-// macOS: 可以使用 github.com/go-vgo/robotgo 或 AppleScript (通过 exec)
-//
-// robotgo 需要 CGO 支持，可能在某些环境下编译困难
-// 建议根据实际平台选择合适的实现方式
-//
-// 当前实现提供了接口框架，实际功能需要根据平台实现
@@ -0,0 +1,93 @@
+//go:build robotgo && cgo
+
+package tool
+
+import (
+	"errors"
+	"image"
+
+	"github.com/go-vgo/robotgo"
+)
+
+// 桌面操作通过 robotgo 实现，robotgo 需要 CGO 和 X11 等系统库，
+// 因此需要以 -tags robotgo 并启用 CGO 构建；否则使用 computer_use_stub.go 中的实现
+
+// robotgoButton 工具参数中的鼠标按键名转换为 robotgo 按键名
+func robotgoButton(button string) string {
+	if button == "middle" {
+		return "center"
+	}
+	return button
+}
+
+func desktopScreenSize() (int, int, error) {
+	width, height := robotgo.GetScreenSize()
+	return width, height, nil
+}
+
+func desktopMove(x, y int) error {
+	robotgo.Move(x, y)
+	return nil
+}
+
+func desktopClick(button string, count int) error {
+	name := robotgoButton(button)
+	// robotgo 的双击参数只支持两次，多次点击逐次发送
+	for i := 0; i < count; i++ {
+		robotgo.Click(name)
+	}
+	return nil
+}
+
+// desktopScroll amount 为正时向上滚动，为负时向下
+func desktopScroll(amount int) error {
+	if amount >= 0 {
+		robotgo.ScrollDir(amount, "up")
+	} else {
+		robotgo.ScrollDir(-amount, "down")
+	}
+	return nil
+}
+
+func desktopType(text string) error {
+	robotgo.TypeStr(text)
+	return nil
+}
+
+func desktopKeyTap(key string, modifiers []string) error {
+	if len(modifiers) == 0 {
+		return robotgo.KeyTap(key)
+	}
+	return robotgo.KeyTap(key, modifiers)
+}
+
+// desktopToggle 按下或松开鼠标按键，direction 为 "down" 或 "up"
+func desktopToggle(button, direction string) error {
+	return robotgo.Toggle(robotgoButton(button), direction)
+}
+
+// desktopDrag 按住左键从当前位置拖动到 (x, y)
+func desktopDrag(x, y int) error {
+	robotgo.DragSmooth(x, y)
+	return nil
+}
+
+// desktopCapture 截取整个屏幕
+func desktopCapture() (image.Image, error) {
+	bitmap := robotgo.CaptureScreen()
+	defer robotgo.FreeBitmap(bitmap)
+
+	img := robotgo.ToImage(bitmap)
+	if img == nil {
+		return nil, errors.New("screen capture returned no image")
+	}
+	return img, nil
+}
+
+func desktopReadClipboard() (string, error) {
+	return robotgo.ReadAll()
+}
+
+func desktopWriteClipboard(text string) error {
+	return robotgo.WriteAll(text)
+}
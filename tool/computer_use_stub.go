@@ -0,0 +1,51 @@
+//go:build !robotgo || !cgo
+
+package tool
+
+import "image"
+
+// 未以 -tags robotgo 构建或未启用 CGO 时无法使用 robotgo，所有桌面操作都返回 errDesktopUnsupported
+
+func desktopScreenSize() (int, int, error) {
+	return 0, 0, errDesktopUnsupported
+}
+
+func desktopMove(x, y int) error {
+	return errDesktopUnsupported
+}
+
+func desktopClick(button string, count int) error {
+	return errDesktopUnsupported
+}
+
+func desktopScroll(amount int) error {
+	return errDesktopUnsupported
+}
+
+func desktopType(text string) error {
+	return errDesktopUnsupported
+}
+
+func desktopKeyTap(key string, modifiers []string) error {
+	return errDesktopUnsupported
+}
+
+func desktopToggle(button, direction string) error {
+	return errDesktopUnsupported
+}
+
+func desktopDrag(x, y int) error {
+	return errDesktopUnsupported
+}
+
+func desktopCapture() (image.Image, error) {
+	return nil, errDesktopUnsupported
+}
+
+func desktopReadClipboard() (string, error) {
+	return "", errDesktopUnsupported
+}
+
+func desktopWriteClipboard(text string) error {
+	return errDesktopUnsupported
+}
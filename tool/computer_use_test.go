@@ -0,0 +1,106 @@
+//go:build !robotgo || !cgo
+
+package tool
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// 这些测试使用 computer_use_stub.go：桌面操作都返回 errDesktopUnsupported，不会真的移动鼠标
+
+func TestComputerUseBoundsRefuseOutsideClicks(t *testing.T) {
+	c := NewComputerUseTool()
+	c.SetBounds(100, 100, 200, 100)
+	run := func(args map[string]interface{}) *ToolResult {
+		t.Helper()
+		result, err := c.Execute(context.Background(), args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"action": "click", "x": float64(50), "y": float64(150)},
+		{"action": "click", "x": float64(300), "y": float64(150)},
+		{"action": "move_to", "x": float64(150), "y": float64(200)},
+		{"action": "drag_to", "x": float64(150), "y": float64(99)},
+	} {
+		if result := run(args); !strings.Contains(result.Error, "outside the allowed region (100, 100)-(299, 199)") {
+			t.Errorf("%v: result = %+v, want refused", args, result)
+		}
+	}
+
+	// 区域内的点击通过检查，交给桌面后端执行
+	for _, args := range []map[string]interface{}{
+		{"action": "click", "x": float64(100), "y": float64(100)},
+		{"action": "click", "x": float64(299), "y": float64(199)},
+	} {
+		result := run(args)
+		if strings.Contains(result.Error, "outside the allowed region") || !strings.Contains(result.Error, errDesktopUnsupported.Error()) {
+			t.Errorf("%v: result = %+v, want it to reach the desktop backend", args, result)
+		}
+	}
+
+	c.ClearBounds()
+	if result := run(map[string]interface{}{"action": "click", "x": float64(50), "y": float64(150)}); strings.Contains(result.Error, "outside the allowed region") {
+		t.Errorf("click refused after ClearBounds: %+v", result)
+	}
+}
+
+func TestComputerUseMacroReplaysInOrder(t *testing.T) {
+	c := NewComputerUseTool()
+	run := func(args map[string]interface{}) *ToolResult {
+		t.Helper()
+		result, err := c.Execute(context.Background(), args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	if result := run(map[string]interface{}{"action": "start_record", "macro": "polling"}); result.Error != "" {
+		t.Fatal(result.Error)
+	}
+	run(map[string]interface{}{"action": "wait", "duration": 0.01})
+	// 失败的动作不录制
+	if result := run(map[string]interface{}{"action": "scroll", "amount": float64(3)}); result.Error == "" {
+		t.Fatalf("scroll succeeded on the stub backend: %+v", result)
+	}
+	run(map[string]interface{}{"action": "wait", "duration": 0.02})
+	run(map[string]interface{}{"action": "wait", "duration": 0.03})
+	if result := run(map[string]interface{}{"action": "stop_record"}); !strings.Contains(result.Output, `Saved macro "polling" with 3 actions`) {
+		t.Fatalf("stop_record = %+v", result)
+	}
+
+	result := run(map[string]interface{}{"action": "replay", "macro": "polling"})
+	want := "Replaying macro \"polling\" (3 actions)\n" +
+		"1. wait: Waited for 0.01 seconds\n" +
+		"2. wait: Waited for 0.02 seconds\n" +
+		"3. wait: Waited for 0.03 seconds\n"
+	if result.Error != "" || result.Output != want {
+		t.Errorf("replay = %+v, want output %q", result, want)
+	}
+}
+
+func TestComputerUseMacroReplayStopsAtFailure(t *testing.T) {
+	c := NewComputerUseTool()
+	writeWorkspaceFile(t, "macros/broken.json", `[
+		{"action": "wait", "duration": 0.01},
+		{"action": "click", "x": 10, "y": 10},
+		{"action": "wait", "duration": 0.02}
+	]`)
+
+	result, err := c.Execute(context.Background(), map[string]interface{}{"action": "replay", "macro": "broken"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Output, "1. wait: Waited for 0.01 seconds") || strings.Contains(result.Output, "0.02") {
+		t.Errorf("replay output = %q, want only the first action run", result.Output)
+	}
+	if !strings.HasPrefix(result.Error, "Action 2 (click) failed") {
+		t.Errorf("replay error = %q, want action 2 reported", result.Error)
+	}
+}
@@ -0,0 +1,369 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// convertFormats 支持的格式及其默认扩展名
+var convertFormats = map[string]string{
+	"csv":  ".csv",
+	"json": ".json",
+	"yaml": ".yaml",
+}
+
+// Convert 在 CSV、JSON、YAML 之间转换工作目录中的数据文件。
+// 数据统一读取为 yaml.Node，以保留对象中键的顺序；对象数组与 CSV 的行互相对应
+type Convert struct{}
+
+func NewConvert() *Convert {
+	return &Convert{}
+}
+
+func (c *Convert) Name() string {
+	return "convert"
+}
+
+func (c *Convert) Description() string {
+	return `Convert a data file between CSV, JSON and YAML and save the result.
+An array of objects maps to CSV rows: the keys become the header, in order of first appearance. Nested values are written to CSV cells as JSON.
+Numbers and booleans in CSV cells become numbers and booleans in JSON/YAML. Relative paths are resolved against the workspace directory.`
+}
+
+func (c *Convert) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"input_path": map[string]interface{}{
+				"type":        "string",
+				"description": "(required) Path of the file to convert.",
+			},
+			"output_format": map[string]interface{}{
+				"type":        "string",
+				"description": "(required) Format to convert to.",
+				"enum":        []string{"csv", "json", "yaml"},
+			},
+			"output_path": map[string]interface{}{
+				"type":        "string",
+				"description": "(optional) Path of the converted file. Default is the input path with the extension of the output format.",
+			},
+			"input_format": map[string]interface{}{
+				"type":        "string",
+				"description": "(optional) Format of the input file. Default is detected from the file extension.",
+				"enum":        []string{"csv", "json", "yaml"},
+			},
+		},
+		"required": []string{"input_path", "output_format"},
+	}
+}
+
+func (c *Convert) Execute(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	inputPath, _ := args["input_path"].(string)
+	if inputPath == "" {
+		return &ToolResult{Error: "input_path is required"}, nil
+	}
+	inputPath, err := ResolveWorkspacePath(inputPath)
+	if err != nil {
+		return &ToolResult{Error: err.Error()}, nil
+	}
+
+	outputFormat, _ := args["output_format"].(string)
+	if _, ok := convertFormats[outputFormat]; !ok {
+		return &ToolResult{Error: "output_format must be one of csv, json, yaml"}, nil
+	}
+
+	inputFormat, _ := args["input_format"].(string)
+	if inputFormat == "" {
+		inputFormat = formatFromExt(inputPath)
+	}
+	if _, ok := convertFormats[inputFormat]; !ok {
+		return &ToolResult{Error: fmt.Sprintf("Cannot detect the format of %s, set input_format to csv, json or yaml", inputPath)}, nil
+	}
+
+	outputPath, _ := args["output_path"].(string)
+	if outputPath == "" {
+		outputPath = strings.TrimSuffix(inputPath, filepath.Ext(inputPath)) + convertFormats[outputFormat]
+	} else if outputPath, err = ResolveWorkspacePath(outputPath); err != nil {
+		return &ToolResult{Error: err.Error()}, nil
+	}
+	if outputPath == inputPath {
+		return &ToolResult{Error: "output_path must differ from input_path"}, nil
+	}
+
+	raw, err := os.ReadFile(inputPath)
+	if err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to read file: %v", err)}, nil
+	}
+	data, err := decodeData(raw, inputFormat)
+	if err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to parse %s as %s: %v", inputPath, inputFormat, err)}, nil
+	}
+	out, err := encodeData(data, outputFormat)
+	if err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to convert to %s: %v", outputFormat, err)}, nil
+	}
+
+	if dir := filepath.Dir(outputPath); dir != "" && dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return &ToolResult{Error: fmt.Sprintf("Failed to create directory: %v", err)}, nil
+		}
+	}
+	if err := os.WriteFile(outputPath, out, 0644); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to write file: %v", err)}, nil
+	}
+
+	summary := fmt.Sprintf("Converted %s (%s) to %s (%s)", inputPath, inputFormat, outputPath, outputFormat)
+	if data.Kind == yaml.SequenceNode {
+		summary += fmt.Sprintf(", %d records", len(data.Content))
+	}
+	return &ToolResult{Output: summary}, nil
+}
+
+func formatFromExt(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return "csv"
+	case ".json":
+		return "json"
+	case ".yaml", ".yml":
+		return "yaml"
+	}
+	return ""
+}
+
+// decodeData 解析文件内容；JSON 是 YAML 的子集，两者都交给 YAML 解析器
+func decodeData(raw []byte, format string) (*yaml.Node, error) {
+	if format == "csv" {
+		records, err := parseCSV(decodeCSVText(raw))
+		if err != nil {
+			return nil, err
+		}
+		return csvToNode(records)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, fmt.Errorf("file is empty")
+	}
+	return doc.Content[0], nil
+}
+
+func encodeData(data *yaml.Node, format string) ([]byte, error) {
+	switch format {
+	case "csv":
+		return nodeToCSV(data)
+	case "json":
+		v, err := nodeToJSON(data)
+		if err != nil {
+			return nil, err
+		}
+		out, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(out, '\n'), nil
+	default:
+		clearStyle(data)
+		return yaml.Marshal(data)
+	}
+}
+
+// csvToNode 将 CSV 转换为对象数组，第一行为表头
+func csvToNode(records [][]string) (*yaml.Node, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no header row")
+	}
+	header := records[0]
+	seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for _, row := range records[1:] {
+		item := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for i, name := range header {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			item.Content = append(item.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name},
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: cellTag(cell), Value: cell},
+			)
+		}
+		seq.Content = append(seq.Content, item)
+	}
+	return seq, nil
+}
+
+// numericCell 可以当作数字的单元格；带前导零的编号（如 "007"）保持为字符串
+var numericCell = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?$`)
+
+// cellTag 推断 CSV 单元格的类型，空单元格为 null
+func cellTag(cell string) string {
+	switch {
+	case cell == "":
+		return "!!null"
+	case cell == "true" || cell == "false":
+		return "!!bool"
+	case !numericCell.MatchString(cell):
+		return "!!str"
+	case strings.Contains(cell, "."):
+		return "!!float"
+	default:
+		return "!!int"
+	}
+}
+
+// nodeToCSV 将对象数组（或单个对象）转换为 CSV，列按键首次出现的顺序排列
+func nodeToCSV(data *yaml.Node) ([]byte, error) {
+	data = resolveAlias(data)
+	items := []*yaml.Node{data}
+	if data.Kind == yaml.SequenceNode {
+		items = data.Content
+	}
+
+	var header []string
+	index := make(map[string]int)
+	rows := make([]map[string]string, 0, len(items))
+	for i, item := range items {
+		item = resolveAlias(item)
+		if item.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("record %d is not an object; CSV needs an array of objects", i+1)
+		}
+		row := make(map[string]string)
+		for j := 0; j+1 < len(item.Content); j += 2 {
+			key := item.Content[j].Value
+			if _, ok := index[key]; !ok {
+				index[key] = len(header)
+				header = append(header, key)
+			}
+			cell, err := csvCell(item.Content[j+1])
+			if err != nil {
+				return nil, err
+			}
+			row[key] = cell
+		}
+		rows = append(rows, row)
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write(header)
+	for _, row := range rows {
+		record := make([]string, len(header))
+		for i, name := range header {
+			record[i] = row[name]
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// csvCell 标量直接写入，null 为空，嵌套的对象和数组写为 JSON
+func csvCell(n *yaml.Node) (string, error) {
+	n = resolveAlias(n)
+	if n.Kind == yaml.ScalarNode {
+		if n.ShortTag() == "!!null" {
+			return "", nil
+		}
+		return n.Value, nil
+	}
+	v, err := nodeToJSON(n)
+	if err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(v)
+	return string(out), err
+}
+
+// orderedObject 按原始顺序输出键的 JSON 对象
+type orderedObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// nodeToJSON 将节点转换为可以 json.Marshal 的值，对象保留键的顺序
+func nodeToJSON(n *yaml.Node) (interface{}, error) {
+	n = resolveAlias(n)
+	switch n.Kind {
+	case yaml.MappingNode:
+		obj := orderedObject{values: make(map[string]interface{})}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i].Value
+			value, err := nodeToJSON(n.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			if _, exists := obj.values[key]; !exists {
+				obj.keys = append(obj.keys, key)
+			}
+			obj.values[key] = value
+		}
+		return obj, nil
+	case yaml.SequenceNode:
+		list := make([]interface{}, 0, len(n.Content))
+		for _, child := range n.Content {
+			value, err := nodeToJSON(child)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	case yaml.ScalarNode:
+		var v interface{}
+		if err := n.Decode(&v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("unsupported YAML node at line %d", n.Line)
+}
+
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	return n
+}
+
+// clearStyle 去掉从 JSON 解析来的流式和引号样式，输出为常规的块状 YAML
+func clearStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, child := range n.Content {
+		clearStyle(child)
+	}
+}
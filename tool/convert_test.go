@@ -0,0 +1,41 @@
+package tool
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestConvertJSONArrayToCSV(t *testing.T) {
+	input := writeWorkspaceFile(t, "convert/users.json", `[
+		{"name": "Ada", "age": 36, "tags": ["math", "engines"]},
+		{"name": "Linus", "email": "linus@example.com", "age": null}
+	]`)
+
+	result, err := NewConvert().Execute(context.Background(), map[string]interface{}{
+		"input_path":    "convert/users.json",
+		"output_format": "csv",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != "" {
+		t.Fatalf("unexpected error: %s", result.Error)
+	}
+	if !strings.Contains(result.Output, "2 records") {
+		t.Errorf("summary = %q, want it to count 2 records", result.Output)
+	}
+
+	out, err := os.ReadFile(strings.TrimSuffix(input, ".json") + ".csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 列按键首次出现的顺序排列，缺少的键和 null 为空，嵌套数组写为 JSON
+	want := "name,age,tags,email\n" +
+		"Ada,36,\"[\"\"math\"\",\"\"engines\"\"]\",\n" +
+		"Linus,,,linus@example.com\n"
+	if string(out) != want {
+		t.Errorf("csv = %q, want %q", out, want)
+	}
+}
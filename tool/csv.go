@@ -0,0 +1,161 @@
+package tool
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// csvDelimiters 自动识别的分隔符，按优先级排列
+var csvDelimiters = []rune{',', ';', '\t', '|'}
+
+// readCSVFile 读取 CSV 文件，自动处理 BOM/编码并根据表头识别分隔符
+func readCSVFile(path string) ([][]string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseCSV(decodeCSVText(raw))
+}
+
+// parseCSV 按识别出的分隔符解析 CSV 文本
+func parseCSV(text string) ([][]string, error) {
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = detectDelimiter(text)
+	return reader.ReadAll()
+}
+
+// decodeCSVText 去掉 BOM 并转换为 UTF-8：支持 UTF-8/UTF-16 BOM，
+// 非法 UTF-8 内容按 Latin-1 处理
+func decodeCSVText(raw []byte) string {
+	switch {
+	case bytes.HasPrefix(raw, []byte{0xEF, 0xBB, 0xBF}):
+		raw = raw[3:]
+	case bytes.HasPrefix(raw, []byte{0xFF, 0xFE}):
+		return decodeUTF16(raw[2:], binary.LittleEndian)
+	case bytes.HasPrefix(raw, []byte{0xFE, 0xFF}):
+		return decodeUTF16(raw[2:], binary.BigEndian)
+	}
+
+	if utf8.Valid(raw) {
+		return string(raw)
+	}
+	runes := make([]rune, len(raw))
+	for i, b := range raw {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+func decodeUTF16(raw []byte, order binary.ByteOrder) string {
+	units := make([]uint16, len(raw)/2)
+	for i := range units {
+		units[i] = order.Uint16(raw[i*2:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// detectDelimiter 统计表头行中（引号外）各候选分隔符的出现次数，取最多的一个，默认逗号
+func detectDelimiter(text string) rune {
+	header := text
+	if i := strings.IndexAny(text, "\r\n"); i >= 0 {
+		header = text[:i]
+	}
+
+	counts := make(map[rune]int)
+	inQuotes := false
+	for _, r := range header {
+		if r == '"' {
+			inQuotes = !inQuotes
+			continue
+		}
+		if !inQuotes {
+			counts[r]++
+		}
+	}
+
+	best := ','
+	for _, d := range csvDelimiters {
+		if counts[d] > counts[best] {
+			best = d
+		}
+	}
+	return best
+}
+
+// decimalCommaLanguages 使用逗号作为小数点的语言
+var decimalCommaLanguages = map[string]bool{
+	"de": true, "fr": true, "es": true, "it": true, "pt": true,
+	"nl": true, "ru": true, "pl": true, "tr": true, "sv": true,
+}
+
+// parseNumber 解析带千位分隔符、货币符号或百分号的数字，如 "1,234.5"、"1 234,5"。
+// 同时出现逗号和点时以最后出现的为小数点；只有一种且有歧义时（如 "1,234"）按 language 判断
+func parseNumber(s, language string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	s = strings.Trim(s, "$€£¥%")
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\u00a0', '\u202f', '\'', '_':
+			return -1
+		}
+		return r
+	}, s)
+	if s == "" {
+		return 0, false
+	}
+
+	lastComma := strings.LastIndex(s, ",")
+	lastDot := strings.LastIndex(s, ".")
+	switch {
+	case lastComma >= 0 && lastDot >= 0:
+		if lastComma > lastDot {
+			s = strings.ReplaceAll(s, ".", "")
+			s = strings.Replace(s, ",", ".", 1)
+		} else {
+			s = strings.ReplaceAll(s, ",", "")
+		}
+	case lastComma >= 0:
+		if decimalCommaLanguages[language] || !isThousandsGrouped(s, ',') {
+			if strings.Count(s, ",") > 1 {
+				return 0, false
+			}
+			s = strings.Replace(s, ",", ".", 1)
+		} else {
+			s = strings.ReplaceAll(s, ",", "")
+		}
+	case lastDot >= 0 && strings.Count(s, ".") > 1:
+		// 多个点只可能是千位分隔符，如 "1.234.567"
+		if !isThousandsGrouped(s, '.') {
+			return 0, false
+		}
+		s = strings.ReplaceAll(s, ".", "")
+	case lastDot >= 0 && decimalCommaLanguages[language] && isThousandsGrouped(s, '.'):
+		s = strings.ReplaceAll(s, ".", "")
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// isThousandsGrouped 判断数字是否按三位一组使用 sep 分隔，如 "12,345,678"
+func isThousandsGrouped(s string, sep rune) bool {
+	parts := strings.Split(strings.TrimLeft(s, "+-"), string(sep))
+	if len(parts) < 2 || len(parts[0]) == 0 || len(parts[0]) > 3 {
+		return false
+	}
+	for _, part := range parts[1:] {
+		if len(part) != 3 {
+			return false
+		}
+	}
+	return true
+}
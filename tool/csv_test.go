@@ -0,0 +1,59 @@
+package tool
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReadCSVDetectsDelimiter(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		content string
+	}{
+		{"semicolon", "city;population\nBerlin;3645000\n\"Frankfurt; Main\";773000\n"},
+		{"tab", "city\tpopulation\nBerlin\t3645000\nFrankfurt; Main\t773000\n"},
+		{"pipe", "city|population\nBerlin|3645000\nFrankfurt; Main|773000\n"},
+		{"comma", "city,population\nBerlin,3645000\nFrankfurt; Main,773000\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := readCSVFile(writeWorkspaceFile(t, "delimiter-"+tc.name+".csv", tc.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := [][]string{{"city", "population"}, {"Berlin", "3645000"}, {"Frankfurt; Main", "773000"}}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("readCSVFile = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	for _, tc := range []struct {
+		in, language string
+		want         float64
+		ok           bool
+	}{
+		{"1,234.5", "", 1234.5, true},
+		{"1.234,5", "", 1234.5, true},
+		{"1 234,5", "de", 1234.5, true},
+		{"1 234,5", "fr", 1234.5, true},
+		{"1,234", "", 1234, true},
+		{"1,234", "de", 1.234, true},
+		{"1.234", "de", 1234, true},
+		{"1.234", "", 1.234, true},
+		{"12,5", "", 12.5, true},
+		{"1.234.567", "", 1234567, true},
+		{"$1,200", "", 1200, true},
+		{"45%", "", 45, true},
+		{"-3.5", "", -3.5, true},
+		{"1,2,3", "de", 0, false},
+		{"abc", "", 0, false},
+		{"", "", 0, false},
+	} {
+		got, ok := parseNumber(tc.in, tc.language)
+		if ok != tc.ok || got != tc.want {
+			t.Errorf("parseNumber(%q, %q) = %v, %v, want %v, %v", tc.in, tc.language, got, ok, tc.want, tc.ok)
+		}
+	}
+}
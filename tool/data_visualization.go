@@ -2,25 +2,84 @@ package tool
 
 import (
 	"context"
-	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"go-manus/config"
 )
 
+// chartJSCDN 在线模式下加载 Chart.js 的地址
+const chartJSCDN = "https://cdn.jsdelivr.net/npm/chart.js"
+
+// chartTheme 图表配色方案
+type chartTheme struct {
+	background string
+	text       string
+	grid       string
+	colors     []string
+}
+
+// chartThemes 内置主题，light 保持原有的青色配色
+var chartThemes = map[string]chartTheme{
+	"light": {
+		background: "#ffffff",
+		text:       "#333333",
+		grid:       "#e5e5e5",
+		colors:     []string{"#4bc0c0", "#36a2eb", "#ff6384", "#ff9f40", "#9966ff", "#ffcd56", "#c9cbcf"},
+	},
+	"dark": {
+		background: "#1e1e1e",
+		text:       "#e0e0e0",
+		grid:       "#3a3a3a",
+		colors:     []string{"#4dd0e1", "#64b5f6", "#f06292", "#ffb74d", "#ba68c8", "#fff176", "#90a4ae"},
+	},
+	"pastel": {
+		background: "#ffffff",
+		text:       "#444444",
+		grid:       "#eeeeee",
+		colors:     []string{"#a8dadc", "#f4a261", "#e9c46a", "#b5838d", "#94d2bd", "#cdb4db", "#ffafcc"},
+	},
+	"vivid": {
+		background: "#ffffff",
+		text:       "#222222",
+		grid:       "#dddddd",
+		colors:     []string{"#e63946", "#1d3557", "#2a9d8f", "#f4a261", "#8338ec", "#ffbe0b", "#3a86ff"},
+	},
+}
+
+// chartStyle 图表的主题与尺寸
+type chartStyle struct {
+	theme  chartTheme
+	width  int
+	height int
+}
+
 // DataVisualization 数据可视化工具
 type DataVisualization struct {
-	outputDir string
+	outputDir   string
+	offline     bool
+	chartJSPath string
 }
 
 func NewDataVisualization() *DataVisualization {
+	settings := config.GetInstance().GetVisualization()
 	return &DataVisualization{
-		outputDir: "workspace/charts",
+		outputDir:   workspaceSubdir(settings.OutputDir),
+		offline:     settings.Offline,
+		chartJSPath: settings.ChartJSPath,
 	}
 }
 
+// SetOutputDir 设置图表输出目录，相对于工作目录
+func (d *DataVisualization) SetOutputDir(dir string) {
+	d.outputDir = workspaceSubdir(dir)
+}
+
 func (d *DataVisualization) Name() string {
 	return "data_visualization"
 }
@@ -51,16 +110,36 @@ func (d *DataVisualization) Parameters() map[string]interface{} {
 			},
 			"tool_type": map[string]interface{}{
 				"type":        "string",
-				"description": "visualize chart or add insights",
+				"description": "visualize chart, or add insights: per-column statistics, trend and outliers of the numeric columns, written as markdown",
 				"enum":        []string{"visualization", "insight"},
 				"default":     "visualization",
 			},
 			"language": map[string]interface{}{
 				"type":        "string",
-				"description": "english(en) / chinese(zh)",
-				"enum":        []string{"zh", "en"},
+				"description": "english(en) / chinese(zh); other languages such as de/fr/es also parse numbers with decimal commas",
+				"enum":        []string{"zh", "en", "de", "fr", "es"},
 				"default":     "en",
 			},
+			"theme": map[string]interface{}{
+				"type":        "string",
+				"description": "Color theme of the chart",
+				"enum":        []string{"light", "dark", "pastel", "vivid"},
+				"default":     "light",
+			},
+			"width": map[string]interface{}{
+				"type":        "integer",
+				"description": "Chart width in pixels",
+				"default":     800,
+			},
+			"height": map[string]interface{}{
+				"type":        "integer",
+				"description": "Chart height in pixels",
+				"default":     400,
+			},
+			"offline": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Inline a local copy of Chart.js instead of loading it from the CDN, so the chart renders without network access",
+			},
 		},
 		"required": []string{"json_path"},
 	}
@@ -87,6 +166,28 @@ func (d *DataVisualization) Execute(ctx context.Context, args map[string]interfa
 		language = lang
 	}
 
+	themeName := "light"
+	if th, ok := args["theme"].(string); ok && th != "" {
+		themeName = th
+	}
+	theme, ok := chartThemes[themeName]
+	if !ok {
+		return &ToolResult{Error: fmt.Sprintf("unknown theme: %s (available: light, dark, pastel, vivid)", themeName)}, nil
+	}
+
+	style := chartStyle{theme: theme, width: 800, height: 400}
+	if w, ok := args["width"].(float64); ok && w > 0 {
+		style.width = int(w)
+	}
+	if h, ok := args["height"].(float64); ok && h > 0 {
+		style.height = int(h)
+	}
+
+	offline := d.offline
+	if o, ok := args["offline"].(bool); ok {
+		offline = o
+	}
+
 	// 读取 JSON 配置
 	jsonData, err := os.ReadFile(jsonPath)
 	if err != nil {
@@ -112,34 +213,27 @@ func (d *DataVisualization) Execute(ctx context.Context, args map[string]interfa
 
 	// 生成图表
 	if toolType == "visualization" {
-		return d.generateChart(ctx, data, config, outputType, language)
+		return d.generateChart(ctx, data, config, outputType, language, style, offline)
 	} else {
 		return d.addInsights(ctx, data, config, language)
 	}
 }
 
 func (d *DataVisualization) readCSV(path string) ([][]string, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		return nil, err
-	}
-
-	return records, nil
+	return readCSVFile(path)
 }
 
-func (d *DataVisualization) generateChart(ctx context.Context, data [][]string, config map[string]interface{}, outputType, language string) (*ToolResult, error) {
+func (d *DataVisualization) generateChart(ctx context.Context, data [][]string, config map[string]interface{}, outputType, language string, style chartStyle, offline bool) (*ToolResult, error) {
 	// 确保输出目录存在
 	os.MkdirAll(d.outputDir, 0755)
 
-	// 获取图表类型和配置
+	// 获取图表类型和配置，未指定或为 auto 时根据数据推荐
 	chartType, _ := config["chartType"].(string)
+	autoSelected := false
+	if chartType == "" || chartType == "auto" {
+		chartType = recommendChartType(data)
+		autoSelected = true
+	}
 	title, _ := config["title"].(string)
 	if title == "" {
 		title = "Chart"
@@ -152,7 +246,11 @@ func (d *DataVisualization) generateChart(ctx context.Context, data [][]string,
 	// 这里应该使用 Go 的图表库生成图表
 	// 简化实现：生成 HTML 图表
 	if outputType == "html" {
-		htmlContent := d.generateHTMLChart(data, config, title, language)
+		scriptTag, err := d.chartJSScriptTag(offline)
+		if err != nil {
+			return &ToolResult{Error: err.Error()}, nil
+		}
+		htmlContent := d.generateHTMLChart(data, chartType, title, language, style, scriptTag)
 		if err := os.WriteFile(chartPath, []byte(htmlContent), 0644); err != nil {
 			return &ToolResult{Error: fmt.Sprintf("Failed to write chart: %v", err)}, nil
 		}
@@ -162,17 +260,150 @@ func (d *DataVisualization) generateChart(ctx context.Context, data [][]string,
 		return &ToolResult{Error: "PNG format requires chart library (e.g., gonum/plot or go-echarts)"}, nil
 	}
 
-	output := fmt.Sprintf("Chart Generated Successfully!\n## %s\nChart saved in: %s", title, chartPath)
+	chartTypeInfo := chartType
+	if autoSelected {
+		chartTypeInfo += " (auto-selected)"
+	}
+	output := fmt.Sprintf("Chart Generated Successfully!\n## %s\nChart type: %s\nChart saved in: %s", title, chartTypeInfo, chartPath)
 	return &ToolResult{Output: output}, nil
 }
 
-func (d *DataVisualization) generateHTMLChart(data [][]string, config map[string]interface{}, title, language string) string {
+// recommendChartType 根据 CSV 数据推荐图表类型：
+// 时间或有序数值 x + 单个数值 y 用折线图，分类 x + 数值 y 用柱状图，
+// 两列无序数值用散点图，分类 x + 单个占比列用饼图
+func recommendChartType(data [][]string) string {
+	if len(data) < 2 || len(data[0]) < 2 {
+		return "bar"
+	}
+	rows := data[1:]
+
+	xNumeric := columnMatches(rows, 0, isNumericValue)
+	xTime := columnMatches(rows, 0, isTimeValue)
+	numericY := 0
+	for col := 1; col < len(data[0]); col++ {
+		if columnMatches(rows, col, isNumericValue) {
+			numericY++
+		}
+	}
+	if numericY == 0 {
+		return "bar"
+	}
+
+	switch {
+	case xTime:
+		return "line"
+	case xNumeric && isSequential(rows, 0):
+		return "line"
+	case xNumeric:
+		return "scatter"
+	case numericY == 1 && isProportion(rows, 1):
+		return "pie"
+	default:
+		return "bar"
+	}
+}
+
+// columnMatches 判断某列所有非空值是否都满足条件
+func columnMatches(rows [][]string, col int, match func(string) bool) bool {
+	seen := false
+	for _, row := range rows {
+		if col >= len(row) || strings.TrimSpace(row[col]) == "" {
+			continue
+		}
+		if !match(strings.TrimSpace(row[col])) {
+			return false
+		}
+		seen = true
+	}
+	return seen
+}
+
+func isNumericValue(s string) bool {
+	_, ok := parseNumber(s, "")
+	return ok
+}
+
+// timeLayouts 识别为时间列的常见日期格式
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"2006/01/02",
+	"2006-01",
+	"2006/01",
+	"Jan 2006",
+	"January 2006",
+}
+
+func isTimeValue(s string) bool {
+	for _, layout := range timeLayouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// isSequential 判断数值列是否严格递增（如年份、序号）
+func isSequential(rows [][]string, col int) bool {
+	prev := 0.0
+	for i, row := range rows {
+		if col >= len(row) {
+			return false
+		}
+		v, ok := parseNumber(row[col], "")
+		if !ok {
+			return false
+		}
+		if i > 0 && v <= prev {
+			return false
+		}
+		prev = v
+	}
+	return true
+}
+
+// isProportion 判断数值列是否表示占比：均非负且总和约为 1 或 100
+func isProportion(rows [][]string, col int) bool {
+	if len(rows) > 12 {
+		return false
+	}
+	sum := 0.0
+	for _, row := range rows {
+		if col >= len(row) {
+			return false
+		}
+		v, ok := parseNumber(row[col], "")
+		if !ok || v < 0 {
+			return false
+		}
+		sum += v
+	}
+	return math.Abs(sum-1) < 0.01 || math.Abs(sum-100) < 0.5
+}
+
+// chartJSScriptTag 返回引入 Chart.js 的 script 标签，离线模式下内联本地副本
+func (d *DataVisualization) chartJSScriptTag(offline bool) (string, error) {
+	if !offline {
+		return fmt.Sprintf(`<script src="%s"></script>`, chartJSCDN), nil
+	}
+
+	path, err := ResolveWorkspacePath(d.chartJSPath)
+	if err != nil {
+		return "", fmt.Errorf("invalid chartjs_path: %v", err)
+	}
+	library, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("offline mode requires a local Chart.js copy at %s (download chart.umd.min.js or set [visualization] chartjs_path): %v", path, err)
+	}
+	// 避免库内容中的 </script> 提前结束标签
+	inlined := strings.ReplaceAll(string(library), "</script", `<\/script`)
+	return "<script>\n" + inlined + "\n</script>", nil
+}
+
+func (d *DataVisualization) generateHTMLChart(data [][]string, chartType, title, language string, style chartStyle, scriptTag string) string {
 	// 使用简单的 HTML + Chart.js 生成交互式图表
 	// 这里是一个简化实现
-	chartType, _ := config["chartType"].(string)
-	if chartType == "" {
-		chartType = "line"
-	}
 
 	// 提取数据（简化：假设第一行是标题，后续是数据）
 	var labels []string
@@ -183,23 +414,37 @@ func (d *DataVisualization) generateHTMLChart(data [][]string, config map[string
 		for i := 1; i < len(data); i++ {
 			if len(data[i]) >= 2 {
 				labels = append(labels, data[i][0])
-				// 简化：假设是数字
-				var val float64
-				fmt.Sscanf(data[i][1], "%f", &val)
+				// 无法解析的值按 0 处理
+				val, _ := parseNumber(data[i][1], language)
 				values = append(values, val)
 			}
 		}
 	}
 
+	// 饼图每个扇区使用不同颜色，其它图表使用主题主色
+	colors := style.theme.colors
+	borderColor := fmt.Sprintf("'%s'", colors[0])
+	backgroundColor := fmt.Sprintf("'%s33'", colors[0])
+	if chartType == "pie" {
+		sliceColors := make([]string, len(values))
+		for i := range sliceColors {
+			sliceColors[i] = colors[i%len(colors)]
+		}
+		backgroundColor = d.arrayToJSON(sliceColors)
+		borderColor = fmt.Sprintf("'%s'", style.theme.background)
+	}
+
 	html := fmt.Sprintf(`<!DOCTYPE html>
 <html>
 <head>
     <title>%s</title>
-    <script src="https://cdn.jsdelivr.net/npm/chart.js"></script>
+    %s
 </head>
-<body>
+<body style="background: %s; color: %s;">
     <h1>%s</h1>
-    <canvas id="myChart" width="400" height="200"></canvas>
+    <div style="position: relative; width: %dpx; height: %dpx;">
+        <canvas id="myChart" width="%d" height="%d"></canvas>
+    </div>
     <script>
         const ctx = document.getElementById('myChart').getContext('2d');
         const chart = new Chart(ctx, {
@@ -209,22 +454,34 @@ func (d *DataVisualization) generateHTMLChart(data [][]string, config map[string
                 datasets: [{
                     label: 'Data',
                     data: %s,
-                    borderColor: 'rgb(75, 192, 192)',
-                    backgroundColor: 'rgba(75, 192, 192, 0.2)',
+                    borderColor: %s,
+                    backgroundColor: %s,
                 }]
             },
             options: {
                 responsive: true,
+                maintainAspectRatio: false,
+                color: '%s',
                 scales: {
+                    x: {
+                        ticks: { color: '%s' },
+                        grid: { color: '%s' }
+                    },
                     y: {
-                        beginAtZero: true
+                        beginAtZero: true,
+                        ticks: { color: '%s' },
+                        grid: { color: '%s' }
                     }
                 }
             }
         });
     </script>
 </body>
-</html>`, title, title, chartType, d.arrayToJSON(labels), d.arrayToJSONFloat(values))
+</html>`, title, scriptTag, style.theme.background, style.theme.text, title,
+		style.width, style.height, style.width, style.height,
+		chartType, d.arrayToJSON(labels), d.arrayToJSONFloat(values),
+		borderColor, backgroundColor,
+		style.theme.text, style.theme.text, style.theme.grid, style.theme.text, style.theme.grid)
 
 	return html
 }
@@ -240,24 +497,23 @@ func (d *DataVisualization) arrayToJSONFloat(arr []float64) string {
 }
 
 func (d *DataVisualization) addInsights(ctx context.Context, data [][]string, config map[string]interface{}, language string) (*ToolResult, error) {
-	// 添加洞察（简化实现）
 	insightPath, _ := config["insight_path"].(string)
 	if insightPath == "" {
 		insightPath = filepath.Join(d.outputDir, "insights.md")
 	}
 
-	insights := fmt.Sprintf("# Chart Insights\n\n## Analysis\n\nBased on the data visualization, here are key insights:\n\n")
-	
-	// 这里可以添加实际的数据分析逻辑
-	// 简化实现
-	insights += "- Data points analyzed\n"
-	insights += "- Trends identified\n"
-	insights += "- Recommendations provided\n"
+	// 统计每个数值列的分布、趋势和异常值
+	insights := renderInsights(numericColumnStats(data, language), language)
 
+	if dir := filepath.Dir(insightPath); dir != "" && dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return &ToolResult{Error: fmt.Sprintf("Failed to create directory: %v", err)}, nil
+		}
+	}
 	if err := os.WriteFile(insightPath, []byte(insights), 0644); err != nil {
 		return &ToolResult{Error: fmt.Sprintf("Failed to write insights: %v", err)}, nil
 	}
 
-	output := fmt.Sprintf("Insights Added Successfully!\nInsights saved in: %s", insightPath)
+	output := fmt.Sprintf("Insights Added Successfully!\nInsights saved in: %s\n\n%s", insightPath, insights)
 	return &ToolResult{Output: output}, nil
 }
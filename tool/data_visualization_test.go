@@ -0,0 +1,147 @@
+package tool
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+// prepareChart 用 visualization_prepare 生成图表元数据，返回 JSON 路径
+func prepareChart(t *testing.T, csvContent string, args map[string]interface{}) string {
+	t.Helper()
+	prepareArgs := map[string]interface{}{"data": csvContent}
+	for k, v := range args {
+		prepareArgs[k] = v
+	}
+	result, err := NewVisualizationPrepare().Execute(context.Background(), prepareArgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != "" {
+		t.Fatalf("visualization_prepare failed: %s", result.Error)
+	}
+	_, rest, _ := strings.Cut(result.Output, "\nJSON: ")
+	jsonPath, _, _ := strings.Cut(rest, "\n")
+	return jsonPath
+}
+
+func TestRecommendChartType(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		csv  string
+		want string
+	}{
+		{"category and value", "fruit,sold\napple,30\nbanana,12\ncherry,45\n", "bar"},
+		{"dates", "date,visits\n2024-01-01,10\n2024-01-02,14\n2024-01-03,9\n", "line"},
+		{"sequential numbers", "year,revenue\n2021,100\n2022,130\n2023,170\n", "line"},
+		{"unordered numbers", "height,weight\n180,75\n165,60\n172,68\n", "scatter"},
+		{"shares", "browser,share\nChrome,65\nSafari,20\nOther,15\n", "pie"},
+		{"no numeric column", "name,team\nada,red\nbob,blue\n", "bar"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := parseCSV(tc.csv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := recommendChartType(data); got != tc.want {
+				t.Errorf("recommendChartType = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDataVisualizationAutoSelectsBarChart(t *testing.T) {
+	jsonPath := prepareChart(t, "fruit,sold\napple,30\nbanana,12\ncherry,45\n", map[string]interface{}{"title": "Fruit Sales"})
+
+	result, err := NewDataVisualization().Execute(context.Background(), map[string]interface{}{"json_path": jsonPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Output, "Chart type: bar (auto-selected)") {
+		t.Errorf("result = %+v, want a bar chart picked automatically", result)
+	}
+}
+
+// renderChart 用给定参数生成 HTML 图表并返回其内容
+func renderChart(t *testing.T, d *DataVisualization, jsonPath string, args map[string]interface{}) string {
+	t.Helper()
+	vizArgs := map[string]interface{}{"json_path": jsonPath}
+	for k, v := range args {
+		vizArgs[k] = v
+	}
+	result, err := d.Execute(context.Background(), vizArgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != "" {
+		t.Fatalf("data_visualization failed: %s", result.Error)
+	}
+	_, chartPath, _ := strings.Cut(result.Output, "Chart saved in: ")
+	html, err := os.ReadFile(chartPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(html)
+}
+
+func TestDataVisualizationAppliesSizeAndTheme(t *testing.T) {
+	jsonPath := prepareChart(t, "fruit,sold\napple,30\nbanana,12\ncherry,45\n", map[string]interface{}{"title": "Styled Chart"})
+
+	html := renderChart(t, NewDataVisualization(), jsonPath, map[string]interface{}{
+		"width": float64(1024), "height": float64(300), "theme": "dark",
+	})
+	dark := chartThemes["dark"]
+	for _, want := range []string{
+		`width: 1024px; height: 300px;`,
+		`<canvas id="myChart" width="1024" height="300">`,
+		"background: " + dark.background + "; color: " + dark.text + ";",
+		"borderColor: '" + dark.colors[0] + "'",
+		"grid: { color: '" + dark.grid + "' }",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("chart HTML does not contain %q", want)
+		}
+	}
+
+	// 饼图每个扇区依次使用主题配色
+	jsonPath = prepareChart(t, "browser,share\nChrome,65\nSafari,20\nOther,15\n", map[string]interface{}{"title": "Styled Pie"})
+	html = renderChart(t, NewDataVisualization(), jsonPath, map[string]interface{}{"theme": "pastel"})
+	pastel := chartThemes["pastel"]
+	want := `backgroundColor: ["` + strings.Join(pastel.colors[:3], `","`) + `"]`
+	if !strings.Contains(html, want) {
+		t.Errorf("pie chart HTML does not contain %q", want)
+	}
+
+	result, err := NewDataVisualization().Execute(context.Background(), map[string]interface{}{"json_path": jsonPath, "theme": "neon"})
+	if err != nil || !strings.Contains(result.Error, "unknown theme: neon") {
+		t.Errorf("unknown theme = %+v, %v, want an error", result, err)
+	}
+}
+
+func TestDataVisualizationOfflineInlinesChartJS(t *testing.T) {
+	jsonPath := prepareChart(t, "fruit,sold\napple,30\nbanana,12\n", map[string]interface{}{"title": "Offline Chart"})
+	d := NewDataVisualization()
+
+	// 默认的 chartjs_path 位于工作目录下
+	library := writeWorkspaceFile(t, "assets/chart.umd.min.js", "window.Chart = function () {}; // </script> in a comment")
+	defer os.Remove(library)
+
+	html := renderChart(t, d, jsonPath, map[string]interface{}{"offline": true})
+	if strings.Contains(html, chartJSCDN) {
+		t.Error("offline chart still loads Chart.js from the CDN")
+	}
+	if !strings.Contains(html, "<script>\nwindow.Chart = function () {}; // <\\/script> in a comment\n</script>") {
+		t.Errorf("offline chart does not inline the local Chart.js copy:\n%s", html)
+	}
+
+	if html := renderChart(t, d, jsonPath, nil); !strings.Contains(html, `<script src="`+chartJSCDN+`"></script>`) {
+		t.Error("online chart does not load Chart.js from the CDN")
+	}
+
+	os.Remove(library)
+	result, err := d.Execute(context.Background(), map[string]interface{}{"json_path": jsonPath, "offline": true})
+	if err != nil || !strings.Contains(result.Error, "offline mode requires a local Chart.js copy") {
+		t.Errorf("offline without a local copy = %+v, %v, want an error", result, err)
+	}
+}
@@ -38,6 +38,11 @@ func (d *DuckDuckGoSearch) Parameters() map[string]interface{} {
 				"description": "(optional) The number of search results to return. Default is 10.",
 				"default":     10,
 			},
+			"snippet_length": map[string]interface{}{
+				"type":        "integer",
+				"description": "(optional) Maximum number of characters kept from each result snippet. Default is 200.",
+				"default":     200,
+			},
 		},
 		"required": []string{"query"},
 	}
@@ -53,6 +58,7 @@ func (d *DuckDuckGoSearch) Execute(ctx context.Context, args map[string]interfac
 	if n, ok := args["num_results"].(float64); ok {
 		numResults = int(n)
 	}
+	snippetLength := snippetLengthArg(args)
 
 	results, err := d.Search(ctx, query, numResults)
 	if err != nil {
@@ -69,7 +75,7 @@ func (d *DuckDuckGoSearch) Execute(ctx context.Context, args map[string]interfac
 		output.WriteString(fmt.Sprintf("%d. %s\n", i+1, result.Title))
 		output.WriteString(fmt.Sprintf("   URL: %s\n", result.URL))
 		if result.Snippet != "" {
-			output.WriteString(fmt.Sprintf("   %s\n", result.Snippet))
+			output.WriteString(fmt.Sprintf("   %s\n", truncateSnippet(result.Snippet, snippetLength)))
 		}
 		output.WriteString("\n")
 	}
@@ -86,8 +92,8 @@ func (d *DuckDuckGoSearch) Search(ctx context.Context, query string, numResults
 		return nil, err
 	}
 
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	if err := d.checkStatus(resp); err != nil {
+		return nil, err
 	}
 
 	// Parse DuckDuckGo results
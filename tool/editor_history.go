@@ -0,0 +1,104 @@
+package tool
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultHistoryDir StrReplaceEditor 默认的编辑历史目录，相对于工作目录
+	defaultHistoryDir = ".go-manus-history"
+	// maxHistoryVersions 每个文件最多保留的可撤销版本数
+	maxHistoryVersions = 20
+)
+
+// editHistoryFile 一个文件的编辑历史，保存为 JSON
+type editHistoryFile struct {
+	Path string   `json:"path"`
+	Undo []string `json:"undo"`
+	Redo []string `json:"redo"`
+}
+
+// SetHistoryDir 设置编辑历史的保存目录（相对于工作目录），为空时只保存在内存中
+func (s *StrReplaceEditor) SetHistoryDir(dir string) {
+	s.historyDir = ""
+	if dir != "" {
+		s.historyDir = workspaceSubdir(dir)
+	}
+	s.historyLoaded = make(map[string]bool)
+}
+
+// historyFile 返回文件对应的历史文件路径，以路径的哈希命名
+func (s *StrReplaceEditor) historyFile(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return filepath.Join(s.historyDir, hex.EncodeToString(sum[:16])+".json")
+}
+
+// loadHistory 首次访问某个文件时从磁盘读取它的编辑历史
+func (s *StrReplaceEditor) loadHistory(path string) {
+	if s.historyDir == "" || s.historyLoaded[path] {
+		return
+	}
+	s.historyLoaded[path] = true
+
+	data, err := os.ReadFile(s.historyFile(path))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logrus.Warnf("Failed to read edit history for %s: %v", path, err)
+		}
+		return
+	}
+	var saved editHistoryFile
+	if err := json.Unmarshal(data, &saved); err != nil || saved.Path != path {
+		logrus.Warnf("Ignoring invalid edit history for %s", path)
+		return
+	}
+	s.fileHistory[path] = saved.Undo
+	s.redoHistory[path] = saved.Redo
+}
+
+// saveHistory 保存文件的编辑历史，只保留最近 maxHistoryVersions 个版本；
+// 保存失败只记录日志，不影响编辑本身
+func (s *StrReplaceEditor) saveHistory(path string) {
+	for _, history := range []map[string][]string{s.fileHistory, s.redoHistory} {
+		if versions := history[path]; len(versions) > maxHistoryVersions {
+			history[path] = versions[len(versions)-maxHistoryVersions:]
+		}
+	}
+	if s.historyDir == "" {
+		return
+	}
+
+	file := s.historyFile(path)
+	if len(s.fileHistory[path]) == 0 && len(s.redoHistory[path]) == 0 {
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logrus.Warnf("Failed to remove edit history for %s: %v", path, err)
+		}
+		return
+	}
+
+	data, err := json.Marshal(editHistoryFile{Path: path, Undo: s.fileHistory[path], Redo: s.redoHistory[path]})
+	if err != nil {
+		logrus.Warnf("Failed to encode edit history for %s: %v", path, err)
+		return
+	}
+	if err := os.MkdirAll(s.historyDir, 0755); err != nil {
+		logrus.Warnf("Failed to create edit history directory: %v", err)
+		return
+	}
+	// 先写临时文件再重命名，避免进程中途退出留下损坏的历史
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		logrus.Warnf("Failed to save edit history for %s: %v", path, err)
+		return
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		logrus.Warnf("Failed to save edit history for %s: %v", path, err)
+	}
+}
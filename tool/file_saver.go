@@ -30,7 +30,7 @@ func (f *FileSaver) Parameters() map[string]interface{} {
 			},
 			"file_path": map[string]interface{}{
 				"type":        "string",
-				"description": "(required) The path where the file should be saved, including filename and extension.",
+				"description": "(required) The path where the file should be saved, including filename and extension. Relative paths are resolved against the workspace directory; paths outside the workspace are rejected.",
 			},
 			"mode": map[string]interface{}{
 				"type":        "string",
@@ -53,6 +53,10 @@ func (f *FileSaver) Execute(ctx context.Context, args map[string]interface{}) (*
 	if !ok {
 		return &ToolResult{Error: "file_path parameter is required"}, nil
 	}
+	filePath, err := ResolveWorkspacePath(filePath)
+	if err != nil {
+		return &ToolResult{Error: err.Error()}, nil
+	}
 
 	mode := "w"
 	if m, ok := args["mode"].(string); ok && m != "" {
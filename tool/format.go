@@ -0,0 +1,258 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/format"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"go-manus/config"
+)
+
+// Format 代码格式化工具，检查文件是否符合格式规范并可选地应用修改
+type Format struct {
+	// formatters 文件扩展名（不含点）到格式化命令的映射，命令从 stdin 读取源码并输出到 stdout
+	formatters map[string]string
+}
+
+func NewFormat() *Format {
+	return &Format{
+		formatters: config.GetInstance().GetFormatters(),
+	}
+}
+
+func (f *Format) Name() string {
+	return "format"
+}
+
+func (f *Format) Description() string {
+	return `Check whether a workspace file is properly formatted and return a diff of the formatting changes.
+* Go files are formatted with goimports if it is installed, otherwise with gofmt
+* Other languages use the formatter command configured for the file extension in the [format] config section
+* Set "apply" to true to write the formatted content back to the file`
+}
+
+func (f *Format) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "(required) Path of the file to format. Relative paths are resolved against the workspace.",
+			},
+			"apply": map[string]interface{}{
+				"type":        "boolean",
+				"description": "(optional) Write the formatted content back to the file. Default is false.",
+				"default":     false,
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+func (f *Format) Execute(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	path, ok := args["path"].(string)
+	if !ok || path == "" {
+		return &ToolResult{Error: "path parameter is required"}, nil
+	}
+	path, err := ResolveWorkspacePath(path)
+	if err != nil {
+		return &ToolResult{Error: err.Error()}, nil
+	}
+	apply, _ := args["apply"].(bool)
+
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to read file: %v", err)}, nil
+	}
+
+	formatted, err := f.format(ctx, path, original)
+	if err != nil {
+		return &ToolResult{Error: err.Error()}, nil
+	}
+
+	if bytes.Equal(original, formatted) {
+		return &ToolResult{Output: fmt.Sprintf("%s is already formatted", path)}, nil
+	}
+
+	diff := unifiedDiff(filepath.Base(path), string(original), string(formatted))
+	if !apply {
+		return &ToolResult{Output: fmt.Sprintf("%s needs formatting:\n%s", path, diff)}, nil
+	}
+
+	if err := os.WriteFile(path, formatted, 0644); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to write file: %v", err)}, nil
+	}
+	return &ToolResult{Output: fmt.Sprintf("%s has been formatted:\n%s", path, diff)}, nil
+}
+
+// format 根据文件扩展名选择格式化方式
+func (f *Format) format(ctx context.Context, path string, src []byte) ([]byte, error) {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+
+	if command, ok := f.formatters[ext]; ok {
+		return runFormatter(ctx, command, src)
+	}
+
+	if ext == "go" {
+		if _, err := exec.LookPath("goimports"); err == nil {
+			return runFormatter(ctx, "goimports", src)
+		}
+		formatted, err := format.Source(src)
+		if err != nil {
+			return nil, fmt.Errorf("gofmt failed: %v", err)
+		}
+		return formatted, nil
+	}
+
+	return nil, fmt.Errorf("No formatter configured for .%s files", ext)
+}
+
+// runFormatter 执行格式化命令，源码通过 stdin 传入
+func runFormatter(ctx context.Context, command string, src []byte) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(src)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("Formatter %q failed: %s", command, msg)
+	}
+	return stdout.Bytes(), nil
+}
+
+// diffOp 行级差异操作，kind 为 ' '、'-' 或 '+'
+type diffOp struct {
+	kind byte
+	line string
+	a, b int // 操作前在原文件和新文件中的行下标
+}
+
+// unifiedDiff 生成两个文本之间的统一格式差异
+func unifiedDiff(name, a, b string) string {
+	const contextLines = 3
+
+	ops := diffLines(splitDiffLines(a), splitDiffLines(b))
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("--- a/%s\n+++ b/%s\n", name, name))
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		start := i - contextLines
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			j := end
+			for j < len(ops) && ops[j].kind == ' ' {
+				j++
+			}
+			if j == len(ops) || j-end > 2*contextLines {
+				if end+contextLines < j {
+					end += contextLines
+				} else {
+					end = j
+				}
+				break
+			}
+			end = j
+		}
+
+		aCount, bCount := 0, 0
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+		}
+		sb.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", ops[start].a+1, aCount, ops[start].b+1, bCount))
+		for _, op := range ops[start:end] {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+			sb.WriteByte('\n')
+		}
+
+		i = end
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// diffLines 基于最长公共子序列计算行级差异
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+
+	// 文件过大时不计算 LCS，直接视为整体替换
+	if n*m > 4000000 {
+		ops := make([]diffOp, 0, n+m)
+		for i, line := range a {
+			ops = append(ops, diffOp{kind: '-', line: line, a: i})
+		}
+		for j, line := range b {
+			ops = append(ops, diffOp{kind: '+', line: line, a: n, b: j})
+		}
+		return ops
+	}
+
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, n+m)
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			ops = append(ops, diffOp{kind: ' ', line: a[i], a: i, b: j})
+			i++
+			j++
+		case j < m && (i == n || lcs[i][j+1] > lcs[i+1][j]):
+			ops = append(ops, diffOp{kind: '+', line: b[j], a: i, b: j})
+			j++
+		default:
+			ops = append(ops, diffOp{kind: '-', line: a[i], a: i, b: j})
+			i++
+		}
+	}
+	return ops
+}
+
+// splitDiffLines 按行拆分文本，忽略末尾换行
+func splitDiffLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,73 @@
+package tool
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+const unformattedGo = "package demo\nfunc  Add(a,b int)int{\nreturn a+b}\n"
+
+const formattedGo = "package demo\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n"
+
+func TestFormatGoFile(t *testing.T) {
+	path := writeWorkspaceFile(t, "format/demo.go", unformattedGo)
+	f := &Format{}
+
+	check, err := f.Execute(context.Background(), map[string]interface{}{"path": "format/demo.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(check.Output, "needs formatting") || !strings.Contains(check.Output, "+func Add(a, b int) int {") {
+		t.Errorf("check output should report a diff:\n%s%s", check.Output, check.Error)
+	}
+	if data, _ := os.ReadFile(path); string(data) != unformattedGo {
+		t.Errorf("file changed without apply: %q", data)
+	}
+
+	applied, err := f.Execute(context.Background(), map[string]interface{}{"path": "format/demo.go", "apply": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(applied.Output, "has been formatted") {
+		t.Errorf("apply output: %s%s", applied.Output, applied.Error)
+	}
+	if data, _ := os.ReadFile(path); string(data) != formattedGo {
+		t.Errorf("formatted file = %q, want %q", data, formattedGo)
+	}
+
+	again, _ := f.Execute(context.Background(), map[string]interface{}{"path": "format/demo.go"})
+	if !strings.Contains(again.Output, "already formatted") {
+		t.Errorf("second check: %s%s", again.Output, again.Error)
+	}
+}
+
+func TestFormatConfiguredFormatter(t *testing.T) {
+	writeWorkspaceFile(t, "format/notes.txt", "hello\n")
+	f := &Format{formatters: map[string]string{"txt": "tr a-z A-Z"}}
+
+	result, err := f.Execute(context.Background(), map[string]interface{}{"path": "format/notes.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Output, "-hello\n+HELLO") {
+		t.Errorf("configured formatter diff:\n%s%s", result.Output, result.Error)
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	writeWorkspaceFile(t, "format/broken.go", "package demo\nfunc {\n")
+	writeWorkspaceFile(t, "format/data.unknown", "x")
+	f := &Format{}
+
+	for _, path := range []string{"format/broken.go", "format/data.unknown", "format/missing.go"} {
+		result, err := f.Execute(context.Background(), map[string]interface{}{"path": path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Error == "" {
+			t.Errorf("%s: expected an error, got %q", path, result.Output)
+		}
+	}
+}
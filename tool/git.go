@@ -0,0 +1,379 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Git Git 仓库操作工具，限定在工作目录内的仓库中执行
+type Git struct {
+	// AllowDestructive 为 true 时才允许 push、reset --hard 等破坏性操作
+	AllowDestructive bool
+}
+
+func NewGit() *Git {
+	return &Git{}
+}
+
+func (g *Git) Name() string {
+	return "git"
+}
+
+func (g *Git) Description() string {
+	return `Run common git operations on a repository inside the workspace and return cleaned-up output.
+Supported operations: status, diff, add, commit, log, branch, reset, push.
+* status: show the current branch, staged, modified and untracked files
+* diff: show unstaged changes (set "staged" to true for staged changes), optionally limited to "paths"
+* add: stage the given "paths" (all changes if omitted)
+* commit: commit staged changes with "message"
+* log: show recent commits (limit with "max_count")
+* branch: list branches, or create and switch to "branch" if given
+* reset: unstage changes; "hard" resets the working tree and is only allowed when destructive operations are enabled
+* push: push to the remote; only allowed when destructive operations are enabled`
+}
+
+// SelfTest 检查 git 命令是否可用
+func (g *Git) SelfTest(ctx context.Context) error {
+	return lookPathAny("git")
+}
+
+func (g *Git) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"operation": map[string]interface{}{
+				"type":        "string",
+				"description": "(required) The git operation to perform.",
+				"enum":        []string{"status", "diff", "add", "commit", "log", "branch", "reset", "push"},
+			},
+			"repo_path": map[string]interface{}{
+				"type":        "string",
+				"description": "(optional) Repository path relative to the workspace. Defaults to the workspace root.",
+			},
+			"paths": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "(optional) File paths for add and diff operations.",
+			},
+			"message": map[string]interface{}{
+				"type":        "string",
+				"description": "Commit message. Required for commit operation.",
+			},
+			"staged": map[string]interface{}{
+				"type":        "boolean",
+				"description": "(optional) Show staged changes for diff operation.",
+			},
+			"branch": map[string]interface{}{
+				"type":        "string",
+				"description": "(optional) Branch name to create and switch to for branch operation.",
+			},
+			"max_count": map[string]interface{}{
+				"type":        "integer",
+				"description": "(optional) Number of commits to show for log operation. Default is 10.",
+				"default":     10,
+			},
+			"hard": map[string]interface{}{
+				"type":        "boolean",
+				"description": "(optional) Use --hard for reset operation.",
+			},
+		},
+		"required": []string{"operation"},
+	}
+}
+
+func (g *Git) Execute(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	operation, ok := args["operation"].(string)
+	if !ok || operation == "" {
+		return &ToolResult{Error: "operation parameter is required"}, nil
+	}
+
+	repoPath, _ := args["repo_path"].(string)
+	repoDir, err := g.resolveRepo(ctx, repoPath)
+	if err != nil {
+		return &ToolResult{Error: err.Error()}, nil
+	}
+
+	switch operation {
+	case "status":
+		return g.status(ctx, repoDir)
+	case "diff":
+		return g.diff(ctx, repoDir, args)
+	case "add":
+		return g.add(ctx, repoDir, args)
+	case "commit":
+		return g.commit(ctx, repoDir, args)
+	case "log":
+		return g.log(ctx, repoDir, args)
+	case "branch":
+		return g.branch(ctx, repoDir, args)
+	case "reset":
+		return g.reset(ctx, repoDir, args)
+	case "push":
+		return g.push(ctx, repoDir)
+	default:
+		return &ToolResult{Error: fmt.Sprintf("Unknown operation: %s", operation)}, nil
+	}
+}
+
+// resolveRepo 解析仓库路径（跟随符号链接）并确保其位于工作目录内，
+// 且所在仓库的根目录也在工作目录内。工作目录本身位于其他仓库中时（如默认的
+// workspace/ 位于 go-manus 仓库内），git 向上查找会找到外层仓库，这里拒绝这种情况
+func (g *Git) resolveRepo(ctx context.Context, repoPath string) (string, error) {
+	root, err := filepath.Abs(workspaceRoot())
+	if err != nil {
+		return "", fmt.Errorf("Failed to resolve workspace: %v", err)
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("Workspace %s does not exist", root)
+	}
+
+	dir := root
+	if repoPath != "" {
+		if filepath.IsAbs(repoPath) {
+			dir = filepath.Clean(repoPath)
+		} else {
+			dir = filepath.Join(root, repoPath)
+		}
+	}
+
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", fmt.Errorf("Repository path %s does not exist", dir)
+	}
+	if !pathWithin(realRoot, realDir) {
+		return "", fmt.Errorf("Repository path %s is outside the workspace %s", repoPath, root)
+	}
+	if info, err := os.Stat(realDir); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("Repository path %s is not a directory", dir)
+	}
+
+	toplevel, err := g.run(ctx, realDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", fmt.Errorf("Repository path %s is not inside a git repository in the workspace", dir)
+	}
+	realToplevel, err := filepath.EvalSymlinks(toplevel)
+	if err != nil || !pathWithin(realRoot, realToplevel) {
+		return "", fmt.Errorf("Repository path %s belongs to the repository %s, which is outside the workspace %s", dir, toplevel, root)
+	}
+
+	return realDir, nil
+}
+
+// pathWithin 判断 path 是否为 root 或位于 root 之下，两者都应为已解析符号链接的绝对路径
+func pathWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// run 在仓库目录中执行 git 命令。GIT_CEILING_DIRECTORIES 设为工作目录的上级目录，
+// git 不会越过工作目录向上查找仓库
+func (g *Git) run(ctx context.Context, dir string, gitArgs ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", gitArgs...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if root, err := filepath.Abs(workspaceRoot()); err == nil {
+		if realRoot, err := filepath.EvalSymlinks(root); err == nil {
+			cmd.Env = append(cmd.Env, "GIT_CEILING_DIRECTORIES="+filepath.Dir(realRoot))
+		}
+	}
+	out, err := cmd.CombinedOutput()
+	output := strings.TrimRight(string(out), "\n")
+	if err != nil {
+		if output == "" {
+			return "", fmt.Errorf("git %s failed: %v", gitArgs[0], err)
+		}
+		return "", fmt.Errorf("git %s failed: %s", gitArgs[0], output)
+	}
+	return output, nil
+}
+
+func (g *Git) status(ctx context.Context, dir string) (*ToolResult, error) {
+	out, err := g.run(ctx, dir, "status", "--porcelain=v1", "--branch")
+	if err != nil {
+		return &ToolResult{Error: err.Error()}, nil
+	}
+
+	branch := ""
+	var staged, modified, untracked []string
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "## ") {
+			branch = strings.TrimPrefix(line, "## ")
+			continue
+		}
+		if len(line) < 4 {
+			continue
+		}
+		x, y, file := line[0], line[1], line[3:]
+		if x == '?' && y == '?' {
+			untracked = append(untracked, file)
+			continue
+		}
+		if x != ' ' {
+			staged = append(staged, fmt.Sprintf("%c %s", x, file))
+		}
+		if y != ' ' {
+			modified = append(modified, fmt.Sprintf("%c %s", y, file))
+		}
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Branch: %s\n", branch))
+	if len(staged) == 0 && len(modified) == 0 && len(untracked) == 0 {
+		output.WriteString("Working tree clean")
+		return &ToolResult{Output: output.String()}, nil
+	}
+	writeGitSection(&output, "Staged", staged)
+	writeGitSection(&output, "Modified", modified)
+	writeGitSection(&output, "Untracked", untracked)
+
+	return &ToolResult{Output: strings.TrimRight(output.String(), "\n")}, nil
+}
+
+func writeGitSection(output *strings.Builder, title string, files []string) {
+	if len(files) == 0 {
+		return
+	}
+	output.WriteString(fmt.Sprintf("%s (%d):\n", title, len(files)))
+	for _, f := range files {
+		output.WriteString("  " + f + "\n")
+	}
+}
+
+func (g *Git) diff(ctx context.Context, dir string, args map[string]interface{}) (*ToolResult, error) {
+	gitArgs := []string{"diff", "--no-color"}
+	if staged, ok := args["staged"].(bool); ok && staged {
+		gitArgs = append(gitArgs, "--cached")
+	}
+	if paths := stringSliceArg(args["paths"]); len(paths) > 0 {
+		gitArgs = append(gitArgs, "--")
+		gitArgs = append(gitArgs, paths...)
+	}
+
+	out, err := g.run(ctx, dir, gitArgs...)
+	if err != nil {
+		return &ToolResult{Error: err.Error()}, nil
+	}
+	if out == "" {
+		return &ToolResult{Output: "No changes"}, nil
+	}
+
+	const maxLength = 16000
+	if len(out) > maxLength {
+		out = out[:maxLength] + "\n<diff clipped>"
+	}
+	return &ToolResult{Output: out}, nil
+}
+
+func (g *Git) add(ctx context.Context, dir string, args map[string]interface{}) (*ToolResult, error) {
+	paths := stringSliceArg(args["paths"])
+	gitArgs := []string{"add"}
+	if len(paths) == 0 {
+		gitArgs = append(gitArgs, "--all")
+	} else {
+		gitArgs = append(gitArgs, "--")
+		gitArgs = append(gitArgs, paths...)
+	}
+
+	if _, err := g.run(ctx, dir, gitArgs...); err != nil {
+		return &ToolResult{Error: err.Error()}, nil
+	}
+	if len(paths) == 0 {
+		return &ToolResult{Output: "Staged all changes"}, nil
+	}
+	return &ToolResult{Output: fmt.Sprintf("Staged: %s", strings.Join(paths, ", "))}, nil
+}
+
+func (g *Git) commit(ctx context.Context, dir string, args map[string]interface{}) (*ToolResult, error) {
+	message, _ := args["message"].(string)
+	if strings.TrimSpace(message) == "" {
+		return &ToolResult{Error: "message is required for commit operation"}, nil
+	}
+
+	out, err := g.run(ctx, dir, "commit", "-m", message)
+	if err != nil {
+		return &ToolResult{Error: err.Error()}, nil
+	}
+	return &ToolResult{Output: out}, nil
+}
+
+func (g *Git) log(ctx context.Context, dir string, args map[string]interface{}) (*ToolResult, error) {
+	maxCount := 10
+	if n, ok := args["max_count"].(float64); ok && n > 0 {
+		maxCount = int(n)
+	}
+
+	out, err := g.run(ctx, dir, "log", fmt.Sprintf("--max-count=%d", maxCount), "--date=short", "--pretty=format:%h %ad %an: %s")
+	if err != nil {
+		return &ToolResult{Error: err.Error()}, nil
+	}
+	if out == "" {
+		return &ToolResult{Output: "No commits yet"}, nil
+	}
+	return &ToolResult{Output: out}, nil
+}
+
+func (g *Git) branch(ctx context.Context, dir string, args map[string]interface{}) (*ToolResult, error) {
+	if name, ok := args["branch"].(string); ok && name != "" {
+		if strings.HasPrefix(name, "-") {
+			return &ToolResult{Error: fmt.Sprintf("Invalid branch name %q: branch names must not start with '-'", name)}, nil
+		}
+		if _, err := g.run(ctx, dir, "checkout", "-b", name); err != nil {
+			return &ToolResult{Error: err.Error()}, nil
+		}
+		return &ToolResult{Output: fmt.Sprintf("Created and switched to branch %s", name)}, nil
+	}
+
+	out, err := g.run(ctx, dir, "branch", "--list", "--no-color")
+	if err != nil {
+		return &ToolResult{Error: err.Error()}, nil
+	}
+	if out == "" {
+		return &ToolResult{Output: "No branches yet"}, nil
+	}
+	return &ToolResult{Output: out}, nil
+}
+
+func (g *Git) reset(ctx context.Context, dir string, args map[string]interface{}) (*ToolResult, error) {
+	hard, _ := args["hard"].(bool)
+	if hard && !g.AllowDestructive {
+		return &ToolResult{Error: "git reset --hard is a destructive operation and is disabled"}, nil
+	}
+
+	gitArgs := []string{"reset"}
+	if hard {
+		gitArgs = append(gitArgs, "--hard")
+	}
+	if paths := stringSliceArg(args["paths"]); len(paths) > 0 && !hard {
+		gitArgs = append(gitArgs, "--")
+		gitArgs = append(gitArgs, paths...)
+	}
+
+	out, err := g.run(ctx, dir, gitArgs...)
+	if err != nil {
+		return &ToolResult{Error: err.Error()}, nil
+	}
+	if out == "" {
+		out = "Reset completed"
+	}
+	return &ToolResult{Output: out}, nil
+}
+
+func (g *Git) push(ctx context.Context, dir string) (*ToolResult, error) {
+	if !g.AllowDestructive {
+		return &ToolResult{Error: "git push is a destructive operation and is disabled"}, nil
+	}
+
+	out, err := g.run(ctx, dir, "push")
+	if err != nil {
+		return &ToolResult{Error: err.Error()}, nil
+	}
+	if out == "" {
+		out = "Push completed"
+	}
+	return &ToolResult{Output: out}, nil
+}
@@ -0,0 +1,137 @@
+package tool
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// initTestRepo 在工作目录下初始化一个 git 仓库
+func initTestRepo(t *testing.T, name string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	dir := filepath.Join(workspaceRoot(), name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	if out, err := exec.Command("git", "init", "-q", "-b", "main", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	return dir
+}
+
+func runGit(t *testing.T, g *Git, args map[string]interface{}) *ToolResult {
+	t.Helper()
+	result, err := g.Execute(context.Background(), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestGitStatusAddCommit(t *testing.T) {
+	dir := initTestRepo(t, "repo")
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	g := NewGit()
+
+	status := runGit(t, g, map[string]interface{}{"operation": "status", "repo_path": "repo"})
+	if status.Error != "" {
+		t.Fatalf("status failed: %s", status.Error)
+	}
+	if !strings.Contains(status.Output, "Untracked (1):\n  main.go") {
+		t.Errorf("status output does not list main.go as untracked:\n%s", status.Output)
+	}
+
+	if add := runGit(t, g, map[string]interface{}{"operation": "add", "repo_path": "repo"}); add.Error != "" {
+		t.Fatalf("add failed: %s", add.Error)
+	}
+	status = runGit(t, g, map[string]interface{}{"operation": "status", "repo_path": "repo"})
+	if !strings.Contains(status.Output, "Staged (1):\n  A main.go") {
+		t.Errorf("status output does not list main.go as staged:\n%s", status.Output)
+	}
+
+	commit := runGit(t, g, map[string]interface{}{"operation": "commit", "repo_path": "repo", "message": "Add main.go"})
+	if commit.Error != "" {
+		t.Fatalf("commit failed: %s", commit.Error)
+	}
+
+	status = runGit(t, g, map[string]interface{}{"operation": "status", "repo_path": "repo"})
+	if !strings.Contains(status.Output, "Working tree clean") {
+		t.Errorf("status after commit:\n%s", status.Output)
+	}
+	log := runGit(t, g, map[string]interface{}{"operation": "log", "repo_path": "repo"})
+	if !strings.Contains(log.Output, "Test: Add main.go") {
+		t.Errorf("log output:\n%s", log.Output)
+	}
+}
+
+func TestGitDestructiveOperationsDisabled(t *testing.T) {
+	initTestRepo(t, "repo")
+	g := NewGit()
+
+	if push := runGit(t, g, map[string]interface{}{"operation": "push", "repo_path": "repo"}); !strings.Contains(push.Error, "disabled") {
+		t.Errorf("push should be disabled, got %+v", push)
+	}
+	if reset := runGit(t, g, map[string]interface{}{"operation": "reset", "repo_path": "repo", "hard": true}); !strings.Contains(reset.Error, "disabled") {
+		t.Errorf("reset --hard should be disabled, got %+v", reset)
+	}
+	if branch := runGit(t, g, map[string]interface{}{"operation": "branch", "repo_path": "repo", "branch": "--orphan"}); branch.Error == "" {
+		t.Errorf("branch names starting with '-' should be rejected, got %q", branch.Output)
+	}
+}
+
+func TestGitRejectsRepositoriesOutsideWorkspace(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	g := NewGit()
+
+	// 工作目录位于外层仓库中时，不属于工作目录内仓库的子目录会被拒绝
+	parent, err := filepath.Abs(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "init", "-q", parent).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	t.Cleanup(func() { os.RemoveAll(filepath.Join(parent, ".git")) })
+	plain := filepath.Join(workspaceRoot(), "plain")
+	if err := os.MkdirAll(plain, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(plain) })
+	if result := runGit(t, g, map[string]interface{}{"operation": "add", "repo_path": "plain"}); result.Error == "" {
+		t.Errorf("add in a directory of the enclosing repository should be rejected, got %q", result.Output)
+	}
+
+	// 指向工作目录外仓库的符号链接也会被拒绝
+	outside := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", outside).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	link := filepath.Join(workspaceRoot(), "link")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(link) })
+	if result := runGit(t, g, map[string]interface{}{"operation": "status", "repo_path": "link"}); !strings.Contains(result.Error, "outside the workspace") {
+		t.Errorf("symlink to a repository outside the workspace should be rejected, got %+v", result)
+	}
+
+	if result := runGit(t, g, map[string]interface{}{"operation": "status", "repo_path": "../"}); !strings.Contains(result.Error, "outside the workspace") {
+		t.Errorf("relative path outside the workspace should be rejected, got %+v", result)
+	}
+}
@@ -0,0 +1,265 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// goTestMaxFailureLines 每个失败测试保留的输出行数
+	goTestMaxFailureLines = 20
+	// goTestMaxOutputLength 返回结果的最大长度
+	goTestMaxOutputLength = 16000
+)
+
+// GoTest Go 测试运行工具，执行 go test 并返回结构化的通过/失败摘要
+type GoTest struct{}
+
+// goTestPackage 单个包的测试结果
+type goTestPackage struct {
+	Name     string
+	Status   string // ok, FAIL, no test files
+	Elapsed  string
+	Passed   int
+	Skipped  int
+	Failures []goTestFailure
+	Output   []string // 不属于任何测试的输出，例如编译错误
+}
+
+// goTestFailure 失败测试及其输出摘录
+type goTestFailure struct {
+	Name   string
+	Output []string
+}
+
+var (
+	goTestResultRe  = regexp.MustCompile(`^--- (PASS|FAIL|SKIP): (\S+)`)
+	goTestPackageRe = regexp.MustCompile(`^(ok|FAIL|\?)\s+(\S+)\s*(.*)$`)
+)
+
+func NewGoTest() *GoTest {
+	return &GoTest{}
+}
+
+func (g *GoTest) Name() string {
+	return "go_test"
+}
+
+func (g *GoTest) Description() string {
+	return `Run "go test" in a Go module inside the workspace and return a structured pass/fail summary.
+* The summary lists every package with its status, the failing tests and an excerpt of their output
+* Verbose failure output is truncated, use "run" to focus on a single test for full details
+* Compilation errors are reported under the package that failed to build`
+}
+
+// SelfTest 检查 go 命令是否可用
+func (g *GoTest) SelfTest(ctx context.Context) error {
+	return lookPathAny("go")
+}
+
+func (g *GoTest) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"dir": map[string]interface{}{
+				"type":        "string",
+				"description": "(optional) Module directory relative to the workspace. Defaults to the workspace root.",
+			},
+			"packages": map[string]interface{}{
+				"type":        "string",
+				"description": "(optional) Package pattern to test. Default is ./...",
+				"default":     "./...",
+			},
+			"run": map[string]interface{}{
+				"type":        "string",
+				"description": "(optional) Only run tests matching this regular expression (passed to -run).",
+			},
+			"timeout": map[string]interface{}{
+				"type":        "integer",
+				"description": "(optional) Timeout in seconds. Default is 300.",
+				"default":     300,
+			},
+		},
+	}
+}
+
+func (g *GoTest) Execute(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	dir := workspaceRoot()
+	if d, ok := args["dir"].(string); ok && d != "" {
+		resolved, err := ResolveWorkspacePath(d)
+		if err != nil {
+			return &ToolResult{Error: err.Error()}, nil
+		}
+		dir = resolved
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return &ToolResult{Error: fmt.Sprintf("Directory %s does not exist", dir)}, nil
+	}
+
+	packages := "./..."
+	if p, ok := args["packages"].(string); ok && p != "" {
+		packages = p
+	}
+
+	timeout := 300 * time.Second
+	if t, ok := args["timeout"].(float64); ok && t > 0 {
+		timeout = time.Duration(t) * time.Second
+	}
+
+	goArgs := []string{"test", "-v", fmt.Sprintf("-timeout=%s", timeout)}
+	if run, ok := args["run"].(string); ok && run != "" {
+		goArgs = append(goArgs, "-run", run)
+	}
+	goArgs = append(goArgs, packages)
+
+	runCtx, cancel := context.WithTimeout(ctx, timeout+10*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(runCtx, "go", goArgs...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if runCtx.Err() == context.DeadlineExceeded {
+		return &ToolResult{Error: fmt.Sprintf("go test timed out after %s", timeout)}, nil
+	}
+
+	pkgs := parseGoTestOutput(string(out))
+	if len(pkgs) == 0 {
+		// 未能解析出任何包，通常是命令本身执行失败
+		if err != nil {
+			return &ToolResult{Error: fmt.Sprintf("go test failed: %v\n%s", err, truncateGoTestOutput(string(out)))}, nil
+		}
+		return &ToolResult{Output: truncateGoTestOutput(string(out))}, nil
+	}
+
+	return &ToolResult{Output: truncateGoTestOutput(formatGoTestSummary(pkgs))}, nil
+}
+
+// parseGoTestOutput 解析 go test -v 的标准输出
+func parseGoTestOutput(out string) []goTestPackage {
+	var pkgs []goTestPackage
+	current := goTestPackage{}
+	logs := make(map[string][]string)
+	running := ""
+
+	for _, raw := range strings.Split(out, "\n") {
+		line := strings.TrimRight(raw, "\r")
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, "=== PAUSE"):
+			running = ""
+		case strings.HasPrefix(trimmed, "=== "):
+			// === RUN / === CONT / === NAME 切换当前输出所属的测试
+			if fields := strings.Fields(trimmed); len(fields) >= 3 {
+				running = fields[2]
+			}
+		case goTestResultRe.MatchString(trimmed):
+			m := goTestResultRe.FindStringSubmatch(trimmed)
+			switch m[1] {
+			case "PASS":
+				current.Passed++
+			case "SKIP":
+				current.Skipped++
+			case "FAIL":
+				current.Failures = append(current.Failures, goTestFailure{Name: m[2], Output: logs[m[2]]})
+			}
+			delete(logs, m[2])
+			running = ""
+		case trimmed == "PASS" || trimmed == "FAIL":
+			running = ""
+		case goTestPackageRe.MatchString(line):
+			m := goTestPackageRe.FindStringSubmatch(line)
+			current.Name = m[2]
+			switch m[1] {
+			case "ok":
+				current.Status = "ok"
+				current.Elapsed = strings.TrimSpace(m[3])
+			case "?":
+				current.Status = "no test files"
+			default:
+				current.Status = "FAIL"
+				current.Elapsed = strings.TrimSpace(m[3])
+			}
+			pkgs = append(pkgs, current)
+			current = goTestPackage{}
+			logs = make(map[string][]string)
+			running = ""
+		case trimmed == "":
+			// 忽略空行
+		default:
+			if running != "" {
+				logs[running] = append(logs[running], trimmed)
+			} else {
+				current.Output = append(current.Output, line)
+			}
+		}
+	}
+
+	return pkgs
+}
+
+// formatGoTestSummary 将解析结果格式化为摘要文本
+func formatGoTestSummary(pkgs []goTestPackage) string {
+	passedPkgs, failedPkgs := 0, 0
+	for _, p := range pkgs {
+		switch p.Status {
+		case "ok":
+			passedPkgs++
+		case "FAIL":
+			failedPkgs++
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Summary: %d packages, %d passed, %d failed\n", len(pkgs), passedPkgs, failedPkgs))
+
+	for _, p := range pkgs {
+		sb.WriteString("\n")
+		if p.Status == "no test files" {
+			sb.WriteString(fmt.Sprintf("?    %s [no test files]\n", p.Name))
+			continue
+		}
+
+		sb.WriteString(fmt.Sprintf("%-4s %s %s - %d passed, %d failed", p.Status, p.Name, p.Elapsed, p.Passed, len(p.Failures)))
+		if p.Skipped > 0 {
+			sb.WriteString(fmt.Sprintf(", %d skipped", p.Skipped))
+		}
+		sb.WriteString("\n")
+
+		for _, f := range p.Failures {
+			sb.WriteString(fmt.Sprintf("  --- FAIL: %s\n", f.Name))
+			writeGoTestExcerpt(&sb, f.Output)
+		}
+
+		// 包失败但没有失败的测试，通常是编译错误或 panic
+		if p.Status == "FAIL" && len(p.Failures) == 0 {
+			writeGoTestExcerpt(&sb, p.Output)
+		}
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// writeGoTestExcerpt 写入截断后的输出摘录
+func writeGoTestExcerpt(sb *strings.Builder, lines []string) {
+	for i, line := range lines {
+		if i >= goTestMaxFailureLines {
+			sb.WriteString(fmt.Sprintf("      ... (%d more lines)\n", len(lines)-i))
+			break
+		}
+		sb.WriteString("      " + strings.TrimSpace(line) + "\n")
+	}
+}
+
+// truncateGoTestOutput 截断过长的输出
+func truncateGoTestOutput(out string) string {
+	if len(out) > goTestMaxOutputLength {
+		return out[:goTestMaxOutputLength] + "\n<output clipped>"
+	}
+	return out
+}
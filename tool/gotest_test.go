@@ -0,0 +1,111 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGoTestSummarizesTinyModule(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+	dir := filepath.Join(workspaceRoot(), "tinymod")
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	writeWorkspaceFile(t, "tinymod/go.mod", "module tinymod\n\ngo 1.21\n")
+	writeWorkspaceFile(t, "tinymod/calc.go", "package tinymod\n\nfunc Add(a, b int) int { return a + b }\n")
+	writeWorkspaceFile(t, "tinymod/calc_test.go", `package tinymod
+
+import "testing"
+
+func TestAddPasses(t *testing.T) {
+	if Add(1, 2) != 3 {
+		t.Fatal("1 + 2 should be 3")
+	}
+}
+
+func TestAddFails(t *testing.T) {
+	t.Errorf("Add(2, 2) = %d, want 5", Add(2, 2))
+}
+`)
+
+	result, err := NewGoTest().Execute(context.Background(), map[string]interface{}{"dir": "tinymod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != "" {
+		t.Fatalf("unexpected error: %s", result.Error)
+	}
+	for _, want := range []string{
+		"Summary: 1 packages, 0 passed, 1 failed",
+		"FAIL tinymod",
+		"1 passed, 1 failed",
+		"--- FAIL: TestAddFails",
+		"Add(2, 2) = 4, want 5",
+	} {
+		if !strings.Contains(result.Output, want) {
+			t.Errorf("summary does not contain %q:\n%s", want, result.Output)
+		}
+	}
+	if strings.Contains(result.Output, "TestAddPasses") {
+		t.Errorf("passing tests should only be counted:\n%s", result.Output)
+	}
+}
+
+func TestParseGoTestOutput(t *testing.T) {
+	out := `=== RUN   TestOK
+--- PASS: TestOK (0.00s)
+=== RUN   TestSkip
+    a_test.go:9: not today
+--- SKIP: TestSkip (0.00s)
+=== RUN   TestBad
+    a_test.go:12: boom
+--- FAIL: TestBad (0.00s)
+FAIL
+FAIL	example.com/a	0.004s
+?   	example.com/b	[no test files]
+# example.com/c
+c/c.go:3:1: syntax error: unexpected }
+FAIL	example.com/c [build failed]
+`
+	pkgs := parseGoTestOutput(out)
+	if len(pkgs) != 3 {
+		t.Fatalf("parsed %d packages, want 3: %+v", len(pkgs), pkgs)
+	}
+
+	a := pkgs[0]
+	if a.Name != "example.com/a" || a.Status != "FAIL" || a.Passed != 1 || a.Skipped != 1 || len(a.Failures) != 1 {
+		t.Errorf("package a = %+v", a)
+	}
+	if f := a.Failures[0]; f.Name != "TestBad" || len(f.Output) != 1 || f.Output[0] != "a_test.go:12: boom" {
+		t.Errorf("failure = %+v", f)
+	}
+	if pkgs[1].Status != "no test files" {
+		t.Errorf("package b status = %q", pkgs[1].Status)
+	}
+	if c := pkgs[2]; c.Status != "FAIL" || !strings.Contains(strings.Join(c.Output, "\n"), "syntax error") {
+		t.Errorf("package c should keep the build error, got %+v", c)
+	}
+}
+
+func TestGoTestTruncatesVerboseFailures(t *testing.T) {
+	lines := make([]string, goTestMaxFailureLines+5)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i)
+	}
+	summary := formatGoTestSummary([]goTestPackage{{
+		Name:     "example.com/a",
+		Status:   "FAIL",
+		Failures: []goTestFailure{{Name: "TestNoisy", Output: lines}},
+	}})
+	if !strings.Contains(summary, "... (5 more lines)") {
+		t.Errorf("summary should truncate the failure output:\n%s", summary)
+	}
+	if strings.Contains(summary, fmt.Sprintf("line %d", goTestMaxFailureLines)) {
+		t.Errorf("summary includes lines past the limit:\n%s", summary)
+	}
+}
@@ -0,0 +1,218 @@
+package tool
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// outlierSigma 偏离均值超过多少个标准差的值视为异常值
+const outlierSigma = 2.0
+
+// flatTrendRatio 拟合直线在整个区间上的变化量小于值域的该比例时视为平稳
+const flatTrendRatio = 0.1
+
+// insightLabels 洞察报告中的标题和说明文字
+type insightLabels struct {
+	title, noNumeric, count, min, max, mean, median, stddev, trend, slope, outliers, none string
+	increasing, decreasing, flat                                                          string
+}
+
+var (
+	insightLabelsEN = insightLabels{
+		title:      "Data Insights",
+		noNumeric:  "No numeric columns found.",
+		count:      "Values",
+		min:        "Min",
+		max:        "Max",
+		mean:       "Mean",
+		median:     "Median",
+		stddev:     "Std dev",
+		trend:      "Trend",
+		slope:      "slope",
+		outliers:   fmt.Sprintf("Outliers (beyond %g sigma)", outlierSigma),
+		none:       "none",
+		increasing: "increasing",
+		decreasing: "decreasing",
+		flat:       "flat",
+	}
+	insightLabelsZH = insightLabels{
+		title:      "数据洞察",
+		noNumeric:  "未找到数值列。",
+		count:      "数值个数",
+		min:        "最小值",
+		max:        "最大值",
+		mean:       "平均值",
+		median:     "中位数",
+		stddev:     "标准差",
+		trend:      "趋势",
+		slope:      "斜率",
+		outliers:   fmt.Sprintf("异常值（偏离均值超过 %g 个标准差）", outlierSigma),
+		none:       "无",
+		increasing: "上升",
+		decreasing: "下降",
+		flat:       "平稳",
+	}
+)
+
+// columnStats 一个数值列的统计结果
+type columnStats struct {
+	name                           string
+	count                          int
+	min, max, mean, median, stddev float64
+	slope                          float64
+	outliers                       []outlier
+}
+
+// outlier 异常值及其所在行的标签
+type outlier struct {
+	label string
+	value float64
+}
+
+// numericColumnStats 计算每个数值列的统计量；第一行为表头，标签取第一个非数值列
+func numericColumnStats(data [][]string, language string) []columnStats {
+	if len(data) < 2 {
+		return nil
+	}
+	header, rows := data[0], data[1:]
+	isNumeric := func(s string) bool {
+		_, ok := parseNumber(s, language)
+		return ok
+	}
+
+	labelCol := -1
+	for col := range header {
+		if !columnMatches(rows, col, isNumeric) {
+			labelCol = col
+			break
+		}
+	}
+
+	stats := make([]columnStats, 0)
+	for col, name := range header {
+		if col == labelCol || !columnMatches(rows, col, isNumeric) {
+			continue
+		}
+		values := make([]float64, 0, len(rows))
+		labels := make([]string, 0, len(rows))
+		for i, row := range rows {
+			if col >= len(row) {
+				continue
+			}
+			v, ok := parseNumber(row[col], language)
+			if !ok {
+				continue
+			}
+			values = append(values, v)
+			if labelCol >= 0 && labelCol < len(row) && strings.TrimSpace(row[labelCol]) != "" {
+				labels = append(labels, strings.TrimSpace(row[labelCol]))
+			} else {
+				labels = append(labels, "#"+strconv.Itoa(i+1))
+			}
+		}
+		stats = append(stats, computeStats(strings.TrimSpace(name), values, labels))
+	}
+	return stats
+}
+
+// computeStats 计算统计量、最小二乘拟合斜率和异常值，标准差为样本标准差
+func computeStats(name string, values []float64, labels []string) columnStats {
+	s := columnStats{name: name, count: len(values)}
+	if len(values) == 0 {
+		return s
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	s.min, s.max = sorted[0], sorted[len(sorted)-1]
+	if n := len(sorted); n%2 == 1 {
+		s.median = sorted[n/2]
+	} else {
+		s.median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	s.mean = sum / float64(len(values))
+	if len(values) < 2 {
+		return s
+	}
+
+	var squares, sxy, sxx float64
+	meanX := float64(len(values)-1) / 2
+	for i, v := range values {
+		squares += (v - s.mean) * (v - s.mean)
+		dx := float64(i) - meanX
+		sxy += dx * (v - s.mean)
+		sxx += dx * dx
+	}
+	s.stddev = math.Sqrt(squares / float64(len(values)-1))
+	s.slope = sxy / sxx
+
+	if s.stddev > 0 {
+		for i, v := range values {
+			if math.Abs(v-s.mean) > outlierSigma*s.stddev {
+				s.outliers = append(s.outliers, outlier{label: labels[i], value: v})
+			}
+		}
+	}
+	return s
+}
+
+// trend 根据拟合斜率判断整体趋势，变化量相对值域很小时为平稳
+func (s columnStats) trend(labels insightLabels) string {
+	change := s.slope * float64(s.count-1)
+	if s.count < 2 || s.max == s.min || math.Abs(change) < flatTrendRatio*(s.max-s.min) {
+		return labels.flat
+	}
+	if change > 0 {
+		return labels.increasing
+	}
+	return labels.decreasing
+}
+
+// formatStat 保留至多四位小数
+func formatStat(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1e4)/1e4, 'f', -1, 64)
+}
+
+// renderInsights 将统计结果写为 Markdown，language 为 zh 时使用中文标题
+func renderInsights(stats []columnStats, language string) string {
+	labels := insightLabelsEN
+	if language == "zh" {
+		labels = insightLabelsZH
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", labels.title)
+	if len(stats) == 0 {
+		b.WriteString(labels.noNumeric + "\n")
+		return b.String()
+	}
+	for _, s := range stats {
+		fmt.Fprintf(&b, "## %s\n\n", s.name)
+		fmt.Fprintf(&b, "- %s: %d\n", labels.count, s.count)
+		fmt.Fprintf(&b, "- %s: %s\n", labels.min, formatStat(s.min))
+		fmt.Fprintf(&b, "- %s: %s\n", labels.max, formatStat(s.max))
+		fmt.Fprintf(&b, "- %s: %s\n", labels.mean, formatStat(s.mean))
+		fmt.Fprintf(&b, "- %s: %s\n", labels.median, formatStat(s.median))
+		fmt.Fprintf(&b, "- %s: %s\n", labels.stddev, formatStat(s.stddev))
+		fmt.Fprintf(&b, "- %s: %s (%s %s)\n", labels.trend, s.trend(labels), labels.slope, formatStat(s.slope))
+		if len(s.outliers) == 0 {
+			fmt.Fprintf(&b, "- %s: %s\n", labels.outliers, labels.none)
+		} else {
+			parts := make([]string, 0, len(s.outliers))
+			for _, o := range s.outliers {
+				parts = append(parts, fmt.Sprintf("%s (%s)", formatStat(o.value), o.label))
+			}
+			fmt.Fprintf(&b, "- %s: %s\n", labels.outliers, strings.Join(parts, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
@@ -0,0 +1,73 @@
+package tool
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-manus/config"
+)
+
+// testConfig 测试使用的最小配置
+const testConfig = `[llm]
+model = "test-model"
+base_url = "http://127.0.0.1:1/v1"
+api_key = "test"
+`
+
+// TestMain 在临时目录中运行测试，工具写出的文件都位于临时目录下的 workspace 中
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "go-manus-tool-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "config"), 0755); err == nil {
+		err = os.WriteFile(filepath.Join(dir, "config", "config.toml"), []byte(testConfig), 0644)
+	}
+	if err == nil {
+		err = os.Chdir(dir)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	config.SetInteractive(false)
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// writeWorkspaceFile 在工作目录下创建文件，返回其绝对路径
+func writeWorkspaceFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path, err := filepath.Abs(filepath.Join(workspaceRoot(), name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// roundTripFunc 以函数实现 http.RoundTripper
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// stubSearchClient 让搜索引擎的请求都交给 handler 处理，不访问网络
+func stubSearchClient(b *BaseSearch, handler http.HandlerFunc) {
+	b.client = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec.Result(), nil
+	})}
+}
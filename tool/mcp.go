@@ -4,7 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
+
+	"go-manus/logger"
+
+	"github.com/sirupsen/logrus"
 )
 
 // MCPClientTool MCP 客户端工具
@@ -14,8 +19,8 @@ type MCPClientTool struct {
 	parameters   map[string]interface{}
 	serverID     string
 	originalName string
-	// session 用于与 MCP 服务器通信
-	// 这里简化实现，实际需要 JSON-RPC 客户端
+	// clients 所属的客户端集合，执行时从中取得服务器会话
+	clients *MCPClients
 }
 
 func NewMCPClientTool(name, description string, parameters map[string]interface{}, serverID, originalName string) *MCPClientTool {
@@ -40,12 +45,65 @@ func (m *MCPClientTool) Parameters() map[string]interface{} {
 	return m.parameters
 }
 
+// Execute 向服务器发送 tools/call 请求，把返回的内容块转换为 ToolResult
 func (m *MCPClientTool) Execute(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
-	// 这里应该通过 JSON-RPC 调用 MCP 服务器
-	// 简化实现：返回错误提示需要实现 JSON-RPC 客户端
-	return &ToolResult{
-		Error: fmt.Sprintf("MCP tool execution requires JSON-RPC client implementation. Tool: %s (original: %s) on server: %s", m.name, m.originalName, m.serverID),
-	}, nil
+	var session mcpSession
+	if m.clients != nil {
+		session = m.clients.session(m.serverID)
+	}
+	if session == nil {
+		return &ToolResult{Error: fmt.Sprintf("MCP server %s is not connected", m.serverID)}, nil
+	}
+
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	raw, err := session.Call(ctx, "tools/call", map[string]interface{}{
+		"name":      m.originalName,
+		"arguments": args,
+	})
+	if err != nil {
+		return &ToolResult{Error: fmt.Sprintf("MCP tool %s failed: %v", m.originalName, err)}, nil
+	}
+
+	var result mcpCallToolResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Invalid tools/call result from MCP server %s: %v", m.serverID, err)}, nil
+	}
+
+	output := formatMCPContent(result.Content)
+	if result.IsError {
+		if output == "" {
+			output = fmt.Sprintf("MCP tool %s reported an error", m.originalName)
+		}
+		return &ToolResult{Error: output}, nil
+	}
+	return &ToolResult{Output: output}, nil
+}
+
+// formatMCPContent 拼接文本内容块，图片等非文本内容以描述代替
+func formatMCPContent(content []mcpContent) string {
+	parts := make([]string, 0, len(content))
+	for _, block := range content {
+		switch block.Type {
+		case "text":
+			parts = append(parts, block.Text)
+		case "image":
+			parts = append(parts, fmt.Sprintf("[image: %s, %d bytes base64]", block.MimeType, len(block.Data)))
+		case "resource":
+			if block.Resource == nil {
+				continue
+			}
+			if block.Resource.Text != "" {
+				parts = append(parts, fmt.Sprintf("[resource %s]\n%s", block.Resource.URI, block.Resource.Text))
+			} else {
+				parts = append(parts, fmt.Sprintf("[resource %s (%s)]", block.Resource.URI, block.Resource.MimeType))
+			}
+		default:
+			parts = append(parts, fmt.Sprintf("[%s content]", block.Type))
+		}
+	}
+	return strings.Join(parts, "\n")
 }
 
 // MCPClients MCP 客户端集合
@@ -64,66 +122,121 @@ func NewMCPClients() *MCPClients {
 	}
 }
 
-// ConnectSSE 通过 SSE 连接 MCP 服务器
+// ConnectSSE 连接 SSE 方式的 MCP 服务器，握手后注册服务器提供的工具。
+// 事件流意外断开时移除其会话和工具
 func (m *MCPClients) ConnectSSE(ctx context.Context, serverURL, serverID string) error {
+	session, err := startSSESession(ctx, serverID, serverURL, func(s *sseSession) {
+		m.removeSession(serverID, s)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to MCP server %s: %w", serverID, err)
+	}
+
+	if err := m.register(ctx, serverID, session); err != nil {
+		_ = session.Close()
+		return err
+	}
+	return nil
+}
+
+// ConnectStdio 启动 MCP 服务器子进程，通过 stdin/stdout 通信，握手后注册服务器提供的工具。
+// 子进程意外退出时移除其会话和工具
+func (m *MCPClients) ConnectStdio(ctx context.Context, command string, args []string, serverID string) error {
+	session, err := startStdioSession(serverID, command, args, func(s *stdioSession) {
+		m.removeSession(serverID, s)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to MCP server %s: %w", serverID, err)
+	}
+
+	if err := m.register(ctx, serverID, session); err != nil {
+		_ = session.Close()
+		return err
+	}
+	return nil
+}
+
+// register 完成握手并发现工具，成功后保存会话，替换该服务器之前的工具
+func (m *MCPClients) register(ctx context.Context, serverID string, session mcpSession) error {
+	if err := initializeSession(ctx, session); err != nil {
+		return fmt.Errorf("MCP server %s: %w", serverID, err)
+	}
+	infos, err := listSessionTools(ctx, session)
+	if err != nil {
+		return fmt.Errorf("MCP server %s: %w", serverID, err)
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// 这里应该实现 SSE 连接和 JSON-RPC 客户端
-	// 简化实现：只记录连接信息
-	m.sessions[serverID] = map[string]string{
-		"type": "sse",
-		"url":  serverURL,
-	}
-
-	// 模拟工具发现（实际应该通过 list_tools 调用）
-	// 这里返回一个示例工具
-	tool := NewMCPClientTool(
-		fmt.Sprintf("mcp_%s_example", serverID),
-		"Example MCP tool",
-		map[string]interface{}{
-			"type": "object",
-			"properties": map[string]interface{}{},
-		},
-		serverID,
-		"example",
-	)
-
-	m.toolMap[tool.Name()] = tool
-	m.tools = append(m.tools, tool)
-
+	m.sessions[serverID] = session
+	m.removeServerToolsLocked(serverID)
+	for _, info := range infos {
+		parameters := info.InputSchema
+		if parameters == nil {
+			parameters = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		tool := NewMCPClientTool(fmt.Sprintf("mcp_%s_%s", serverID, info.Name), info.Description, parameters, serverID, info.Name)
+		tool.clients = m
+		m.toolMap[tool.Name()] = tool
+		m.tools = append(m.tools, tool)
+	}
+	logger.FromContext(ctx).Infof("Connected to MCP server %s with %d tools", serverID, len(infos))
 	return nil
 }
 
-// ConnectStdio 通过 stdio 连接 MCP 服务器
-func (m *MCPClients) ConnectStdio(ctx context.Context, command string, args []string, serverID string) error {
+// removeSession 会话失效后移除其工具，会话已被替换或断开时不做处理
+func (m *MCPClients) removeSession(serverID string, session mcpSession) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// 这里应该启动子进程并通过 stdio 进行 JSON-RPC 通信
-	// 简化实现：只记录连接信息
-	m.sessions[serverID] = map[string]interface{}{
-		"type":    "stdio",
-		"command": command,
-		"args":    args,
-	}
-
-	// 模拟工具发现
-	tool := NewMCPClientTool(
-		fmt.Sprintf("mcp_%s_example", serverID),
-		"Example MCP tool",
-		map[string]interface{}{
-			"type": "object",
-			"properties": map[string]interface{}{},
-		},
-		serverID,
-		"example",
-	)
-
-	m.toolMap[tool.Name()] = tool
-	m.tools = append(m.tools, tool)
+	if current, ok := m.sessions[serverID].(mcpSession); !ok || current != session {
+		return
+	}
+	delete(m.sessions, serverID)
+	m.removeServerToolsLocked(serverID)
+}
 
-	return nil
+// removeServerToolsLocked 移除服务器的全部工具，调用方需持有写锁
+func (m *MCPClients) removeServerToolsLocked(serverID string) {
+	newTools := make([]*MCPClientTool, 0, len(m.tools))
+	for _, tool := range m.tools {
+		if tool.serverID != serverID {
+			newTools = append(newTools, tool)
+		} else {
+			delete(m.toolMap, tool.Name())
+		}
+	}
+	m.tools = newTools
+}
+
+// session 返回服务器的 JSON-RPC 会话，未连接时返回 nil
+func (m *MCPClients) session(serverID string) mcpSession {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	session, _ := m.sessions[serverID].(mcpSession)
+	return session
+}
+
+// Sessions 返回当前连接的服务器
+func (m *MCPClients) Sessions() map[string]interface{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sessions := make(map[string]interface{}, len(m.sessions))
+	for id, session := range m.sessions {
+		sessions[id] = session
+	}
+	return sessions
+}
+
+// Tools 返回当前可用工具的副本
+func (m *MCPClients) Tools() []*MCPClientTool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]*MCPClientTool(nil), m.tools...)
 }
 
 // ListTools 列出所有可用工具
@@ -137,21 +250,18 @@ func (m *MCPClients) ListTools(ctx context.Context) ([]*MCPClientTool, error) {
 // Disconnect 断开连接
 func (m *MCPClients) Disconnect(serverID string) error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
+	session, _ := m.sessions[serverID].(mcpSession)
 	delete(m.sessions, serverID)
-
 	// 移除该服务器的工具
-	newTools := make([]*MCPClientTool, 0)
-	for _, tool := range m.tools {
-		if tool.serverID != serverID {
-			newTools = append(newTools, tool)
-		} else {
-			delete(m.toolMap, tool.Name())
+	m.removeServerToolsLocked(serverID)
+	m.mu.Unlock()
+
+	// 等待子进程退出可能需要几秒，不持有锁
+	if session != nil {
+		if err := session.Close(); err != nil {
+			logrus.Warnf("Failed to close MCP session %s: %v", serverID, err)
 		}
 	}
-	m.tools = newTools
-
 	return nil
 }
 
@@ -170,6 +280,7 @@ func (m *MCPClients) AddTool(t Tool) {
 		m.mu.Lock()
 		defer m.mu.Unlock()
 
+		mcpTool.clients = m
 		m.toolMap[mcpTool.Name()] = mcpTool
 		m.tools = append(m.tools, mcpTool)
 	}
@@ -0,0 +1,223 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// mcpSession 与单个 MCP 服务器的 JSON-RPC 会话，由 stdio 或 SSE 传输实现
+type mcpSession interface {
+	// Call 发送请求并等待响应，服务器返回的错误为 *jsonrpcError
+	Call(ctx context.Context, method string, params interface{}) (json.RawMessage, error)
+	// Notify 发送不需要响应的通知
+	Notify(method string, params interface{}) error
+	Close() error
+}
+
+// mcpProtocolVersion 客户端支持的 MCP 协议版本
+const mcpProtocolVersion = "2024-11-05"
+
+// jsonrpcRequest JSON-RPC 2.0 请求，ID 为 nil 时是通知
+type jsonrpcRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      *int64      `json:"id,omitempty"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// jsonrpcResponse JSON-RPC 2.0 响应。服务器发来的请求和通知也按此结构解析，此时 Method 非空
+type jsonrpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id"`
+	Method  string          `json:"method,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *jsonrpcError   `json:"error,omitempty"`
+}
+
+// replyToServerRequest 生成对服务器请求的应答：ping 返回空结果，其他方法不支持
+func replyToServerRequest(req *jsonrpcResponse) *jsonrpcResponse {
+	reply := &jsonrpcResponse{JSONRPC: "2.0", ID: req.ID}
+	if req.Method == "ping" {
+		reply.Result = json.RawMessage("{}")
+	} else {
+		reply.Error = &jsonrpcError{Code: -32601, Message: "method not found: " + req.Method}
+	}
+	return reply
+}
+
+// jsonrpcError 服务器返回的错误
+type jsonrpcError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *jsonrpcError) Error() string {
+	if len(e.Data) > 0 {
+		return fmt.Sprintf("MCP server error %d: %s (%s)", e.Code, e.Message, string(e.Data))
+	}
+	return fmt.Sprintf("MCP server error %d: %s", e.Code, e.Message)
+}
+
+// pendingCalls 按请求 ID 把响应交给等待中的调用，供各传输共用
+type pendingCalls struct {
+	mu     sync.Mutex
+	nextID int64
+	calls  map[int64]chan *jsonrpcResponse
+	// err 非 nil 表示会话已失效
+	err error
+}
+
+func newPendingCalls() *pendingCalls {
+	return &pendingCalls{calls: make(map[int64]chan *jsonrpcResponse)}
+}
+
+// call 通过 send 发出请求并等待对应 ID 的响应
+func (p *pendingCalls) call(ctx context.Context, method string, params interface{}, send func(context.Context, interface{}) error) (json.RawMessage, error) {
+	p.mu.Lock()
+	if p.err != nil {
+		err := p.err
+		p.mu.Unlock()
+		return nil, err
+	}
+	p.nextID++
+	id := p.nextID
+	ch := make(chan *jsonrpcResponse, 1)
+	p.calls[id] = ch
+	p.mu.Unlock()
+
+	if err := send(ctx, jsonrpcRequest{JSONRPC: "2.0", ID: &id, Method: method, Params: params}); err != nil {
+		p.remove(id)
+		return nil, err
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return nil, p.failure()
+		}
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+		return resp.Result, nil
+	case <-ctx.Done():
+		p.remove(id)
+		return nil, ctx.Err()
+	}
+}
+
+func (p *pendingCalls) remove(id int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.calls, id)
+}
+
+// deliver 把响应交给等待中的调用，没有对应调用时忽略
+func (p *pendingCalls) deliver(resp *jsonrpcResponse) {
+	if resp.ID == nil {
+		return
+	}
+	p.mu.Lock()
+	ch, ok := p.calls[*resp.ID]
+	delete(p.calls, *resp.ID)
+	p.mu.Unlock()
+	if ok {
+		ch <- resp
+	}
+}
+
+// fail 标记会话失效，所有等待中和之后的调用都返回 err
+func (p *pendingCalls) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+	for id, ch := range p.calls {
+		close(ch)
+		delete(p.calls, id)
+	}
+}
+
+func (p *pendingCalls) failure() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// mcpCallToolResult tools/call 的返回结果
+type mcpCallToolResult struct {
+	Content []mcpContent `json:"content"`
+	IsError bool         `json:"isError"`
+}
+
+// mcpContent 工具返回的内容块：text、image 或 resource
+type mcpContent struct {
+	Type     string       `json:"type"`
+	Text     string       `json:"text,omitempty"`
+	Data     string       `json:"data,omitempty"`
+	MimeType string       `json:"mimeType,omitempty"`
+	Resource *mcpResource `json:"resource,omitempty"`
+}
+
+// mcpResource 嵌入的资源内容
+type mcpResource struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+}
+
+// mcpToolInfo tools/list 返回的工具定义
+type mcpToolInfo struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+// initializeSession 执行 MCP 握手：发送 initialize 请求，随后发送 initialized 通知
+func initializeSession(ctx context.Context, session mcpSession) error {
+	params := map[string]interface{}{
+		"protocolVersion": mcpProtocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo": map[string]interface{}{
+			"name":    "go-manus",
+			"version": "0.1.0",
+		},
+	}
+	if _, err := session.Call(ctx, "initialize", params); err != nil {
+		return fmt.Errorf("initialize failed: %w", err)
+	}
+	if err := session.Notify("notifications/initialized", nil); err != nil {
+		return fmt.Errorf("initialized notification failed: %w", err)
+	}
+	return nil
+}
+
+// listSessionTools 调用 tools/list 获取服务器提供的全部工具，处理分页
+func listSessionTools(ctx context.Context, session mcpSession) ([]mcpToolInfo, error) {
+	tools := make([]mcpToolInfo, 0)
+	cursor := ""
+	for {
+		params := map[string]interface{}{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		raw, err := session.Call(ctx, "tools/list", params)
+		if err != nil {
+			return nil, fmt.Errorf("tools/list failed: %w", err)
+		}
+
+		var page struct {
+			Tools      []mcpToolInfo `json:"tools"`
+			NextCursor string        `json:"nextCursor"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return nil, fmt.Errorf("invalid tools/list result: %w", err)
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
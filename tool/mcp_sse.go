@@ -0,0 +1,230 @@
+package tool
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sseSession 通过 SSE 接收服务器消息，通过 POST 到 endpoint 事件给出的地址发送请求
+type sseSession struct {
+	serverID string
+	client   *http.Client
+	endpoint string
+	pending  *pendingCalls
+	cancel   context.CancelFunc
+
+	mu      sync.Mutex
+	closing bool
+	// done 在事件流结束后关闭
+	done chan struct{}
+	// onExit 事件流意外断开时调用，主动 Close 时不调用
+	onExit func(*sseSession)
+}
+
+// sseEvent 一条 SSE 事件
+type sseEvent struct {
+	name string
+	data string
+}
+
+// startSSESession 打开事件流并等待服务器通过 endpoint 事件告知请求地址
+func startSSESession(ctx context.Context, serverID, serverURL string, onExit func(*sseSession)) (*sseSession, error) {
+	base, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server URL: %w", err)
+	}
+
+	// 事件流的生命周期不跟随 ctx，ctx 只限制连接和等待 endpoint 的时间
+	streamCtx, cancel := context.WithCancel(context.Background())
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, serverURL, nil)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open event stream: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to open event stream: HTTP %d", resp.StatusCode)
+	}
+
+	events := make(chan sseEvent)
+	go readSSEEvents(resp.Body, events)
+
+	// 放弃连接时断开事件流，并读完剩余事件让读取 goroutine 退出
+	abort := func() {
+		cancel()
+		go func() {
+			for range events {
+			}
+		}()
+	}
+
+	// 等待 endpoint 事件，之前的其他事件忽略
+	var endpoint string
+	for endpoint == "" {
+		event, ok := <-events
+		if !ok {
+			cancel()
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("no endpoint event received: %w", ctx.Err())
+			}
+			return nil, errors.New("event stream closed before the endpoint event")
+		}
+		if event.name != "endpoint" {
+			continue
+		}
+		ref, err := url.Parse(strings.TrimSpace(event.data))
+		if err != nil {
+			abort()
+			return nil, fmt.Errorf("invalid endpoint %q: %w", event.data, err)
+		}
+		endpoint = base.ResolveReference(ref).String()
+	}
+
+	s := &sseSession{
+		serverID: serverID,
+		client:   client,
+		endpoint: endpoint,
+		pending:  newPendingCalls(),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		onExit:   onExit,
+	}
+	go s.readLoop(events)
+	return s, nil
+}
+
+// readSSEEvents 解析事件流，按空行分隔事件，流结束时关闭 events
+func readSSEEvents(body io.ReadCloser, events chan<- sseEvent) {
+	defer close(events)
+	defer body.Close()
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	name := ""
+	data := make([]string, 0)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				if name == "" {
+					name = "message"
+				}
+				events <- sseEvent{name: name, data: strings.Join(data, "\n")}
+			}
+			name = ""
+			data = data[:0]
+		case strings.HasPrefix(line, ":"):
+			// 注释，常用作心跳
+		case strings.HasPrefix(line, "event:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+}
+
+// readLoop 分发 message 事件中的响应，事件流结束后让所有等待中的请求失败
+func (s *sseSession) readLoop(events <-chan sseEvent) {
+	for event := range events {
+		if event.name != "message" {
+			continue
+		}
+		var resp jsonrpcResponse
+		if err := json.Unmarshal([]byte(event.data), &resp); err != nil {
+			logrus.Debugf("MCP %s: ignoring invalid message: %s", s.serverID, event.data)
+			continue
+		}
+		if resp.Method == "" {
+			s.pending.deliver(&resp)
+		} else if resp.ID != nil {
+			// 在单独的 goroutine 中应答，避免阻塞事件读取
+			go func(method string, reply *jsonrpcResponse) {
+				if err := s.post(context.Background(), reply); err != nil {
+					logrus.Debugf("MCP %s: failed to reply to %s: %v", s.serverID, method, err)
+				}
+			}(resp.Method, replyToServerRequest(&resp))
+		}
+	}
+
+	s.pending.fail(errors.New("MCP server closed the event stream"))
+	close(s.done)
+
+	s.mu.Lock()
+	closing := s.closing
+	s.mu.Unlock()
+	if !closing {
+		logrus.Warnf("MCP server %s closed the event stream unexpectedly", s.serverID)
+		if s.onExit != nil {
+			s.onExit(s)
+		}
+	}
+}
+
+// post 把消息 POST 到 endpoint，响应通过事件流返回
+func (s *sseSession) post(ctx context.Context, msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request to MCP server: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("MCP server returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// Call 发送请求并等待事件流中的响应
+func (s *sseSession) Call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	return s.pending.call(ctx, method, params, s.post)
+}
+
+// Notify 发送不需要响应的通知
+func (s *sseSession) Notify(method string, params interface{}) error {
+	return s.post(context.Background(), jsonrpcRequest{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// Close 断开事件流
+func (s *sseSession) Close() error {
+	s.mu.Lock()
+	s.closing = true
+	s.mu.Unlock()
+
+	s.cancel()
+	<-s.done
+	return nil
+}
@@ -0,0 +1,170 @@
+package tool
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// mcpStdioCloseTimeout 关闭 stdin 后等待子进程自行退出的时间，超时后强制结束
+const mcpStdioCloseTimeout = 3 * time.Second
+
+// stdioSession 通过子进程的 stdin/stdout 按行收发 JSON-RPC 消息
+type stdioSession struct {
+	serverID string
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	writeMu  sync.Mutex
+	pending  *pendingCalls
+
+	mu      sync.Mutex
+	closing bool
+	// done 在子进程退出后关闭
+	done chan struct{}
+	// onExit 子进程意外退出时调用，主动 Close 时不调用
+	onExit func(*stdioSession)
+}
+
+// startStdioSession 启动子进程并开始读取其输出
+func startStdioSession(serverID, command string, args []string, onExit func(*stdioSession)) (*stdioSession, error) {
+	cmd := exec.Command(command, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdout: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stderr: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", command, err)
+	}
+
+	s := &stdioSession{
+		serverID: serverID,
+		cmd:      cmd,
+		stdin:    stdin,
+		pending:  newPendingCalls(),
+		done:     make(chan struct{}),
+		onExit:   onExit,
+	}
+	go s.logStderr(stderr)
+	go s.readLoop(stdout)
+	return s, nil
+}
+
+// readLoop 分发响应，stdout 关闭后等待子进程退出并让所有等待中的请求失败
+func (s *stdioSession) readLoop(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var resp jsonrpcResponse
+		if err := json.Unmarshal(line, &resp); err != nil {
+			logrus.Debugf("MCP %s: ignoring non JSON-RPC output: %s", s.serverID, string(line))
+			continue
+		}
+		s.handle(&resp)
+	}
+	if err := scanner.Err(); err != nil {
+		// 无法继续读取时结束子进程，避免请求一直等待
+		logrus.Warnf("MCP %s: failed to read output: %v", s.serverID, err)
+		_ = s.cmd.Process.Kill()
+	}
+
+	exitErr := errors.New("MCP server process exited")
+	if err := s.cmd.Wait(); err != nil {
+		exitErr = fmt.Errorf("MCP server process exited: %w", err)
+	}
+	s.pending.fail(exitErr)
+	close(s.done)
+
+	s.mu.Lock()
+	closing := s.closing
+	s.mu.Unlock()
+	if !closing {
+		logrus.Warnf("MCP server %s exited unexpectedly: %v", s.serverID, exitErr)
+		if s.onExit != nil {
+			s.onExit(s)
+		}
+	}
+}
+
+// handle 把响应交给对应的请求，应答服务器发来的请求，忽略通知
+func (s *stdioSession) handle(resp *jsonrpcResponse) {
+	if resp.Method == "" {
+		s.pending.deliver(resp)
+		return
+	}
+	if resp.ID != nil {
+		if err := s.write(context.Background(), replyToServerRequest(resp)); err != nil {
+			logrus.Debugf("MCP %s: failed to reply to %s: %v", s.serverID, resp.Method, err)
+		}
+	}
+}
+
+func (s *stdioSession) logStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		logrus.Debugf("MCP %s stderr: %s", s.serverID, scanner.Text())
+	}
+}
+
+func (s *stdioSession) write(_ context.Context, msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if _, err := s.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write to MCP server: %w", err)
+	}
+	return nil
+}
+
+// Call 发送请求并等待响应
+func (s *stdioSession) Call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	return s.pending.call(ctx, method, params, s.write)
+}
+
+// Notify 发送不需要响应的通知
+func (s *stdioSession) Notify(method string, params interface{}) error {
+	return s.write(context.Background(), jsonrpcRequest{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// Close 关闭 stdin 让子进程退出，超时后强制结束
+func (s *stdioSession) Close() error {
+	s.mu.Lock()
+	s.closing = true
+	s.mu.Unlock()
+
+	_ = s.stdin.Close()
+	select {
+	case <-s.done:
+		return nil
+	case <-time.After(mcpStdioCloseTimeout):
+	}
+
+	if err := s.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		logrus.Debugf("Failed to kill MCP server %s: %v", s.serverID, err)
+	}
+	<-s.done
+	return nil
+}
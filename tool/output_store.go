@@ -0,0 +1,150 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+const (
+	// outputStoreCapacity 最多保留的完整输出数，超出后丢弃最早的
+	outputStoreCapacity = 50
+	// defaultChunkLength get_more 默认每次返回的字符数
+	defaultChunkLength = 4000
+	maxChunkLength     = 20000
+)
+
+// OutputStore 保存被截断的完整工具输出，get_more 按句柄和偏移量分段读取
+type OutputStore struct {
+	mu      sync.Mutex
+	outputs map[string][]rune
+	order   []string
+	next    int
+}
+
+// NewOutputStore 创建输出存储
+func NewOutputStore() *OutputStore {
+	return &OutputStore{outputs: make(map[string][]rune)}
+}
+
+// Put 保存完整输出并返回句柄
+func (s *OutputStore) Put(content string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.next++
+	handle := fmt.Sprintf("out_%d", s.next)
+	s.outputs[handle] = []rune(content)
+	s.order = append(s.order, handle)
+	if len(s.order) > outputStoreCapacity {
+		delete(s.outputs, s.order[0])
+		s.order = s.order[1:]
+	}
+	return handle
+}
+
+// Get 返回句柄对应的完整输出
+func (s *OutputStore) Get(handle string) ([]rune, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content, ok := s.outputs[handle]
+	return content, ok
+}
+
+// Truncate 输出超过 limit 个字符时保存完整内容，返回前 limit 个字符和读取剩余部分的说明
+func (s *OutputStore) Truncate(content string, limit int) string {
+	runes := []rune(content)
+	if limit <= 0 || len(runes) <= limit {
+		return content
+	}
+	handle := s.Put(content)
+	return string(runes[:limit]) + truncationNotice(handle, limit, len(runes))
+}
+
+// truncationNotice 告诉模型如何读取被截断的部分
+func truncationNotice(handle string, shown, total int) string {
+	return fmt.Sprintf("\n\n[Output truncated: showing %d of %d characters. Call get_more with handle %q and offset %d to read the rest.]", shown, total, handle, shown)
+}
+
+// GetMore 分段读取被截断的工具输出
+type GetMore struct {
+	store *OutputStore
+}
+
+// NewGetMore 创建分段读取工具，store 为所属 Agent 的输出存储
+func NewGetMore(store *OutputStore) *GetMore {
+	return &GetMore{store: store}
+}
+
+func (g *GetMore) Name() string {
+	return "get_more"
+}
+
+func (g *GetMore) Description() string {
+	return `Read more of a tool output that was truncated. Truncated outputs end with a note giving a handle and the offset to continue from.
+Use this instead of running the tool again.`
+}
+
+func (g *GetMore) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"handle": map[string]interface{}{
+				"type":        "string",
+				"description": "(required) Handle from the truncation note, e.g. \"out_3\".",
+			},
+			"offset": map[string]interface{}{
+				"type":        "integer",
+				"description": "(optional) Character offset to start reading from. Default is 0.",
+				"default":     0,
+			},
+			"length": map[string]interface{}{
+				"type":        "integer",
+				"description": "(optional) Number of characters to return. Default is 4000, maximum 20000.",
+				"default":     defaultChunkLength,
+			},
+		},
+		"required": []string{"handle"},
+	}
+}
+
+func (g *GetMore) Execute(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	handle, _ := args["handle"].(string)
+	if handle == "" {
+		return &ToolResult{Error: "handle is required"}, nil
+	}
+	content, ok := g.store.Get(handle)
+	if !ok {
+		return &ToolResult{Error: fmt.Sprintf("Unknown handle %q, the output may have expired", handle)}, nil
+	}
+
+	offset := 0
+	if n, ok := args["offset"].(float64); ok && n > 0 {
+		offset = int(n)
+	}
+	length := defaultChunkLength
+	if n, ok := args["length"].(float64); ok && n > 0 {
+		length = int(n)
+	}
+	if length > maxChunkLength {
+		length = maxChunkLength
+	}
+
+	total := len(content)
+	if offset >= total {
+		return &ToolResult{Error: fmt.Sprintf("Offset %d is past the end of the output (%d characters)", offset, total)}, nil
+	}
+	end := offset + length
+	if end > total {
+		end = total
+	}
+
+	output := string(content[offset:end])
+	if end < total {
+		output += fmt.Sprintf("\n\n[Characters %d-%d of %d. Call get_more with handle %q and offset %d to continue.]", offset, end, total, handle, end)
+	} else {
+		output += fmt.Sprintf("\n\n[Characters %d-%d of %d, end of output.]", offset, end, total)
+	}
+	return &ToolResult{Output: output}, nil
+}
@@ -0,0 +1,60 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestGetMoreReadsTruncatedOutputByHandle(t *testing.T) {
+	var full strings.Builder
+	for i := 0; full.Len() < 30000; i++ {
+		fmt.Fprintf(&full, "line %d: données ✓\n", i)
+	}
+	content := full.String()
+	total := len([]rune(content))
+
+	store := NewOutputStore()
+	truncated := store.Truncate(content, 1000)
+	notice := regexp.MustCompile(`handle "(out_\d+)" and offset (\d+)`).FindStringSubmatch(truncated)
+	if notice == nil {
+		t.Fatalf("truncated output has no handle:\n%s", truncated[len(truncated)-200:])
+	}
+	handle := notice[1]
+	if !strings.HasPrefix(content, strings.SplitN(truncated, "\n\n[Output truncated", 2)[0]) || notice[2] != "1000" {
+		t.Fatalf("truncated output does not start with the first 1000 characters and offset 1000")
+	}
+
+	// 从截断处开始逐段读取，拼接后与完整输出一致
+	more := NewGetMore(store)
+	chunk := regexp.MustCompile(`(?s)^(.*)\n\n\[Characters (\d+)-(\d+) of (\d+)`)
+	rebuilt := string([]rune(content)[:1000])
+	for offset := 1000; offset < total; {
+		result, err := more.Execute(context.Background(), map[string]interface{}{"handle": handle, "offset": float64(offset), "length": float64(7000)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := chunk.FindStringSubmatch(result.Output)
+		if result.Error != "" || m == nil {
+			t.Fatalf("get_more at offset %d = %+v", offset, result)
+		}
+		if m[2] != fmt.Sprint(offset) || m[4] != fmt.Sprint(total) {
+			t.Fatalf("chunk header %q, want offset %d of %d", m[0][len(m[1]):], offset, total)
+		}
+		rebuilt += m[1]
+		fmt.Sscan(m[3], &offset)
+	}
+	if rebuilt != content {
+		t.Error("the chunks read by get_more do not add up to the full output")
+	}
+
+	result, err := more.Execute(context.Background(), map[string]interface{}{"handle": "out_999"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Error, "Unknown handle") {
+		t.Errorf("unknown handle result = %+v", result)
+	}
+}
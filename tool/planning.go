@@ -6,8 +6,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
+
+	"go-manus/config"
 )
 
 // PlanStepStatus 计划步骤状态
@@ -36,8 +40,56 @@ type PlanStep struct {
 	Status      PlanStepStatus `json:"status"`
 	Result      string         `json:"result,omitempty"`
 	Error       string         `json:"error,omitempty"`
+	Notes       string         `json:"notes,omitempty"`
+	// StartedAt 步骤进入 in_progress 的时间，CompletedAt 步骤完成的时间
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// DependsOn 必须先完成的步骤下标（从 0 开始），为空时没有前置步骤
+	DependsOn []int `json:"depends_on,omitempty"`
+}
+
+// markStatus 更新步骤状态并记录开始和完成时间
+func (s *PlanStep) markStatus(status PlanStepStatus, now time.Time) {
+	switch status {
+	case PlanStepNotStarted:
+		s.StartedAt = nil
+		s.CompletedAt = nil
+	case PlanStepInProgress:
+		if s.StartedAt == nil || s.CompletedAt != nil {
+			s.StartedAt = &now
+		}
+		s.CompletedAt = nil
+	case PlanStepCompleted:
+		if s.Status != PlanStepCompleted || s.CompletedAt == nil {
+			s.CompletedAt = &now
+		}
+	case PlanStepBlocked:
+		s.CompletedAt = nil
+	}
+	s.Status = status
+}
+
+// elapsed 返回步骤耗时，进行中的步骤返回已运行时间，未开始时返回 false
+func (s *PlanStep) elapsed(now time.Time) (time.Duration, bool) {
+	if s.StartedAt == nil {
+		return 0, false
+	}
+	if s.CompletedAt != nil {
+		return s.CompletedAt.Sub(*s.StartedAt), true
+	}
+	return now.Sub(*s.StartedAt), true
 }
 
+// PlanTemplate 计划模板，只保存标题和步骤描述，用于反复创建相似的计划
+type PlanTemplate struct {
+	Name  string   `json:"name"`
+	Title string   `json:"title"`
+	Steps []string `json:"steps"`
+}
+
+// templatesDir 模板保存在计划目录下的子目录中
+const templatesDir = "templates"
+
 // PlanningTool 计划管理工具
 type PlanningTool struct {
 	plans      map[string]*Plan
@@ -49,7 +101,7 @@ type PlanningTool struct {
 func NewPlanningTool() *PlanningTool {
 	pt := &PlanningTool{
 		plans:      make(map[string]*Plan),
-		storageDir: "workspace/plans",
+		storageDir: workspaceSubdir(config.GetInstance().GetPlanning().StorageDir),
 	}
 
 	// 确保存储目录存在
@@ -61,13 +113,32 @@ func NewPlanningTool() *PlanningTool {
 	return pt
 }
 
+// SetStorageDir 设置计划文件保存目录（相对于工作目录），并重新加载该目录下的计划
+func (p *PlanningTool) SetStorageDir(dir string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.storageDir = workspaceSubdir(dir)
+	os.MkdirAll(p.storageDir, 0755)
+	p.plans = make(map[string]*Plan)
+	p.activePlan = ""
+	p.loadPlans()
+}
+
 func (p *PlanningTool) Name() string {
 	return "planning"
 }
 
 func (p *PlanningTool) Description() string {
 	return `A planning tool that allows the agent to create and manage plans for solving complex tasks.
-The tool provides functionality for creating plans, updating plan steps, and tracking progress.`
+The tool provides functionality for creating plans, updating plan steps, and tracking progress.
+Recurring workflows can be reused: "clone" copies a plan under new_plan_id with every step reset to not_started,
+"save_template" stores a plan's title and steps under a template name, "create_from_template" creates a new plan from it,
+and "list_templates" shows the saved templates.
+To grow a plan without losing progress, use "add_steps" to append steps or "insert_step" to insert one at step_index;
+"update" with steps replaces every step and resets their status.
+Steps can depend on other steps: set depends_on with "set_dependencies" (or when using "insert_step"),
+and "get_next" returns the first not_started step whose dependencies are all completed.`
 }
 
 func (p *PlanningTool) Parameters() map[string]interface{} {
@@ -75,7 +146,7 @@ func (p *PlanningTool) Parameters() map[string]interface{} {
 		"type": "object",
 		"properties": map[string]interface{}{
 			"command": map[string]interface{}{
-				"description": "The command to execute. Available commands: create, update, list, get, set_active, mark_step, delete.",
+				"description": "The command to execute. Available commands: create, update, list, get, set_active, mark_step, add_steps, insert_step, set_dependencies, get_next, delete, clone, save_template, create_from_template, list_templates.",
 				"enum": []string{
 					"create",
 					"update",
@@ -83,29 +154,56 @@ func (p *PlanningTool) Parameters() map[string]interface{} {
 					"get",
 					"set_active",
 					"mark_step",
+					"add_steps",
+					"insert_step",
+					"set_dependencies",
+					"get_next",
 					"delete",
+					"clone",
+					"save_template",
+					"create_from_template",
+					"list_templates",
 				},
 				"type": "string",
 			},
 			"plan_id": map[string]interface{}{
-				"description": "Unique identifier for the plan. Required for create, update, set_active, and delete commands. Optional for get and mark_step (uses active plan if not specified).",
+				"description": "Unique identifier for the plan. Required for create, update, set_active, and delete commands. Optional for get, mark_step, add_steps, insert_step, set_dependencies and get_next (uses active plan if not specified).",
+				"type":        "string",
+			},
+			"new_plan_id": map[string]interface{}{
+				"description": "Identifier of the new plan. Required for clone command.",
+				"type":        "string",
+			},
+			"template": map[string]interface{}{
+				"description": "Template name. Required for save_template and create_from_template commands.",
 				"type":        "string",
 			},
 			"title": map[string]interface{}{
-				"description": "Title for the plan. Required for create command, optional for update command.",
+				"description": "Title for the plan. Required for create command, optional for update, clone and create_from_template commands.",
 				"type":        "string",
 			},
 			"steps": map[string]interface{}{
-				"description": "List of plan steps. Required for create command, optional for update command.",
+				"description": "List of plan steps. Required for create and add_steps commands, optional for update command.",
 				"type":        "array",
 				"items": map[string]interface{}{
 					"type": "string",
 				},
 			},
+			"step": map[string]interface{}{
+				"description": "Description of the step to insert. Required for insert_step command.",
+				"type":        "string",
+			},
 			"step_index": map[string]interface{}{
-				"description": "Index of the step to mark (0-based). Required for mark_step command.",
+				"description": "Index of the step to mark (0-based). Required for mark_step command. For insert_step, the position of the new step (0-based); existing steps from that position move down. Required for set_dependencies command.",
 				"type":        "integer",
 			},
+			"depends_on": map[string]interface{}{
+				"description": "Indexes (0-based) of the steps that must be completed before the step can start. Required for set_dependencies command (an empty list removes the dependencies), optional for insert_step command.",
+				"type":        "array",
+				"items": map[string]interface{}{
+					"type": "integer",
+				},
+			},
 			"status": map[string]interface{}{
 				"description": "Status to set for the step. Required for mark_step command.",
 				"enum": []string{
@@ -120,6 +218,10 @@ func (p *PlanningTool) Parameters() map[string]interface{} {
 				"description": "Result or error message for the step. Optional for mark_step command.",
 				"type":        "string",
 			},
+			"notes": map[string]interface{}{
+				"description": "Free-form notes for the step. Optional for mark_step command.",
+				"type":        "string",
+			},
 		},
 		"required": []string{"command"},
 	}
@@ -144,8 +246,24 @@ func (p *PlanningTool) Execute(ctx context.Context, args map[string]interface{})
 		return p.setActivePlan(ctx, args)
 	case "mark_step":
 		return p.markStep(ctx, args)
+	case "add_steps":
+		return p.addSteps(ctx, args)
+	case "insert_step":
+		return p.insertStep(ctx, args)
+	case "set_dependencies":
+		return p.setDependencies(ctx, args)
+	case "get_next":
+		return p.getNextStep(ctx, args)
 	case "delete":
 		return p.deletePlan(ctx, args)
+	case "clone":
+		return p.clonePlan(ctx, args)
+	case "save_template":
+		return p.saveTemplate(ctx, args)
+	case "create_from_template":
+		return p.createFromTemplate(ctx, args)
+	case "list_templates":
+		return p.listTemplates(ctx)
 	default:
 		return &ToolResult{Error: fmt.Sprintf("Unknown command: %s", command)}, nil
 	}
@@ -194,7 +312,9 @@ func (p *PlanningTool) createPlan(ctx context.Context, args map[string]interface
 	}
 
 	p.plans[planID] = plan
-	p.savePlan(plan)
+	if err := p.savePlan(plan); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Plan '%s' created but could not be saved: %v", title, err)}, nil
+	}
 
 	return &ToolResult{
 		Output: fmt.Sprintf("Plan '%s' created successfully with %d steps", title, len(steps)),
@@ -233,7 +353,9 @@ func (p *PlanningTool) updatePlan(ctx context.Context, args map[string]interface
 	}
 
 	plan.UpdatedAt = time.Now()
-	p.savePlan(plan)
+	if err := p.savePlan(plan); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Plan '%s' updated but could not be saved: %v", planID, err)}, nil
+	}
 
 	return &ToolResult{Output: fmt.Sprintf("Plan '%s' updated successfully", planID)}, nil
 }
@@ -261,6 +383,9 @@ func (p *PlanningTool) listPlans(ctx context.Context) (*ToolResult, error) {
 }
 
 func (p *PlanningTool) getPlan(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
 	planID, _ := args["plan_id"].(string)
 	if planID == "" {
 		planID = p.activePlan
@@ -270,9 +395,6 @@ func (p *PlanningTool) getPlan(ctx context.Context, args map[string]interface{})
 		return &ToolResult{Error: "No plan_id provided and no active plan set"}, nil
 	}
 
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-
 	plan, exists := p.plans[planID]
 	if !exists {
 		return &ToolResult{Error: fmt.Sprintf("Plan with ID %s not found", planID)}, nil
@@ -295,6 +417,19 @@ func (p *PlanningTool) getPlan(ctx context.Context, args map[string]interface{})
 		if step.Error != "" {
 			output += fmt.Sprintf("     Error: %s\n", step.Error)
 		}
+		if step.Notes != "" {
+			output += fmt.Sprintf("     Notes: %s\n", step.Notes)
+		}
+		if len(step.DependsOn) > 0 {
+			output += fmt.Sprintf("     Depends on: %s\n", formatStepNumbers(step.DependsOn))
+		}
+		if elapsed, ok := step.elapsed(time.Now()); ok {
+			label := "Elapsed"
+			if step.CompletedAt == nil {
+				label = "Running for"
+			}
+			output += fmt.Sprintf("     %s: %s\n", label, elapsed.Round(time.Second))
+		}
 	}
 
 	return &ToolResult{Output: output}, nil
@@ -318,15 +453,6 @@ func (p *PlanningTool) setActivePlan(ctx context.Context, args map[string]interf
 }
 
 func (p *PlanningTool) markStep(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
-	planID, _ := args["plan_id"].(string)
-	if planID == "" {
-		planID = p.activePlan
-	}
-
-	if planID == "" {
-		return &ToolResult{Error: "No plan_id provided and no active plan set"}, nil
-	}
-
 	stepIndex, ok := args["step_index"].(float64)
 	if !ok {
 		return &ToolResult{Error: "step_index is required for mark_step command"}, nil
@@ -346,6 +472,15 @@ func (p *PlanningTool) markStep(ctx context.Context, args map[string]interface{}
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	planID, _ := args["plan_id"].(string)
+	if planID == "" {
+		planID = p.activePlan
+	}
+
+	if planID == "" {
+		return &ToolResult{Error: "No plan_id provided and no active plan set"}, nil
+	}
+
 	plan, exists := p.plans[planID]
 	if !exists {
 		return &ToolResult{Error: fmt.Sprintf("Plan with ID %s not found", planID)}, nil
@@ -356,20 +491,291 @@ func (p *PlanningTool) markStep(ctx context.Context, args map[string]interface{}
 		return &ToolResult{Error: fmt.Sprintf("Invalid step_index: %d (plan has %d steps)", idx, len(plan.Steps))}, nil
 	}
 
-	plan.Steps[idx].Status = status
+	now := time.Now()
+	plan.Steps[idx].markStatus(status, now)
 
 	if result, ok := args["result"].(string); ok {
 		plan.Steps[idx].Result = result
 	}
+	if notes, ok := args["notes"].(string); ok {
+		plan.Steps[idx].Notes = notes
+	}
 
-	plan.UpdatedAt = time.Now()
-	p.savePlan(plan)
+	plan.UpdatedAt = now
+	if err := p.savePlan(plan); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Step %d marked as %s but the plan could not be saved: %v", idx+1, status, err)}, nil
+	}
 
 	return &ToolResult{
 		Output: fmt.Sprintf("Step %d marked as %s", idx+1, status),
 	}, nil
 }
 
+// addSteps 在计划末尾追加 not_started 的步骤，已有步骤的状态和结果保持不变
+func (p *PlanningTool) addSteps(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	descriptions := stringSliceArg(args["steps"])
+	if len(descriptions) == 0 {
+		return &ToolResult{Error: "steps is required for add_steps command"}, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	plan, errMsg := p.targetPlan(args)
+	if plan == nil {
+		return &ToolResult{Error: errMsg}, nil
+	}
+
+	for _, desc := range descriptions {
+		plan.Steps = append(plan.Steps, PlanStep{Description: desc, Status: PlanStepNotStarted})
+	}
+
+	plan.UpdatedAt = time.Now()
+	if err := p.savePlan(plan); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Steps added to plan '%s' but the plan could not be saved: %v", plan.ID, err)}, nil
+	}
+
+	return &ToolResult{
+		Output: fmt.Sprintf("Added %d steps to plan '%s', which now has %d steps", len(descriptions), plan.ID, len(plan.Steps)),
+	}, nil
+}
+
+// insertStep 在 step_index 处插入一个 not_started 的步骤，原有步骤依次后移
+func (p *PlanningTool) insertStep(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	desc, _ := args["step"].(string)
+	if desc == "" {
+		return &ToolResult{Error: "step is required for insert_step command"}, nil
+	}
+	stepIndex, ok := args["step_index"].(float64)
+	if !ok {
+		return &ToolResult{Error: "step_index is required for insert_step command"}, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	plan, errMsg := p.targetPlan(args)
+	if plan == nil {
+		return &ToolResult{Error: errMsg}, nil
+	}
+
+	idx := int(stepIndex)
+	if idx < 0 || idx > len(plan.Steps) {
+		return &ToolResult{Error: fmt.Sprintf("Invalid step_index: %d (should be within [0, %d])", idx, len(plan.Steps))}, nil
+	}
+	// 新步骤的依赖使用插入前的下标，只能依赖已有的步骤
+	dependsOn, errMsg := dependsOnArg(args["depends_on"], len(plan.Steps), -1)
+	if errMsg != "" {
+		return &ToolResult{Error: errMsg}, nil
+	}
+
+	// 插入位置之后的步骤下标加一，依赖关系随之更新
+	shift := func(deps []int) {
+		for i, dep := range deps {
+			if dep >= idx {
+				deps[i] = dep + 1
+			}
+		}
+	}
+	for i := range plan.Steps {
+		shift(plan.Steps[i].DependsOn)
+	}
+	shift(dependsOn)
+
+	plan.Steps = append(plan.Steps, PlanStep{})
+	copy(plan.Steps[idx+1:], plan.Steps[idx:])
+	plan.Steps[idx] = PlanStep{Description: desc, Status: PlanStepNotStarted, DependsOn: dependsOn}
+
+	plan.UpdatedAt = time.Now()
+	if err := p.savePlan(plan); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Step inserted into plan '%s' but the plan could not be saved: %v", plan.ID, err)}, nil
+	}
+
+	return &ToolResult{
+		Output: fmt.Sprintf("Inserted step %d into plan '%s', which now has %d steps", idx+1, plan.ID, len(plan.Steps)),
+	}, nil
+}
+
+// setDependencies 设置步骤的前置步骤，会拒绝越界、依赖自身和循环依赖
+func (p *PlanningTool) setDependencies(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	stepIndex, ok := args["step_index"].(float64)
+	if !ok {
+		return &ToolResult{Error: "step_index is required for set_dependencies command"}, nil
+	}
+	if _, ok := args["depends_on"].([]interface{}); !ok {
+		return &ToolResult{Error: "depends_on is required for set_dependencies command"}, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	plan, errMsg := p.targetPlan(args)
+	if plan == nil {
+		return &ToolResult{Error: errMsg}, nil
+	}
+
+	idx := int(stepIndex)
+	if idx < 0 || idx >= len(plan.Steps) {
+		return &ToolResult{Error: fmt.Sprintf("Invalid step_index: %d (plan has %d steps)", idx, len(plan.Steps))}, nil
+	}
+	dependsOn, errMsg := dependsOnArg(args["depends_on"], len(plan.Steps), idx)
+	if errMsg != "" {
+		return &ToolResult{Error: errMsg}, nil
+	}
+
+	previous := plan.Steps[idx].DependsOn
+	plan.Steps[idx].DependsOn = dependsOn
+	if hasDependencyCycle(plan.Steps) {
+		plan.Steps[idx].DependsOn = previous
+		return &ToolResult{Error: fmt.Sprintf("Step %d cannot depend on %s: that would create a dependency cycle", idx+1, formatStepNumbers(dependsOn))}, nil
+	}
+
+	plan.UpdatedAt = time.Now()
+	if err := p.savePlan(plan); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Dependencies of step %d set but the plan could not be saved: %v", idx+1, err)}, nil
+	}
+
+	if len(dependsOn) == 0 {
+		return &ToolResult{Output: fmt.Sprintf("Step %d no longer depends on other steps", idx+1)}, nil
+	}
+	return &ToolResult{Output: fmt.Sprintf("Step %d now depends on %s", idx+1, formatStepNumbers(dependsOn))}, nil
+}
+
+// getNextStep 返回第一个依赖都已完成的 not_started 步骤；没有依赖的计划中即第一个 not_started 步骤
+func (p *PlanningTool) getNextStep(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	plan, errMsg := p.targetPlan(args)
+	if plan == nil {
+		return &ToolResult{Error: errMsg}, nil
+	}
+
+	idx := plan.NextReadyStep()
+	if idx >= 0 {
+		return &ToolResult{
+			Output: fmt.Sprintf("Next step (step_index %d): %d. %s", idx, idx+1, plan.Steps[idx].Description),
+		}, nil
+	}
+
+	waiting := make([]string, 0)
+	for i, step := range plan.Steps {
+		if step.Status == PlanStepNotStarted {
+			waiting = append(waiting, fmt.Sprintf("%d (waiting for %s)", i+1, formatStepNumbers(step.DependsOn)))
+		}
+	}
+	if len(waiting) == 0 {
+		return &ToolResult{Output: fmt.Sprintf("Plan '%s' has no not_started steps left", plan.ID)}, nil
+	}
+	return &ToolResult{
+		Output: fmt.Sprintf("No step is ready: the remaining steps wait for unfinished dependencies: %s", strings.Join(waiting, ", ")),
+	}, nil
+}
+
+// NextReadyStep 返回第一个依赖都已完成的 not_started 步骤下标，没有时返回 -1
+func (plan *Plan) NextReadyStep() int {
+	for i, step := range plan.Steps {
+		if step.Status == PlanStepNotStarted && plan.DependenciesMet(i) {
+			return i
+		}
+	}
+	return -1
+}
+
+// DependenciesMet 检查第 i 个步骤依赖的步骤是否都已完成
+func (plan *Plan) DependenciesMet(i int) bool {
+	for _, dep := range plan.Steps[i].DependsOn {
+		if dep < 0 || dep >= len(plan.Steps) || plan.Steps[dep].Status != PlanStepCompleted {
+			return false
+		}
+	}
+	return true
+}
+
+// dependsOnArg 解析 depends_on 参数并去重排序；self 为步骤自身的下标，不允许依赖自身（-1 表示不检查）
+func dependsOnArg(raw interface{}, nSteps, self int) ([]int, string) {
+	items, _ := raw.([]interface{})
+	seen := make(map[int]bool)
+	deps := make([]int, 0, len(items))
+	for _, item := range items {
+		f, ok := item.(float64)
+		if !ok {
+			return nil, "depends_on must be a list of step indexes"
+		}
+		dep := int(f)
+		if dep < 0 || dep >= nSteps {
+			return nil, fmt.Sprintf("Invalid depends_on index: %d (plan has %d steps)", dep, nSteps)
+		}
+		if dep == self {
+			return nil, fmt.Sprintf("Step %d cannot depend on itself", self+1)
+		}
+		if !seen[dep] {
+			seen[dep] = true
+			deps = append(deps, dep)
+		}
+	}
+	sort.Ints(deps)
+	if len(deps) == 0 {
+		return nil, ""
+	}
+	return deps, ""
+}
+
+// hasDependencyCycle 检查步骤之间的依赖是否存在环
+func hasDependencyCycle(steps []PlanStep) bool {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(steps))
+	var visit func(i int) bool
+	visit = func(i int) bool {
+		state[i] = visiting
+		for _, dep := range steps[i].DependsOn {
+			if dep < 0 || dep >= len(steps) {
+				continue
+			}
+			if state[dep] == visiting || (state[dep] == unvisited && visit(dep)) {
+				return true
+			}
+		}
+		state[i] = done
+		return false
+	}
+	for i := range steps {
+		if state[i] == unvisited && visit(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatStepNumbers 将步骤下标格式化为从 1 开始的步骤编号，与 get 的输出一致
+func formatStepNumbers(indexes []int) string {
+	numbers := make([]string, len(indexes))
+	for i, idx := range indexes {
+		numbers[i] = fmt.Sprintf("step %d", idx+1)
+	}
+	return strings.Join(numbers, ", ")
+}
+
+// targetPlan 返回 plan_id 指定的计划，未指定时使用活动计划；找不到时返回错误信息。调用方需持有锁
+func (p *PlanningTool) targetPlan(args map[string]interface{}) (*Plan, string) {
+	planID, _ := args["plan_id"].(string)
+	if planID == "" {
+		planID = p.activePlan
+	}
+	if planID == "" {
+		return nil, "No plan_id provided and no active plan set"
+	}
+	plan, exists := p.plans[planID]
+	if !exists {
+		return nil, fmt.Sprintf("Plan with ID %s not found", planID)
+	}
+	return plan, ""
+}
+
 func (p *PlanningTool) deletePlan(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
 	planID, ok := args["plan_id"].(string)
 	if !ok || planID == "" {
@@ -396,6 +802,204 @@ func (p *PlanningTool) deletePlan(ctx context.Context, args map[string]interface
 	return &ToolResult{Output: fmt.Sprintf("Plan '%s' deleted successfully", planID)}, nil
 }
 
+func (p *PlanningTool) clonePlan(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	planID, ok := args["plan_id"].(string)
+	if !ok || planID == "" {
+		return &ToolResult{Error: "plan_id is required for clone command"}, nil
+	}
+	newPlanID, ok := args["new_plan_id"].(string)
+	if !ok || newPlanID == "" {
+		return &ToolResult{Error: "new_plan_id is required for clone command"}, nil
+	}
+	if !validPlanID(planID) || !validPlanID(newPlanID) {
+		return &ToolResult{Error: "plan_id and new_plan_id must not contain path separators"}, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	source, exists := p.plans[planID]
+	if !exists {
+		return &ToolResult{Error: fmt.Sprintf("Plan with ID %s not found", planID)}, nil
+	}
+	if _, exists := p.plans[newPlanID]; exists {
+		return &ToolResult{Error: fmt.Sprintf("Plan with ID %s already exists", newPlanID)}, nil
+	}
+
+	title := source.Title
+	if t, ok := args["title"].(string); ok && t != "" {
+		title = t
+	}
+	descriptions := make([]string, len(source.Steps))
+	for i, step := range source.Steps {
+		descriptions[i] = step.Description
+	}
+
+	plan := newPlan(newPlanID, title, descriptions)
+	for i, step := range source.Steps {
+		plan.Steps[i].DependsOn = append([]int(nil), step.DependsOn...)
+	}
+	p.plans[newPlanID] = plan
+	if err := p.savePlan(plan); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Plan '%s' cloned but could not be saved: %v", newPlanID, err)}, nil
+	}
+
+	return &ToolResult{
+		Output: fmt.Sprintf("Plan '%s' cloned to '%s' with %d steps reset to not_started", planID, newPlanID, len(plan.Steps)),
+	}, nil
+}
+
+func (p *PlanningTool) saveTemplate(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	planID, ok := args["plan_id"].(string)
+	if !ok || planID == "" {
+		return &ToolResult{Error: "plan_id is required for save_template command"}, nil
+	}
+	name, _ := args["template"].(string)
+	if !validTemplateName(name) {
+		return &ToolResult{Error: "template must be a non-empty name without path separators"}, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	plan, exists := p.plans[planID]
+	if !exists {
+		return &ToolResult{Error: fmt.Sprintf("Plan with ID %s not found", planID)}, nil
+	}
+
+	template := PlanTemplate{Name: name, Title: plan.Title, Steps: make([]string, len(plan.Steps))}
+	for i, step := range plan.Steps {
+		template.Steps[i] = step.Description
+	}
+	data, err := json.MarshalIndent(template, "", "  ")
+	if err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to encode template: %v", err)}, nil
+	}
+
+	dir := filepath.Join(p.storageDir, templatesDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to create template directory: %v", err)}, nil
+	}
+	if err := writeFileAtomic(filepath.Join(dir, name+".json"), data, 0644); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to save template: %v", err)}, nil
+	}
+
+	return &ToolResult{Output: fmt.Sprintf("Plan '%s' saved as template '%s' (%d steps)", planID, name, len(template.Steps))}, nil
+}
+
+func (p *PlanningTool) createFromTemplate(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	planID, ok := args["plan_id"].(string)
+	if !ok || planID == "" {
+		return &ToolResult{Error: "plan_id is required for create_from_template command"}, nil
+	}
+	if !validPlanID(planID) {
+		return &ToolResult{Error: "plan_id must not contain path separators"}, nil
+	}
+	name, _ := args["template"].(string)
+	if !validTemplateName(name) {
+		return &ToolResult{Error: "template must be a non-empty name without path separators"}, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, exists := p.plans[planID]; exists {
+		return &ToolResult{Error: fmt.Sprintf("Plan with ID %s already exists", planID)}, nil
+	}
+
+	template, err := p.loadTemplate(name)
+	if err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to load template '%s': %v", name, err)}, nil
+	}
+
+	title := template.Title
+	if t, ok := args["title"].(string); ok && t != "" {
+		title = t
+	}
+
+	plan := newPlan(planID, title, template.Steps)
+	p.plans[planID] = plan
+	if err := p.savePlan(plan); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Plan '%s' created but could not be saved: %v", planID, err)}, nil
+	}
+
+	return &ToolResult{
+		Output: fmt.Sprintf("Plan '%s' created from template '%s' with %d steps", title, name, len(plan.Steps)),
+	}, nil
+}
+
+func (p *PlanningTool) listTemplates(ctx context.Context) (*ToolResult, error) {
+	p.mu.RLock()
+	dir := filepath.Join(p.storageDir, templatesDir)
+	p.mu.RUnlock()
+
+	files, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return &ToolResult{Error: fmt.Sprintf("Failed to read templates: %v", err)}, nil
+	}
+
+	output := ""
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		template, err := p.loadTemplate(strings.TrimSuffix(file.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		output += fmt.Sprintf("- %s: %s [%d steps]\n", template.Name, template.Title, len(template.Steps))
+	}
+	if output == "" {
+		return &ToolResult{Output: "No templates found"}, nil
+	}
+	return &ToolResult{Output: "Available templates:\n" + output}, nil
+}
+
+func (p *PlanningTool) loadTemplate(name string) (*PlanTemplate, error) {
+	data, err := os.ReadFile(filepath.Join(p.storageDir, templatesDir, name+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var template PlanTemplate
+	if err := json.Unmarshal(data, &template); err != nil {
+		return nil, err
+	}
+	if template.Name == "" {
+		template.Name = name
+	}
+	return &template, nil
+}
+
+// validTemplateName 模板名直接用作文件名，不允许包含路径
+func validTemplateName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+// validPlanID 计划 ID 直接用作计划文件名，与模板名一样不允许包含路径
+func validPlanID(id string) bool {
+	return validTemplateName(id)
+}
+
+// newPlan 根据步骤描述创建新计划，所有步骤均为 not_started
+func newPlan(id, title string, descriptions []string) *Plan {
+	steps := make([]PlanStep, len(descriptions))
+	for i, desc := range descriptions {
+		steps[i] = PlanStep{
+			Description: desc,
+			Status:      PlanStepNotStarted,
+		}
+	}
+	now := time.Now()
+	return &Plan{
+		ID:        id,
+		Title:     title,
+		Steps:     steps,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Metadata:  make(map[string]interface{}),
+	}
+}
+
 func (p *PlanningTool) getStatusMark(status PlanStepStatus) string {
 	marks := map[PlanStepStatus]string{
 		PlanStepCompleted:  "[✓]",
@@ -409,13 +1013,40 @@ func (p *PlanningTool) getStatusMark(status PlanStepStatus) string {
 	return "[ ]"
 }
 
+// savePlan 保存计划文件，调用方需持有 p.mu 写锁
 func (p *PlanningTool) savePlan(plan *Plan) error {
 	planFile := filepath.Join(p.storageDir, plan.ID+".json")
 	data, err := json.MarshalIndent(plan, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(planFile, data, 0644)
+	return writeFileAtomic(planFile, data, 0644)
+}
+
+// writeFileAtomic 先写入同目录下的临时文件再重命名，写入中途失败不会损坏原文件
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // 重命名成功后临时文件已不存在，删除失败可忽略
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, path)
 }
 
 func (p *PlanningTool) loadPlans() {
@@ -425,7 +1056,7 @@ func (p *PlanningTool) loadPlans() {
 	}
 
 	for _, file := range files {
-		if file.IsDir() || !filepath.Ext(file.Name()) == ".json" {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
 			continue
 		}
 
@@ -0,0 +1,218 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestPlanningTool 创建计划保存在以测试名命名的目录中的 PlanningTool
+func newTestPlanningTool(t *testing.T) *PlanningTool {
+	t.Helper()
+	p := NewPlanningTool()
+	p.SetStorageDir(filepath.Join("plans", t.Name()))
+	return p
+}
+
+func TestLoadPlansSkipsNonJSONFiles(t *testing.T) {
+	dir := filepath.Join("plans", t.Name())
+	writeWorkspaceFile(t, filepath.Join(dir, "real.json"), `{"id": "real", "title": "Real plan", "steps": []}`)
+	writeWorkspaceFile(t, filepath.Join(dir, "notes.txt"), `{"id": "stray", "title": "Not a plan file", "steps": []}`)
+
+	p := NewPlanningTool()
+	p.SetStorageDir(dir)
+
+	if p.GetPlan("real") == nil {
+		t.Error("the .json plan was not loaded")
+	}
+	if p.GetPlan("stray") != nil {
+		t.Error("the .txt file was loaded as a plan")
+	}
+}
+
+// runPlanning 执行 planning 命令，返回错误结果时测试失败
+func runPlanning(t *testing.T, p *PlanningTool, args map[string]interface{}) string {
+	t.Helper()
+	result, err := p.Execute(context.Background(), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != "" {
+		t.Fatalf("%v: %s", args["command"], result.Error)
+	}
+	return result.Output
+}
+
+func TestConcurrentMarkStepKeepsPlanFileValid(t *testing.T) {
+	p := newTestPlanningTool(t)
+	const n = 20
+	steps := make([]interface{}, n)
+	for i := range steps {
+		steps[i] = fmt.Sprintf("Step %d", i+1)
+	}
+	runPlanning(t, p, map[string]interface{}{"command": "create", "plan_id": "busy", "title": "Busy plan", "steps": steps})
+	planFile := filepath.Join(p.storageDir, "busy.json")
+
+	// 标记步骤的同时反复读取计划文件，任何时刻读到的都应是完整的 JSON
+	done := make(chan struct{})
+	readErrs := make(chan error, 1)
+	go func() {
+		defer close(readErrs)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			data, err := os.ReadFile(planFile)
+			if err == nil && !json.Valid(data) {
+				readErrs <- fmt.Errorf("read an invalid plan file: %q", data)
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, err := p.Execute(context.Background(), map[string]interface{}{
+				"command": "mark_step", "plan_id": "busy", "step_index": float64(i), "status": "completed",
+			})
+			if err != nil || result.Error != "" {
+				t.Errorf("mark_step %d: %v %+v", i, err, result)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(done)
+	if err := <-readErrs; err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(planFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		t.Fatalf("plan file is not valid JSON: %v", err)
+	}
+	for i, step := range plan.Steps {
+		if step.Status != PlanStepCompleted {
+			t.Errorf("step %d is %s in the saved plan, want completed", i+1, step.Status)
+		}
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(p.storageDir, ".busy.json.tmp-*"))
+	if len(leftovers) > 0 {
+		t.Errorf("temporary files left behind: %v", leftovers)
+	}
+}
+
+func TestCloneResetsCompletedPlan(t *testing.T) {
+	p := newTestPlanningTool(t)
+	runPlanning(t, p, map[string]interface{}{
+		"command": "create", "plan_id": "release", "title": "Release",
+		"steps": []interface{}{"Tag", "Build", "Publish"},
+	})
+	for i := 0; i < 3; i++ {
+		runPlanning(t, p, map[string]interface{}{
+			"command": "mark_step", "plan_id": "release", "step_index": float64(i), "status": "completed", "result": "done",
+		})
+	}
+
+	runPlanning(t, p, map[string]interface{}{"command": "clone", "plan_id": "release", "new_plan_id": "release-2"})
+
+	clone := p.GetPlan("release-2")
+	if clone == nil {
+		t.Fatal("clone was not created")
+	}
+	if len(clone.Steps) != 3 || clone.Title != "Release" {
+		t.Fatalf("clone = %+v, want the source's title and 3 steps", clone)
+	}
+	for i, step := range clone.Steps {
+		if step.Status != PlanStepNotStarted || step.Result != "" || step.StartedAt != nil || step.CompletedAt != nil {
+			t.Errorf("clone step %d = %+v, want a fresh not_started step", i+1, step)
+		}
+	}
+	for i, step := range p.GetPlan("release").Steps {
+		if step.Status != PlanStepCompleted {
+			t.Errorf("source step %d is %s after cloning, want completed", i+1, step.Status)
+		}
+	}
+}
+
+func TestCloneAndTemplateRejectPathPlanIDs(t *testing.T) {
+	p := newTestPlanningTool(t)
+	runPlanning(t, p, map[string]interface{}{"command": "create", "plan_id": "base", "title": "Base", "steps": []interface{}{"One"}})
+	runPlanning(t, p, map[string]interface{}{"command": "save_template", "plan_id": "base", "template": "weekly"})
+
+	for _, args := range []map[string]interface{}{
+		{"command": "clone", "plan_id": "base", "new_plan_id": "../escaped"},
+		{"command": "clone", "plan_id": "base", "new_plan_id": ".."},
+		{"command": "clone", "plan_id": "../base", "new_plan_id": "copy"},
+		{"command": "create_from_template", "plan_id": "../../escaped", "template": "weekly"},
+		{"command": "create_from_template", "plan_id": `nested\escaped`, "template": "weekly"},
+	} {
+		result, err := p.Execute(context.Background(), args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(result.Error, "must not contain path separators") {
+			t.Errorf("%v: result = %+v, want the ID rejected", args, result)
+		}
+	}
+
+	escaped, _ := filepath.Glob(filepath.Join(workspaceRoot(), "plans", "*.json"))
+	escaped2, _ := filepath.Glob(filepath.Join(workspaceRoot(), "*.json"))
+	if files := append(escaped, escaped2...); len(files) > 0 {
+		t.Errorf("plan files written outside the storage directory: %v", files)
+	}
+}
+
+func TestMarkStepRecordsTimestampsAndNotes(t *testing.T) {
+	p := newTestPlanningTool(t)
+	runPlanning(t, p, map[string]interface{}{"command": "create", "plan_id": "timed", "title": "Timed", "steps": []interface{}{"Build", "Ship"}})
+	mark := func(status, notes string) {
+		t.Helper()
+		runPlanning(t, p, map[string]interface{}{"command": "mark_step", "plan_id": "timed", "step_index": float64(0), "status": status, "notes": notes})
+	}
+
+	before := time.Now()
+	mark("in_progress", "compiling")
+	step := p.GetPlan("timed").Steps[0]
+	if step.StartedAt == nil || step.StartedAt.Before(before) || step.CompletedAt != nil {
+		t.Fatalf("in_progress step = %+v, want StartedAt set and no CompletedAt", step)
+	}
+	started := *step.StartedAt
+	if out := runPlanning(t, p, map[string]interface{}{"command": "get", "plan_id": "timed"}); !strings.Contains(out, "Running for:") {
+		t.Errorf("get output does not show the running time:\n%s", out)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	mark("completed", "binary in dist/")
+	step = p.GetPlan("timed").Steps[0]
+	if step.StartedAt == nil || !step.StartedAt.Equal(started) {
+		t.Errorf("StartedAt changed on completion: %v, want %v", step.StartedAt, started)
+	}
+	if step.CompletedAt == nil || !step.CompletedAt.After(started) {
+		t.Fatalf("completed step = %+v, want CompletedAt after StartedAt", step)
+	}
+
+	out := runPlanning(t, p, map[string]interface{}{"command": "get", "plan_id": "timed"})
+	for _, want := range []string{"Notes: binary in dist/", "Elapsed: 0s"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("get output does not contain %q:\n%s", want, out)
+		}
+	}
+	if p.GetPlan("timed").Steps[1].StartedAt != nil {
+		t.Error("unmarked step has a StartedAt")
+	}
+}
@@ -0,0 +1,125 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go-manus/schema"
+)
+
+// recallMaxChars 每条消息默认保留的最大字符数
+const recallMaxChars = 500
+
+// Recall 查询 Agent 自身最近的记忆，用于回顾之前的工具输出或对话内容。
+// Memory 没有加锁，Execute 只能在所属 Agent 执行工具调用的 goroutine 中调用
+type Recall struct {
+	memory *schema.Memory
+}
+
+// NewRecall 创建记忆查询工具，memory 为所属 Agent 的记忆
+func NewRecall(memory *schema.Memory) *Recall {
+	return &Recall{memory: memory}
+}
+
+func (r *Recall) Name() string {
+	return "recall"
+}
+
+func (r *Recall) Description() string {
+	return `Look back at your own recent memory, e.g. to re-read what the last bash command printed or what the user originally asked.
+Returns a condensed view of the most recent messages, newest last. Filter by "role" (user, assistant, tool, system) and, for tool results, by "tool_name".`
+}
+
+func (r *Recall) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"role": map[string]interface{}{
+				"type":        "string",
+				"description": "(optional) Only return messages with this role.",
+				"enum":        []string{"user", "assistant", "tool", "system"},
+			},
+			"tool_name": map[string]interface{}{
+				"type":        "string",
+				"description": "(optional) Only return results of this tool, e.g. \"bash\". Implies role \"tool\".",
+			},
+			"count": map[string]interface{}{
+				"type":        "integer",
+				"description": "(optional) Number of matching messages to return. Default is 5.",
+				"default":     5,
+			},
+			"max_chars": map[string]interface{}{
+				"type":        "integer",
+				"description": "(optional) Maximum characters shown per message. Default is 500.",
+				"default":     recallMaxChars,
+			},
+		},
+	}
+}
+
+func (r *Recall) Execute(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	if r.memory == nil {
+		return &ToolResult{Error: "No memory available"}, nil
+	}
+
+	role, _ := args["role"].(string)
+	toolName, _ := args["tool_name"].(string)
+	if toolName != "" {
+		role = string(schema.RoleTool)
+	}
+	count := 5
+	if n, ok := args["count"].(float64); ok && n > 0 {
+		count = int(n)
+	}
+	maxChars := recallMaxChars
+	if n, ok := args["max_chars"].(float64); ok && n > 0 {
+		maxChars = int(n)
+	}
+
+	// 从最新的消息往前找，输出时恢复时间顺序
+	messages := r.memory.Messages
+	matched := make([]int, 0, count)
+	for i := len(messages) - 1; i >= 0 && len(matched) < count; i-- {
+		msg := messages[i]
+		if role != "" && string(msg.Role) != role {
+			continue
+		}
+		if toolName != "" && (msg.Name == nil || *msg.Name != toolName) {
+			continue
+		}
+		matched = append(matched, i)
+	}
+
+	if len(matched) == 0 {
+		return &ToolResult{Output: "No matching messages in memory"}, nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Recalled %d of %d messages (newest last):\n", len(matched), len(messages)))
+	for i := len(matched) - 1; i >= 0; i-- {
+		output.WriteString("\n" + formatRecalledMessage(matched[i], messages[matched[i]], maxChars))
+	}
+	return &ToolResult{Output: output.String()}, nil
+}
+
+// formatRecalledMessage 生成单条消息的简要表示
+func formatRecalledMessage(index int, msg schema.Message, maxChars int) string {
+	label := string(msg.Role)
+	if msg.Name != nil && *msg.Name != "" {
+		label += " (" + *msg.Name + ")"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("[#%d] %s:", index, label))
+	if msg.Content != nil && *msg.Content != "" {
+		sb.WriteString(" " + truncateSnippet(*msg.Content, maxChars))
+	}
+	for _, call := range msg.ToolCalls {
+		sb.WriteString(fmt.Sprintf("\n  -> %s(%s)", call.Function.Name, truncateSnippet(call.Function.Arguments, maxChars)))
+	}
+	if msg.Base64Image != nil && *msg.Base64Image != "" {
+		sb.WriteString(" [image]")
+	}
+	return sb.String()
+}
@@ -0,0 +1,68 @@
+package tool
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go-manus/schema"
+)
+
+// newTestRecall 创建带有一段对话记忆的 recall 工具：两次 bash 调用之间穿插一次 sleep
+func newTestRecall() *Recall {
+	memory := &schema.Memory{MaxMessages: 100}
+	memory.AddMessages([]schema.Message{
+		schema.NewUserMessage("list the files"),
+		schema.NewToolMessage("a.txt\nb.txt", "bash", "call-1"),
+		schema.NewToolMessage("Slept for 1s", "sleep", "call-2"),
+		schema.NewToolMessage("total 8", "bash", "call-3"),
+		schema.NewAssistantMessage("There are two files."),
+	})
+	return NewRecall(memory)
+}
+
+func runRecall(t *testing.T, r *Recall, args map[string]interface{}) string {
+	t.Helper()
+	result, err := r.Execute(context.Background(), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != "" {
+		t.Fatalf("recall failed: %s", result.Error)
+	}
+	return result.Output
+}
+
+func TestRecallReturnsLastToolResult(t *testing.T) {
+	r := newTestRecall()
+
+	output := runRecall(t, r, map[string]interface{}{"role": "tool", "count": float64(1)})
+	if want := "Recalled 1 of 5 messages (newest last):\n\n[#3] tool (bash): total 8"; output != want {
+		t.Errorf("output = %q, want %q", output, want)
+	}
+
+	// tool_name 只返回该工具的结果，按时间顺序输出
+	output = runRecall(t, r, map[string]interface{}{"tool_name": "bash"})
+	if want := "Recalled 2 of 5 messages (newest last):\n\n[#1] tool (bash): a.txt\nb.txt\n[#3] tool (bash): total 8"; output != want {
+		t.Errorf("output = %q, want %q", output, want)
+	}
+
+	output = runRecall(t, r, map[string]interface{}{"tool_name": "sleep", "count": float64(3)})
+	if !strings.Contains(output, "Recalled 1 of 5") || !strings.Contains(output, "[#2] tool (sleep): Slept for 1s") {
+		t.Errorf("output = %q, want only the sleep result", output)
+	}
+
+	if output := runRecall(t, r, map[string]interface{}{"tool_name": "browser_use"}); output != "No matching messages in memory" {
+		t.Errorf("output = %q, want no matches", output)
+	}
+}
+
+func TestRecallTruncatesLongMessages(t *testing.T) {
+	r := NewRecall(&schema.Memory{MaxMessages: 100})
+	r.memory.AddMessage(schema.NewToolMessage(strings.Repeat("x", 100), "bash", "call-1"))
+
+	output := runRecall(t, r, map[string]interface{}{"max_chars": float64(10)})
+	if !strings.Contains(output, "[#0] tool (bash): xxx") || strings.Contains(output, strings.Repeat("x", 11)) {
+		t.Errorf("output = %q, want the message cut to 10 characters", output)
+	}
+}
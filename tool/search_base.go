@@ -1,8 +1,11 @@
 package tool
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -24,6 +27,28 @@ type SearchResult struct {
 	Snippet string
 }
 
+// ErrSearchBlocked 搜索引擎返回了限流或验证码页面，应换用其他引擎而不是当作没有结果
+var ErrSearchBlocked = errors.New("search engine blocked the request (rate limited or captcha)")
+
+// blockPageMarkers 限流或验证码页面中的特征文本（小写）
+var blockPageMarkers = []string{
+	"anomaly-modal",           // DuckDuckGo
+	"bots use duckduckgo too", // DuckDuckGo
+	"b_captcha",               // Bing
+	"captcha",                 // 通用验证码
+	"unusual traffic",         // Google/Bing
+	"too many requests",       // 通用限流
+	"wappass.baidu.com",       // 百度安全验证
+	"百度安全验证",
+}
+
+const (
+	// defaultSnippetLength 未指定 snippet_length 时保留的摘要长度
+	defaultSnippetLength = 200
+	// maxSnippetLength 解析时保留的摘要上限，实际长度在输出时按 snippet_length 截断
+	maxSnippetLength = 2000
+)
+
 // BaseSearch 基础搜索工具
 type BaseSearch struct {
 	client *http.Client
@@ -50,10 +75,40 @@ func (b *BaseSearch) makeRequest(ctx context.Context, searchURL string) (*http.R
 	return b.client.Do(req)
 }
 
+// checkStatus 检查搜索响应的状态码，429 和 202（DuckDuckGo 限流时返回）视为被限流
+func (b *BaseSearch) checkStatus(resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusTooManyRequests, http.StatusAccepted:
+		resp.Body.Close()
+		return fmt.Errorf("%w: HTTP %d", ErrSearchBlocked, resp.StatusCode)
+	default:
+		resp.Body.Close()
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+}
+
+// detectBlockPage 返回页面中命中的限流/验证码特征，未命中时返回空字符串
+func detectBlockPage(body []byte) string {
+	lower := bytes.ToLower(body)
+	for _, marker := range blockPageMarkers {
+		if bytes.Contains(lower, []byte(marker)) {
+			return marker
+		}
+	}
+	return ""
+}
+
 func (b *BaseSearch) parseHTMLResults(resp *http.Response, selector string, maxResults int) ([]SearchResult, error) {
 	defer resp.Body.Close()
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
@@ -73,10 +128,7 @@ func (b *BaseSearch) parseHTMLResults(resp *http.Response, selector string, maxR
 		// Get snippet (next sibling or parent's text)
 		snippet := ""
 		if s.Parent() != nil {
-			snippet = strings.TrimSpace(s.Parent().Text())
-			if len(snippet) > 200 {
-				snippet = snippet[:200] + "..."
-			}
+			snippet = truncateSnippet(strings.TrimSpace(s.Parent().Text()), maxSnippetLength)
 		}
 
 		results = append(results, SearchResult{
@@ -86,5 +138,82 @@ func (b *BaseSearch) parseHTMLResults(resp *http.Response, selector string, maxR
 		})
 	})
 
+	// 正常结果页也可能提到验证码，只在没有解析出结果时检查拦截页特征
+	if len(results) == 0 {
+		if marker := detectBlockPage(body); marker != "" {
+			return nil, fmt.Errorf("%w: page contains %q", ErrSearchBlocked, marker)
+		}
+	}
+
 	return results, nil
 }
+
+// snippetLengthArg 读取 snippet_length 参数，未指定或无效时使用默认长度
+func snippetLengthArg(args map[string]interface{}) int {
+	if n, ok := args["snippet_length"].(float64); ok && n > 0 {
+		return int(n)
+	}
+	return defaultSnippetLength
+}
+
+// truncateSnippet 按字符数截断摘要，避免截断多字节字符
+func truncateSnippet(snippet string, length int) string {
+	runes := []rune(snippet)
+	if len(runes) <= length {
+		return snippet
+	}
+	return string(runes[:length]) + "..."
+}
+
+// trackingParams 规范化 URL 时去掉的跟踪参数
+var trackingParams = map[string]bool{
+	"gclid": true, "dclid": true, "fbclid": true, "msclkid": true, "yclid": true,
+	"mc_cid": true, "mc_eid": true, "ref": true, "ref_src": true, "_ga": true,
+	"igshid": true, "spm": true,
+}
+
+// canonicalURL 生成用于去重的规范 URL：忽略 http/https 差异、主机名大小写、
+// 末尾斜杠、片段和跟踪参数（utm_* 等），其余参数排序
+func canonicalURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return strings.TrimRight(strings.TrimSpace(raw), "/")
+	}
+
+	query := u.Query()
+	for key := range query {
+		lower := strings.ToLower(key)
+		if strings.HasPrefix(lower, "utm_") || trackingParams[lower] {
+			query.Del(key)
+		}
+	}
+
+	host := strings.ToLower(u.Host)
+	host = strings.TrimSuffix(strings.TrimSuffix(host, ":80"), ":443")
+	canonical := host + strings.TrimRight(u.EscapedPath(), "/")
+	if encoded := query.Encode(); encoded != "" {
+		canonical += "?" + encoded
+	}
+	return canonical
+}
+
+// dedupResults 按规范 URL 去重，保留首次出现的位置，摘要取较长的一条
+func dedupResults(results []SearchResult) []SearchResult {
+	deduped := make([]SearchResult, 0, len(results))
+	index := make(map[string]int)
+	for _, result := range results {
+		key := canonicalURL(result.URL)
+		if i, ok := index[key]; ok {
+			if len(result.Snippet) > len(deduped[i].Snippet) {
+				deduped[i].Snippet = result.Snippet
+			}
+			if deduped[i].Title == "" {
+				deduped[i].Title = result.Title
+			}
+			continue
+		}
+		index[key] = len(deduped)
+		deduped = append(deduped, result)
+	}
+	return deduped
+}
@@ -0,0 +1,127 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// duckDuckGoBlockPage DuckDuckGo 限流时以 HTTP 200 返回的验证页面（节选）
+const duckDuckGoBlockPage = `<!DOCTYPE html>
+<html><body>
+<form id="challenge-form" action="//duckduckgo.com/anomaly.js" method="POST">
+  <div class="anomaly-modal__title">Unfortunately, bots use DuckDuckGo too.</div>
+  <div class="anomaly-modal__description">Please complete the following challenge to confirm this search was made by a human.</div>
+</form>
+</body></html>`
+
+func TestSearchDetectsBlockPage(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"captcha page", http.StatusOK, duckDuckGoBlockPage},
+		{"HTTP 429", http.StatusTooManyRequests, "slow down"},
+		{"HTTP 202", http.StatusAccepted, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ddg := NewDuckDuckGoSearch()
+			stubSearchClient(ddg.BaseSearch, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				fmt.Fprint(w, tc.body)
+			})
+			results, err := ddg.Search(context.Background(), "golang", 5)
+			if !errors.Is(err, ErrSearchBlocked) {
+				t.Errorf("Search = %v, %v, want ErrSearchBlocked", results, err)
+			}
+		})
+	}
+}
+
+func TestSearchWithoutResultsIsNotBlocked(t *testing.T) {
+	ddg := NewDuckDuckGoSearch()
+	stubSearchClient(ddg.BaseSearch, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><div class="no-results">No results.</div></body></html>`)
+	})
+	results, err := ddg.Search(context.Background(), "an obscure query", 5)
+	if err != nil || len(results) != 0 {
+		t.Errorf("Search = %v, %v, want no results and no error", results, err)
+	}
+}
+
+func TestWebSearchFailsOverFromBlockedEngine(t *testing.T) {
+	ddg := NewDuckDuckGoSearch()
+	stubSearchClient(ddg.BaseSearch, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, duckDuckGoBlockPage)
+	})
+	bing := &fakeEngine{name: "bing", results: []SearchResult{{Title: "Go", URL: "https://go.dev"}}}
+	ws := newFakeWebSearch(bing)
+	ws.engines["duckduckgo"] = ddg
+
+	result, err := ws.Execute(context.Background(), map[string]interface{}{
+		"query": "golang", "engine": "duckduckgo", "fallback_engines": []interface{}{"bing"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Output, "Primary engine (duckduckgo) failed, but fallback engine (bing) succeeded") {
+		t.Errorf("result = %+v, want a failover to bing", result)
+	}
+	if health := ws.HealthStatus()["duckduckgo"]; health.Healthy || !strings.Contains(health.LastError, "captcha") {
+		t.Errorf("duckduckgo health = %+v, want marked down as blocked", health)
+	}
+}
+
+// snippetLine 返回搜索输出中第一条结果的摘要行
+func snippetLine(t *testing.T, output string) string {
+	t.Helper()
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "URL:") && i+1 < len(lines) {
+			return strings.TrimSpace(lines[i+1])
+		}
+	}
+	t.Fatalf("no result in output:\n%s", output)
+	return ""
+}
+
+func TestSnippetLengthLimitsSnippets(t *testing.T) {
+	// 摘要取链接所在元素的文本，包含多字节字符
+	long := strings.Repeat("搜索结果摘要 snippet text ", 40)
+	ddg := NewDuckDuckGoSearch()
+	stubSearchClient(ddg.BaseSearch, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<html><body><div class="result"><a class="result__a" href="https://go.dev/">Go</a> %s</div></body></html>`, long)
+	})
+
+	for _, tc := range []struct {
+		args map[string]interface{}
+		want int
+	}{
+		{map[string]interface{}{"query": "golang"}, defaultSnippetLength},
+		{map[string]interface{}{"query": "golang", "snippet_length": float64(50)}, 50},
+		{map[string]interface{}{"query": "golang", "snippet_length": float64(600)}, 600},
+	} {
+		result, err := ddg.Execute(context.Background(), tc.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		snippet := snippetLine(t, result.Output)
+		if !strings.HasSuffix(snippet, "...") || len([]rune(strings.TrimSuffix(snippet, "..."))) != tc.want {
+			t.Errorf("snippet_length %v: snippet has %d characters, want %d followed by ...", tc.args["snippet_length"], len([]rune(snippet)), tc.want)
+		}
+	}
+
+	// WebSearch 把 snippet_length 传给引擎的输出
+	ws := newFakeWebSearch(&fakeEngine{name: "google", results: []SearchResult{{Title: "Go", URL: "https://go.dev/", Snippet: long}}})
+	result, err := ws.Execute(context.Background(), map[string]interface{}{"query": "golang", "snippet_length": float64(30)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snippet := snippetLine(t, result.Output); len([]rune(snippet)) != 33 {
+		t.Errorf("web_search snippet = %q, want 30 characters followed by ...", snippet)
+	}
+}
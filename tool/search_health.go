@@ -0,0 +1,128 @@
+package tool
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// EngineHealth 搜索引擎的可用状态
+type EngineHealth struct {
+	Healthy   bool
+	CheckedAt time.Time
+	LastError string
+}
+
+// engineHealthTracker 记录各搜索引擎最近一次的可用状态，不可用状态在 ttl 后过期重新尝试
+type engineHealthTracker struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu     sync.Mutex
+	status map[string]EngineHealth
+}
+
+func newEngineHealthTracker(ttl time.Duration) *engineHealthTracker {
+	return &engineHealthTracker{
+		ttl:    ttl,
+		now:    time.Now,
+		status: make(map[string]EngineHealth),
+	}
+}
+
+// record 根据一次搜索或检查的结果更新状态
+func (t *engineHealthTracker) record(name string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	health := EngineHealth{Healthy: err == nil, CheckedAt: t.now()}
+	if err != nil {
+		health.LastError = err.Error()
+	}
+	t.status[name] = health
+}
+
+// isDown 判断引擎是否已知不可用且状态未过期
+func (t *engineHealthTracker) isDown(name string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	health, ok := t.status[name]
+	if !ok || health.Healthy {
+		return false
+	}
+	return t.now().Sub(health.CheckedAt) < t.ttl
+}
+
+// snapshot 返回当前所有已知状态
+func (t *engineHealthTracker) snapshot() map[string]EngineHealth {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status := make(map[string]EngineHealth, len(t.status))
+	for name, health := range t.status {
+		status[name] = health
+	}
+	return status
+}
+
+// HealthChecker 可由搜索引擎实现的轻量健康检查，未实现时使用一次小查询探测
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// healthCheckTimeout 单个引擎健康检查的超时时间
+const healthCheckTimeout = 10 * time.Second
+
+// CheckHealth 并发检查所有引擎的可用性并返回最新状态
+func (w *WebSearch) CheckHealth(ctx context.Context) map[string]EngineHealth {
+	var wg sync.WaitGroup
+	for name, engine := range w.engines {
+		wg.Add(1)
+		go func(name string, engine SearchEngine) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			var err error
+			if checker, ok := engine.(HealthChecker); ok {
+				err = checker.HealthCheck(checkCtx)
+			} else {
+				_, err = engine.Search(checkCtx, "health check", 1)
+			}
+			w.health.record(name, err)
+		}(name, engine)
+	}
+	wg.Wait()
+	return w.health.snapshot()
+}
+
+// HealthStatus 返回各引擎最近一次搜索或检查得到的状态，未使用过的引擎不在结果中
+func (w *WebSearch) HealthStatus() map[string]EngineHealth {
+	return w.health.snapshot()
+}
+
+// orderEngines 返回实际尝试的引擎顺序：主引擎在前，已知不可用的引擎移到最后，
+// 其他引擎都失败时才尝试
+func (w *WebSearch) orderEngines(primary string, fallbacks []string) []string {
+	candidates := []string{primary}
+	for _, name := range fallbacks {
+		if name == primary {
+			continue
+		}
+		if _, exists := w.engines[name]; exists {
+			candidates = append(candidates, name)
+		}
+	}
+
+	ordered := make([]string, 0, len(candidates))
+	var down []string
+	for _, name := range candidates {
+		if w.health.isDown(name) {
+			down = append(down, name)
+		} else {
+			ordered = append(ordered, name)
+		}
+	}
+	return append(ordered, down...)
+}
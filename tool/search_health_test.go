@@ -0,0 +1,58 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownEngineMovesToBackUntilTTLExpires(t *testing.T) {
+	google := &fakeEngine{name: "google", results: []SearchResult{{Title: "From google", URL: "https://go.dev"}}}
+	bing := &fakeEngine{name: "bing", results: []SearchResult{{Title: "From bing", URL: "https://go.dev"}}}
+	duckduckgo := &fakeEngine{name: "duckduckgo"}
+	ws := newFakeWebSearch(google, bing, duckduckgo)
+	now := time.Now()
+	ws.health.now = func() time.Time { return now }
+
+	ws.health.record("google", errors.New("HTTP 503"))
+	if got, want := ws.orderEngines("google", []string{"bing", "duckduckgo"}), []string{"bing", "duckduckgo", "google"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("order with google down = %v, want %v", got, want)
+	}
+	result, err := ws.Execute(context.Background(), map[string]interface{}{"query": "golang", "fallback_engines": []interface{}{"bing"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Output, "From bing") || google.calls != 0 {
+		t.Errorf("google ran %d times while down, result = %+v", google.calls, result)
+	}
+
+	// 过期后恢复原来的顺序
+	now = now.Add(engineDownTTL)
+	if got, want := ws.orderEngines("google", []string{"bing", "duckduckgo"}), []string{"google", "bing", "duckduckgo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("order after the TTL = %v, want %v", got, want)
+	}
+	if _, err := ws.Execute(context.Background(), map[string]interface{}{"query": "golang"}); err != nil {
+		t.Fatal(err)
+	}
+	if google.calls != 1 || !ws.HealthStatus()["google"].Healthy {
+		t.Errorf("google ran %d times after the TTL, health = %+v", google.calls, ws.HealthStatus()["google"])
+	}
+}
+
+func TestDownEngineStillTriedLast(t *testing.T) {
+	google := &fakeEngine{name: "google", results: []SearchResult{{Title: "From google", URL: "https://go.dev"}}}
+	bing := &fakeEngine{name: "bing", err: errors.New("HTTP 500")}
+	ws := newFakeWebSearch(google, bing)
+	ws.health.record("google", errors.New("HTTP 503"))
+
+	result, err := ws.Execute(context.Background(), map[string]interface{}{"query": "golang", "fallback_engines": []interface{}{"bing"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Output, "From google") || bing.calls != 1 || google.calls != 1 {
+		t.Errorf("bing ran %d times and google %d times, result = %+v", bing.calls, google.calls, result)
+	}
+}
@@ -0,0 +1,144 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go-manus/config"
+)
+
+// SearxSearch 通过自建的 SearXNG 实例进行元搜索
+type SearxSearch struct {
+	*BaseSearch
+	baseURL string
+}
+
+func NewSearxSearch() *SearxSearch {
+	return &SearxSearch{
+		BaseSearch: NewBaseSearch(),
+		baseURL:    config.GetInstance().GetSearch().SearxngURL,
+	}
+}
+
+func (s *SearxSearch) Name() string {
+	return "searxng_search"
+}
+
+func (s *SearxSearch) Description() string {
+	return "Perform a search through a self-hosted SearXNG meta-search instance and return a list of relevant links."
+}
+
+func (s *SearxSearch) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "(required) The search query to submit to SearXNG.",
+			},
+			"num_results": map[string]interface{}{
+				"type":        "integer",
+				"description": "(optional) The number of search results to return. Default is 10.",
+				"default":     10,
+			},
+			"snippet_length": map[string]interface{}{
+				"type":        "integer",
+				"description": "(optional) Maximum number of characters kept from each result snippet. Default is 200.",
+				"default":     200,
+			},
+		},
+		"required": []string{"query"},
+	}
+}
+
+func (s *SearxSearch) Execute(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	query, ok := args["query"].(string)
+	if !ok {
+		return &ToolResult{Error: "query parameter is required"}, nil
+	}
+
+	numResults := 10
+	if n, ok := args["num_results"].(float64); ok {
+		numResults = int(n)
+	}
+	snippetLength := snippetLengthArg(args)
+
+	results, err := s.Search(ctx, query, numResults)
+	if err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Search failed: %v", err)}, nil
+	}
+
+	if len(results) == 0 {
+		return &ToolResult{Output: "No search results found"}, nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("SearXNG Search Results for: %s\n\n", query))
+	for i, result := range results {
+		output.WriteString(fmt.Sprintf("%d. %s\n", i+1, result.Title))
+		output.WriteString(fmt.Sprintf("   URL: %s\n", result.URL))
+		if result.Snippet != "" {
+			output.WriteString(fmt.Sprintf("   %s\n", truncateSnippet(result.Snippet, snippetLength)))
+		}
+		output.WriteString("\n")
+	}
+
+	return &ToolResult{Output: output.String()}, nil
+}
+
+// searxResponse SearXNG JSON 响应中用到的字段
+type searxResponse struct {
+	Results []struct {
+		Title   string `json:"title"`
+		URL     string `json:"url"`
+		Content string `json:"content"`
+	} `json:"results"`
+}
+
+func (s *SearxSearch) Search(ctx context.Context, query string, numResults int) ([]SearchResult, error) {
+	if s.baseURL == "" {
+		return nil, fmt.Errorf("SearXNG is not configured (set [search] searxng_url)")
+	}
+
+	// 需要在 SearXNG 的 settings.yml 中启用 json 输出格式
+	searchURL := fmt.Sprintf("%s/search?q=%s&format=json",
+		strings.TrimRight(s.baseURL, "/"), url.QueryEscape(query))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	var data searxResponse
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode SearXNG response: %w", err)
+	}
+
+	results := make([]SearchResult, 0, len(data.Results))
+	for _, item := range data.Results {
+		if len(results) >= numResults {
+			break
+		}
+		results = append(results, SearchResult{
+			Title:   item.Title,
+			URL:     item.URL,
+			Snippet: item.Content,
+		})
+	}
+	return results, nil
+}
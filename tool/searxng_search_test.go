@@ -0,0 +1,74 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newSearxServer 模拟 SearXNG 的 JSON 接口
+func newSearxServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search" || r.URL.Query().Get("format") != "json" || r.URL.Query().Get("q") != "golang" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"query": "golang", "results": [
+			{"title": "The Go Programming Language", "url": "https://go.dev/", "content": "Build simple, secure, scalable systems with Go.", "engine": "google"},
+			{"title": "golang/go", "url": "https://github.com/golang/go", "content": "The Go programming language.", "engine": "bing"},
+			{"title": "Extra", "url": "https://example.com/extra", "content": "Beyond num_results."}
+		]}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSearxSearchMapsJSONResults(t *testing.T) {
+	s := NewSearxSearch()
+	s.baseURL = newSearxServer(t).URL + "/"
+
+	results, err := s.Search(context.Background(), "golang", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SearchResult{
+		{Title: "The Go Programming Language", URL: "https://go.dev/", Snippet: "Build simple, secure, scalable systems with Go."},
+		{Title: "golang/go", URL: "https://github.com/golang/go", Snippet: "The Go programming language."},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("results = %+v, want %+v", results, want)
+	}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, results[i], want[i])
+		}
+	}
+}
+
+func TestSearxSearchRequiresURL(t *testing.T) {
+	s := NewSearxSearch()
+	if _, err := s.Search(context.Background(), "golang", 5); err == nil || !strings.Contains(err.Error(), "searxng_url") {
+		t.Errorf("Search without searxng_url = %v, want a configuration error", err)
+	}
+}
+
+func TestWebSearchRegistersSearxng(t *testing.T) {
+	ws := NewWebSearch()
+	engine, ok := ws.engines["searxng"].(*SearxSearch)
+	if !ok {
+		t.Fatalf("engines[searxng] = %T, want *SearxSearch", ws.engines["searxng"])
+	}
+	engine.baseURL = newSearxServer(t).URL
+
+	result, err := ws.Execute(context.Background(), map[string]interface{}{"query": "golang", "engine": "searxng", "num_results": float64(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Output, "https://go.dev/") {
+		t.Errorf("result = %+v, want the SearXNG results", result)
+	}
+}
@@ -0,0 +1,115 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// selfTestTimeout 单项自检的最长时间
+const selfTestTimeout = 30 * time.Second
+
+// SelfTester 可选接口，工具实现后可在 doctor 命令中检查自身依赖是否可用
+type SelfTester interface {
+	SelfTest(ctx context.Context) error
+}
+
+// Check 一项自检
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// CheckResult 自检结果，Err 为 nil 表示通过
+type CheckResult struct {
+	Name     string
+	Err      error
+	Duration time.Duration
+}
+
+// ToolChecks 为集合中实现了 SelfTester 的工具生成自检项，按名称排序
+func (tc *ToolCollection) ToolChecks() []Check {
+	names := make([]string, 0, len(tc.tools))
+	for name, t := range tc.tools {
+		if _, ok := t.(SelfTester); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	checks := make([]Check, 0, len(names))
+	for _, name := range names {
+		tester := tc.tools[name].(SelfTester)
+		checks = append(checks, Check{Name: "tool: " + name, Run: tester.SelfTest})
+	}
+	return checks
+}
+
+// RunChecks 依次执行自检，单项 panic 或超时都记为失败，不影响其他检查
+func RunChecks(ctx context.Context, checks []Check) []CheckResult {
+	results := make([]CheckResult, 0, len(checks))
+	for _, check := range checks {
+		start := time.Now()
+		err := runCheck(ctx, check)
+		results = append(results, CheckResult{Name: check.Name, Err: err, Duration: time.Since(start)})
+	}
+	return results
+}
+
+func runCheck(ctx context.Context, check Check) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return check.Run(ctx)
+}
+
+// FormatReport 生成 PASS/FAIL 报告，第二个返回值表示是否全部通过
+func FormatReport(results []CheckResult) (string, bool) {
+	var sb strings.Builder
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			sb.WriteString(fmt.Sprintf("[FAIL] %s: %v\n", r.Name, r.Err))
+		} else {
+			sb.WriteString(fmt.Sprintf("[PASS] %s (%s)\n", r.Name, r.Duration.Round(time.Millisecond)))
+		}
+	}
+	sb.WriteString(fmt.Sprintf("\n%d checks, %d passed, %d failed", len(results), len(results)-failed, failed))
+	return sb.String(), failed == 0
+}
+
+// CommandCheck 检查任一候选命令存在于 PATH 中
+func CommandCheck(name string, candidates ...string) Check {
+	return commandCheck(name, exec.LookPath, candidates...)
+}
+
+// commandCheck 用 lookPath 查找候选命令，测试中可替换为桩
+func commandCheck(name string, lookPath func(string) (string, error), candidates ...string) Check {
+	return Check{
+		Name: name,
+		Run: func(ctx context.Context) error {
+			return findAny(lookPath, candidates...)
+		},
+	}
+}
+
+func lookPathAny(candidates ...string) error {
+	return findAny(exec.LookPath, candidates...)
+}
+
+func findAny(lookPath func(string) (string, error), candidates ...string) error {
+	for _, c := range candidates {
+		if _, err := lookPath(c); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("none of %s found in PATH", strings.Join(candidates, ", "))
+}
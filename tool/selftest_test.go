@@ -0,0 +1,49 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestRunChecksReportsMissingPython(t *testing.T) {
+	// 桩：PATH 中没有 Python
+	lookPath := func(name string) (string, error) {
+		return "", &exec.Error{Name: name, Err: exec.ErrNotFound}
+	}
+	checks := []Check{
+		commandCheck("python3", lookPath, "python3", "python"),
+		{Name: "llm", Run: func(ctx context.Context) error { return nil }},
+		{Name: "tool: broken", Run: func(ctx context.Context) error { panic("boom") }},
+		{Name: "tool: offline", Run: func(ctx context.Context) error { return errors.New("service unavailable") }},
+	}
+
+	report, ok := FormatReport(RunChecks(context.Background(), checks))
+	if ok {
+		t.Errorf("report passed with Python missing:\n%s", report)
+	}
+	for _, want := range []string{
+		"[FAIL] python3: none of python3, python found in PATH\n",
+		"[PASS] llm (",
+		"[FAIL] tool: broken: panic: boom\n",
+		"[FAIL] tool: offline: service unavailable\n",
+		"4 checks, 1 passed, 3 failed",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report does not contain %q:\n%s", want, report)
+		}
+	}
+
+	// 找到任一候选命令即通过
+	lookPath = func(name string) (string, error) {
+		if name == "python" {
+			return "/usr/bin/python", nil
+		}
+		return "", &exec.Error{Name: name, Err: exec.ErrNotFound}
+	}
+	if report, ok := FormatReport(RunChecks(context.Background(), []Check{commandCheck("python3", lookPath, "python3", "python")})); !ok {
+		t.Errorf("report failed with python on PATH:\n%s", report)
+	}
+}
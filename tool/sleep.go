@@ -0,0 +1,91 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// defaultMaxSleep Sleep 单次等待的默认上限
+const defaultMaxSleep = 10 * time.Minute
+
+// Sleep 暂停一段时间或等待到指定时刻后返回，用于轮询任务时在两次检查之间等待，
+// 不需要启动 shell 执行 sleep。等待时间超过上限时按上限等待，ctx 取消时立即返回
+type Sleep struct {
+	maxDuration time.Duration
+}
+
+func NewSleep() *Sleep {
+	return &Sleep{maxDuration: defaultMaxSleep}
+}
+
+// SetMaxDuration 设置单次等待的上限
+func (s *Sleep) SetMaxDuration(d time.Duration) {
+	s.maxDuration = d
+}
+
+func (s *Sleep) Name() string {
+	return "sleep"
+}
+
+func (s *Sleep) Description() string {
+	return fmt.Sprintf(`Wait before continuing, e.g. to give a background job time to finish before checking on it again.
+Pass either "seconds" to wait for a duration or "until" to wait until a point in time. A single wait is capped at %s; call the tool again to wait longer.`, s.maxDuration)
+}
+
+func (s *Sleep) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"seconds": map[string]interface{}{
+				"type":        "number",
+				"description": "(optional) Number of seconds to wait.",
+			},
+			"until": map[string]interface{}{
+				"type":        "string",
+				"description": "(optional) Time to wait until, in RFC 3339 format, e.g. 2024-05-01T12:00:00Z. Used instead of \"seconds\".",
+			},
+		},
+	}
+}
+
+func (s *Sleep) Execute(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	var wait time.Duration
+	if until, ok := args["until"].(string); ok && until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return &ToolResult{Error: fmt.Sprintf("Invalid until %q: use RFC 3339 format, e.g. 2024-05-01T12:00:00Z", until)}, nil
+		}
+		wait = time.Until(t)
+	} else if seconds, ok := args["seconds"].(float64); ok {
+		if seconds < 0 {
+			return &ToolResult{Error: "seconds must not be negative"}, nil
+		}
+		wait = time.Duration(seconds * float64(time.Second))
+	} else {
+		return &ToolResult{Error: "seconds or until parameter is required"}, nil
+	}
+
+	if wait <= 0 {
+		return &ToolResult{Output: "The requested time has already passed, no wait needed"}, nil
+	}
+	capped := wait > s.maxDuration
+	if capped {
+		wait = s.maxDuration
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	start := time.Now()
+	select {
+	case <-ctx.Done():
+		return &ToolResult{Error: fmt.Sprintf("Wait cancelled after %s: %v", time.Since(start).Round(time.Millisecond), ctx.Err())}, nil
+	case <-timer.C:
+	}
+
+	output := fmt.Sprintf("Waited %s", wait)
+	if capped {
+		output += fmt.Sprintf(" (capped at the maximum of %s, call sleep again to wait longer)", s.maxDuration)
+	}
+	return &ToolResult{Output: output}, nil
+}
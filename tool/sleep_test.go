@@ -0,0 +1,56 @@
+package tool
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSleepWaitsForRequestedDuration(t *testing.T) {
+	start := time.Now()
+	result, err := NewSleep().Execute(context.Background(), map[string]interface{}{"seconds": 0.1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("returned after %s, want at least 100ms", elapsed)
+	}
+	if result.Output != "Waited 100ms" {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestSleepCapsLongWaits(t *testing.T) {
+	s := NewSleep()
+	s.SetMaxDuration(50 * time.Millisecond)
+
+	start := time.Now()
+	result, err := s.Execute(context.Background(), map[string]interface{}{"until": time.Now().Add(time.Hour).Format(time.RFC3339)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("capped wait took %s", elapsed)
+	}
+	if !strings.Contains(result.Output, "capped at the maximum of 50ms") {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestSleepAbortsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	result, err := NewSleep().Execute(ctx, map[string]interface{}{"seconds": float64(30)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("cancelled wait took %s", elapsed)
+	}
+	if !strings.Contains(result.Error, "Wait cancelled") {
+		t.Errorf("result = %+v, want a cancellation error", result)
+	}
+}
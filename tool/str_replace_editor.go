@@ -10,14 +10,29 @@ import (
 
 type StrReplaceEditor struct {
 	fileHistory map[string][]string
+	// redoHistory 每个文件被撤销的内容，redo_edit 按后进先出恢复，新的编辑会清空
+	redoHistory map[string][]string
+	// historyDir 编辑历史的保存目录，进程重启后仍可撤销；historyLoaded 记录已从磁盘读取历史的文件
+	historyDir    string
+	historyLoaded map[string]bool
+	// outputs 非 nil 时，view 截断的完整内容保存在其中，可通过 get_more 读取
+	outputs *OutputStore
 }
 
 func NewStrReplaceEditor() *StrReplaceEditor {
 	return &StrReplaceEditor{
-		fileHistory: make(map[string][]string),
+		fileHistory:   make(map[string][]string),
+		redoHistory:   make(map[string][]string),
+		historyDir:    workspaceSubdir(defaultHistoryDir),
+		historyLoaded: make(map[string]bool),
 	}
 }
 
+// SetOutputStore 设置保存被截断输出的存储，通常为所属 Agent 的 Outputs
+func (s *StrReplaceEditor) SetOutputStore(store *OutputStore) {
+	s.outputs = store
+}
+
 func (s *StrReplaceEditor) Name() string {
 	return "str_replace_editor"
 }
@@ -29,6 +44,8 @@ func (s *StrReplaceEditor) Description() string {
 * The create command cannot be used if the specified path already exists as a file
 * If a command generates a long output, it will be truncated and marked with <response clipped>
 * The undo_edit command will revert the last edit made to the file at path
+* The redo_edit command will re-apply the last edit reverted by undo_edit, until the file is edited again
+* The delete_lines command removes the lines in view_range, e.g. [11, 12] deletes lines 11 and 12
 
 Notes for using the str_replace command:
 * The old_str parameter should match EXACTLY one or more consecutive lines from the original file. Be mindful of whitespaces!
@@ -41,12 +58,12 @@ func (s *StrReplaceEditor) Parameters() map[string]interface{} {
 		"type": "object",
 		"properties": map[string]interface{}{
 			"command": map[string]interface{}{
-				"description": "The commands to run. Allowed options are: view, create, str_replace, insert, undo_edit.",
-				"enum":        []string{"view", "create", "str_replace", "insert", "undo_edit"},
+				"description": "The commands to run. Allowed options are: view, create, str_replace, insert, delete_lines, undo_edit, redo_edit.",
+				"enum":        []string{"view", "create", "str_replace", "insert", "delete_lines", "undo_edit", "redo_edit"},
 				"type":        "string",
 			},
 			"path": map[string]interface{}{
-				"description": "Absolute path to file or directory.",
+				"description": "Absolute path to file or directory, inside the workspace directory.",
 				"type":        "string",
 			},
 			"file_text": map[string]interface{}{
@@ -61,12 +78,17 @@ func (s *StrReplaceEditor) Parameters() map[string]interface{} {
 				"description": "Optional parameter of str_replace command containing the new string (if not given, no string will be added). Required parameter of insert command containing the string to insert.",
 				"type":        "string",
 			},
+			"replace_all": map[string]interface{}{
+				"description": "Optional parameter of str_replace command. If true, every occurrence of old_str is replaced instead of requiring it to be unique. Default is false.",
+				"type":        "boolean",
+				"default":     false,
+			},
 			"insert_line": map[string]interface{}{
 				"description": "Required parameter of insert command. The new_str will be inserted AFTER the line insert_line of path.",
 				"type":        "integer",
 			},
 			"view_range": map[string]interface{}{
-				"description": "Optional parameter of view command when path points to a file. If none is given, the full file is shown. If provided, the file will be shown in the indicated line number range, e.g. [11, 12] will show lines 11 and 12. Indexing at 1 to start. Setting [start_line, -1] shows all lines from start_line to the end of the file.",
+				"description": "Optional parameter of view command when path points to a file. If none is given, the full file is shown. If provided, the file will be shown in the indicated line number range, e.g. [11, 12] will show lines 11 and 12. Indexing at 1 to start. Setting [start_line, -1] shows all lines from start_line to the end of the file. Required parameter of delete_lines command: the lines in the range (inclusive) are deleted, with [start_line, -1] deleting to the end of the file.",
 				"type":        "array",
 				"items": map[string]interface{}{
 					"type": "integer",
@@ -92,6 +114,10 @@ func (s *StrReplaceEditor) Execute(ctx context.Context, args map[string]interfac
 	if !filepath.IsAbs(path) {
 		return &ToolResult{Error: fmt.Sprintf("The path %s is not an absolute path", path)}, nil
 	}
+	path, err := ResolveWorkspacePath(path)
+	if err != nil {
+		return &ToolResult{Error: err.Error()}, nil
+	}
 
 	switch command {
 	case "view":
@@ -102,8 +128,12 @@ func (s *StrReplaceEditor) Execute(ctx context.Context, args map[string]interfac
 		return s.strReplace(ctx, path, args)
 	case "insert":
 		return s.insert(ctx, path, args)
+	case "delete_lines":
+		return s.deleteLines(ctx, path, args)
 	case "undo_edit":
 		return s.undoEdit(ctx, path)
+	case "redo_edit":
+		return s.redoEdit(ctx, path)
 	default:
 		return &ToolResult{Error: fmt.Sprintf("Unrecognized command: %s", command)}, nil
 	}
@@ -120,17 +150,22 @@ func (s *StrReplaceEditor) view(ctx context.Context, path string, args map[strin
 	}
 
 	// View file
-	var viewRange []int
-	if vr, ok := args["view_range"].([]interface{}); ok && len(vr) > 0 {
-		viewRange = make([]int, len(vr))
-		for i, v := range vr {
-			if f, ok := v.(float64); ok {
-				viewRange[i] = int(f)
-			}
+	return s.viewFile(ctx, path, viewRangeArg(args))
+}
+
+// viewRangeArg 读取 view_range 参数，未提供时返回 nil
+func viewRangeArg(args map[string]interface{}) []int {
+	vr, ok := args["view_range"].([]interface{})
+	if !ok || len(vr) == 0 {
+		return nil
+	}
+	viewRange := make([]int, len(vr))
+	for i, v := range vr {
+		if f, ok := v.(float64); ok {
+			viewRange[i] = int(f)
 		}
 	}
-
-	return s.viewFile(ctx, path, viewRange)
+	return viewRange
 }
 
 func (s *StrReplaceEditor) viewDirectory(ctx context.Context, path string) (*ToolResult, error) {
@@ -223,7 +258,9 @@ func (s *StrReplaceEditor) viewFile(ctx context.Context, path string, viewRange
 	output := result.String()
 	// Truncate if too long
 	const maxLength = 16000
-	if len(output) > maxLength {
+	if s.outputs != nil && len([]rune(output)) > maxLength {
+		output = s.outputs.Truncate(output, maxLength)
+	} else if len(output) > maxLength {
 		output = output[:maxLength] + "<response clipped><NOTE>To save on context only part of this file has been shown to you. You should retry this tool after you have searched inside the file with `grep -n` in order to find the line numbers of what you are looking for.</NOTE>"
 	}
 
@@ -253,7 +290,7 @@ func (s *StrReplaceEditor) create(ctx context.Context, path string, args map[str
 	}
 
 	// Save to history
-	s.fileHistory[path] = append(s.fileHistory[path], fileText)
+	s.recordEdit(path, fileText)
 
 	return &ToolResult{Output: fmt.Sprintf("File created successfully at: %s", path)}, nil
 }
@@ -268,6 +305,7 @@ func (s *StrReplaceEditor) strReplace(ctx context.Context, path string, args map
 	if ns, ok := args["new_str"].(string); ok {
 		newStr = ns
 	}
+	replaceAll, _ := args["replace_all"].(bool)
 
 	// Read file
 	content, err := os.ReadFile(path)
@@ -275,28 +313,30 @@ func (s *StrReplaceEditor) strReplace(ctx context.Context, path string, args map
 		return &ToolResult{Error: fmt.Sprintf("Failed to read file: %v", err)}, nil
 	}
 
-	fileContent := strings.ReplaceAll(string(content), "\t", "    ") // Expand tabs
-	oldStr = strings.ReplaceAll(oldStr, "\t", "    ")
-	newStr = strings.ReplaceAll(newStr, "\t", "    ")
+	if oldStr == "" {
+		return &ToolResult{Error: "old_str must not be empty"}, nil
+	}
 
-	// Check occurrences
-	occurrences := strings.Count(fileContent, oldStr)
+	// 只在匹配时展开制表符，写回时保留未修改部分的原始字节
+	fileContent := string(content)
+	matches := findOccurrences(fileContent, oldStr)
+	occurrences := len(matches)
 	if occurrences == 0 {
 		return &ToolResult{Error: fmt.Sprintf("No replacement was performed, old_str did not appear verbatim in %s.", path)}, nil
-	} else if occurrences > 1 {
+	} else if occurrences > 1 && !replaceAll {
 		// Find line numbers
 		lines := make([]int, 0)
-		fileLines := strings.Split(fileContent, "\n")
-		for i, line := range fileLines {
-			if strings.Contains(line, oldStr) {
-				lines = append(lines, i+1)
+		for _, m := range matches {
+			line := strings.Count(fileContent[:m[0]], "\n") + 1
+			if len(lines) == 0 || lines[len(lines)-1] != line {
+				lines = append(lines, line)
 			}
 		}
-		return &ToolResult{Error: fmt.Sprintf("No replacement was performed. Multiple occurrences of old_str in lines %v. Please ensure it is unique", lines)}, nil
+		return &ToolResult{Error: fmt.Sprintf("No replacement was performed. Multiple occurrences of old_str in lines %v. Please ensure it is unique, or set replace_all to replace every occurrence", lines)}, nil
 	}
 
 	// Replace
-	newFileContent := strings.Replace(fileContent, oldStr, newStr, 1)
+	newFileContent := replaceSpans(fileContent, matches, newStr)
 
 	// Write file
 	if err := os.WriteFile(path, []byte(newFileContent), 0644); err != nil {
@@ -304,10 +344,10 @@ func (s *StrReplaceEditor) strReplace(ctx context.Context, path string, args map
 	}
 
 	// Save to history
-	s.fileHistory[path] = append(s.fileHistory[path], fileContent)
+	s.recordEdit(path, fileContent)
 
 	// Create snippet
-	replacementLine := strings.Count(strings.Split(fileContent, oldStr)[0], "\n")
+	replacementLine := strings.Count(fileContent[:matches[0][0]], "\n")
 	startLine := replacementLine - 4
 	if startLine < 0 {
 		startLine = 0
@@ -322,7 +362,11 @@ func (s *StrReplaceEditor) strReplace(ctx context.Context, path string, args map
 	// Format output with line numbers
 	var result strings.Builder
 	result.WriteString(fmt.Sprintf("The file %s has been edited. ", path))
-	result.WriteString(fmt.Sprintf("Here's the result of running `cat -n` on a snippet of %s:\n", path))
+	if replaceAll {
+		result.WriteString(fmt.Sprintf("Replaced %d occurrence(s) of old_str. Here's the result of running `cat -n` on a snippet around the first one:\n", occurrences))
+	} else {
+		result.WriteString(fmt.Sprintf("Here's the result of running `cat -n` on a snippet of %s:\n", path))
+	}
 	snippetLines := strings.Split(snippet, "\n")
 	for i, line := range snippetLines {
 		result.WriteString(fmt.Sprintf("%6d\t%s\n", startLine+i+1, line))
@@ -349,8 +393,7 @@ func (s *StrReplaceEditor) insert(ctx context.Context, path string, args map[str
 		return &ToolResult{Error: fmt.Sprintf("Failed to read file: %v", err)}, nil
 	}
 
-	fileText := strings.ReplaceAll(string(content), "\t", "    ")
-	newStr = strings.ReplaceAll(newStr, "\t", "    ")
+	fileText := string(content)
 	fileLines := strings.Split(fileText, "\n")
 	nLines := len(fileLines)
 
@@ -361,7 +404,10 @@ func (s *StrReplaceEditor) insert(ctx context.Context, path string, args map[str
 
 	// Insert
 	newStrLines := strings.Split(newStr, "\n")
-	newFileLines := append(fileLines[:lineNum], append(newStrLines, fileLines[lineNum:]...)...)
+	newFileLines := make([]string, 0, nLines+len(newStrLines))
+	newFileLines = append(newFileLines, fileLines[:lineNum]...)
+	newFileLines = append(newFileLines, newStrLines...)
+	newFileLines = append(newFileLines, fileLines[lineNum:]...)
 
 	// Create snippet
 	startLine := lineNum - 4
@@ -381,7 +427,7 @@ func (s *StrReplaceEditor) insert(ctx context.Context, path string, args map[str
 	}
 
 	// Save to history
-	s.fileHistory[path] = append(s.fileHistory[path], fileText)
+	s.recordEdit(path, fileText)
 
 	// Format output
 	var result strings.Builder
@@ -395,20 +441,163 @@ func (s *StrReplaceEditor) insert(ctx context.Context, path string, args map[str
 	return &ToolResult{Output: result.String()}, nil
 }
 
+// deleteLines 删除 view_range 指定的行（从 1 开始，包含两端），校验规则与 view 相同
+func (s *StrReplaceEditor) deleteLines(ctx context.Context, path string, args map[string]interface{}) (*ToolResult, error) {
+	viewRange := viewRangeArg(args)
+	if len(viewRange) != 2 {
+		return &ToolResult{Error: "view_range parameter [start_line, end_line] is required for delete_lines command"}, nil
+	}
+
+	// Read file
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to read file: %v", err)}, nil
+	}
+
+	fileText := string(content)
+	fileLines := strings.Split(fileText, "\n")
+	nLines := len(fileLines)
+
+	initLine, finalLine := viewRange[0], viewRange[1]
+	if initLine < 1 || initLine > nLines {
+		return &ToolResult{Error: fmt.Sprintf("Invalid view_range: [%d, %d]. First element should be within [1, %d]", initLine, finalLine, nLines)}, nil
+	}
+	if finalLine == -1 {
+		finalLine = nLines
+	} else {
+		if finalLine > nLines {
+			return &ToolResult{Error: fmt.Sprintf("Invalid view_range: [%d, %d]. Second element should be <= %d", initLine, finalLine, nLines)}, nil
+		}
+		if finalLine < initLine {
+			return &ToolResult{Error: fmt.Sprintf("Invalid view_range: [%d, %d]. Second element should be >= first element", initLine, finalLine)}, nil
+		}
+	}
+
+	// Delete
+	newFileLines := make([]string, 0, nLines-(finalLine-initLine+1))
+	newFileLines = append(newFileLines, fileLines[:initLine-1]...)
+	newFileLines = append(newFileLines, fileLines[finalLine:]...)
+
+	// Write file
+	newFileText := strings.Join(newFileLines, "\n")
+	if err := os.WriteFile(path, []byte(newFileText), 0644); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to write file: %v", err)}, nil
+	}
+
+	// Save to history
+	s.recordEdit(path, fileText)
+
+	// Create snippet around the deleted lines
+	startLine := initLine - 1 - 4
+	if startLine < 0 {
+		startLine = 0
+	}
+	endLine := initLine - 1 + 4
+	if endLine >= len(newFileLines) {
+		endLine = len(newFileLines) - 1
+	}
+
+	// Format output
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Deleted lines %d-%d of %s. ", initLine, finalLine, path))
+	result.WriteString("Here's the result of running `cat -n` on a snippet of the edited file:\n")
+	for i := startLine; i <= endLine; i++ {
+		result.WriteString(fmt.Sprintf("%6d\t%s\n", i+1, newFileLines[i]))
+	}
+	result.WriteString("Review the changes and make sure they are as expected. Edit the file again if necessary.")
+
+	return &ToolResult{Output: result.String()}, nil
+}
+
+// findOccurrences 返回 oldStr 在 content 中不重叠出现的字节区间。
+// 没有原样匹配时，把两者的制表符都展开为四个空格后再匹配，区间仍是 content 中的原始位置
+func findOccurrences(content, oldStr string) [][2]int {
+	var matches [][2]int
+	for start := 0; ; {
+		i := strings.Index(content[start:], oldStr)
+		if i < 0 {
+			break
+		}
+		matches = append(matches, [2]int{start + i, start + i + len(oldStr)})
+		start += i + len(oldStr)
+	}
+	if len(matches) > 0 {
+		return matches
+	}
+
+	expanded, offsets := expandTabs(content)
+	target, _ := expandTabs(oldStr)
+	for start := 0; ; {
+		i := strings.Index(expanded[start:], target)
+		if i < 0 {
+			break
+		}
+		begin, end := start+i, start+i+len(target)
+		// 匹配的起止位置落在某个制表符展开后的空格中间时不算匹配
+		if offsets[begin] >= 0 && offsets[end] >= 0 {
+			matches = append(matches, [2]int{offsets[begin], offsets[end]})
+			start = end
+		} else {
+			start = begin + 1
+		}
+	}
+	return matches
+}
+
+// expandTabs 把制表符展开为四个空格，offsets[i] 为展开后第 i 个字节在原文中的位置，
+// 制表符展开出的后三个空格记为 -1；offsets 比展开后的文本多一项，对应文本末尾
+func expandTabs(text string) (string, []int) {
+	var sb strings.Builder
+	offsets := make([]int, 0, len(text)+1)
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\t' {
+			sb.WriteString("    ")
+			offsets = append(offsets, i, -1, -1, -1)
+			continue
+		}
+		sb.WriteByte(text[i])
+		offsets = append(offsets, i)
+	}
+	offsets = append(offsets, len(text))
+	return sb.String(), offsets
+}
+
+// replaceSpans 把 content 中的各个区间替换为 newStr，区间按位置递增且不重叠
+func replaceSpans(content string, spans [][2]int, newStr string) string {
+	var sb strings.Builder
+	last := 0
+	for _, span := range spans {
+		sb.WriteString(content[last:span[0]])
+		sb.WriteString(newStr)
+		last = span[1]
+	}
+	sb.WriteString(content[last:])
+	return sb.String()
+}
+
 func (s *StrReplaceEditor) undoEdit(ctx context.Context, path string) (*ToolResult, error) {
+	s.loadHistory(path)
 	history, exists := s.fileHistory[path]
 	if !exists || len(history) == 0 {
 		return &ToolResult{Error: fmt.Sprintf("No edit history found for %s.", path)}, nil
 	}
 
+	// 保存当前内容以便 redo_edit 恢复
+	current, err := os.ReadFile(path)
+	if err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to read file: %v", err)}, nil
+	}
+
 	// Get last version
 	oldText := history[len(history)-1]
-	s.fileHistory[path] = history[:len(history)-1]
 
 	// Write old content
 	if err := os.WriteFile(path, []byte(oldText), 0644); err != nil {
 		return &ToolResult{Error: fmt.Sprintf("Failed to write file: %v", err)}, nil
 	}
+	s.fileHistory[path] = history[:len(history)-1]
+	s.redoHistory[path] = append(s.redoHistory[path], string(current))
+	s.saveHistory(path)
 
 	// Format output
 	var result strings.Builder
@@ -421,3 +610,43 @@ func (s *StrReplaceEditor) undoEdit(ctx context.Context, path string) (*ToolResu
 
 	return &ToolResult{Output: result.String()}, nil
 }
+
+// recordEdit 保存编辑前的内容供 undo_edit 使用，并清空该文件的 redo 记录
+func (s *StrReplaceEditor) recordEdit(path, previous string) {
+	s.loadHistory(path)
+	s.fileHistory[path] = append(s.fileHistory[path], previous)
+	delete(s.redoHistory, path)
+	s.saveHistory(path)
+}
+
+func (s *StrReplaceEditor) redoEdit(ctx context.Context, path string) (*ToolResult, error) {
+	s.loadHistory(path)
+	redo := s.redoHistory[path]
+	if len(redo) == 0 {
+		return &ToolResult{Error: fmt.Sprintf("Nothing to redo for %s.", path)}, nil
+	}
+
+	current, err := os.ReadFile(path)
+	if err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to read file: %v", err)}, nil
+	}
+
+	newText := redo[len(redo)-1]
+	if err := os.WriteFile(path, []byte(newText), 0644); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to write file: %v", err)}, nil
+	}
+	s.redoHistory[path] = redo[:len(redo)-1]
+	s.fileHistory[path] = append(s.fileHistory[path], string(current))
+	s.saveHistory(path)
+
+	// Format output
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Last undone edit to %s redone successfully. ", path))
+	result.WriteString(fmt.Sprintf("Here's the result of running `cat -n` on %s:\n", path))
+	lines := strings.Split(newText, "\n")
+	for i, line := range lines {
+		result.WriteString(fmt.Sprintf("%6d\t%s\n", i+1, line))
+	}
+
+	return &ToolResult{Output: result.String()}, nil
+}
@@ -0,0 +1,44 @@
+package tool
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEditorHistorySurvivesRestartInWorkspace(t *testing.T) {
+	path := writeWorkspaceFile(t, "editor/notes.txt", "hello world\n")
+	run := func(s *StrReplaceEditor, args map[string]interface{}) {
+		t.Helper()
+		args["path"] = path
+		result, err := s.Execute(context.Background(), args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Error != "" {
+			t.Fatalf("%s: %s", args["command"], result.Error)
+		}
+	}
+
+	editor := NewStrReplaceEditor()
+	editor.SetHistoryDir(filepath.Join(".go-manus-history", t.Name()))
+	run(editor, map[string]interface{}{"command": "str_replace", "old_str": "world", "new_str": "there"})
+
+	// 历史保存在工作目录下，而不是进程的当前目录
+	historyFile := editor.historyFile(path)
+	if _, err := os.Stat(historyFile); err != nil {
+		t.Fatalf("history file not written: %v", err)
+	}
+	if want := filepath.Join(workspaceRoot(), ".go-manus-history", t.Name()); filepath.Dir(historyFile) != want {
+		t.Errorf("history file %s is not in %s", historyFile, want)
+	}
+
+	// 新的编辑器实例从磁盘读取历史后撤销
+	restarted := NewStrReplaceEditor()
+	restarted.SetHistoryDir(filepath.Join(".go-manus-history", t.Name()))
+	run(restarted, map[string]interface{}{"command": "undo_edit"})
+	if content, _ := os.ReadFile(path); string(content) != "hello world\n" {
+		t.Errorf("content after undo = %q, want the original text", content)
+	}
+}
@@ -0,0 +1,161 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Template 模板渲染工具，基于 text/template 生成配置或代码文件
+type Template struct{}
+
+func NewTemplate() *Template {
+	return &Template{}
+}
+
+func (t *Template) Name() string {
+	return "template"
+}
+
+func (t *Template) Description() string {
+	return `Render a Go text/template with the provided variables and return or save the result.
+Use this tool to scaffold repetitive files such as configs or boilerplate code.
+* The template can be given inline via "template" or loaded from a workspace file via "template_path"
+* Variables are passed in "vars" and referenced as {{.name}} in the template
+* Besides the standard template functions, the helpers upper, lower and default are available, e.g. {{.name | upper}} or {{.port | default 8080}}
+* If "output_path" is given, the rendered content is written to that file, otherwise it is returned directly`
+}
+
+func (t *Template) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"template": map[string]interface{}{
+				"type":        "string",
+				"description": "(optional) Inline template content. Either template or template_path is required.",
+			},
+			"template_path": map[string]interface{}{
+				"type":        "string",
+				"description": "(optional) Path of the template file. Relative paths are resolved against the workspace.",
+			},
+			"vars": map[string]interface{}{
+				"type":        "object",
+				"description": "(optional) Variables available in the template. A JSON object string is also accepted.",
+			},
+			"output_path": map[string]interface{}{
+				"type":        "string",
+				"description": "(optional) File path to write the rendered result to. Relative paths are resolved against the workspace.",
+			},
+		},
+	}
+}
+
+func (t *Template) Execute(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	content, _ := args["template"].(string)
+	templatePath, _ := args["template_path"].(string)
+
+	name := "inline"
+	if content == "" {
+		if templatePath == "" {
+			return &ToolResult{Error: "either template or template_path parameter is required"}, nil
+		}
+		resolved, err := ResolveWorkspacePath(templatePath)
+		if err != nil {
+			return &ToolResult{Error: err.Error()}, nil
+		}
+		data, err := os.ReadFile(resolved)
+		if err != nil {
+			return &ToolResult{Error: fmt.Sprintf("Failed to read template file: %v", err)}, nil
+		}
+		content = string(data)
+		name = filepath.Base(templatePath)
+	}
+
+	vars, err := parseTemplateVars(args["vars"])
+	if err != nil {
+		return &ToolResult{Error: err.Error()}, nil
+	}
+
+	rendered, err := renderTemplate(name, content, vars)
+	if err != nil {
+		return &ToolResult{Error: err.Error()}, nil
+	}
+
+	outputPath, _ := args["output_path"].(string)
+	if outputPath == "" {
+		return &ToolResult{Output: rendered}, nil
+	}
+
+	outputPath, err = ResolveWorkspacePath(outputPath)
+	if err != nil {
+		return &ToolResult{Error: err.Error()}, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to create directory: %v", err)}, nil
+	}
+	if err := os.WriteFile(outputPath, []byte(rendered), 0644); err != nil {
+		return &ToolResult{Error: fmt.Sprintf("Failed to write file: %v", err)}, nil
+	}
+
+	return &ToolResult{Output: fmt.Sprintf("Template rendered successfully and saved to %s", outputPath)}, nil
+}
+
+// templateFuncs 模板中可用的辅助函数
+var templateFuncs = template.FuncMap{
+	"upper": func(v interface{}) string {
+		return strings.ToUpper(fmt.Sprint(v))
+	},
+	"lower": func(v interface{}) string {
+		return strings.ToLower(fmt.Sprint(v))
+	},
+	// default 在值为空时返回默认值，用法：{{.port | default 8080}}
+	"default": func(def interface{}, v interface{}) interface{} {
+		if v == nil {
+			return def
+		}
+		if s, ok := v.(string); ok && s == "" {
+			return def
+		}
+		return v
+	},
+}
+
+// renderTemplate 使用给定变量渲染模板
+func renderTemplate(name, content string, vars map[string]interface{}) (string, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=default").Parse(content)
+	if err != nil {
+		return "", fmt.Errorf("Failed to parse template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("Failed to render template: %v", err)
+	}
+	return buf.String(), nil
+}
+
+// parseTemplateVars 解析 vars 参数，支持对象或 JSON 字符串
+func parseTemplateVars(raw interface{}) (map[string]interface{}, error) {
+	switch v := raw.(type) {
+	case nil:
+		return map[string]interface{}{}, nil
+	case map[string]interface{}:
+		return v, nil
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return map[string]interface{}{}, nil
+		}
+		var vars map[string]interface{}
+		if err := json.Unmarshal([]byte(v), &vars); err != nil {
+			return nil, fmt.Errorf("vars must be a JSON object: %v", err)
+		}
+		return vars, nil
+	default:
+		return nil, fmt.Errorf("vars must be an object")
+	}
+}
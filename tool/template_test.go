@@ -0,0 +1,77 @@
+package tool
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTemplateRendersMapVars(t *testing.T) {
+	tmpl := NewTemplate()
+	result, err := tmpl.Execute(context.Background(), map[string]interface{}{
+		"template": "service: {{.name | upper}}\nenv: {{.env | lower}}\nport: {{.port | default 8080}}\nreplicas: {{.replicas | default 1}}\n{{range .hosts}}- {{.}}\n{{end}}",
+		"vars": map[string]interface{}{
+			"name":     "billing",
+			"env":      "PROD",
+			"replicas": float64(3),
+			"hosts":    []interface{}{"a.internal", "b.internal"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != "" {
+		t.Fatalf("unexpected error: %s", result.Error)
+	}
+	want := "service: BILLING\nenv: prod\nport: 8080\nreplicas: 3\n- a.internal\n- b.internal\n"
+	if result.Output != want {
+		t.Errorf("output = %q, want %q", result.Output, want)
+	}
+}
+
+func TestTemplateJSONVarsAndFiles(t *testing.T) {
+	writeWorkspaceFile(t, "templates/greeting.tmpl", "Hello, {{.who}}!")
+
+	result, err := NewTemplate().Execute(context.Background(), map[string]interface{}{
+		"template_path": "templates/greeting.tmpl",
+		"vars":          `{"who": "world"}`,
+		"output_path":   "out/greeting.txt",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != "" {
+		t.Fatalf("unexpected error: %s", result.Error)
+	}
+	data, err := os.ReadFile(filepath.Join(workspaceRoot(), "out", "greeting.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "Hello, world!" {
+		t.Errorf("written content = %q", data)
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]interface{}
+	}{
+		{"no template", map[string]interface{}{}},
+		{"bad vars JSON", map[string]interface{}{"template": "x", "vars": "{not json"}},
+		{"parse error", map[string]interface{}{"template": "{{.name"}},
+		{"missing file", map[string]interface{}{"template_path": "does/not/exist.tmpl"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewTemplate().Execute(context.Background(), tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Error == "" {
+				t.Errorf("expected an error result, got output %q", result.Output)
+			}
+		})
+	}
+}
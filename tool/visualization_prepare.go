@@ -7,7 +7,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+
+	"go-manus/config"
 )
 
 // VisualizationPrepare 可视化准备工具
@@ -17,10 +20,15 @@ type VisualizationPrepare struct {
 
 func NewVisualizationPrepare() *VisualizationPrepare {
 	return &VisualizationPrepare{
-		outputDir: "workspace/charts",
+		outputDir: workspaceSubdir(config.GetInstance().GetVisualization().OutputDir),
 	}
 }
 
+// SetOutputDir 设置 CSV/JSON 输出目录，相对于工作目录
+func (v *VisualizationPrepare) SetOutputDir(dir string) {
+	v.outputDir = workspaceSubdir(dir)
+}
+
 func (v *VisualizationPrepare) Name() string {
 	return "visualization_prepare"
 }
@@ -40,9 +48,9 @@ func (v *VisualizationPrepare) Parameters() map[string]interface{} {
 			},
 			"chart_type": map[string]interface{}{
 				"type":        "string",
-				"description": "Type of chart to create",
-				"enum":        []string{"line", "bar", "pie", "scatter"},
-				"default":     "line",
+				"description": "Type of chart to create, auto picks one based on the data",
+				"enum":        []string{"auto", "line", "bar", "pie", "scatter"},
+				"default":     "auto",
 			},
 			"title": map[string]interface{}{
 				"type":        "string",
@@ -56,6 +64,19 @@ func (v *VisualizationPrepare) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "Label for Y axis",
 			},
+			"group_by": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional column name to group rows by before charting (e.g. month, category)",
+			},
+			"aggregate": map[string]interface{}{
+				"type":        "string",
+				"description": "Aggregation applied to value_column within each group (required with group_by)",
+				"enum":        []string{"sum", "avg", "count", "max", "min"},
+			},
+			"value_column": map[string]interface{}{
+				"type":        "string",
+				"description": "Column to aggregate (not needed for count)",
+			},
 		},
 		"required": []string{"data"},
 	}
@@ -67,7 +88,7 @@ func (v *VisualizationPrepare) Execute(ctx context.Context, args map[string]inte
 		return &ToolResult{Error: "data parameter is required"}, nil
 	}
 
-	chartType := "line"
+	chartType := "auto"
 	if ct, ok := args["chart_type"].(string); ok && ct != "" {
 		chartType = ct
 	}
@@ -101,11 +122,12 @@ func (v *VisualizationPrepare) Execute(ctx context.Context, args map[string]inte
 				return &ToolResult{Error: fmt.Sprintf("Failed to write CSV: %v", err)}, nil
 			}
 		} else {
-			// 是文件路径
-			csvPath = data
-			if !filepath.IsAbs(csvPath) {
-				csvPath = filepath.Join("workspace", csvPath)
+			// 是文件路径，相对路径相对于工作目录
+			path, err := ResolveWorkspacePath(data)
+			if err != nil {
+				return &ToolResult{Error: err.Error()}, nil
 			}
+			csvPath = path
 		}
 	} else {
 		// 尝试解析为 CSV
@@ -115,6 +137,33 @@ func (v *VisualizationPrepare) Execute(ctx context.Context, args map[string]inte
 		}
 	}
 
+	// 按列分组聚合，聚合结果写入输出目录，不覆盖用户提供的原始文件
+	if groupBy, ok := args["group_by"].(string); ok && groupBy != "" {
+		aggregate, _ := args["aggregate"].(string)
+		valueColumn, _ := args["value_column"].(string)
+
+		records, err := v.validateCSV(csvPath)
+		if err != nil {
+			return &ToolResult{Error: fmt.Sprintf("CSV validation failed: %v", err)}, nil
+		}
+		aggregated, err := aggregateRecords(records, groupBy, aggregate, valueColumn)
+		if err != nil {
+			return &ToolResult{Error: fmt.Sprintf("Aggregation failed: %v", err)}, nil
+		}
+
+		csvPath = filepath.Join(v.outputDir, fmt.Sprintf("%s.csv", strings.ReplaceAll(title, " ", "_")))
+		file, err := os.Create(csvPath)
+		if err != nil {
+			return &ToolResult{Error: fmt.Sprintf("Failed to write CSV: %v", err)}, nil
+		}
+		writer := csv.NewWriter(file)
+		writer.WriteAll(aggregated)
+		file.Close()
+		if err := writer.Error(); err != nil {
+			return &ToolResult{Error: fmt.Sprintf("Failed to write CSV: %v", err)}, nil
+		}
+	}
+
 	// 生成 JSON 元数据
 	jsonPath := filepath.Join(v.outputDir, fmt.Sprintf("%s.json", strings.ReplaceAll(title, " ", "_")))
 	metadata := map[string]interface{}{
@@ -135,22 +184,145 @@ func (v *VisualizationPrepare) Execute(ctx context.Context, args map[string]inte
 	}
 
 	// 验证 CSV 文件
-	if err := v.validateCSV(csvPath); err != nil {
+	records, err := v.validateCSV(csvPath)
+	if err != nil {
 		return &ToolResult{Error: fmt.Sprintf("CSV validation failed: %v", err)}, nil
 	}
 
-	output := fmt.Sprintf("Data prepared successfully!\nCSV: %s\nJSON: %s\n\nUse data_visualization tool with json_path='%s' to generate the chart.", csvPath, jsonPath, jsonPath)
+	output := fmt.Sprintf("Data prepared successfully!\nCSV: %s\nJSON: %s\n\n%s\n\nUse data_visualization tool with json_path='%s' to generate the chart.", csvPath, jsonPath, csvPreview(records, 5), jsonPath)
 	return &ToolResult{Output: output}, nil
 }
 
-func (v *VisualizationPrepare) validateCSV(path string) error {
-	file, err := os.Open(path)
-	if err != nil {
-		return err
+func (v *VisualizationPrepare) validateCSV(path string) ([][]string, error) {
+	return readCSVFile(path)
+}
+
+// aggregateRecords 按 groupBy 列分组，对 valueColumn 做 sum/avg/count/max/min 聚合，
+// 分组按首次出现的顺序输出
+func aggregateRecords(records [][]string, groupBy, aggregate, valueColumn string) ([][]string, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("CSV is empty")
+	}
+	header := records[0]
+
+	groupIdx := columnIndex(header, groupBy)
+	if groupIdx < 0 {
+		return nil, fmt.Errorf("group_by column %q not found (columns: %s)", groupBy, strings.Join(header, ", "))
+	}
+
+	switch aggregate {
+	case "sum", "avg", "count", "max", "min":
+	case "":
+		return nil, fmt.Errorf("aggregate is required when group_by is set")
+	default:
+		return nil, fmt.Errorf("unsupported aggregate %q (use sum, avg, count, max or min)", aggregate)
+	}
+
+	valueIdx := -1
+	if aggregate != "count" {
+		if valueColumn == "" {
+			return nil, fmt.Errorf("value_column is required for %s", aggregate)
+		}
+		valueIdx = columnIndex(header, valueColumn)
+		if valueIdx < 0 {
+			return nil, fmt.Errorf("value_column %q not found (columns: %s)", valueColumn, strings.Join(header, ", "))
+		}
+	}
+
+	type group struct {
+		count int
+		sum   float64
+		min   float64
+		max   float64
+	}
+	var order []string
+	groups := make(map[string]*group)
+
+	for i, row := range records[1:] {
+		if groupIdx >= len(row) {
+			continue
+		}
+		key := row[groupIdx]
+		g, ok := groups[key]
+		if !ok {
+			g = &group{}
+			groups[key] = g
+			order = append(order, key)
+		}
+
+		if valueIdx < 0 {
+			g.count++
+			continue
+		}
+		if valueIdx >= len(row) || strings.TrimSpace(row[valueIdx]) == "" {
+			continue
+		}
+		value, ok := parseNumber(row[valueIdx], "")
+		if !ok {
+			return nil, fmt.Errorf("row %d: %q in column %s is not a number", i+2, row[valueIdx], valueColumn)
+		}
+		if g.count == 0 || value < g.min {
+			g.min = value
+		}
+		if g.count == 0 || value > g.max {
+			g.max = value
+		}
+		g.sum += value
+		g.count++
 	}
-	defer file.Close()
 
-	reader := csv.NewReader(file)
-	_, err = reader.ReadAll()
-	return err
+	valueHeader := "count"
+	if aggregate != "count" {
+		valueHeader = aggregate + "_" + valueColumn
+	}
+	result := [][]string{{groupBy, valueHeader}}
+	for _, key := range order {
+		g := groups[key]
+		var value float64
+		switch aggregate {
+		case "sum":
+			value = g.sum
+		case "avg":
+			if g.count > 0 {
+				value = g.sum / float64(g.count)
+			}
+		case "count":
+			value = float64(g.count)
+		case "max":
+			value = g.max
+		case "min":
+			value = g.min
+		}
+		result = append(result, []string{key, strconv.FormatFloat(value, 'f', -1, 64)})
+	}
+	return result, nil
+}
+
+// columnIndex 查找列名位置（忽略大小写和首尾空白），未找到返回 -1
+func columnIndex(header []string, name string) int {
+	for i, column := range header {
+		if strings.EqualFold(strings.TrimSpace(column), strings.TrimSpace(name)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// csvPreview 生成 CSV 的行列统计和前几行预览
+func csvPreview(records [][]string, maxRows int) string {
+	if len(records) == 0 {
+		return "Rows: 0, Columns: 0 (empty CSV)"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Rows: %d, Columns: %d\n", len(records)-1, len(records[0])))
+	sb.WriteString("Preview:\n")
+	sb.WriteString(strings.Join(records[0], " | "))
+	for i := 1; i < len(records) && i <= maxRows; i++ {
+		sb.WriteString("\n" + strings.Join(records[i], " | "))
+	}
+	if len(records)-1 > maxRows {
+		sb.WriteString(fmt.Sprintf("\n... (%d more rows)", len(records)-1-maxRows))
+	}
+	return sb.String()
 }
@@ -0,0 +1,89 @@
+package tool
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// salesCSV 测试用的销售数据，3 列 6 行
+const salesCSV = `month,region,revenue
+Jan,North,100
+Jan,South,80
+Feb,North,120
+Feb,South,90
+Mar,North,150
+Mar,South,70
+`
+
+func TestVisualizationPreparePreviewsCSV(t *testing.T) {
+	v := NewVisualizationPrepare()
+	result, err := v.Execute(context.Background(), map[string]interface{}{"data": salesCSV, "title": "Preview Sales"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != "" {
+		t.Fatalf("Execute failed: %s", result.Error)
+	}
+
+	// 预览最多 5 行，其余行只给出数量
+	want := "Rows: 6, Columns: 3\nPreview:\nmonth | region | revenue\nJan | North | 100\nJan | South | 80\nFeb | North | 120\nFeb | South | 90\nMar | North | 150\n... (1 more rows)"
+	if !strings.Contains(result.Output, want) {
+		t.Errorf("output does not contain the preview %q:\n%s", want, result.Output)
+	}
+}
+
+func TestVisualizationPrepareGroupsRows(t *testing.T) {
+	v := NewVisualizationPrepare()
+	for _, tc := range []struct {
+		aggregate string
+		want      [][]string
+	}{
+		{"sum", [][]string{{"month", "sum_revenue"}, {"Jan", "180"}, {"Feb", "210"}, {"Mar", "220"}}},
+		{"avg", [][]string{{"month", "avg_revenue"}, {"Jan", "90"}, {"Feb", "105"}, {"Mar", "110"}}},
+		{"max", [][]string{{"month", "max_revenue"}, {"Jan", "100"}, {"Feb", "120"}, {"Mar", "150"}}},
+		{"count", [][]string{{"month", "count"}, {"Jan", "2"}, {"Feb", "2"}, {"Mar", "2"}}},
+	} {
+		t.Run(tc.aggregate, func(t *testing.T) {
+			result, err := v.Execute(context.Background(), map[string]interface{}{
+				"data": salesCSV, "title": "Grouped " + tc.aggregate,
+				"group_by": "month", "aggregate": tc.aggregate, "value_column": "revenue",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Error != "" {
+				t.Fatalf("Execute failed: %s", result.Error)
+			}
+			_, rest, _ := strings.Cut(result.Output, "CSV: ")
+			csvPath, _, _ := strings.Cut(rest, "\n")
+			got, err := readCSVFile(csvPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("grouped CSV = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	result, err := v.Execute(context.Background(), map[string]interface{}{
+		"data": salesCSV, "title": "Grouped missing", "group_by": "quarter", "aggregate": "sum", "value_column": "revenue",
+	})
+	if err != nil || !strings.Contains(result.Error, `group_by column "quarter" not found (columns: month, region, revenue)`) {
+		t.Errorf("unknown group_by column = %+v, %v, want an error listing the columns", result, err)
+	}
+}
+
+func TestVisualizationPrepareReadsWorkspaceFile(t *testing.T) {
+	writeWorkspaceFile(t, "data/sales.csv", salesCSV)
+
+	result, err := NewVisualizationPrepare().Execute(context.Background(), map[string]interface{}{"data": "data/sales.csv", "title": "From File"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != "" || !strings.Contains(result.Output, "Rows: 6, Columns: 3") {
+		t.Errorf("result = %+v, want the CSV read relative to the workspace", result)
+	}
+}
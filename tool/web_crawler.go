@@ -2,19 +2,44 @@ package tool
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"html"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
+	"go-manus/logger"
+
 	"github.com/PuerkitoBio/goquery"
-	"github.com/sirupsen/logrus"
 )
 
 type WebCrawler struct{}
 
+const (
+	// defaultCrawlConcurrency 同时抓取的 URL 数
+	defaultCrawlConcurrency = 3
+	maxCrawlConcurrency     = 10
+)
+
+// skippedMessage 超出总时间预算而未完成的 URL 的说明
+const skippedMessage = "skipped: time budget exceeded"
+
+// crawlOptions 单次抓取的可选行为
+type crawlOptions struct {
+	// extractLinks 返回页面中的全部链接而不是正文
+	extractLinks bool
+	// sameDomain 仅保留与页面同域名的链接
+	sameDomain bool
+	// keepLinks 在正文中保留链接地址，形如 "text (url)"
+	keepLinks bool
+	// keepImages 在正文中以 "[image: alt]" 表示带 alt 文本的图片
+	keepImages bool
+}
+
 func NewWebCrawler() *WebCrawler {
 	return &WebCrawler{}
 }
@@ -29,7 +54,11 @@ func (w *WebCrawler) Description() string {
 Features:
 - Extracts clean text content optimized for LLMs
 - Handles basic HTML parsing
-- Supports multiple URLs in a single request
+- Supports multiple URLs in a single request, crawled concurrently
+- Optional overall time budget: URLs not finished in time are reported as skipped
+- Captures page metadata (meta description, OpenGraph title/description/image, canonical URL)
+- Can return all links on each page (absolute, deduplicated) instead of text content
+- Can keep link targets inline ("text (url)") and images as their alt text in the extracted content
 - Fast and reliable with built-in error handling
 
 Perfect for content analysis, research, and feeding web content to AI models.`
@@ -52,6 +81,33 @@ func (w *WebCrawler) Parameters() map[string]interface{} {
 				"minimum":     5,
 				"maximum":     120,
 			},
+			"concurrency": map[string]interface{}{
+				"type":        "integer",
+				"description": "(optional) Number of URLs crawled at the same time. Default is 3.",
+				"default":     defaultCrawlConcurrency,
+				"minimum":     1,
+				"maximum":     maxCrawlConcurrency,
+			},
+			"time_budget": map[string]interface{}{
+				"type":        "integer",
+				"description": "(optional) Total time in seconds for the whole batch. When exceeded, remaining crawls are cancelled and reported as skipped. Default is no limit.",
+			},
+			"extract_links": map[string]interface{}{
+				"type":        "boolean",
+				"description": "(optional) Return all <a href> links found on each page, resolved to absolute URLs and deduplicated, instead of text content. Default is false.",
+			},
+			"same_domain": map[string]interface{}{
+				"type":        "boolean",
+				"description": "(optional) With extract_links, only keep links on the same domain as the crawled page. Default is false.",
+			},
+			"keep_links": map[string]interface{}{
+				"type":        "boolean",
+				"description": "(optional) Keep link targets in the text content, written as \"link text (url)\". Default is false.",
+			},
+			"keep_images": map[string]interface{}{
+				"type":        "boolean",
+				"description": "(optional) Represent images in the text content by their alt text, written as \"[image: alt]\". Default is false.",
+			},
 		},
 		"required": []string{"urls"},
 	}
@@ -73,6 +129,25 @@ func (w *WebCrawler) Execute(ctx context.Context, args map[string]interface{}) (
 		timeout = int(t)
 	}
 
+	concurrency := defaultCrawlConcurrency
+	if c, ok := args["concurrency"].(float64); ok && c >= 1 {
+		concurrency = int(c)
+	}
+	if concurrency > maxCrawlConcurrency {
+		concurrency = maxCrawlConcurrency
+	}
+
+	var timeBudget time.Duration
+	if b, ok := args["time_budget"].(float64); ok && b > 0 {
+		timeBudget = time.Duration(b * float64(time.Second))
+	}
+
+	var opts crawlOptions
+	opts.extractLinks, _ = args["extract_links"].(bool)
+	opts.sameDomain, _ = args["same_domain"].(bool)
+	opts.keepLinks, _ = args["keep_links"].(bool)
+	opts.keepImages, _ = args["keep_images"].(bool)
+
 	// Convert to string slice
 	urls := make([]string, 0, len(urlsInterface))
 	for _, u := range urlsInterface {
@@ -80,7 +155,7 @@ func (w *WebCrawler) Execute(ctx context.Context, args map[string]interface{}) (
 			if w.isValidURL(urlStr) {
 				urls = append(urls, urlStr)
 			} else {
-				logrus.Warnf("Invalid URL skipped: %s", urlStr)
+				logger.FromContext(ctx).Warnf("Invalid URL skipped: %s", urlStr)
 			}
 		}
 	}
@@ -89,23 +164,23 @@ func (w *WebCrawler) Execute(ctx context.Context, args map[string]interface{}) (
 		return &ToolResult{Error: "No valid URLs provided"}, nil
 	}
 
-	results := make([]map[string]interface{}, 0)
-	successfulCount := 0
-	failedCount := 0
-
 	// Create HTTP client with timeout
 	client := &http.Client{
 		Timeout: time.Duration(timeout) * time.Second,
 	}
 
-	// Process each URL
-	for _, urlStr := range urls {
-		result := w.crawlURL(ctx, client, urlStr, timeout)
-		results = append(results, result)
+	results := w.crawlAll(ctx, client, urls, timeout, concurrency, timeBudget, opts)
 
-		if result["success"].(bool) {
+	successfulCount := 0
+	failedCount := 0
+	skippedCount := 0
+	for _, result := range results {
+		switch {
+		case result["success"].(bool):
 			successfulCount++
-		} else {
+		case result["skipped"] == true:
+			skippedCount++
+		default:
 			failedCount++
 		}
 	}
@@ -115,7 +190,11 @@ func (w *WebCrawler) Execute(ctx context.Context, args map[string]interface{}) (
 	output.WriteString("🕷️ Web Crawler Results Summary:\n")
 	output.WriteString(fmt.Sprintf("📊 Total URLs: %d\n", len(urls)))
 	output.WriteString(fmt.Sprintf("✅ Successful: %d\n", successfulCount))
-	output.WriteString(fmt.Sprintf("❌ Failed: %d\n\n", failedCount))
+	output.WriteString(fmt.Sprintf("❌ Failed: %d\n", failedCount))
+	if skippedCount > 0 {
+		output.WriteString(fmt.Sprintf("⏭️ Skipped: %d (time budget of %s exceeded)\n", skippedCount, timeBudget))
+	}
+	output.WriteString("\n")
 
 	for i, result := range results {
 		output.WriteString(fmt.Sprintf("%d. %s\n", i+1, result["url"]))
@@ -125,7 +204,20 @@ func (w *WebCrawler) Execute(ctx context.Context, args map[string]interface{}) (
 			if title, ok := result["title"].(string); ok && title != "" {
 				output.WriteString(fmt.Sprintf("   📄 Title: %s\n", title))
 			}
-			if content, ok := result["content"].(string); ok {
+			if metadata, ok := result["metadata"].(map[string]string); ok && len(metadata) > 0 {
+				output.WriteString("   🏷️ Metadata:\n")
+				for _, key := range metadataKeys {
+					if value := metadata[key]; value != "" {
+						output.WriteString(fmt.Sprintf("      %s: %s\n", key, value))
+					}
+				}
+			}
+			if links, ok := result["links"].([]string); ok {
+				output.WriteString(fmt.Sprintf("   🔗 Links (%d):\n", len(links)))
+				for _, link := range links {
+					output.WriteString("      " + link + "\n")
+				}
+			} else if content, ok := result["content"].(string); ok {
 				preview := content
 				if len(preview) > 300 {
 					preview = preview[:300] + "..."
@@ -135,6 +227,8 @@ func (w *WebCrawler) Execute(ctx context.Context, args map[string]interface{}) (
 			if wordCount, ok := result["word_count"].(int); ok {
 				output.WriteString(fmt.Sprintf("   📊 Word Count: %d\n", wordCount))
 			}
+		} else if result["skipped"] == true {
+			output.WriteString("   ⏭️ Status: Skipped (time budget exceeded)\n")
 		} else {
 			output.WriteString("   ❌ Status: Failed\n")
 			if errMsg, ok := result["error_message"].(string); ok {
@@ -147,7 +241,60 @@ func (w *WebCrawler) Execute(ctx context.Context, args map[string]interface{}) (
 	return &ToolResult{Output: output.String()}, nil
 }
 
-func (w *WebCrawler) crawlURL(ctx context.Context, client *http.Client, urlStr string, timeout int) map[string]interface{} {
+// crawlAll 以固定数量的 worker 抓取全部 URL，结果顺序与 urls 一致。
+// 设置了 timeBudget 时，超时后尚未开始或被中断的 URL 标记为 skipped
+func (w *WebCrawler) crawlAll(ctx context.Context, client *http.Client, urls []string, timeout, concurrency int, timeBudget time.Duration, opts crawlOptions) []map[string]interface{} {
+	budgetCtx := ctx
+	if timeBudget > 0 {
+		var cancel context.CancelFunc
+		budgetCtx, cancel = context.WithTimeout(ctx, timeBudget)
+		defer cancel()
+	}
+
+	// 只有预算耗尽（而不是调用方取消）才算 skipped
+	budgetExceeded := func() bool {
+		return ctx.Err() == nil && errors.Is(budgetCtx.Err(), context.DeadlineExceeded)
+	}
+
+	results := make([]map[string]interface{}, len(urls))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency && i < len(urls); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				if budgetExceeded() {
+					results[idx] = skippedResult(urls[idx])
+					continue
+				}
+				result := w.crawlURL(budgetCtx, client, urls[idx], timeout, opts)
+				if !result["success"].(bool) && budgetExceeded() {
+					result = skippedResult(urls[idx])
+				}
+				results[idx] = result
+			}
+		}()
+	}
+	for idx := range urls {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+func skippedResult(urlStr string) map[string]interface{} {
+	return map[string]interface{}{
+		"url":           urlStr,
+		"success":       false,
+		"skipped":       true,
+		"error_message": skippedMessage,
+	}
+}
+
+func (w *WebCrawler) crawlURL(ctx context.Context, client *http.Client, urlStr string, timeout int, opts crawlOptions) map[string]interface{} {
 	startTime := time.Now()
 
 	// Create request with context
@@ -205,9 +352,26 @@ func (w *WebCrawler) crawlURL(ctx context.Context, client *http.Client, urlStr s
 	// Extract title
 	title := doc.Find("title").First().Text()
 	title = strings.TrimSpace(title)
+	metadata := extractMetadata(doc, resp.Request.URL)
+
+	if opts.extractLinks {
+		// 重定向后以最终地址为基准解析相对链接
+		links := extractLinks(doc, resp.Request.URL, opts.sameDomain)
+		logger.FromContext(ctx).Infof("✅ Extracted %d links from %s in %.2fs", len(links), urlStr, time.Since(startTime).Seconds())
+		return map[string]interface{}{
+			"url":            urlStr,
+			"success":        true,
+			"status_code":    resp.StatusCode,
+			"title":          title,
+			"metadata":       metadata,
+			"links":          links,
+			"execution_time": time.Since(startTime).Seconds(),
+		}
+	}
 
 	// Extract text content (remove script and style tags)
 	doc.Find("script, style").Remove()
+	annotateContent(doc, resp.Request.URL, opts)
 	content := doc.Find("body").Text()
 	content = strings.TrimSpace(content)
 
@@ -225,19 +389,125 @@ func (w *WebCrawler) crawlURL(ctx context.Context, client *http.Client, urlStr s
 	wordCount := len(strings.Fields(content))
 	executionTime := time.Since(startTime).Seconds()
 
-	logrus.Infof("✅ Successfully crawled %s in %.2fs", urlStr, executionTime)
+	logger.FromContext(ctx).Infof("✅ Successfully crawled %s in %.2fs", urlStr, executionTime)
 
 	return map[string]interface{}{
 		"url":          urlStr,
 		"success":      true,
 		"status_code":  resp.StatusCode,
 		"title":        title,
+		"metadata":     metadata,
 		"content":      content,
 		"word_count":   wordCount,
 		"execution_time": executionTime,
 	}
 }
 
+// metadataKeys 元数据字段及输出顺序
+var metadataKeys = []string{"description", "og:title", "og:description", "og:image", "canonical"}
+
+// extractMetadata 提取 meta description、OpenGraph 标签和 canonical 地址，图片和 canonical 解析为绝对地址
+func extractMetadata(doc *goquery.Document, pageURL *url.URL) map[string]string {
+	metadata := make(map[string]string)
+	doc.Find("meta").Each(func(_ int, sel *goquery.Selection) {
+		key := strings.ToLower(strings.TrimSpace(sel.AttrOr("property", sel.AttrOr("name", ""))))
+		content := strings.TrimSpace(sel.AttrOr("content", ""))
+		if content == "" || metadata[key] != "" {
+			return
+		}
+		switch key {
+		case "description", "og:title", "og:description":
+			metadata[key] = content
+		case "og:image":
+			metadata[key] = resolveURL(pageURL, content)
+		}
+	})
+	if href, ok := doc.Find(`link[rel="canonical"]`).First().Attr("href"); ok && strings.TrimSpace(href) != "" {
+		metadata["canonical"] = resolveURL(pageURL, strings.TrimSpace(href))
+	}
+	return metadata
+}
+
+// resolveURL 将相对地址解析为绝对地址，解析失败时原样返回
+func resolveURL(base *url.URL, ref string) string {
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+// documentBase 返回解析相对链接的基准地址，页面声明了 <base href> 时以其为准
+func documentBase(doc *goquery.Document, pageURL *url.URL) *url.URL {
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if u, err := pageURL.Parse(strings.TrimSpace(href)); err == nil {
+			return u
+		}
+	}
+	return pageURL
+}
+
+// annotateContent 按选项把图片和链接改写为文本，使提取的正文保留这些信息。
+// 先处理图片，图片链接的文字即为图片的 alt 文本
+func annotateContent(doc *goquery.Document, pageURL *url.URL, opts crawlOptions) {
+	if opts.keepImages {
+		doc.Find("img").Each(func(_ int, sel *goquery.Selection) {
+			alt := strings.TrimSpace(sel.AttrOr("alt", ""))
+			if alt == "" {
+				return
+			}
+			sel.ReplaceWithHtml(" [image: " + html.EscapeString(alt) + "] ")
+		})
+	}
+
+	if opts.keepLinks {
+		base := documentBase(doc, pageURL)
+		doc.Find("a[href]").Each(func(_ int, sel *goquery.Selection) {
+			href, _ := sel.Attr("href")
+			u, err := base.Parse(strings.TrimSpace(href))
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				return
+			}
+			link := u.String()
+			text := strings.Join(strings.Fields(sel.Text()), " ")
+			switch {
+			case text == "":
+				sel.SetText(link)
+			case text != link:
+				sel.SetText(text + " (" + link + ")")
+			}
+		})
+	}
+}
+
+// extractLinks 提取页面中 <a href> 的绝对地址，去掉锚点、主机名转为小写后去重，保持出现顺序
+func extractLinks(doc *goquery.Document, pageURL *url.URL, sameDomain bool) []string {
+	base := documentBase(doc, pageURL)
+
+	seen := make(map[string]bool)
+	links := make([]string, 0)
+	doc.Find("a[href]").Each(func(_ int, sel *goquery.Selection) {
+		href, _ := sel.Attr("href")
+		u, err := base.Parse(strings.TrimSpace(href))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return
+		}
+		if sameDomain && !strings.EqualFold(u.Hostname(), pageURL.Hostname()) {
+			return
+		}
+		// 主机名不区分大小写，统一小写后再去重
+		u.Host = strings.ToLower(u.Host)
+		u.Fragment = ""
+		u.RawFragment = ""
+		link := u.String()
+		if !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+	})
+	return links
+}
+
 func (w *WebCrawler) isValidURL(urlStr string) bool {
 	u, err := url.Parse(urlStr)
 	if err != nil {
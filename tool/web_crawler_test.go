@@ -0,0 +1,150 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWebCrawlerKeepLinks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><p>Read the <a href="/docs/intro">introduction</a> first.</p><img src="/logo.png" alt="Project logo"></body></html>`)
+	}))
+	defer srv.Close()
+
+	crawl := func(args map[string]interface{}) string {
+		t.Helper()
+		args["urls"] = []interface{}{srv.URL + "/page"}
+		result, err := NewWebCrawler().Execute(context.Background(), args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(result.Output, "Successful: 1") {
+			t.Fatalf("crawl failed:\n%s", result.Output)
+		}
+		return result.Output
+	}
+
+	link := "introduction (" + srv.URL + "/docs/intro)"
+	if out := crawl(map[string]interface{}{}); strings.Contains(out, link) || strings.Contains(out, "[image:") {
+		t.Errorf("links or images kept without keep_links/keep_images:\n%s", out)
+	}
+	out := crawl(map[string]interface{}{"keep_links": true, "keep_images": true})
+	if !strings.Contains(out, link) {
+		t.Errorf("output does not contain %q:\n%s", link, out)
+	}
+	if !strings.Contains(out, "[image: Project logo]") {
+		t.Errorf("output does not contain the image alt text:\n%s", out)
+	}
+}
+
+func TestWebCrawlerTimeBudgetSkipsSlowURLs(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		fmt.Fprint(w, `<html><body>fast page</body></html>`)
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	result, err := NewWebCrawler().Execute(context.Background(), map[string]interface{}{
+		"urls":        []interface{}{srv.URL + "/fast", srv.URL + "/slow", srv.URL + "/slow?page=2"},
+		"time_budget": 0.2,
+		"concurrency": float64(2),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Successful: 1", "Failed: 0", "Skipped: 2"} {
+		if !strings.Contains(result.Output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, result.Output)
+		}
+	}
+}
+
+func TestWebCrawlerExtractLinks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body>
+			<a href="intro.html">Intro</a>
+			<a href="/about">About</a>
+			<a href="../blog/post#comments">Post</a>
+			<a href="https://example.com/elsewhere">Elsewhere</a>
+			<a href="intro.html#install">Intro again</a>
+			<a href="/about">About again</a>
+			<a href="mailto:team@example.com">Mail</a>
+			<a href="javascript:void(0)">Menu</a>
+			<a href="https://EXAMPLE.com/elsewhere#top">Elsewhere again</a>
+		</body></html>`)
+	}))
+	defer srv.Close()
+
+	crawl := func(sameDomain bool) []string {
+		t.Helper()
+		result := NewWebCrawler().crawlURL(context.Background(), srv.Client(), srv.URL+"/docs/page.html", 10,
+			crawlOptions{extractLinks: true, sameDomain: sameDomain})
+		links, ok := result["links"].([]string)
+		if !ok {
+			t.Fatalf("crawl result has no links: %v", result)
+		}
+		return links
+	}
+
+	all := []string{
+		srv.URL + "/docs/intro.html",
+		srv.URL + "/about",
+		srv.URL + "/blog/post",
+		"https://example.com/elsewhere",
+	}
+	if got := crawl(false); !reflect.DeepEqual(got, all) {
+		t.Errorf("links = %q, want %q", got, all)
+	}
+	if got, want := crawl(true), all[:3]; !reflect.DeepEqual(got, want) {
+		t.Errorf("same_domain links = %q, want %q", got, want)
+	}
+}
+
+func TestWebCrawlerExtractsMetadata(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head>
+			<title>Release notes</title>
+			<meta name="description" content="What changed in this release.">
+			<meta property="og:title" content="Release 2.0">
+			<meta property="og:description" content="Faster builds and a new CLI.">
+			<meta property="og:image" content="/img/cover.png">
+			<meta property="og:title" content="A later duplicate">
+			<meta name="keywords" content="ignored">
+			<link rel="canonical" href="/releases/2.0">
+		</head><body>Notes</body></html>`)
+	}))
+	defer srv.Close()
+
+	result := NewWebCrawler().crawlURL(context.Background(), srv.Client(), srv.URL+"/news?id=7", 10, crawlOptions{})
+	want := map[string]string{
+		"description":    "What changed in this release.",
+		"og:title":       "Release 2.0",
+		"og:description": "Faster builds and a new CLI.",
+		"og:image":       srv.URL + "/img/cover.png",
+		"canonical":      srv.URL + "/releases/2.0",
+	}
+	if got, _ := result["metadata"].(map[string]string); !reflect.DeepEqual(got, want) {
+		t.Errorf("metadata = %v, want %v", got, want)
+	}
+
+	output, err := NewWebCrawler().Execute(context.Background(), map[string]interface{}{"urls": []interface{}{srv.URL + "/news"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output.Output, "🏷️ Metadata:\n      description: What changed in this release.\n      og:title: Release 2.0\n") {
+		t.Errorf("output does not list the metadata in order:\n%s", output.Output)
+	}
+}
@@ -3,16 +3,31 @@ package tool
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
+	"time"
+
+	"go-manus/config"
+
+	"github.com/sirupsen/logrus"
 )
 
+// engineDownTTL 引擎失败后被排到最后的时长，之后恢复原来的顺序
+const engineDownTTL = 5 * time.Minute
+
 type WebSearch struct {
 	engines map[string]SearchEngine
+	health  *engineHealthTracker
+	// defaultEngine 和 fallbackOrder 来自 [search] 配置，未指定 engine 和 fallback_engines 时使用
+	defaultEngine string
+	fallbackOrder []string
 }
 
 func NewWebSearch() *WebSearch {
 	ws := &WebSearch{
 		engines: make(map[string]SearchEngine),
+		health:  newEngineHealthTracker(engineDownTTL),
 	}
 
 	// Register search engines
@@ -20,18 +35,41 @@ func NewWebSearch() *WebSearch {
 	ws.engines["baidu"] = NewBaiduSearch()
 	ws.engines["bing"] = NewBingSearch()
 	ws.engines["duckduckgo"] = NewDuckDuckGoSearch()
+	ws.engines["searxng"] = NewSearxSearch()
 
+	settings := config.GetInstance().GetSearch()
+	ws.SetEngineOrder(settings.DefaultEngine, settings.FallbackOrder)
 	return ws
 }
 
+// SetEngineOrder 设置默认引擎和回退顺序，忽略未注册的引擎名
+func (w *WebSearch) SetEngineOrder(defaultEngine string, fallbackOrder []string) {
+	w.defaultEngine = "google"
+	if _, exists := w.engines[defaultEngine]; exists {
+		w.defaultEngine = defaultEngine
+	} else if defaultEngine != "" {
+		logrus.Warnf("Unknown search engine %q in [search] default_engine, using google", defaultEngine)
+	}
+
+	w.fallbackOrder = make([]string, 0, len(fallbackOrder))
+	for _, name := range fallbackOrder {
+		if _, exists := w.engines[name]; !exists {
+			logrus.Warnf("Unknown search engine %q in [search] fallback_order, ignoring it", name)
+			continue
+		}
+		w.fallbackOrder = append(w.fallbackOrder, name)
+	}
+}
+
 func (w *WebSearch) Name() string {
 	return "web_search"
 }
 
 func (w *WebSearch) Description() string {
 	return `Unified web search tool that supports multiple search engines.
-Available engines: google, baidu, bing, duckduckgo.
-Use this tool when you need to find information on the web. The tool will try multiple engines if one fails.`
+Available engines: google, baidu, bing, duckduckgo, searxng.
+Use this tool when you need to find information on the web. The tool will try multiple engines if one fails,
+skipping engines that failed recently.`
 }
 
 func (w *WebSearch) Parameters() map[string]interface{} {
@@ -44,19 +82,34 @@ func (w *WebSearch) Parameters() map[string]interface{} {
 			},
 			"engine": map[string]interface{}{
 				"type":        "string",
-				"description": "(optional) Search engine to use. Options: google, baidu, bing, duckduckgo. Default is google.",
-				"enum":        []string{"google", "baidu", "bing", "duckduckgo"},
-				"default":     "google",
+				"description": fmt.Sprintf("(optional) Search engine to use. Options: google, baidu, bing, duckduckgo, searxng. Default is %s.", w.defaultEngine),
+				"enum":        []string{"google", "baidu", "bing", "duckduckgo", "searxng"},
+				"default":     w.defaultEngine,
 			},
 			"num_results": map[string]interface{}{
 				"type":        "integer",
 				"description": "(optional) The number of search results to return. Default is 10.",
 				"default":     10,
 			},
+			"snippet_length": map[string]interface{}{
+				"type":        "integer",
+				"description": "(optional) Maximum number of characters kept from each result snippet. Default is 200.",
+				"default":     200,
+			},
 			"fallback_engines": map[string]interface{}{
 				"type":        "array",
 				"items":       map[string]interface{}{"type": "string"},
-				"description": "(optional) Fallback engines to try if primary engine fails.",
+				"description": fmt.Sprintf("(optional) Fallback engines to try if primary engine fails. Default is [%s].", strings.Join(w.fallbackOrder, ", ")),
+			},
+			"aggregate": map[string]interface{}{
+				"type":        "boolean",
+				"description": "(optional) Query several engines concurrently and return merged, deduplicated and ranked results with their source engines. Default is false.",
+				"default":     false,
+			},
+			"engines": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "(optional) Engines to query in aggregate mode. Default is all engines.",
 			},
 		},
 		"required": []string{"query"},
@@ -69,7 +122,7 @@ func (w *WebSearch) Execute(ctx context.Context, args map[string]interface{}) (*
 		return &ToolResult{Error: "query parameter is required"}, nil
 	}
 
-	engineName := "google"
+	engineName := w.defaultEngine
 	if e, ok := args["engine"].(string); ok && e != "" {
 		engineName = e
 	}
@@ -78,20 +131,30 @@ func (w *WebSearch) Execute(ctx context.Context, args map[string]interface{}) (*
 	if n, ok := args["num_results"].(float64); ok {
 		numResults = int(n)
 	}
+	snippetLength := snippetLengthArg(args)
 
-	// Get primary engine
-	engine, exists := w.engines[engineName]
-	if !exists {
-		return &ToolResult{Error: fmt.Sprintf("Unknown search engine: %s", engineName)}, nil
+	if aggregate, ok := args["aggregate"].(bool); ok && aggregate {
+		names := stringSliceArg(args["engines"])
+		if len(names) == 0 {
+			for name := range w.engines {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+		}
+		for _, name := range names {
+			if _, exists := w.engines[name]; !exists {
+				return &ToolResult{Error: fmt.Sprintf("Unknown search engine: %s", name)}, nil
+			}
+		}
+		return w.aggregateSearch(ctx, names, query, numResults, snippetLength), nil
 	}
 
-	// Try primary engine
-	result, err := w.trySearch(ctx, engine, query, numResults)
-	if err == nil {
-		return result, nil
+	// Get primary engine
+	if _, exists := w.engines[engineName]; !exists {
+		return &ToolResult{Error: fmt.Sprintf("Unknown search engine: %s", engineName)}, nil
 	}
 
-	// Try fallback engines
+	// Collect fallback engines
 	var fallbackEngines []string
 	if fe, ok := args["fallback_engines"].([]interface{}); ok {
 		for _, e := range fe {
@@ -100,28 +163,27 @@ func (w *WebSearch) Execute(ctx context.Context, args map[string]interface{}) (*
 			}
 		}
 	} else {
-		// Default fallback order
-		fallbackEngines = []string{"bing", "duckduckgo", "baidu"}
+		fallbackEngines = w.fallbackOrder
 	}
 
-	errors := []string{fmt.Sprintf("%s: %v", engineName, err)}
-	for _, fallbackName := range fallbackEngines {
-		if fallbackName == engineName {
-			continue
+	// Try engines in order, engines known to be down last
+	var errors []string
+	for _, name := range w.orderEngines(engineName, fallbackEngines) {
+		result, err := w.trySearch(ctx, w.engines[name], query, numResults, snippetLength)
+		if ctx.Err() == nil {
+			w.health.record(name, err)
 		}
-		fallbackEngine, exists := w.engines[fallbackName]
-		if !exists {
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("%s: %v", name, err))
 			continue
 		}
-
-		result, err := w.trySearch(ctx, fallbackEngine, query, numResults)
-		if err == nil {
-			return &ToolResult{
-				Output: fmt.Sprintf("Primary engine (%s) failed, but fallback engine (%s) succeeded:\n\n%s",
-					engineName, fallbackName, result.Output),
-			}, nil
+		if name == engineName {
+			return result, nil
 		}
-		errors = append(errors, fmt.Sprintf("%s: %v", fallbackName, err))
+		return &ToolResult{
+			Output: fmt.Sprintf("Primary engine (%s) failed, but fallback engine (%s) succeeded:\n\n%s",
+				engineName, name, result.Output),
+		}, nil
 	}
 
 	return &ToolResult{
@@ -129,7 +191,7 @@ func (w *WebSearch) Execute(ctx context.Context, args map[string]interface{}) (*
 	}, nil
 }
 
-func (w *WebSearch) trySearch(ctx context.Context, engine SearchEngine, query string, numResults int) (*ToolResult, error) {
+func (w *WebSearch) trySearch(ctx context.Context, engine SearchEngine, query string, numResults, snippetLength int) (*ToolResult, error) {
 	// Try to use Search method if available
 	if searcher, ok := engine.(interface {
 		Search(ctx context.Context, query string, numResults int) ([]SearchResult, error)
@@ -138,6 +200,7 @@ func (w *WebSearch) trySearch(ctx context.Context, engine SearchEngine, query st
 		if err != nil {
 			return nil, err
 		}
+		results = dedupResults(results)
 
 		var output strings.Builder
 		output.WriteString(fmt.Sprintf("%s Search Results for: %s\n\n", engine.Name(), query))
@@ -145,7 +208,7 @@ func (w *WebSearch) trySearch(ctx context.Context, engine SearchEngine, query st
 			output.WriteString(fmt.Sprintf("%d. %s\n", i+1, result.Title))
 			output.WriteString(fmt.Sprintf("   URL: %s\n", result.URL))
 			if result.Snippet != "" {
-				output.WriteString(fmt.Sprintf("   %s\n", result.Snippet))
+				output.WriteString(fmt.Sprintf("   %s\n", truncateSnippet(result.Snippet, snippetLength)))
 			}
 			output.WriteString("\n")
 		}
@@ -156,11 +219,102 @@ func (w *WebSearch) trySearch(ctx context.Context, engine SearchEngine, query st
 	// Fallback to Execute method (engine must also implement Tool interface)
 	if toolEngine, ok := engine.(Tool); ok {
 		args := map[string]interface{}{
-			"query":       query,
-			"num_results": numResults,
+			"query":          query,
+			"num_results":    numResults,
+			"snippet_length": float64(snippetLength),
 		}
 		return toolEngine.Execute(ctx, args)
 	}
 
 	return nil, fmt.Errorf("engine does not implement Search or Tool interface")
 }
+
+// rrfK 倒数排名融合（Reciprocal Rank Fusion）的平滑常数
+const rrfK = 60
+
+// aggregatedResult 合并后的搜索结果及其来源引擎
+type aggregatedResult struct {
+	SearchResult
+	sources []string
+	score   float64
+}
+
+// aggregateSearch 并发查询多个引擎，按规范 URL 合并去重，
+// 并按倒数排名融合打分排序，被多个引擎返回的结果排名更靠前
+func (w *WebSearch) aggregateSearch(ctx context.Context, names []string, query string, numResults, snippetLength int) *ToolResult {
+	type engineResults struct {
+		results []SearchResult
+		err     error
+	}
+	collected := make([]engineResults, len(names))
+
+	var wg sync.WaitGroup
+	for i, name := range names {
+		if w.health.isDown(name) {
+			collected[i].err = fmt.Errorf("skipped, marked unavailable after a recent failure")
+			continue
+		}
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			results, err := w.engines[name].Search(ctx, query, numResults)
+			if ctx.Err() == nil {
+				w.health.record(name, err)
+			}
+			collected[i] = engineResults{results: dedupResults(results), err: err}
+		}(i, name)
+	}
+	wg.Wait()
+
+	merged := make([]*aggregatedResult, 0)
+	index := make(map[string]*aggregatedResult)
+	var errors []string
+	for i, name := range names {
+		if collected[i].err != nil {
+			errors = append(errors, fmt.Sprintf("%s: %v", name, collected[i].err))
+			continue
+		}
+		for rank, result := range collected[i].results {
+			key := canonicalURL(result.URL)
+			item, ok := index[key]
+			if !ok {
+				item = &aggregatedResult{SearchResult: result}
+				index[key] = item
+				merged = append(merged, item)
+			} else if len(result.Snippet) > len(item.Snippet) {
+				item.Snippet = result.Snippet
+			}
+			item.sources = append(item.sources, name)
+			item.score += 1.0 / float64(rrfK+rank+1)
+		}
+	}
+
+	if len(merged) == 0 {
+		if len(errors) == len(names) {
+			return &ToolResult{Error: fmt.Sprintf("All search engines failed:\n%s", strings.Join(errors, "\n"))}
+		}
+		return &ToolResult{Output: "No search results found"}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].score > merged[j].score
+	})
+	if len(merged) > numResults {
+		merged = merged[:numResults]
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Aggregated Search Results for: %s (engines: %s)\n\n", query, strings.Join(names, ", ")))
+	for i, result := range merged {
+		output.WriteString(fmt.Sprintf("%d. %s\n", i+1, result.Title))
+		output.WriteString(fmt.Sprintf("   URL: %s\n", result.URL))
+		if result.Snippet != "" {
+			output.WriteString(fmt.Sprintf("   %s\n", truncateSnippet(result.Snippet, snippetLength)))
+		}
+		output.WriteString(fmt.Sprintf("   Sources: %s\n\n", strings.Join(result.sources, ", ")))
+	}
+	if len(errors) > 0 {
+		output.WriteString(fmt.Sprintf("Failed engines:\n%s\n", strings.Join(errors, "\n")))
+	}
+	return &ToolResult{Output: output.String()}
+}
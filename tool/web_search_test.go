@@ -0,0 +1,131 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeEngine 返回固定结果或错误的搜索引擎，并记录调用次数
+type fakeEngine struct {
+	name    string
+	results []SearchResult
+	err     error
+	calls   int
+}
+
+func (f *fakeEngine) Name() string { return f.name }
+func (f *fakeEngine) Search(ctx context.Context, query string, numResults int) ([]SearchResult, error) {
+	f.calls++
+	return f.results, f.err
+}
+
+// newFakeWebSearch 创建只包含给定引擎的 WebSearch
+func newFakeWebSearch(engines ...*fakeEngine) *WebSearch {
+	ws := NewWebSearch()
+	ws.engines = make(map[string]SearchEngine)
+	for _, e := range engines {
+		ws.engines[e.name] = e
+	}
+	return ws
+}
+
+func TestWebSearchUsesConfiguredEngineOrder(t *testing.T) {
+	down := errors.New("unavailable")
+	google := &fakeEngine{name: "google", err: down}
+	bing := &fakeEngine{name: "bing", err: down}
+	searxng := &fakeEngine{name: "searxng", err: down}
+	duckduckgo := &fakeEngine{name: "duckduckgo", results: []SearchResult{{Title: "Go", URL: "https://go.dev"}}}
+	ws := newFakeWebSearch(google, bing, searxng, duckduckgo)
+	ws.SetEngineOrder("searxng", []string{"duckduckgo", "unknown", "bing"})
+
+	result, err := ws.Execute(context.Background(), map[string]interface{}{"query": "golang"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Output, "Primary engine (searxng) failed, but fallback engine (duckduckgo) succeeded") {
+		t.Fatalf("output = %q, error = %q", result.Output, result.Error)
+	}
+	if searxng.calls != 1 || duckduckgo.calls != 1 {
+		t.Errorf("searxng ran %d times and duckduckgo %d times, want 1 each", searxng.calls, duckduckgo.calls)
+	}
+	if google.calls != 0 || bing.calls != 0 {
+		t.Errorf("google ran %d times and bing %d times, want neither tried", google.calls, bing.calls)
+	}
+}
+
+func TestWebSearchEmptyFallbackOrderDisablesFallback(t *testing.T) {
+	google := &fakeEngine{name: "google", err: errors.New("unavailable")}
+	bing := &fakeEngine{name: "bing", results: []SearchResult{{Title: "Go", URL: "https://go.dev"}}}
+	ws := newFakeWebSearch(google, bing)
+	ws.SetEngineOrder("google", []string{})
+
+	result, err := ws.Execute(context.Background(), map[string]interface{}{"query": "golang"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Error, "All search engines failed") {
+		t.Errorf("result = %+v, want all engines failed", result)
+	}
+	if bing.calls != 0 {
+		t.Errorf("bing ran %d times with fallback disabled", bing.calls)
+	}
+}
+
+func TestWebSearchDedupsCanonicalURLs(t *testing.T) {
+	google := &fakeEngine{name: "google", results: []SearchResult{
+		{Title: "Go", URL: "https://go.dev/doc/?utm_source=feed", Snippet: "short"},
+		{Title: "Blog", URL: "https://go.dev/blog"},
+		{Title: "Go again", URL: "http://GO.dev/doc#install", Snippet: "a longer snippet"},
+	}}
+	ws := newFakeWebSearch(google)
+
+	result, err := ws.Execute(context.Background(), map[string]interface{}{"query": "golang"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(result.Output, "Go again") || !strings.Contains(result.Output, "2. Blog") {
+		t.Errorf("duplicate URL was not removed:\n%s", result.Output)
+	}
+	if !strings.Contains(result.Output, "a longer snippet") {
+		t.Errorf("the longer snippet was not kept:\n%s", result.Output)
+	}
+}
+
+func TestAggregateSearchMergesEngines(t *testing.T) {
+	google := &fakeEngine{name: "google", results: []SearchResult{
+		{Title: "Go docs", URL: "https://go.dev/doc/?utm_source=feed", Snippet: "short"},
+		{Title: "Go blog", URL: "https://go.dev/blog"},
+	}}
+	bing := &fakeEngine{name: "bing", results: []SearchResult{
+		{Title: "Go by Example", URL: "https://gobyexample.com/"},
+		{Title: "Documentation", URL: "http://go.dev/doc", Snippet: "a longer snippet from bing"},
+	}}
+	baidu := &fakeEngine{name: "baidu", err: errors.New("unavailable")}
+	ws := newFakeWebSearch(google, bing, baidu)
+
+	result, err := ws.Execute(context.Background(), map[string]interface{}{
+		"query": "golang", "aggregate": true, "engines": []interface{}{"google", "bing", "baidu"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 两个引擎都返回的结果排在最前，只出现一次，保留较长的摘要
+	for _, want := range []string{
+		"1. Go docs\n   URL: https://go.dev/doc/?utm_source=feed\n   a longer snippet from bing\n   Sources: google, bing",
+		"2. Go by Example\n   URL: https://gobyexample.com/\n   Sources: bing",
+		"3. Go blog\n   URL: https://go.dev/blog\n   Sources: google",
+		"Failed engines:\nbaidu: unavailable",
+	} {
+		if !strings.Contains(result.Output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, result.Output)
+		}
+	}
+	if strings.Contains(result.Output, "Documentation") || strings.Contains(result.Output, "4.") {
+		t.Errorf("duplicate URL was not merged:\n%s", result.Output)
+	}
+	if google.calls != 1 || bing.calls != 1 {
+		t.Errorf("google ran %d times and bing %d times, want 1 each", google.calls, bing.calls)
+	}
+}